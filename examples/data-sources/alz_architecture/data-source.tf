@@ -0,0 +1,48 @@
+# The architecture is read from a library file, e.g. `architecture_definition_alz_dev.json`.
+# The defaults are inherited by the descendants, and the policy assignment parameters apply to one management group:
+#
+# {
+#   "name": "alz_dev",
+#   "management_groups": [
+#     {
+#       "id": "alz-dev", "display_name": "Azure Landing Zones (dev)", "parent_id": null, "exists": false, "archetypes": ["root"],
+#       "defaults": { "location": "northeurope" }
+#     },
+#     {
+#       "id": "landingzones-dev", "display_name": "Landing zones (dev)", "parent_id": "alz-dev", "exists": false, "archetypes": ["landing_zones"],
+#       "policy_assignment_parameters": { "Enable-DDoS-VNET": { "effect": "Disabled" } }
+#     }
+#   ]
+# }
+data "azurerm_client_config" "current" {}
+
+data "alz_architecture" "example" {
+  name           = "alz_dev"
+  root_parent_id = data.azurerm_client_config.current.tenant_id
+}
+
+# Read each level after its parents, so that the parents are in the same deployment.
+data "alz_archetype" "level_0" {
+  for_each       = { for id, mg in data.alz_architecture.example.management_groups : id => mg if mg.level == 0 }
+  id             = each.key
+  display_name   = each.value.display_name
+  parent_id      = each.value.parent_id
+  base_archetype = one(each.value.archetypes)
+  defaults       = merge({ location = "westeurope" }, each.value.defaults)
+  policy_assignments_to_modify = {
+    for k, v in each.value.policy_assignment_parameters : k => { parameters = v }
+  }
+}
+
+data "alz_archetype" "level_1" {
+  for_each       = { for id, mg in data.alz_architecture.example.management_groups : id => mg if mg.level == 1 }
+  id             = each.key
+  display_name   = each.value.display_name
+  parent_id      = each.value.parent_id
+  base_archetype = one(each.value.archetypes)
+  defaults       = merge({ location = "westeurope" }, each.value.defaults)
+  policy_assignments_to_modify = {
+    for k, v in each.value.policy_assignment_parameters : k => { parameters = v }
+  }
+  depends_on = [data.alz_archetype.level_0]
+}
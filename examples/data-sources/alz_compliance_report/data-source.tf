@@ -0,0 +1,13 @@
+data "alz_compliance_report" "example" {
+  policy_exemptions = { for k, v in data.alz_policy_exemptions.example.alz_policy_exemptions : k => v.policy_exemption }
+
+  depends_on = [
+    data.alz_archetype.root,
+    data.alz_archetype.landing_zones,
+  ]
+}
+
+# The decoded report, e.g. for a run task or OPA policy.
+output "compliance_report" {
+  value = jsondecode(data.alz_compliance_report.example.report)
+}
@@ -0,0 +1,11 @@
+data "alz_deployment_waves" "example" {
+  depends_on = [
+    data.alz_archetype.root,
+    data.alz_archetype.landing_zones,
+  ]
+}
+
+# The policy assignments of the first wave that has any, e.g. for a resource with `for_each`.
+output "first_policy_assignment_wave" {
+  value = [for w in data.alz_deployment_waves.example.waves : w.policy_assignments if length(w.policy_assignments) > 0][0]
+}
@@ -0,0 +1,9 @@
+data "alz_hierarchy_import" "example" {
+  root_management_group_id = "contoso"
+  default_location         = "westeurope"
+}
+
+resource "local_file" "archetypes" {
+  filename = "${path.module}/archetypes.tf"
+  content  = data.alz_hierarchy_import.example.content
+}
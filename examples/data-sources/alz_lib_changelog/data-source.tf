@@ -0,0 +1,8 @@
+data "alz_lib_changelog" "example" {
+  from_ref = "platform/alz/2024.03.00"
+  to_ref   = "platform/alz/2024.03.01"
+}
+
+output "changelog" {
+  value = data.alz_lib_changelog.example.summary
+}
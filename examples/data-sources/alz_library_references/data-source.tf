@@ -0,0 +1,5 @@
+data "alz_library_references" "example" {}
+
+output "alz_lib_ref" {
+  value = data.alz_library_references.example.alz_lib_ref_resolved
+}
@@ -0,0 +1,8 @@
+data "alz_policy_definition_search" "example" {
+  category = "Monitoring"
+  effect   = "DeployIfNotExists"
+}
+
+output "monitoring_policy_definitions" {
+  value = data.alz_policy_definition_search.example.names
+}
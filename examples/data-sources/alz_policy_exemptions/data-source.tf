@@ -0,0 +1,18 @@
+data "alz_policy_exemptions" "example" {
+  subscription_tags = {
+    "00000000-0000-0000-0000-000000000000" = {
+      env = "sandbox"
+    }
+  }
+  rules = [
+    {
+      name                    = "sandbox-public-ip"
+      management_group_id     = data.alz_archetype.landing_zones.id
+      policy_assignment_names = ["Deny-Public-IP"]
+      tags = {
+        env = "sandbox"
+      }
+      description = "Sandbox subscriptions may use public IP addresses."
+    }
+  ]
+}
@@ -0,0 +1,10 @@
+data "alz_provider_info" "example" {}
+
+output "governance_provenance" {
+  value = {
+    provider_version = data.alz_provider_info.example.provider_version
+    alz_lib_ref      = data.alz_provider_info.example.alz_lib_ref_resolved
+    alz_lib_commit   = data.alz_provider_info.example.alz_lib_commit
+    libraries        = { for lib in data.alz_provider_info.example.libraries : lib.source => lib.sha256 }
+  }
+}
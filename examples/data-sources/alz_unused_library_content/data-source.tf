@@ -0,0 +1,6 @@
+data "alz_unused_library_content" "example" {
+  depends_on = [
+    data.alz_archetype.root,
+    data.alz_archetype.landing_zones,
+  ]
+}
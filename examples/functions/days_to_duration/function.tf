@@ -0,0 +1,7 @@
+# Set a retention parameter that expects an ISO 8601 duration from a number of days.
+locals {
+  retention_days = 90
+  policy_assignment_parameters = {
+    retentionPeriod = provider::alz::days_to_duration(local.retention_days)
+  }
+}
@@ -0,0 +1,10 @@
+# Set the enforcement mode of a generated policy assignment and remove one of its parameters.
+locals {
+  policy_assignment = provider::alz::jsonpatch(
+    data.alz_archetype.example.alz_policy_assignments["Deploy-MDFC-Config"],
+    jsonencode([
+      { op = "replace", path = "/properties/enforcementMode", value = "DoNotEnforce" },
+      { op = "remove", path = "/properties/parameters/emailSecurityContact" },
+    ])
+  )
+}
@@ -0,0 +1,10 @@
+# Write the schemas to files, for validation of `terraform output -json` by an external test suite.
+locals {
+  output_schemas = toset(["alz_archetype", "alz_policy_assignments", "alz_policy_definitions"])
+}
+
+resource "local_file" "output_schema" {
+  for_each = local.output_schemas
+  filename = "${path.module}/schemas/${data.alz_archetype.example.output_schema_version}/${each.key}.schema.json"
+  content  = provider::alz::output_schema(each.key)
+}
@@ -0,0 +1,5 @@
+# Convert a hand-written policy assignment into a library policy assignment file.
+resource "local_file" "policy_assignment" {
+  filename = "${path.module}/lib/policy_assignment_${azurerm_management_group_policy_assignment.example.name}.json"
+  content  = provider::alz::policy_assignment_to_library(jsonencode(azurerm_management_group_policy_assignment.example))
+}
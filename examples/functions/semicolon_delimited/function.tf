@@ -0,0 +1,7 @@
+# Set a parameter that expects a semicolon-delimited string from a list of locations.
+locals {
+  allowed_locations = ["uksouth", "ukwest"]
+  policy_assignment_parameters = {
+    listOfAllowedLocations = provider::alz::semicolon_delimited(local.allowed_locations)
+  }
+}
@@ -0,0 +1,10 @@
+# Generate a library with 2 archetypes, 100 policy definitions and 10 policy set definitions.
+locals {
+  synthetic_library = provider::alz::synthetic_library(2, 100, 10)
+}
+
+resource "local_file" "synthetic_library" {
+  for_each = local.synthetic_library
+  filename = "${path.module}/synthlib/${each.key}"
+  content  = each.value
+}
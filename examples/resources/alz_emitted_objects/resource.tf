@@ -0,0 +1,14 @@
+resource "alz_emitted_objects" "example" {
+  id = "corp"
+  objects = {
+    policy_assignments     = keys(data.alz_archetype.example.alz_policy_assignments)
+    policy_definitions     = keys(data.alz_archetype.example.alz_policy_definitions)
+    policy_set_definitions = keys(data.alz_archetype.example.alz_policy_set_definitions)
+    role_definitions       = keys(data.alz_archetype.example.alz_role_definitions)
+  }
+}
+
+# The policy assignments that are no longer emitted by the archetype, to delete explicitly.
+output "removed_policy_assignments" {
+  value = lookup(alz_emitted_objects.example.removed_objects, "policy_assignments", [])
+}
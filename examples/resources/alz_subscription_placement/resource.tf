@@ -0,0 +1,22 @@
+resource "alz_subscription_placement" "example" {
+  id = "alz-root"
+  management_groups = {
+    corp   = ["00000000-0000-0000-0000-000000000001"]
+    online = ["00000000-0000-0000-0000-000000000002"]
+  }
+}
+
+# Move the subscriptions after the management groups they move into have been created.
+resource "azurerm_management_group_subscription_association" "example" {
+  for_each = merge([
+    for mg, subs in alz_subscription_placement.example.management_groups : {
+      for sub in subs : sub => mg
+    }
+  ]...)
+  management_group_id = "/providers/Microsoft.Management/managementGroups/${each.value}"
+  subscription_id     = "/subscriptions/${each.key}"
+}
+
+output "subscription_moves" {
+  value = alz_subscription_placement.example.moves
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package alztypes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const (
+	// EnforcementModeDefault is the policy assignment enforcement mode used by ARM when none is set.
+	EnforcementModeDefault = "Default"
+	// EnforcementModeDoNotEnforce is the policy assignment enforcement mode that disables policy effects.
+	EnforcementModeDoNotEnforce = "DoNotEnforce"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringTypable = EnforcementModeType{}
+
+// EnforcementModeType is a string type for policy assignment enforcement modes.
+// A null or empty value is treated as `Default`, the value that ARM returns when the enforcement mode is omitted.
+type EnforcementModeType struct {
+	basetypes.StringType
+}
+
+func (t EnforcementModeType) Equal(o attr.Type) bool {
+	other, ok := o.(EnforcementModeType)
+
+	if !ok {
+		return false
+	}
+
+	return t.StringType.Equal(other.StringType)
+}
+
+func (t EnforcementModeType) String() string {
+	return "EnforcementModeType"
+}
+
+func (t EnforcementModeType) ValueFromString(ctx context.Context, in basetypes.StringValue) (basetypes.StringValuable, diag.Diagnostics) {
+	value := EnforcementModeValue{
+		StringValue: in,
+	}
+
+	return value, nil
+}
+
+func (t EnforcementModeType) ValueFromTerraform(ctx context.Context, in tftypes.Value) (attr.Value, error) {
+	attrValue, err := t.StringType.ValueFromTerraform(ctx, in)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stringValue, ok := attrValue.(basetypes.StringValue)
+
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type of %T", attrValue)
+	}
+
+	stringValuable, diags := t.ValueFromString(ctx, stringValue)
+
+	if diags.HasError() {
+		return nil, fmt.Errorf("unexpected error converting StringValue to StringValuable: %v", diags)
+	}
+
+	return stringValuable, nil
+}
+
+func (t EnforcementModeType) ValueType(ctx context.Context) attr.Value {
+	return EnforcementModeValue{}
+}
+
+// Validate checks that the value is one of the enforcement modes supported by ARM, or empty, which is treated as `Default`.
+func (t EnforcementModeType) Validate(ctx context.Context, value tftypes.Value, valuePath path.Path) diag.Diagnostics {
+	if value.IsNull() || !value.IsKnown() {
+		return nil
+	}
+
+	var diags diag.Diagnostics
+	var valueString string
+
+	if err := value.As(&valueString); err != nil {
+		diags.AddAttributeError(
+			valuePath,
+			"Invalid Terraform Value",
+			"An unexpected error occurred while attempting to convert a Terraform value to a string. "+
+				"This generally is an issue with the provider schema implementation. "+
+				"Please contact the provider developers.\n\n"+
+				"Path: "+valuePath.String()+"\n"+
+				"Error: "+err.Error(),
+		)
+
+		return diags
+	}
+
+	switch valueString {
+	case "", EnforcementModeDefault, EnforcementModeDoNotEnforce:
+		return diags
+	}
+
+	diags.AddAttributeError(
+		valuePath,
+		"Invalid enforcement mode",
+		"The enforcement mode must be one of `"+EnforcementModeDefault+"` or `"+EnforcementModeDoNotEnforce+"`, or empty.\n\n"+
+			"Path: "+valuePath.String()+"\n"+
+			"Given Value: "+valueString,
+	)
+
+	return diags
+}
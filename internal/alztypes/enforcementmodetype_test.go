@@ -0,0 +1,37 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package alztypes_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnforcementModeTypeValidate(t *testing.T) {
+	var emt alztypes.EnforcementModeType
+	ctx := context.Background()
+	p := path.Root("enforcement_mode")
+
+	assert.False(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, "Default"), p).HasError())
+	assert.False(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, "DoNotEnforce"), p).HasError())
+	assert.False(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, ""), p).HasError(), "an empty value is treated as Default")
+	assert.False(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, nil), p).HasError())
+	assert.False(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, tftypes.UnknownValue), p).HasError())
+	assert.True(t, emt.Validate(ctx, tftypes.NewValue(tftypes.String, "doNotEnforce"), p).HasError())
+}
+
+func TestEnforcementModeTypeValueFromTerraform(t *testing.T) {
+	var emt alztypes.EnforcementModeType
+	ctx := context.Background()
+
+	v, err := emt.ValueFromTerraform(ctx, tftypes.NewValue(tftypes.String, "DoNotEnforce"))
+	assert.NoError(t, err)
+	assert.Equal(t, alztypes.EnforcementModeValue{StringValue: basetypes.NewStringValue("DoNotEnforce")}, v)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package alztypes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringValuable = EnforcementModeValue{}
+
+type EnforcementModeValue struct {
+	basetypes.StringValue
+}
+
+// NewEnforcementModeValue returns a known EnforcementModeValue.
+func NewEnforcementModeValue(s string) EnforcementModeValue {
+	return EnforcementModeValue{
+		StringValue: basetypes.NewStringValue(s),
+	}
+}
+
+// NewEnforcementModeNull returns a null EnforcementModeValue.
+func NewEnforcementModeNull() EnforcementModeValue {
+	return EnforcementModeValue{
+		StringValue: basetypes.NewStringNull(),
+	}
+}
+
+// ValueEnforcementMode returns the enforcement mode, using `Default` if the value is null, unknown or empty.
+func (v EnforcementModeValue) ValueEnforcementMode() string {
+	if s := v.StringValue.ValueString(); s != "" {
+		return s
+	}
+	return EnforcementModeDefault
+}
+
+func (v EnforcementModeValue) Equal(o attr.Value) bool {
+	other, ok := o.(EnforcementModeValue)
+
+	if !ok {
+		return false
+	}
+
+	return v.StringValue.Equal(other.StringValue)
+}
+
+func (v EnforcementModeValue) Type(ctx context.Context) attr.Type {
+	return EnforcementModeType{}
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ basetypes.StringValuableWithSemanticEquals = EnforcementModeValue{}
+
+// StringSemanticEquals returns true if both values resolve to the same enforcement mode,
+// so an empty value is equal to an explicit `Default`.
+func (v EnforcementModeValue) StringSemanticEquals(ctx context.Context, newValuable basetypes.StringValuable) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// The framework should always pass the correct value type, but always check.
+	newValue, ok := newValuable.(EnforcementModeValue)
+
+	if !ok {
+		diags.AddError(
+			"Semantic Equality Check Error",
+			"An unexpected value type was received while performing semantic equality checks. "+
+				"Please report this to the provider developers.\n\n"+
+				"Expected Value Type: "+fmt.Sprintf("%T", v)+"\n"+
+				"Got Value Type: "+fmt.Sprintf("%T", newValuable),
+		)
+
+		return false, diags
+	}
+
+	return v.ValueEnforcementMode() == newValue.ValueEnforcementMode(), diags
+}
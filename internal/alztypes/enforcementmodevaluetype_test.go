@@ -0,0 +1,34 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package alztypes_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnforcementModeValueEnforcementMode(t *testing.T) {
+	assert.Equal(t, "Default", alztypes.NewEnforcementModeNull().ValueEnforcementMode())
+	assert.Equal(t, "Default", alztypes.NewEnforcementModeValue("").ValueEnforcementMode())
+	assert.Equal(t, "DoNotEnforce", alztypes.NewEnforcementModeValue("DoNotEnforce").ValueEnforcementMode())
+}
+
+func TestEnforcementModeSemanticEquals(t *testing.T) {
+	ctx := context.Background()
+
+	equal, diags := alztypes.NewEnforcementModeValue("").StringSemanticEquals(ctx, alztypes.NewEnforcementModeValue("Default"))
+	assert.False(t, diags.HasError())
+	assert.True(t, equal)
+
+	equal, diags = alztypes.NewEnforcementModeValue("Default").StringSemanticEquals(ctx, alztypes.NewEnforcementModeValue("DoNotEnforce"))
+	assert.False(t, diags.HasError())
+	assert.False(t, equal)
+
+	_, diags = alztypes.NewEnforcementModeValue("Default").StringSemanticEquals(ctx, basetypes.NewStringValue("Default"))
+	assert.True(t, diags.HasError())
+}
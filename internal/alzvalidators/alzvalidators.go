@@ -15,15 +15,27 @@ import (
 
 var _ validator.String = armPolicyDefinitionValidator{}
 
-// armPolicyDefinitionValidator validates that a string Attribute's value matches the specified regular expression.
+// armPolicyDefinitionValidator validates that a string Attribute's value is the ARM resource id of one of the resource types.
 type armPolicyDefinitionValidator struct {
+	types []armResourceType
+}
+
+// armResourceType is a resource type in a resource provider namespace, e.g. `virtualNetworks/subnets` in `Microsoft.Network`.
+type armResourceType struct {
 	armtype   string
 	namespace string
 }
 
 // Description describes the validation in plain text formatting.
 func (validator armPolicyDefinitionValidator) Description(_ context.Context) string {
-	return fmt.Sprintf("value must be ARM resource id in namespace '%s', of type, '%s'", validator.namespace, validator.armtype)
+	if len(validator.types) == 1 {
+		return fmt.Sprintf("value must be ARM resource id in namespace '%s', of type, '%s'", validator.types[0].namespace, validator.types[0].armtype)
+	}
+	types := make([]string, len(validator.types))
+	for i, t := range validator.types {
+		types[i] = fmt.Sprintf("'%s/%s'", t.namespace, t.armtype)
+	}
+	return fmt.Sprintf("value must be ARM resource id of one of the types %s", strings.Join(types, ", "))
 }
 
 // MarkdownDescription describes the validation in Markdown formatting.
@@ -39,13 +51,18 @@ func (v armPolicyDefinitionValidator) ValidateString(ctx context.Context, reques
 
 	value := request.ConfigValue.ValueString()
 	rt, err := arm.ParseResourceType(value)
-	if err != nil || !strings.EqualFold(rt.Namespace, v.namespace) || !strings.EqualFold(rt.Type, v.armtype) {
-		response.Diagnostics.Append(validatordiag.InvalidAttributeValueMatchDiagnostic(
-			request.Path,
-			v.Description(ctx),
-			value,
-		))
+	if err == nil {
+		for _, t := range v.types {
+			if strings.EqualFold(rt.Namespace, t.namespace) && strings.EqualFold(rt.Type, t.armtype) {
+				return
+			}
+		}
 	}
+	response.Diagnostics.Append(validatordiag.InvalidAttributeValueMatchDiagnostic(
+		request.Path,
+		v.Description(ctx),
+		value,
+	))
 }
 
 // ArmTypeResourceId returns an AttributeValidator which ensures that any configured
@@ -57,7 +74,25 @@ func (v armPolicyDefinitionValidator) ValidateString(ctx context.Context, reques
 // Null (unconfigured) and unknown (known after apply) values are skipped.
 func ArmTypeResourceId(ns, t string) validator.String {
 	return armPolicyDefinitionValidator{
-		armtype:   t,
-		namespace: ns,
+		types: []armResourceType{{armtype: t, namespace: ns}},
+	}
+}
+
+// ArmTypeResourceIdOneOf returns an AttributeValidator which ensures that any configured
+// attribute value:
+//
+//   - Is a valid ARM resource id
+//   - Matches one of the given resource types, each the namespace and type joined by a slash,
+//     e.g. `Microsoft.EventHub/namespaces/authorizationRules`
+//
+// Null (unconfigured) and unknown (known after apply) values are skipped.
+func ArmTypeResourceIdOneOf(resourceTypes ...string) validator.String {
+	types := make([]armResourceType, len(resourceTypes))
+	for i, rt := range resourceTypes {
+		ns, t, _ := strings.Cut(rt, "/")
+		types[i] = armResourceType{armtype: t, namespace: ns}
+	}
+	return armPolicyDefinitionValidator{
+		types: types,
 	}
 }
@@ -53,6 +53,55 @@ func TestArmTypeResourceIds(t *testing.T) {
 				"resourceGroups",
 			),
 		},
+		"type-mismatch": {
+			rid: types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo"),
+			validator: alzvalidators.ArmTypeResourceId(
+				"Microsoft.OperationalInsights",
+				"workspaces",
+			),
+			expErrors: 1,
+		},
+		"invalid-id": {
+			rid:       types.StringValue("foo"),
+			validator: alzvalidators.ArmTypeResourceId("Microsoft.Resources", "resourceGroups"),
+			expErrors: 1,
+		},
+		"one-of-match": {
+			rid: types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.EventHub/namespaces/bar/eventhubs/baz/authorizationRules/qux"),
+			validator: alzvalidators.ArmTypeResourceIdOneOf(
+				"Microsoft.EventHub/namespaces/authorizationRules",
+				"Microsoft.EventHub/namespaces/eventhubs/authorizationRules",
+			),
+		},
+		"one-of-mismatch": {
+			rid: types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.EventHub/namespaces/bar"),
+			validator: alzvalidators.ArmTypeResourceIdOneOf(
+				"Microsoft.EventHub/namespaces/authorizationRules",
+				"Microsoft.EventHub/namespaces/eventhubs/authorizationRules",
+			),
+			expErrors: 1,
+		},
+		"dcr-match": {
+			rid:       types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.Insights/dataCollectionRules/bar"),
+			validator: alzvalidators.DataCollectionRuleId(),
+		},
+		"automation-account-match": {
+			rid:       types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.Automation/automationAccounts/bar"),
+			validator: alzvalidators.AutomationAccountId(),
+		},
+		"uami-mismatch": {
+			rid:       types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.Automation/automationAccounts/bar"),
+			validator: alzvalidators.UserAssignedIdentityId(),
+			expErrors: 1,
+		},
+		"la-table-match": {
+			rid:       types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.OperationalInsights/workspaces/bar/tables/baz"),
+			validator: alzvalidators.LogAnalyticsTableId(),
+		},
+		"la-solution-match": {
+			rid:       types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/foo/providers/Microsoft.OperationsManagement/solutions/bar"),
+			validator: alzvalidators.LogAnalyticsSolutionId(),
+		},
 	}
 
 	for name, test := range testCases {
@@ -79,3 +128,17 @@ func TestArmTypeResourceIds(t *testing.T) {
 		})
 	}
 }
+
+func TestArmTypeResourceIdDescription(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if got, want := alzvalidators.ArmTypeResourceId("Microsoft.Insights", "dataCollectionRules").Description(ctx),
+		"value must be ARM resource id in namespace 'Microsoft.Insights', of type, 'dataCollectionRules'"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got, want := alzvalidators.EventHubAuthorizationRuleId().Description(ctx),
+		"value must be ARM resource id of one of the types 'Microsoft.EventHub/namespaces/authorizationRules', 'Microsoft.EventHub/namespaces/eventhubs/authorizationRules'"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
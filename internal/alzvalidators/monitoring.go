@@ -0,0 +1,50 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package alzvalidators
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// The validators of the monitoring resource ids that are used as parameters of the ALZ policies.
+// Null (unconfigured) and unknown (known after apply) values are skipped.
+
+// AutomationAccountId validates an Azure Automation account resource id.
+func AutomationAccountId() validator.String {
+	return ArmTypeResourceId("Microsoft.Automation", "automationAccounts")
+}
+
+// DataCollectionRuleId validates an Azure Monitor data collection rule resource id.
+func DataCollectionRuleId() validator.String {
+	return ArmTypeResourceId("Microsoft.Insights", "dataCollectionRules")
+}
+
+// EventHubAuthorizationRuleId validates an Event Hubs authorization rule resource id,
+// of either a namespace or an event hub.
+func EventHubAuthorizationRuleId() validator.String {
+	return ArmTypeResourceIdOneOf(
+		"Microsoft.EventHub/namespaces/authorizationRules",
+		"Microsoft.EventHub/namespaces/eventhubs/authorizationRules",
+	)
+}
+
+// LogAnalyticsSolutionId validates a Log Analytics solution resource id.
+func LogAnalyticsSolutionId() validator.String {
+	return ArmTypeResourceId("Microsoft.OperationsManagement", "solutions")
+}
+
+// LogAnalyticsTableId validates a Log Analytics workspace table resource id.
+func LogAnalyticsTableId() validator.String {
+	return ArmTypeResourceId("Microsoft.OperationalInsights", "workspaces/tables")
+}
+
+// LogAnalyticsWorkspaceId validates a Log Analytics workspace resource id.
+func LogAnalyticsWorkspaceId() validator.String {
+	return ArmTypeResourceId("Microsoft.OperationalInsights", "workspaces")
+}
+
+// UserAssignedIdentityId validates a user assigned managed identity resource id.
+func UserAssignedIdentityId() validator.String {
+	return ArmTypeResourceId("Microsoft.ManagedIdentity", "userAssignedIdentities")
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	azblobScope   = "https://storage.azure.com/.default"
+	azblobVersion = "2021-08-06"
+)
+
+var _ LibraryFetcher = azblobFetcher{}
+
+// azblobFetcher downloads a library from Azure blob storage.
+// The source is either a library archive, e.g. `azblob::https://myaccount.blob.core.windows.net/libs/alz.tar.gz`,
+// or a container or a prefix in a container ending with `/`, e.g. `azblob::https://myaccount.blob.core.windows.net/libs/alz/2024.07.0/`,
+// in which case every blob under the prefix is downloaded as a file of the library.
+// If the URL has a SAS token it is used as is, otherwise the request is authorized with a Microsoft Entra ID token from the options credential.
+// An archive must be in a format that go-getter can decompress, e.g. `.zip` or `.tar.gz`.
+type azblobFetcher struct{}
+
+// Fetch downloads the blobs under the prefix, or downloads and decompresses the archive, into dst.
+func (azblobFetcher) Fetch(ctx context.Context, src, dst string, opts *Options) (fs.FS, error) {
+	rawUrl := strings.TrimPrefix(src, "azblob::")
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Scheme != "https" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("azblob source %s must be in the form azblob::https://account.blob.core.windows.net/container/blob or azblob::https://account.blob.core.windows.net/container/prefix/", src)
+	}
+	client := opts.httpClient()
+	if !u.Query().Has("sig") {
+		if opts.Credential == nil {
+			return nil, errors.New("azblob source has no SAS token and no credential is configured")
+		}
+		cpy := *client
+		cpy.Transport = &azblobTransport{
+			base:       client.Transport,
+			credential: opts.Credential,
+			host:       u.Host,
+		}
+		client = &cpy
+	}
+	if container, prefix, ok := azblobContainerPrefix(u); ok {
+		return fetchAzblobContainer(ctx, client, u, container, prefix, dst)
+	}
+	archiveOpts := *opts
+	archiveOpts.HttpClient = client
+	return newGetterFetcher(httpGetters()...).Fetch(ctx, rawUrl, dst, &archiveOpts)
+}
+
+// azblobTransport adds a bearer token and the storage API version to the requests to the storage account host.
+// Requests to other hosts, e.g. redirects, are sent without the token.
+type azblobTransport struct {
+	base       http.RoundTripper
+	credential azcore.TokenCredential
+	host       string
+}
+
+func (t *azblobTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !strings.EqualFold(req.URL.Host, t.host) {
+		return base.RoundTrip(req)
+	}
+	tok, err := t.credential.GetToken(req.Context(), policy.TokenRequestOptions{Scopes: []string{azblobScope}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get a token for azure storage: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok.Token)
+	req.Header.Set("x-ms-version", azblobVersion)
+	return base.RoundTrip(req)
+}
+
+// azblobContainerPrefix returns the container and blob name prefix of the URL, if it is a container or ends with `/`.
+func azblobContainerPrefix(u *url.URL) (string, string, bool) {
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		return "", "", false
+	}
+	return container, prefix, true
+}
+
+// azblobListResult is the response body of the List Blobs operation.
+type azblobListResult struct {
+	Blobs []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// fetchAzblobContainer downloads the blobs in the container with names starting with the prefix into dst, removing any existing content first.
+// The file names are the blob names without the prefix. The query of the URL, e.g. a SAS token, is sent with every request.
+func fetchAzblobContainer(ctx context.Context, client *http.Client, u *url.URL, container, prefix, dst string) (fs.FS, error) {
+	if err := os.RemoveAll(dst); err != nil {
+		return nil, fmt.Errorf("failed to remove existing directory %s: %w", dst, err)
+	}
+	names, err := listAzblobs(ctx, client, u, container, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no blobs found in container %s with prefix %q", container, prefix)
+	}
+	for _, name := range names {
+		rel := strings.TrimPrefix(name, prefix)
+		if strings.HasSuffix(rel, "/") {
+			// Directory markers of accounts with a hierarchical namespace.
+			continue
+		}
+		if !fs.ValidPath(rel) {
+			return nil, fmt.Errorf("blob %s is not a valid library file name", name)
+		}
+		blobUrl := *u
+		blobUrl.Path = "/" + container + "/" + name
+		blobUrl.RawPath = ""
+		if err := downloadAzblob(ctx, client, blobUrl.String(), filepath.Join(dst, filepath.FromSlash(rel))); err != nil {
+			return nil, err
+		}
+	}
+	return os.DirFS(dst), nil
+}
+
+// listAzblobs returns the names of the blobs in the container that start with the prefix, following the continuation markers.
+func listAzblobs(ctx context.Context, client *http.Client, u *url.URL, container, prefix string) ([]string, error) {
+	var res []string
+	marker := ""
+	for {
+		listUrl := *u
+		listUrl.Path = "/" + container
+		listUrl.RawPath = ""
+		q := listUrl.Query()
+		q.Set("restype", "container")
+		q.Set("comp", "list")
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		listUrl.RawQuery = q.Encode()
+		body, err := azblobGet(ctx, client, listUrl.String())
+		if err != nil {
+			return nil, fmt.Errorf("unable to list blobs in container %s: %w", container, err)
+		}
+		var page azblobListResult
+		if err := xml.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("unable to parse blob list of container %s: %w", container, err)
+		}
+		for _, b := range page.Blobs {
+			res = append(res, b.Name)
+		}
+		if page.NextMarker == "" {
+			return res, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// downloadAzblob writes the blob to the file, creating the parent directories.
+func downloadAzblob(ctx context.Context, client *http.Client, blobUrl, name string) error {
+	body, err := azblobGet(ctx, client, blobUrl)
+	if err != nil {
+		return fmt.Errorf("unable to download blob %s: %w", filepath.Base(name), err)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, body, 0644)
+}
+
+// azblobGet returns the body of a successful GET request.
+func azblobGet(ctx context.Context, client *http.Client, rawUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,158 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// cacheVersionRetention is how long a superseded version of a cached library is kept, so that runs that are still reading it are not affected.
+const cacheVersionRetention = time.Hour
+
+// Cache is an on-disk cache of remote libraries, so that a library is downloaded once per machine rather than on every run.
+// Each download of a library is stored in its own version directory, named after the SHA-256 of its source,
+// and a metadata file next to them names the current version. Local directories are not cached.
+type Cache struct {
+	Dir     string        // Dir is the cache directory, which is created if it does not exist
+	TTL     time.Duration // TTL is how long a cached library is used before it is downloaded again, zero means it never expires
+	Refresh bool          // Refresh downloads every library, replacing the cached copy
+	Offline bool          // Offline uses the cached copy regardless of TTL and Refresh, and never downloads a library
+
+	now func() time.Time
+}
+
+// cacheEntry is the metadata of a cached library.
+type cacheEntry struct {
+	Source     string               `json:"source"`  // the source, with its credentials redacted
+	Version    string               `json:"version"` // the name of the version directory
+	FetchedAt  time.Time            `json:"fetched_at"`
+	Superseded map[string]time.Time `json:"superseded,omitempty"` // the superseded version directories and when they were superseded
+}
+
+// Fetch returns the cached library for the source if it has not expired, otherwise it fetches the library into the cache.
+// The library is fetched into a new version directory, and the metadata file is replaced to name it once the download has finished,
+// so that a failed download does not remove the cached copy and concurrent runs never see a partial or removed library.
+// Superseded versions are removed by a later download, once they were superseded longer than cacheVersionRetention ago.
+func (c *Cache) Fetch(ctx context.Context, src string, opts *Options) (fs.FS, error) {
+	if Scheme(src) == "file" {
+		return Fetch(ctx, src, "", opts)
+	}
+	redactedSrc := RedactSource(src)
+	sum := sha256.Sum256([]byte(src))
+	key := hex.EncodeToString(sum[:])
+	meta := filepath.Join(c.Dir, key+".json")
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	if c.Offline || !c.Refresh {
+		if entry, ok := readCacheEntry(meta); ok && entry.Source == redactedSrc && entry.Version != "" && (c.Offline || c.TTL == 0 || now().Sub(entry.FetchedAt) < c.TTL) {
+			dir := filepath.Join(c.Dir, entry.Version)
+			if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+				return os.DirFS(dir), nil
+			}
+		}
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("the library %s is not in the cache %s and cannot be downloaded offline", redactedSrc, c.Dir)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", c.Dir, err)
+	}
+	dir, err := os.MkdirTemp(c.Dir, key+".v-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create version directory in %s: %w", c.Dir, err)
+	}
+	if _, err := Fetch(ctx, src, dir, opts); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	prev, _ := readCacheEntry(meta)
+	superseded, expired := c.supersededVersions(key, filepath.Base(dir), prev, now().UTC())
+	b, err := json.Marshal(cacheEntry{Source: redactedSrc, Version: filepath.Base(dir), FetchedAt: now().UTC(), Superseded: superseded})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := writeCacheEntry(c.Dir, meta, b); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to write cache metadata %s: %w", meta, err)
+	}
+	for _, v := range expired {
+		os.RemoveAll(filepath.Join(c.Dir, v))
+	}
+	return os.DirFS(dir), nil
+}
+
+// supersededVersions returns the version directories of the library other than the current version, with the time they were superseded,
+// and the versions that were superseded longer than cacheVersionRetention ago, which are removed.
+// The previous version is superseded now, and a version directory not named in the previous metadata,
+// e.g. one written by a concurrent run, is treated as superseded now, so the retention never counts from the download time.
+func (c *Cache) supersededVersions(key, current string, prev cacheEntry, now time.Time) (map[string]time.Time, []string) {
+	dirs, err := filepath.Glob(filepath.Join(c.Dir, key+".v-*"))
+	if err != nil {
+		return prev.Superseded, nil
+	}
+	res := make(map[string]time.Time)
+	var expired []string
+	for _, d := range dirs {
+		v := filepath.Base(d)
+		if v == current {
+			continue
+		}
+		at, ok := prev.Superseded[v]
+		if !ok {
+			at = now
+		}
+		if now.Sub(at) >= cacheVersionRetention {
+			expired = append(expired, v)
+			continue
+		}
+		res[v] = at
+	}
+	slices.Sort(expired)
+	return res, expired
+}
+
+// writeCacheEntry writes the metadata to a temporary file first, and renames it over the metadata file,
+// so that concurrent runs read either the previous or the new metadata.
+func writeCacheEntry(dir, name string, b []byte) error {
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// readCacheEntry reads the metadata of a cached library.
+func readCacheEntry(name string) (cacheEntry, bool) {
+	var res cacheEntry
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return res, false
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return res, false
+	}
+	return res, true
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hashicorp/go-getter/v2"
+)
+
+var _ LibraryFetcher = getterFetcher{}
+
+// getterFetcher uses go-getter to download a library.
+// It is used for git repositories, HTTP(S) archives, sources without a scheme and sources with a scheme that has no other fetcher,
+// e.g. Mercurial and SMB sources.
+type getterFetcher struct {
+	getters []getter.Getter
+}
+
+func newGetterFetcher(getters ...getter.Getter) getterFetcher {
+	return getterFetcher{getters: getters}
+}
+
+// Fetch downloads the source into dst, removing any existing content first.
+func (f getterFetcher) Fetch(ctx context.Context, src, dst string, opts *Options) (fs.FS, error) {
+	if _, err := os.Stat(dst); err == nil {
+		if err := os.RemoveAll(dst); err != nil {
+			return nil, fmt.Errorf("failed to remove existing directory %s: %w", dst, err)
+		}
+	}
+	client := &getter.Client{
+		Getters: withGitEnv(withHttpClient(f.getters, opts), opts.gitEnv()),
+	}
+	req := &getter.Request{
+		Src: src,
+		Dst: dst,
+		Pwd: opts.Pwd,
+	}
+	if _, err := client.Get(ctx, req); err != nil {
+		return nil, redactSourceError(err, src)
+	}
+	return os.DirFS(dst), nil
+}
+
+// withHttpClient returns a copy of the getters with any http getter configured to use the http client from the options.
+func withHttpClient(getters []getter.Getter, opts *Options) []getter.Getter {
+	res := make([]getter.Getter, 0, len(getters))
+	for _, g := range getters {
+		if hg, ok := g.(*getter.HttpGetter); ok {
+			cpy := *hg
+			cpy.Client = opts.httpClient()
+			g = &cpy
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+// gitGetters returns the go-getter getters used for git sources.
+func gitGetters() []getter.Getter {
+	res := make([]getter.Getter, 0, 1)
+	for _, g := range getter.Getters {
+		if _, ok := g.(*getter.GitGetter); ok {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// httpGetters returns the go-getter getters used for HTTP(S) sources.
+// Archives, e.g. `.zip` files, are decompressed by go-getter.
+func httpGetters() []getter.Getter {
+	res := make([]getter.Getter, 0, 1)
+	for _, g := range getter.Getters {
+		if _, ok := g.(*getter.HttpGetter); ok {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// defaultGetters returns all go-getter getters, for sources where go-getter detects the protocol.
+func defaultGetters() []getter.Getter {
+	return getter.Getters
+}
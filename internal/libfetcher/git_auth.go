@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+)
+
+// gitEnv returns the environment variables that configure git with the authentication, proxy and CA certificates in the options.
+// The HTTP headers are set with `GIT_CONFIG_*` variables as `http.<url>.extraHeader`, so that each header is only sent to its URL prefix,
+// the SSH key is set with `GIT_SSH_COMMAND`, the proxy with `http_proxy` and `https_proxy`, and the CA certificates with `GIT_SSL_CAINFO`.
+func (o *Options) gitEnv() map[string]string {
+	res := make(map[string]string)
+	if o == nil {
+		return res
+	}
+	if len(o.GitHeaders) != 0 {
+		prefixes := make([]string, 0, len(o.GitHeaders))
+		for p := range o.GitHeaders {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		res["GIT_CONFIG_COUNT"] = strconv.Itoa(len(prefixes))
+		for i, p := range prefixes {
+			res["GIT_CONFIG_KEY_"+strconv.Itoa(i)] = fmt.Sprintf("http.%s.extraHeader", p)
+			res["GIT_CONFIG_VALUE_"+strconv.Itoa(i)] = o.GitHeaders[p]
+		}
+	}
+	if o.GitSshKeyPath != "" {
+		res["GIT_SSH_COMMAND"] = fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes", strconv.Quote(o.GitSshKeyPath))
+	}
+	if o.GitProxyUrl != "" {
+		res["http_proxy"] = o.GitProxyUrl
+		res["https_proxy"] = o.GitProxyUrl
+	}
+	if o.GitCaCertsPath != "" {
+		res["GIT_SSL_CAINFO"] = o.GitCaCertsPath
+	}
+	return res
+}
+
+// GitEnviron returns the environment of the process with the git environment variables of the options,
+// for running git commands other than downloads, e.g. `git ls-remote`.
+func (o *Options) GitEnviron() []string {
+	return gitEnviron(o.gitEnv())
+}
+
+// gitEnviron returns the environment of the process with the variables in env replacing those of the process.
+func gitEnviron(env map[string]string) []string {
+	res := make([]string, 0, len(os.Environ())+len(env))
+	for _, kv := range os.Environ() {
+		k, _, _ := strings.Cut(kv, "=")
+		if _, ok := env[k]; !ok {
+			res = append(res, kv)
+		}
+	}
+	for k, v := range env {
+		res = append(res, k+"="+v)
+	}
+	return res
+}
+
+// withGitEnv returns a copy of the getters with any git getter replaced by one that runs git with the environment variables.
+// The getters are returned unchanged if there are no variables.
+func withGitEnv(getters []getter.Getter, env map[string]string) []getter.Getter {
+	if len(env) == 0 {
+		return getters
+	}
+	res := make([]getter.Getter, 0, len(getters))
+	for _, g := range getters {
+		if gg, ok := g.(*getter.GitGetter); ok {
+			g = &authGitGetter{GitGetter: gg, env: env}
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+// authGitGetter clones a git repository as the go-getter git getter does, with the environment variables set on the git commands only.
+// go-getter runs git with the environment of the process, which would expose the credentials to concurrent fetches and to the rest of the provider.
+type authGitGetter struct {
+	*getter.GitGetter
+	env map[string]string
+}
+
+func (g *authGitGetter) Get(ctx context.Context, req *getter.Request) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return errors.New("git must be available and on the PATH")
+	}
+	if _, err := os.Stat(req.Dst); err == nil {
+		return fmt.Errorf("the destination %s already exists", req.Dst)
+	}
+	u := *req.URL()
+	q := u.Query()
+	if q.Has("sshkey") {
+		return errors.New("the sshkey parameter cannot be used with the git authentication of the provider, use the git SSH key setting instead")
+	}
+	ref := q.Get("ref")
+	var depth int
+	if v := q.Get("depth"); v != "" {
+		var err error
+		if depth, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("the depth parameter must be a number: %w", err)
+		}
+	}
+	q.Del("ref")
+	q.Del("depth")
+	u.RawQuery = q.Encode()
+
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+
+	// A shallow clone only has the tip of the ref, so the ref is cloned as a branch or tag as go-getter does,
+	// rather than checked out after the clone.
+	args := []string{"clone"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+	}
+	if err := g.run(ctx, "", append(args, "--", u.String(), req.Dst)...); err != nil {
+		return err
+	}
+	if depth <= 0 && ref != "" {
+		if err := g.run(ctx, req.Dst, "checkout", ref); err != nil {
+			return err
+		}
+	}
+	args = []string{"submodule", "update", "--init", "--recursive"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	return g.run(ctx, req.Dst, args...)
+}
+
+// run runs git in the directory, with the environment of the process and the environment variables of the getter.
+// The output is returned in the error if git fails.
+func (g *authGitGetter) run(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitEnviron(g.env)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package libfetcher downloads ALZ libraries from local directories and remote sources.
+// Fetchers are selected by the scheme of the library source, and forks can add new sources
+// by registering a LibraryFetcher for a scheme with Register.
+package libfetcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// SchemeDefault is the scheme used for sources that do not declare a scheme and are not local paths,
+// e.g. `github.com/Azure/Azure-Landing-Zones-Library//platform/alz`.
+const SchemeDefault = ""
+
+// forcedRegexp matches the go-getter forced getter syntax, e.g. `git::https://example.com/repo.git`.
+var forcedRegexp = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// LibraryFetcher fetches a library from a source and returns it as an fs.FS.
+type LibraryFetcher interface {
+	// Fetch fetches the library at src, using dst as the download directory if required.
+	Fetch(ctx context.Context, src, dst string, opts *Options) (fs.FS, error)
+}
+
+// Options are the options supplied to a LibraryFetcher.
+type Options struct {
+	HttpClient *http.Client           // HttpClient is used for all HTTP requests, if nil http.DefaultClient is used
+	Pwd        string                 // Pwd is the working directory used to resolve relative paths
+	Credential azcore.TokenCredential // Credential is used to authorize requests to Azure storage, if nil only SAS URLs can be used
+	// GitHeaders are extra HTTP headers sent by git, keyed by URL prefix, e.g. `https://dev.azure.com/`.
+	// Use them to authenticate to private repositories without adding credentials to the source URL.
+	GitHeaders map[string]string
+	// GitSshKeyPath is the path to the private key used by git for SSH sources.
+	GitSshKeyPath string
+	// GitProxyUrl is the proxy used by git for HTTP and HTTPS sources, as git does not use the HttpClient.
+	GitProxyUrl string
+	// GitCaCertsPath is the path to the PEM encoded CA certificates used by git to verify HTTPS sources.
+	GitCaCertsPath string
+}
+
+var (
+	registry   = make(map[string]LibraryFetcher)
+	registryMu sync.RWMutex
+)
+
+func init() {
+	Register("azblob", azblobFetcher{})
+	Register("file", localFetcher{})
+	Register("git", newGetterFetcher(gitGetters()...))
+	Register("ssh", newGetterFetcher(gitGetters()...))
+	Register("http", newGetterFetcher(httpGetters()...))
+	Register("https", newGetterFetcher(httpGetters()...))
+	Register("oci", ociFetcher{})
+	Register(SchemeDefault, newGetterFetcher(defaultGetters()...))
+}
+
+// Register adds a LibraryFetcher to the registry for the supplied scheme,
+// replacing any existing fetcher for that scheme.
+func Register(scheme string, fetcher LibraryFetcher) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(scheme)] = fetcher
+}
+
+// Schemes returns the sorted list of registered schemes.
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	res := make([]string, 0, len(registry))
+	for k := range registry {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Lookup returns the LibraryFetcher registered for the scheme of the supplied source.
+// Sources with a scheme that has no registered fetcher, e.g. `hg::` or `smb://`, use the fetcher of SchemeDefault,
+// so that go-getter downloads them with its own getters.
+func Lookup(src string) (LibraryFetcher, error) {
+	scheme := Scheme(src)
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if fetcher, ok := registry[scheme]; ok {
+		return fetcher, nil
+	}
+	fetcher, ok := registry[SchemeDefault]
+	if !ok {
+		return nil, fmt.Errorf("no library fetcher registered for scheme `%s` in source %s", scheme, RedactSource(src))
+	}
+	return fetcher, nil
+}
+
+// Fetch looks up the LibraryFetcher for the source and uses it to fetch the library.
+func Fetch(ctx context.Context, src, dst string, opts *Options) (fs.FS, error) {
+	fetcher, err := Lookup(src)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = new(Options)
+	}
+	return fetcher.Fetch(ctx, src, dst, opts)
+}
+
+// Scheme returns the scheme of the supplied library source.
+// Forced getter syntax (`git::`) takes precedence, then the URL scheme.
+// Local paths return `file` and anything else returns SchemeDefault.
+func Scheme(src string) string {
+	if m := forcedRegexp.FindStringSubmatch(src); m != nil {
+		return strings.ToLower(m[1])
+	}
+	if filepath.IsAbs(src) || strings.HasPrefix(src, ".") {
+		return "file"
+	}
+	// Single letter schemes are Windows drive letters.
+	if u, err := url.Parse(src); err == nil && len(u.Scheme) > 1 {
+		return strings.ToLower(u.Scheme)
+	}
+	return SchemeDefault
+}
+
+// httpClient returns the configured http client or the default client.
+func (o *Options) httpClient() *http.Client {
+	if o == nil || o.HttpClient == nil {
+		return http.DefaultClient
+	}
+	return o.HttpClient
+}
@@ -0,0 +1,35 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ LibraryFetcher = localFetcher{}
+
+// localFetcher uses a library directory on the local file system in place.
+type localFetcher struct{}
+
+// Fetch returns an fs.FS for the local directory. Relative paths are resolved against opts.Pwd.
+func (localFetcher) Fetch(_ context.Context, src, _ string, opts *Options) (fs.FS, error) {
+	path := strings.TrimPrefix(src, "file::")
+	path = strings.TrimPrefix(path, "file://")
+	if !filepath.IsAbs(path) && opts.Pwd != "" {
+		path = filepath.Join(opts.Pwd, path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read local library %s: %w", path, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("local library %s is not a directory", path)
+	}
+	return os.DirFS(path), nil
+}
@@ -0,0 +1,400 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociMaxManifestSize   = 4 << 20
+	// acrScope is the scope of the Entra ID token that is exchanged for an Azure Container Registry token.
+	acrScope = "https://containerregistry.azure.net/.default"
+	// acrTokenPath is the path of the Azure Container Registry token endpoint, used to recognise its challenge.
+	acrTokenPath = "/oauth2/token"
+	// acrExchangePath is the path of the Azure Container Registry endpoint that exchanges an Entra ID token for a refresh token.
+	acrExchangePath = "/oauth2/exchange"
+)
+
+var _ LibraryFetcher = ociFetcher{}
+
+// ociFetcher pulls a library published as an OCI artifact, e.g. `oci://myregistry.azurecr.io/alz/lib:1.2.0`.
+// The artifact layers must be tar archives (optionally gzip compressed) containing the library files.
+// Azure Container Registries are authorized with the provider credential if there is one, other registries anonymously.
+type ociFetcher struct{}
+
+// ociReference is a parsed OCI artifact reference.
+type ociReference struct {
+	Registry   string
+	Repository string
+	Reference  string // Reference is either a tag or a digest
+}
+
+// ociManifest is the subset of the OCI image manifest used to find the library layers.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociDescriptor is an OCI content descriptor.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Fetch pulls the artifact manifest and extracts each layer into dst.
+func (f ociFetcher) Fetch(ctx context.Context, src, dst string, opts *Options) (fs.FS, error) {
+	ref, err := parseOciReference(src)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		if err := os.RemoveAll(dst); err != nil {
+			return nil, fmt.Errorf("failed to remove existing directory %s: %w", dst, err)
+		}
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dst, err)
+	}
+
+	client := &ociClient{http: opts.httpClient(), credential: opts.Credential}
+	manifest, err := client.manifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("oci artifact %s has no layers", src)
+	}
+	for _, layer := range manifest.Layers {
+		if err := client.extractLayer(ctx, ref, layer, dst); err != nil {
+			return nil, err
+		}
+	}
+	return os.DirFS(dst), nil
+}
+
+// parseOciReference parses a source in the form `oci://registry/repository[:tag|@digest]`.
+// If no tag or digest is supplied then `latest` is used.
+func parseOciReference(src string) (ociReference, error) {
+	var ref ociReference
+	rest, ok := strings.CutPrefix(src, "oci://")
+	if !ok {
+		return ref, fmt.Errorf("oci source %s must begin with oci://", src)
+	}
+	registry, repo, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" || repo == "" {
+		return ref, fmt.Errorf("oci source %s must be in the form oci://registry/repository[:tag|@digest]", src)
+	}
+	ref.Registry = registry
+	ref.Reference = "latest"
+	switch {
+	case strings.Contains(repo, "@"):
+		repo, ref.Reference, _ = strings.Cut(repo, "@")
+	case strings.LastIndex(repo, ":") > strings.LastIndex(repo, "/"):
+		idx := strings.LastIndex(repo, ":")
+		repo, ref.Reference = repo[:idx], repo[idx+1:]
+	}
+	if repo == "" || ref.Reference == "" {
+		return ref, fmt.Errorf("oci source %s must be in the form oci://registry/repository[:tag|@digest]", src)
+	}
+	ref.Repository = repo
+	return ref, nil
+}
+
+// ociClient is a minimal client for the OCI distribution API.
+type ociClient struct {
+	http       *http.Client
+	credential azcore.TokenCredential // credential is used to authorize requests to Azure Container Registry, if nil requests are anonymous
+	token      string
+}
+
+// manifest gets the image manifest for the reference.
+func (c *ociClient) manifest(ctx context.Context, ref ociReference) (*ociManifest, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Reference)
+	resp, err := c.get(ctx, u, ociManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, ociMaxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read oci manifest: %w", err)
+	}
+	manifest := new(ociManifest)
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal oci manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// extractLayer downloads the layer blob, verifies its digest and extracts it into dst.
+func (c *ociClient) extractLayer(ctx context.Context, ref ociReference, layer ociDescriptor, dst string) error {
+	algo, want, ok := strings.Cut(layer.Digest, ":")
+	if !ok || algo != "sha256" {
+		return fmt.Errorf("unsupported oci layer digest %s", layer.Digest)
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.Registry, ref.Repository, layer.Digest)
+	resp, err := c.get(ctx, u, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp("", "alzlib-oci-*")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), resp.Body); err != nil {
+		return fmt.Errorf("unable to download oci layer %s: %w", layer.Digest, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("oci layer digest mismatch, expected %s, got sha256:%s", layer.Digest, got)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var r io.Reader = tmp
+	if strings.HasSuffix(layer.MediaType, "gzip") {
+		gz, err := gzip.NewReader(tmp)
+		if err != nil {
+			return fmt.Errorf("unable to decompress oci layer %s: %w", layer.Digest, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return extractTar(r, dst)
+}
+
+// get performs a GET request, handling the bearer token challenge used by OCI registries.
+func (c *ociClient) get(ctx context.Context, u, accept string) (*http.Response, error) {
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		return c.http.Do(req)
+	}
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if c.token, err = c.registryToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d from oci registry for %s", resp.StatusCode, u)
+	}
+	return resp, nil
+}
+
+// registryToken requests a token from the realm in a bearer challenge.
+// If the realm is an Azure Container Registry token endpoint and there is a credential, the token is authorized with the credential,
+// otherwise it is anonymous.
+func (c *ociClient) registryToken(ctx context.Context, challenge string) (string, error) {
+	params := parseBearerChallenge(challenge)
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("unsupported oci registry authentication challenge: %s", challenge)
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse oci registry token realm: %w", err)
+	}
+	if c.credential != nil && u.Path == acrTokenPath {
+		return c.acrToken(ctx, u, params)
+	}
+	return c.anonymousToken(ctx, u, params)
+}
+
+// anonymousToken requests an anonymous token from the realm.
+func (c *ociClient) anonymousToken(ctx context.Context, u *url.URL, params map[string]string) (string, error) {
+	q := u.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d requesting oci registry token", resp.StatusCode)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("unable to decode oci registry token: %w", err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", errors.New("oci registry token response did not contain a token")
+}
+
+// acrToken requests an Azure Container Registry access token from the realm.
+// An Entra ID token from the credential is exchanged for a refresh token, which is then exchanged for an access token with the scope of the challenge.
+func (c *ociClient) acrToken(ctx context.Context, realm *url.URL, params map[string]string) (string, error) {
+	aad, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{acrScope}})
+	if err != nil {
+		return "", fmt.Errorf("unable to get token for azure container registry: %w", err)
+	}
+	exchange := *realm
+	exchange.Path = acrExchangePath
+	exchange.RawQuery = ""
+	var refresh struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.postForm(ctx, exchange.String(), url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {params["service"]},
+		"access_token": {aad.Token},
+	}, &refresh); err != nil {
+		return "", fmt.Errorf("unable to exchange token for azure container registry refresh token: %w", err)
+	}
+	if refresh.RefreshToken == "" {
+		return "", errors.New("azure container registry exchange response did not contain a refresh token")
+	}
+	var access struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.postForm(ctx, realm.String(), url.Values{
+		"grant_type":    {"refresh_token"},
+		"service":       {params["service"]},
+		"scope":         {params["scope"]},
+		"refresh_token": {refresh.RefreshToken},
+	}, &access); err != nil {
+		return "", fmt.Errorf("unable to get azure container registry access token: %w", err)
+	}
+	if access.AccessToken == "" {
+		return "", errors.New("azure container registry token response did not contain an access token")
+	}
+	return access.AccessToken, nil
+}
+
+// postForm posts the form to u and decodes the JSON response into v.
+func (c *ociClient) postForm(ctx context.Context, u string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// parseBearerChallenge parses the parameters of a `WWW-Authenticate: Bearer` header.
+func parseBearerChallenge(challenge string) map[string]string {
+	res := make(map[string]string)
+	rest, ok := strings.CutPrefix(challenge, "Bearer ")
+	if !ok {
+		return res
+	}
+	for _, part := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		res[k] = strings.Trim(v, `"`)
+	}
+	return res
+}
+
+// extractTar extracts the regular files and directories in a tar stream into dst.
+// Entries that would be written outside of dst are rejected.
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read tar archive: %w", err)
+		}
+		name := filepath.Clean(hdr.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("tar entry %s is outside of the destination directory", hdr.Name)
+		}
+		target := filepath.Join(dst, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			// #nosec G110 -- layer size is bounded by the verified digest.
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
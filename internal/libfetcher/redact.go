@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package libfetcher
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redacted replaces the credentials in redacted sources.
+const redacted = "REDACTED"
+
+// safeQueryParameters are the query parameters of library sources that never contain credentials, e.g. the go-getter `ref`.
+var safeQueryParameters = map[string]bool{
+	"archive":  true,
+	"depth":    true,
+	"filename": true,
+	"ref":      true,
+}
+
+// RedactSource returns the library source with the credentials it may contain replaced, so that it can be logged or stored,
+// e.g. the token in `https://token@host/repo.git` or the SAS signature in `azblob::https://account.blob.core.windows.net/lib?sig=...`.
+// The user info of the URL is replaced, other than an SSH user without a password, and so are the values of the query parameters
+// other than those known to be safe, e.g. `ref`. Sources without credentials, and local paths, are returned unchanged.
+func RedactSource(src string) string {
+	if Scheme(src) == "file" {
+		return src
+	}
+	prefix, rest := "", src
+	if m := forcedRegexp.FindStringSubmatch(src); m != nil {
+		prefix, rest = m[1]+"::", m[2]
+	}
+	u, err := url.Parse(rest)
+	if err != nil {
+		// Sources that are not URLs, e.g. `git@github.com:org/repo.git`, have no password or query.
+		return src
+	}
+	changed := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok || strings.ToLower(u.Scheme) != "ssh" {
+			u.User = url.User(redacted)
+			changed = true
+		}
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if safeQueryParameters[strings.ToLower(k)] {
+				continue
+			}
+			q.Set(k, redacted)
+			changed = true
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+	if !changed {
+		return src
+	}
+	return prefix + u.String()
+}
+
+// redactedError is an error whose message has the credentials of a library source redacted.
+// The original error is still returned by Unwrap.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactSourceError returns the error with the source in its message replaced by the redacted source,
+// for errors of libraries that include the source, e.g. those of go-getter.
+func redactSourceError(err error, src string) error {
+	redactedSrc := RedactSource(src)
+	if err == nil || redactedSrc == src || !strings.Contains(err.Error(), src) {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), src, redactedSrc), err: err}
+}
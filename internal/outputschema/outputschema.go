@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package outputschema embeds the JSON schemas of the computed outputs of the provider.
+// The schemas are published so that external test suites can validate the outputs structurally.
+// Each schema is named after the output it describes; the schemas of the maps of ARM JSON describe each value of the map.
+package outputschema
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Version is the version of the output schemas.
+// Increment the major version for changes that may fail validation of existing outputs,
+// the minor version for new outputs or properties, and the patch version for corrections.
+// The version is also in the `$id` of each schema.
+const Version = "1.9.0"
+
+const schemaFileSuffix = ".schema.json"
+
+//go:embed schemas/*.schema.json
+var schemas embed.FS
+
+// Names returns the sorted names of the schemas.
+func Names() []string {
+	entries, _ := fs.ReadDir(schemas, "schemas")
+	res := make([]string, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, strings.TrimSuffix(e.Name(), schemaFileSuffix))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Get returns the JSON schema with the supplied name.
+func Get(name string) ([]byte, bool) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, false
+	}
+	b, err := schemas.ReadFile(path.Join("schemas", name+schemaFileSuffix))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
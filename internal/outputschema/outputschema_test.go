@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package outputschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchemas tests that each schema is valid JSON with an `$id` that includes the schema version and name.
+func TestSchemas(t *testing.T) {
+	names := Names()
+	require.NotEmpty(t, names)
+	assert.Contains(t, names, "alz_archetype")
+	for _, name := range names {
+		b, ok := Get(name)
+		require.True(t, ok, name)
+		var s struct {
+			Schema string `json:"$schema"`
+			Id     string `json:"$id"`
+			Type   string `json:"type"`
+		}
+		require.NoError(t, json.Unmarshal(b, &s), name)
+		assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", s.Schema, name)
+		assert.Equal(t, "urn:terraform-provider-alz:output-schema:"+Version+":"+name, s.Id, name)
+		assert.Equal(t, "object", s.Type, name)
+	}
+}
+
+func TestGet(t *testing.T) {
+	_, ok := Get("missing")
+	assert.False(t, ok)
+	_, ok = Get("../outputschema")
+	assert.False(t, ok)
+}
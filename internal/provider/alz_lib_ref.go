@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	alzLibRepoUrl        = "https://github.com/Azure/Azure-Landing-Zones-Library"
+	alzLibProfileDefault = "alz"
+)
+
+// alzLibProfile is a flavor of the ALZ library, stored under its own path in the library repository
+// and released with tags prefixed by the path.
+type alzLibProfile struct {
+	path       string // the path in the library repository, e.g. `platform/alz`
+	defaultRef string // the ref used if alz_lib_ref is not set, empty if alz_lib_ref is required
+}
+
+// alzLibProfiles are the profiles that can be selected with the alz_lib_profile provider attribute.
+// The alz profile defaults to a pinned release, so that a new library release is only used when alz_lib_ref is changed.
+// The other profiles are released independently of the provider, so alz_lib_ref must be set to one of their releases.
+var alzLibProfiles = map[string]alzLibProfile{
+	"alz":  {path: "platform/alz", defaultRef: alzLibRef},
+	"amba": {path: "platform/amba"},
+	"slz":  {path: "platform/slz"},
+}
+
+// alzLibProfileNames returns the sorted names of the profiles.
+func alzLibProfileNames() []string {
+	res := make([]string, 0, len(alzLibProfiles))
+	for k := range alzLibProfiles {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// tagPrefix returns the prefix of the release tags of the profile.
+func (p alzLibProfile) tagPrefix() string {
+	return p.path + "/"
+}
+
+// url returns the go-getter url of the profile at the ref.
+func (p alzLibProfile) url(ref string) string {
+	q := url.Values{}
+	q.Add("ref", ref)
+	q.Add("depth", "1")
+	return alzLibUrlBase + p.path + "?" + q.Encode()
+}
+
+// normalizeAlzLibRef returns the tag or version constraint of an alz_lib_ref in the release syntax, e.g. `platform/alz@2024.07.5`.
+// The path must be the path of the profile, and a `v` before the version is ignored, as the release tags do not have one.
+// Refs without `@` are returned unchanged.
+func normalizeAlzLibRef(ref string, p alzLibProfile) (string, error) {
+	libPath, ver, ok := strings.Cut(ref, "@")
+	if !ok {
+		return ref, nil
+	}
+	libPath = strings.Trim(strings.TrimSpace(libPath), "/")
+	ver = strings.TrimSpace(ver)
+	if libPath != p.path {
+		return "", fmt.Errorf("the alz_lib_ref path %s does not match the alz_lib_profile path %s", libPath, p.path)
+	}
+	if ver == "" {
+		return "", fmt.Errorf("the alz_lib_ref %s must be in the form %s@<version>", ref, p.path)
+	}
+	if isAlzLibRefConstraint(ver) {
+		return ver, nil
+	}
+	if len(ver) > 1 && (ver[0] == 'v' || ver[0] == 'V') && ver[1] >= '0' && ver[1] <= '9' {
+		ver = ver[1:]
+	}
+	return p.tagPrefix() + ver, nil
+}
+
+// isAlzLibRefConstraint returns true if the alz_lib_ref is a version constraint, e.g. `~> 2024.03`,
+// rather than a tag.
+func isAlzLibRefConstraint(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	for _, op := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(ref, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAlzLibRef returns the tag with the latest version that matches the constraint.
+// Tags that do not have the prefix, e.g. `platform/alz/`, or are not valid versions, are ignored.
+func resolveAlzLibRef(constraint, prefix string, tags []string) (string, error) {
+	c, err := version.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid alz_lib_ref version constraint %s: %w", constraint, err)
+	}
+	var latest *version.Version
+	var res string
+	for _, tag := range tags {
+		v, ok := strings.CutPrefix(tag, prefix)
+		if !ok {
+			continue
+		}
+		ver, err := version.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if !c.Check(ver) {
+			continue
+		}
+		if latest == nil || ver.GreaterThan(latest) {
+			latest = ver
+			res = tag
+		}
+	}
+	if res == "" {
+		return "", fmt.Errorf("no ALZ library release matches the alz_lib_ref version constraint %s", constraint)
+	}
+	return res, nil
+}
+
+// listAlzLibTags lists the tags of the ALZ library repository using the git CLI,
+// which is also used by go-getter to download the library, with the git environment of the download options.
+func listAlzLibTags(ctx context.Context, opts *libfetcher.Options) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--tags", "--refs", alzLibRepoUrl)
+	cmd.Env = opts.GitEnviron()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", alzLibRepoUrl, err)
+	}
+	return parseLsRemoteTags(out), nil
+}
+
+// parseLsRemoteTags returns the tag names from the output of `git ls-remote --tags`.
+func parseLsRemoteTags(out []byte) []string {
+	var res []string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(fields[1], "refs/tags/"); ok {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAlzLibRefConstraint(t *testing.T) {
+	assert.True(t, isAlzLibRefConstraint("~> 2024.03"))
+	assert.True(t, isAlzLibRefConstraint(">= 2024.03.00, < 2025.0.0"))
+	assert.True(t, isAlzLibRefConstraint(" = 2024.03.00"))
+	assert.False(t, isAlzLibRefConstraint("platform/alz/2024.03.00"))
+	assert.False(t, isAlzLibRefConstraint("main"))
+}
+
+func TestNormalizeAlzLibRef(t *testing.T) {
+	p := alzLibProfiles["alz"]
+	for ref, want := range map[string]string{
+		"platform/alz/2024.07.5":   "platform/alz/2024.07.5",
+		"platform/alz@2024.07.5":   "platform/alz/2024.07.5",
+		"platform/alz@v2024.03.00": "platform/alz/2024.03.00",
+		"/platform/alz/@2024.07.5": "platform/alz/2024.07.5",
+		"platform/alz@~> 2024.07":  "~> 2024.07",
+		"~> 2024.07":               "~> 2024.07",
+	} {
+		res, err := normalizeAlzLibRef(ref, p)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, res, ref)
+	}
+
+	_, err := normalizeAlzLibRef("platform/slz@2024.07.5", p)
+	assert.ErrorContains(t, err, "does not match the alz_lib_profile path platform/alz")
+	_, err = normalizeAlzLibRef("platform/alz@", p)
+	assert.ErrorContains(t, err, "must be in the form platform/alz@<version>")
+}
+
+func TestResolveAlzLibRef(t *testing.T) {
+	tags := []string{
+		"platform/alz/2024.01.00",
+		"platform/alz/2024.03.00",
+		"platform/alz/2024.03.01",
+		"platform/alz/2025.01.00",
+		"platform/other/2024.03.02",
+		"platform/alz/not-a-version",
+	}
+
+	res, err := resolveAlzLibRef("~> 2024.03.0", "platform/alz/", tags)
+	require.NoError(t, err)
+	assert.Equal(t, "platform/alz/2024.03.01", res)
+
+	res, err = resolveAlzLibRef(">= 2024.01.0", "platform/alz/", tags)
+	require.NoError(t, err)
+	assert.Equal(t, "platform/alz/2025.01.00", res)
+
+	_, err = resolveAlzLibRef("~> 2023.0", "platform/alz/", tags)
+	assert.ErrorContains(t, err, "no ALZ library release matches")
+
+	_, err = resolveAlzLibRef("~> invalid", "platform/alz/", tags)
+	assert.ErrorContains(t, err, "invalid alz_lib_ref version constraint")
+}
+
+func TestParseLsRemoteTags(t *testing.T) {
+	out := []byte("abc123\trefs/tags/platform/alz/2024.03.00\n" +
+		"def456\trefs/tags/platform/alz/2024.03.01\n" +
+		"ghi789\trefs/heads/main\n" +
+		"malformed\n")
+	assert.Equal(t, []string{"platform/alz/2024.03.00", "platform/alz/2024.03.01"}, parseLsRemoteTags(out))
+}
+
+func TestAlzLibProfiles(t *testing.T) {
+	assert.Equal(t, []string{"alz", "amba", "slz"}, alzLibProfileNames())
+
+	p := alzLibProfiles["slz"]
+	assert.Equal(t, "platform/slz/", p.tagPrefix())
+	assert.Equal(t, "github.com/Azure/Azure-Landing-Zones-Library//platform/slz?depth=1&ref=platform%2Fslz%2F2024.03.00", p.url("platform/slz/2024.03.00"))
+
+	// Only the alz profile defaults to a pinned release, the other profiles require alz_lib_ref.
+	for name, p := range alzLibProfiles {
+		if name == alzLibProfileDefault {
+			continue
+		}
+		assert.Empty(t, p.defaultRef, name)
+	}
+	assert.Equal(t, alzLibRef, alzLibProfiles[alzLibProfileDefault].defaultRef)
+	assert.False(t, isAlzLibRefConstraint(alzLibRef))
+	assert.True(t, strings.HasPrefix(alzLibRef, alzLibProfiles[alzLibProfileDefault].tagPrefix()))
+}
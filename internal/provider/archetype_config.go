@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+// cafArchetypeIds maps the names of the ALZ library archetypes to the archetype ids of the built-in archetypes of the
+// caf-enterprise-scale Terraform module.
+var cafArchetypeIds = map[string]string{
+	"connectivity":   "es_connectivity",
+	"corp":           "es_corp",
+	"decommissioned": "es_decommissioned",
+	"identity":       "es_identity",
+	"landing_zones":  "es_landing_zones",
+	"management":     "es_management",
+	"online":         "es_online",
+	"platform":       "es_platform",
+	"root":           "es_root",
+	"sandboxes":      "es_sandboxes",
+}
+
+// archetypeConfig is the `archetype_config` object of the caf-enterprise-scale Terraform module,
+// used in the `archetype_config_overrides` and `custom_landing_zones` variables.
+type archetypeConfig struct {
+	ArchetypeId   string                    `json:"archetype_id"`
+	Parameters    map[string]map[string]any `json:"parameters"`
+	AccessControl map[string][]string       `json:"access_control"`
+}
+
+// newArchetypeConfig returns the `archetype_config` of the resolved archetype as JSON.
+// The parameters are the values of every policy assignment that has parameters, so that the module generates the same assignments.
+// The access control is the principal ids of the role assignments at the management group, keyed by role definition.
+func newArchetypeConfig(baseArchetype string, pas map[string]armpolicy.Assignment, roleAssignments map[string]AlzRoleAssignmentType, mgResourceId string) (string, error) {
+	res := archetypeConfig{
+		ArchetypeId:   baseArchetype,
+		Parameters:    make(map[string]map[string]any),
+		AccessControl: make(map[string][]string),
+	}
+	if id, ok := cafArchetypeIds[baseArchetype]; ok {
+		res.ArchetypeId = id
+	}
+	for name, pa := range pas {
+		if pa.Properties == nil || len(pa.Properties.Parameters) == 0 {
+			continue
+		}
+		params := make(map[string]any, len(pa.Properties.Parameters))
+		for k, v := range pa.Properties.Parameters {
+			if v == nil {
+				continue
+			}
+			params[k] = v.Value
+		}
+		res.Parameters[name] = params
+	}
+	for _, ra := range roleAssignments {
+		if !strings.EqualFold(ra.Scope.ValueString(), mgResourceId) {
+			continue
+		}
+		role := ra.RoleDefinitionId.ValueString()
+		res.AccessControl[role] = append(res.AccessControl[role], ra.PrincipalId.ValueString())
+	}
+	for role, principals := range res.AccessControl {
+		slices.Sort(principals)
+		res.AccessControl[role] = slices.Compact(principals)
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArchetypeConfig(t *testing.T) {
+	const mgId = "/providers/Microsoft.Management/managementGroups/corp"
+	pas := map[string]armpolicy.Assignment{
+		"Deny-Public-Endpoints": {Name: to.Ptr("Deny-Public-Endpoints"), Properties: &armpolicy.AssignmentProperties{}},
+		"Deploy-Private-DNS-Zones": {
+			Name: to.Ptr("Deploy-Private-DNS-Zones"),
+			Properties: &armpolicy.AssignmentProperties{
+				Parameters: map[string]*armpolicy.ParameterValuesValue{
+					"effect":   {Value: "DeployIfNotExists"},
+					"dnsZones": {Value: []any{"privatelink.blob.core.windows.net"}},
+				},
+			},
+		},
+	}
+	ras := map[string]AlzRoleAssignmentType{
+		"reader1": {PrincipalId: types.StringValue("b"), RoleDefinitionId: types.StringValue("Reader"), Scope: types.StringValue(mgId)},
+		"reader2": {PrincipalId: types.StringValue("a"), RoleDefinitionId: types.StringValue("Reader"), Scope: types.StringValue(mgId)},
+		"reader3": {PrincipalId: types.StringValue("a"), RoleDefinitionId: types.StringValue("Reader"), Scope: types.StringValue(mgId)},
+		"sub":     {PrincipalId: types.StringValue("c"), RoleDefinitionId: types.StringValue("Owner"), Scope: types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000")},
+	}
+	res, err := newArchetypeConfig("corp", pas, ras, mgId)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"archetype_id": "es_corp",
+		"parameters": {
+			"Deploy-Private-DNS-Zones": {"dnsZones": ["privatelink.blob.core.windows.net"], "effect": "DeployIfNotExists"}
+		},
+		"access_control": {"Reader": ["a", "b"]}
+	}`, res)
+
+	res, err = newArchetypeConfig("custom", nil, nil, mgId)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"archetype_id": "custom", "parameters": {}, "access_control": {}}`, res)
+}
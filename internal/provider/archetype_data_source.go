@@ -8,6 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/alzlib"
@@ -16,11 +19,13 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
 	"github.com/Azure/terraform-provider-alz/internal/alztypes"
 	"github.com/Azure/terraform-provider-alz/internal/alzvalidators"
+	"github.com/Azure/terraform-provider-alz/internal/outputschema"
 	"github.com/Azure/terraform-provider-alz/internal/typehelper"
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
@@ -38,7 +43,9 @@ import (
 var _ datasource.DataSource = &ArchetypeDataSource{}
 
 const (
-	archetypeDataSourceReadTimeoutInMins = 5
+	rolloutPhaseAudit      = "audit"
+	rolloutPhaseEnforceNew = "enforce-new"
+	rolloutPhaseEnforceAll = "enforce-all"
 )
 
 func NewArchetypeDataSource() datasource.DataSource {
@@ -67,18 +74,45 @@ type checkExistsInAlzLib struct {
 
 // ArchetypeDataSourceModel describes the data source data model.
 type ArchetypeDataSourceModel struct {
-	AlzPolicyAssignments      types.Map                              `tfsdk:"alz_policy_assignments"`     // map of string, computed
-	AlzPolicyDefinitions      types.Map                              `tfsdk:"alz_policy_definitions"`     // map of string, computed
-	AlzPolicySetDefinitions   types.Map                              `tfsdk:"alz_policy_set_definitions"` // map of string, computed
-	AlzPolicyRoleAssignments  map[string]AlzPolicyRoleAssignmentType `tfsdk:"alz_policy_role_assignments"`
-	AlzRoleDefinitions        types.Map                              `tfsdk:"alz_role_definitions"` // map of string, computed
-	BaseArchetype             types.String                           `tfsdk:"base_archetype"`
-	Defaults                  ArchetypeDataSourceModelDefaults       `tfsdk:"defaults"`
-	DisplayName               types.String                           `tfsdk:"display_name"`
-	Id                        types.String                           `tfsdk:"id"`
-	ParentId                  types.String                           `tfsdk:"parent_id"`
-	PolicyAssignmentsToModify map[string]PolicyAssignmentType        `tfsdk:"policy_assignments_to_modify"`
-	Timeouts                  timeouts.Value                         `tfsdk:"timeouts"`
+	AlzDefenderPricings                 types.Map                                   `tfsdk:"alz_defender_pricings"` // map of string, computed
+	AlzPimRoleEligibilityRequests       map[string]AlzPimRoleEligibilityRequestType `tfsdk:"alz_pim_role_eligibility_requests"`
+	AlzPolicyAssignmentDependencies     types.Map                                   `tfsdk:"alz_policy_assignment_dependencies"` // map of set of string, computed
+	AlzPolicyAssignmentIdentities       map[string]AlzPolicyAssignmentIdentityType  `tfsdk:"alz_policy_assignment_identities"`
+	AlzPolicyAssignments                types.Map                                   `tfsdk:"alz_policy_assignments"`                  // map of string, computed
+	AlzPolicyAssignmentParameterSources types.Map                                   `tfsdk:"alz_policy_assignment_parameter_sources"` // map of map of string, computed
+	AlzPolicyDefinitions                types.Map                                   `tfsdk:"alz_policy_definitions"`                  // map of string, computed
+	AlzPolicySetDefinitions             types.Map                                   `tfsdk:"alz_policy_set_definitions"`              // map of string, computed
+	AlzPolicyRoleAssignments            map[string]AlzPolicyRoleAssignmentType      `tfsdk:"alz_policy_role_assignments"`
+	AlzRoleDefinitionPermissions        map[string]AlzRoleDefinitionPermissionsType `tfsdk:"alz_role_definition_permissions"`
+	AlzRoleAssignments                  map[string]AlzRoleAssignmentType            `tfsdk:"alz_role_assignments"`
+	AlzRoleDefinitions                  types.Map                                   `tfsdk:"alz_role_definitions"`  // map of string, computed
+	AlzSecurityContacts                 types.Map                                   `tfsdk:"alz_security_contacts"` // map of string, computed
+	AlzTemplateSpec                     types.String                                `tfsdk:"alz_template_spec"`
+	AlzUserAssignedIdentities           map[string]AlzUserAssignedIdentityType      `tfsdk:"alz_user_assigned_identities"`
+	Ancestry                            types.List                                  `tfsdk:"ancestry"` // list of string, computed
+	ArchetypeConfig                     types.String                                `tfsdk:"archetype_config"`
+	AssignmentPrincipalIds              types.Map                                   `tfsdk:"assignment_principal_ids"` // map of string
+	BaseArchetype                       types.String                                `tfsdk:"base_archetype"`
+	BaseArchetypeDefinition             *BaseArchetypeDefinitionType                `tfsdk:"base_archetype_definition"`
+	CanarySuffix                        types.String                                `tfsdk:"canary_suffix"`
+	Defaults                            *ArchetypeDataSourceModelDefaults           `tfsdk:"defaults"`
+	DisableDefaultSubstitution          types.Bool                                  `tfsdk:"disable_default_substitution"`
+	DisplayName                         types.String                                `tfsdk:"display_name"`
+	Id                                  types.String                                `tfsdk:"id"`
+	IdentityResourceGroupId             types.String                                `tfsdk:"identity_resource_group_id"`
+	ManagementGroupName                 types.String                                `tfsdk:"management_group_name"`
+	OutputSchemaVersion                 types.String                                `tfsdk:"output_schema_version"`
+	ParentId                            types.String                                `tfsdk:"parent_id"`
+	PolicyAssignmentsToFanOut           map[string]PolicyAssignmentFanOutType       `tfsdk:"policy_assignments_to_fan_out"`
+	PolicyAssignmentsToModify           map[string]PolicyAssignmentType             `tfsdk:"policy_assignments_to_modify"`
+	RegionalDefaults                    map[string]ArchetypeRegionalDefaultsType    `tfsdk:"regional_defaults"`
+	RoleAssignmentsToAdd                map[string]RoleAssignmentToAddType          `tfsdk:"role_assignments_to_add"`
+	ResolutionTrace                     types.String                                `tfsdk:"resolution_trace"`
+	RolloutPhase                        types.String                                `tfsdk:"rollout_phase"`
+	SubscriptionIds                     types.Set                                   `tfsdk:"subscription_ids"` // set of string
+	TemplateSpecEnabled                 types.Bool                                  `tfsdk:"template_spec_enabled"`
+	Timeouts                            timeouts.Value                              `tfsdk:"timeouts"`
+	TraceResolution                     types.Bool                                  `tfsdk:"trace_resolution"`
 }
 
 // AlzPolicyRoleAssignmentType is a representation of the policy assignments
@@ -87,6 +121,30 @@ type AlzPolicyRoleAssignmentType struct {
 	RoleDefinitionId types.String `tfsdk:"role_definition_id"`
 	Scope            types.String `tfsdk:"scope"`
 	AssignmentName   types.String `tfsdk:"assignment_name"`
+	PrincipalId      types.String `tfsdk:"principal_id"`
+}
+
+// AlzPolicyAssignmentIdentityType is the managed identity of a policy assignment, in the shape of the azapi `identity` block.
+type AlzPolicyAssignmentIdentityType struct {
+	IdentityIds types.List   `tfsdk:"identity_ids"` // list of string
+	Type        types.String `tfsdk:"type"`
+}
+
+// AlzRoleDefinitionPermissionsType is the union of the permission blocks of a role definition.
+type AlzRoleDefinitionPermissionsType struct {
+	Actions        types.Set `tfsdk:"actions"`          // set of string
+	NotActions     types.Set `tfsdk:"not_actions"`      // set of string
+	DataActions    types.Set `tfsdk:"data_actions"`     // set of string
+	NotDataActions types.Set `tfsdk:"not_data_actions"` // set of string
+}
+
+// BaseArchetypeDefinitionType is the content of the library archetype, before any customization.
+type BaseArchetypeDefinitionType struct {
+	Name                 types.String `tfsdk:"name"`
+	PolicyAssignments    types.Set    `tfsdk:"policy_assignments"`     // set of string
+	PolicyDefinitions    types.Set    `tfsdk:"policy_definitions"`     // set of string
+	PolicySetDefinitions types.Set    `tfsdk:"policy_set_definitions"` // set of string
+	RoleDefinitions      types.Set    `tfsdk:"role_definitions"`       // set of string
 }
 
 // ArchetypeDataSourceModelDefaults describes the defaults used in the alz data processing.
@@ -96,13 +154,53 @@ type ArchetypeDataSourceModelDefaults struct {
 	PrivateDnsZoneResourceGroupId types.String `tfsdk:"private_dns_zone_resource_group_id"`
 }
 
+// ArchetypeRegionalDefaultsType describes the default values for one region.
+type ArchetypeRegionalDefaultsType struct {
+	DefaultLaWorkspaceId          types.String `tfsdk:"log_analytics_workspace_id"`
+	PrivateDnsZoneResourceGroupId types.String `tfsdk:"private_dns_zone_resource_group_id"`
+}
+
+// ArchetypeDefaultsType describes the archetype_defaults provider attribute.
+type ArchetypeDefaultsType struct {
+	Location                types.String `tfsdk:"location"`
+	LogAnalyticsWorkspaceId types.String `tfsdk:"log_analytics_workspace_id"`
+}
+
+// RoleAssignmentToAddType describes a role assignment to add to the management group or one of its subscriptions.
+type RoleAssignmentToAddType struct {
+	PimDuration      types.String `tfsdk:"pim_duration"`
+	PimEligible      types.Bool   `tfsdk:"pim_eligible"`
+	PimJustification types.String `tfsdk:"pim_justification"`
+	PrincipalId      types.String `tfsdk:"principal_id"`
+	RoleDefinitionId types.String `tfsdk:"role_definition_id"`
+	Scope            types.String `tfsdk:"scope"`
+}
+
+// AlzRoleAssignmentType describes a generated role assignment from `role_assignments_to_add`.
+type AlzRoleAssignmentType struct {
+	PrincipalId      types.String `tfsdk:"principal_id"`
+	RoleDefinitionId types.String `tfsdk:"role_definition_id"`
+	Scope            types.String `tfsdk:"scope"`
+}
+
+// PolicyAssignmentFanOutType describes a policy assignment that is split into one instance per destination.
+type PolicyAssignmentFanOutType struct {
+	Destinations types.Map    `tfsdk:"destinations"` // map of string
+	Parameter    types.String `tfsdk:"parameter"`
+}
+
 // PolicyAssignmentType describes the policy assignment data model.
 type PolicyAssignmentType struct {
-	EnforcementMode      types.String                           `tfsdk:"enforcement_mode"`
+	DependsOnAssignments types.Set                              `tfsdk:"depends_on_assignments"` // set of string
+	EnforcementMode      alztypes.EnforcementModeValue          `tfsdk:"enforcement_mode"`
 	Identity             types.String                           `tfsdk:"identity"`
-	IdentityIds          types.Set                              `tfsdk:"identity_ids"`           // set of string
+	IdentityIds          types.Set                              `tfsdk:"identity_ids"` // set of string
+	IdentityName         types.String                           `tfsdk:"identity_name"`
+	Location             types.String                           `tfsdk:"location"`
 	NonComplianceMessage []PolicyAssignmentNonComplianceMessage `tfsdk:"non_compliance_message"` // set of PolicyAssignmentNonComplianceMessage
+	NotScopes            types.Set                              `tfsdk:"not_scopes"`             // set of string
 	Parameters           alztypes.PolicyParameterValue          `tfsdk:"parameters"`
+	RawParameters        alztypes.PolicyParameterValue          `tfsdk:"raw_parameters"`
 	Overrides            []PolicyAssignmentOverrideType         `tfsdk:"overrides"`
 	ResourceSelectors    []ResourceSelectorType                 `tfsdk:"resource_selectors"`
 }
@@ -142,7 +240,7 @@ func (d *ArchetypeDataSource) Metadata(ctx context.Context, req datasource.Metad
 
 func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Archetype data source. This provides data in order to create resources. Where possible, the data is provided in the form of ARM JSON.",
+		MarkdownDescription: "Archetype data source. This provides data in order to create resources. Where possible, the data is provided in the form of ARM JSON. Data sources have no private state, so the archetype is resolved on every read, including refresh-only plans. The libraries are only read once, when the provider is configured.",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				MarkdownDescription: "The management group name, forming part of the resource id.",
@@ -168,6 +266,210 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 				Required:            true,
 			},
 
+			"base_archetype_definition": schema.SingleNestedAttribute{
+				MarkdownDescription: "The content of the library archetype named in `base_archetype`, before any customization by this data source or the provider configuration. " +
+					"Use this to compare the generated resources with the library baseline.",
+				Computed: true,
+				Attributes: map[string]schema.Attribute{
+					"name": schema.StringAttribute{
+						MarkdownDescription: "The name of the archetype.",
+						Computed:            true,
+					},
+					"policy_assignments": schema.SetAttribute{
+						MarkdownDescription: "The names of the policy assignments in the archetype.",
+						Computed:            true,
+						ElementType:         types.StringType,
+					},
+					"policy_definitions": schema.SetAttribute{
+						MarkdownDescription: "The names of the policy definitions in the archetype.",
+						Computed:            true,
+						ElementType:         types.StringType,
+					},
+					"policy_set_definitions": schema.SetAttribute{
+						MarkdownDescription: "The names of the policy set definitions in the archetype.",
+						Computed:            true,
+						ElementType:         types.StringType,
+					},
+					"role_definitions": schema.SetAttribute{
+						MarkdownDescription: "The names of the role definitions in the archetype.",
+						Computed:            true,
+						ElementType:         types.StringType,
+					},
+				},
+			},
+
+			"canary_suffix": schema.StringAttribute{
+				MarkdownDescription: "Generate the management group as part of a parallel canary hierarchy. " +
+					"The suffix is appended to the management group name and display name, and to the parent name unless the parent is external, e.g. the tenant root group. " +
+					"A parent is external if neither it nor its canary management group is in the deployment. " +
+					"It is an error if the parent is in the deployment but its canary management group is not, so set `parent_id` to the `id` of the parent `alz_archetype` so that it is read first. " +
+					"The resource ids in the generated policy and role resources refer to the canary management groups. " +
+					"Use the same suffix for every management group in the canary hierarchy, e.g. with `for_each` over the primary and canary hierarchies.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(regexp.MustCompile("^[().a-zA-Z0-9_-]{1,20}$"), "Max length is 20 characters. Suffix can only contain an letter, digit, -, _, (, ), ."),
+				},
+			},
+
+			"management_group_name": schema.StringAttribute{
+				MarkdownDescription: "The name of the generated management group. This is the `id`, with the `canary_suffix` appended if set.",
+				Computed:            true,
+			},
+
+			"identity_resource_group_id": schema.StringAttribute{
+				MarkdownDescription: "The resource id of the resource group of the user assigned identities named in `identity_name` of `policy_assignments_to_modify`.",
+				Optional:            true,
+				Validators: []validator.String{
+					alzvalidators.ArmTypeResourceId("Microsoft.Resources", "resourceGroups"),
+				},
+			},
+
+			"output_schema_version": schema.StringAttribute{
+				MarkdownDescription: "The version of the JSON schemas of the computed attributes, returned by the `output_schema` provider function. " +
+					"External test suites can use this to select the schemas to validate the outputs with.",
+				Computed: true,
+			},
+
+			"trace_resolution": schema.BoolAttribute{
+				MarkdownDescription: "Whether to record each decision made while resolving the archetype in `resolution_trace`, for audits. Default is `false`.",
+				Optional:            true,
+			},
+
+			"resolution_trace": schema.StringAttribute{
+				MarkdownDescription: "A JSON document with an `events` list of each decision made while resolving the archetype, in order, if `trace_resolution` is set, otherwise null. " +
+					"Each event has the `kind` and `name` of the object, the `action` (`added`, `modified` or `removed`), and the `reason`, e.g. `base_archetype`, `exclude_default_assignments_matching` or `policy_assignments_to_modify`, with an optional `detail`. " +
+					"Objects added by the base archetype have the `library` that contributed them, and the earlier libraries whose definition they replaced in `overrides`. " +
+					"The JSON schema is in the `alz_archetype` output schema.",
+				Computed: true,
+			},
+
+			"template_spec_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether to generate `alz_template_spec`, so that the resolved archetype can be published as a template spec for teams that do not use Terraform. Default is `false`.",
+				Optional:            true,
+			},
+
+			"alz_template_spec": schema.StringAttribute{
+				MarkdownDescription: "The JSON body of a template spec version, e.g. for the `Microsoft.Resources/templateSpecs/versions` resource, if `template_spec_enabled` is set, otherwise null. " +
+					"The main template deploys the policy definitions, policy set definitions, role definitions and policy assignments of the archetype, as they are in the other outputs, and must be deployed at the management group. " +
+					"The location of the template spec version is the default location. " +
+					"The role assignments for the managed identities of the policy assignments are not included, as the principal ids are not known until the policy assignments are created.",
+				Computed: true,
+			},
+
+			"archetype_config": schema.StringAttribute{
+				MarkdownDescription: "The resolved archetype as a JSON `archetype_config` object of the caf-enterprise-scale Terraform module, for use in its `archetype_config_overrides` and `custom_landing_zones` variables with `jsondecode()`, " +
+					"so that management groups can be migrated from the module to this provider one at a time. " +
+					"The `archetype_id` is the built-in module archetype of the `base_archetype`, e.g. `es_landing_zones` for `landing_zones`, or the `base_archetype` if there is none. " +
+					"The `parameters` are the parameter values of each policy assignment in `alz_policy_assignments`, and the `access_control` is the principal ids of the `alz_role_assignments` at the management group, keyed by role definition id.",
+				Computed: true,
+			},
+
+			"assignment_principal_ids": schema.MapAttribute{
+				MarkdownDescription: "A map of policy assignment names to the principal id of the assignment's managed identity. " +
+					"When supplied, the principal id is added to the matching entries in `alz_policy_role_assignments`, " +
+					"so that the output can be passed directly to the `alz_policy_role_assignments` resource once the policy assignments have been created. " +
+					"The policy assignment **must** exist in the archetype.",
+				Optional:    true,
+				ElementType: types.StringType,
+				Validators: []validator.Map{
+					mapvalidator.ValueStringsAre(
+						stringvalidator.RegexMatches(regexp.MustCompile(`^[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}$`), "The principal id must be a valid lowercase UUID."),
+					),
+				},
+			},
+
+			"rollout_phase": schema.StringAttribute{
+				MarkdownDescription: "The staged enforcement phase for the policy assignments in the archetype. " +
+					"The phase only sets the `enforcementMode` of the policy assignments, their `overrides`, `notScopes` and `scope` are not changed. Must be one of:\n\n" +
+					"  - `audit` - all policy assignments are set to `DoNotEnforce`. Compliance is evaluated, but no effects are applied.\n" +
+					"  - `enforce-new` - policy assignments with a managed identity, i.e. an identity type other than `None`, are set to `DoNotEnforce`, and all other policy assignments are set to `Default`. " +
+					"The phase is keyed on the identity, not on the policy effect, as the policies that deploy or modify resources need a managed identity. " +
+					"So `Deny` and `Audit` policies are enforced, which prevents new non-compliant resources, and `DeployIfNotExists` and `Modify` policies do not change existing resources.\n" +
+					"  - `enforce-all` - all policy assignments are set to `Default`.\n\n" +
+					"If not set, the enforcement mode from the library is used. " +
+					"The `enforcement_mode` in `policy_assignments_to_modify` takes precedence over the rollout phase.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(rolloutPhaseAudit, rolloutPhaseEnforceNew, rolloutPhaseEnforceAll),
+				},
+			},
+
+			"subscription_ids": schema.SetAttribute{
+				MarkdownDescription: "The ids of the subscriptions in the management group. Used as the allowed values of `scope` in `role_assignments_to_add`. A subscription can only be in one management group, so it is an error if another `alz_archetype` data source, e.g. of an ancestor or descendant management group, also has the subscription in `subscription_ids`.",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+
+			"role_assignments_to_add": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of role assignments to add, so that the management group and subscription role assignments are declared with the hierarchy. " +
+					"The generated role assignments are in `alz_role_assignments`, with the same keys, and can be passed to the `alz_policy_role_assignments` resource. " +
+					"Role assignments with `pim_eligible` set are instead in `alz_pim_role_eligibility_requests`.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"role_definition_id": schema.StringAttribute{
+							MarkdownDescription: "The role definition resource id to assign.",
+							Required:            true,
+						},
+
+						"principal_id": schema.StringAttribute{
+							MarkdownDescription: "The principal id to assign the role to.",
+							Required:            true,
+						},
+
+						"scope": schema.StringAttribute{
+							MarkdownDescription: "The id of the subscription to assign the role at. The subscription **must** be in `subscription_ids`. If not set, the role is assigned at the management group.",
+							Optional:            true,
+						},
+
+						"pim_eligible": schema.BoolAttribute{
+							MarkdownDescription: "If true, the principal is made eligible for the role with Privileged Identity Management, instead of being assigned the role permanently. " +
+								"The role eligibility schedule request is in `alz_pim_role_eligibility_requests` rather than `alz_role_assignments`, and the principal must activate the role when it is needed. Default is `false`.",
+							Optional: true,
+						},
+
+						"pim_duration": schema.StringAttribute{
+							MarkdownDescription: "The ISO 8601 duration of the eligibility, e.g. `P365D`. If not set, the eligibility does not expire. Requires `pim_eligible`.",
+							Optional:            true,
+						},
+
+						"pim_justification": schema.StringAttribute{
+							MarkdownDescription: "The justification of the role eligibility schedule request. If not set, a placeholder that names the role definition and management group is used. Requires `pim_eligible`.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+
+			"policy_assignments_to_fan_out": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of policy assignments to split into one instance per destination, e.g. to send the logs of one assignment to more than one Log Analytics workspace. The map key is the policy assignment name. " +
+					"The policy assignment **must** exist in the archetype. It is replaced by instances named after the policy assignment and the destination key, joined by a hyphen, " +
+					"with the policy assignment name shortened so that the instance names fit the 24 character limit. " +
+					"The additional role assignments of the policy assignment are generated for each instance. " +
+					"Fan out is applied after `policy_assignments_to_modify`, so modifications of the policy assignment apply to every instance, and `assignment_principal_ids` uses the instance names.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"parameter": schema.StringAttribute{
+							MarkdownDescription: "The name of the policy assignment parameter that is set to the destination in each instance, e.g. `logAnalytics`.",
+							Required:            true,
+						},
+
+						"destinations": schema.MapAttribute{
+							MarkdownDescription: "A map of instance name suffixes to parameter values, e.g. `{ weu = \"<workspace resource id>\" }`.",
+							Required:            true,
+							ElementType:         types.StringType,
+							Validators: []validator.Map{
+								mapvalidator.SizeAtLeast(1),
+								mapvalidator.KeysAre(
+									stringvalidator.RegexMatches(regexp.MustCompile(`^[a-zA-Z0-9-]{1,22}$`), "The destination key must be between 1 and 22 alphanumeric characters or hyphens."),
+								),
+							},
+						},
+					},
+				},
+			},
+
 			"policy_assignments_to_modify": schema.MapNestedAttribute{
 				MarkdownDescription: "A map of policy assignments names to change in the archetype. The map key is the policy assignment name." +
 					"The policy assignment **must** exist in the archetype." +
@@ -176,12 +478,18 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 				NestedObject: schema.NestedAttributeObject{
 					Validators: []validator.Object{},
 					Attributes: map[string]schema.Attribute{
+						"depends_on_assignments": schema.SetAttribute{
+							MarkdownDescription: "The names of the policy assignments in the archetype that must be assigned before this policy assignment, " +
+								"e.g. a policy that creates a tag before a `Modify` policy that inherits it. The dependencies are returned in `alz_policy_assignment_dependencies`. " +
+								"A fanned out policy assignment depends on, or is depended on by, all of its instances. The dependencies must not form a cycle.",
+							Optional:    true,
+							ElementType: types.StringType,
+						},
+
 						"enforcement_mode": schema.StringAttribute{
-							MarkdownDescription: "The enforcement mode of the policy assignment. Must be one of `Default`, or `DoNotEnforce`.",
+							MarkdownDescription: "The enforcement mode of the policy assignment. Must be one of `Default`, or `DoNotEnforce`. An empty value is equivalent to `Default`.",
+							CustomType:          alztypes.EnforcementModeType{},
 							Optional:            true,
-							Validators: []validator.String{
-								stringvalidator.OneOf("Default", "DoNotEnforce"),
-							},
 						},
 
 						"identity": schema.StringAttribute{
@@ -198,13 +506,35 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 							ElementType:         types.StringType,
 							Validators: []validator.Set{
 								setvalidator.ValueStringsAre(
-									alzvalidators.ArmTypeResourceId("Microsoft.ManagedIdentity", "userAssignedIdentities"),
+									alzvalidators.UserAssignedIdentityId(),
 								),
 								setvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName("identity")),
 								setvalidator.SizeBetween(0, 1),
 							},
 						},
 
+						"identity_name": schema.StringAttribute{
+							MarkdownDescription: "The name of a user assigned identity to create for the policy assignment, instead of supplying its id in `identity_ids`. " +
+								"The identity is in `identity_resource_group_id`, and is returned in `alz_user_assigned_identities` so that it can be created with the policy assignment. " +
+								"Policy assignments can share an identity by using the same name. Requires `identity` to be `UserAssigned`.",
+							Optional: true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_-]{2,127}$"), "The name must be 3 to 128 characters, start with a letter or digit, and can only contain letters, digits, - and _."),
+								stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("identity_ids")),
+								stringvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName("identity")),
+								stringvalidator.AlsoRequires(path.MatchRoot("identity_resource_group_id")),
+							},
+						},
+
+						"location": schema.StringAttribute{
+							MarkdownDescription: "The location of the policy assignment and its managed identity, overriding `defaults.location`. " +
+								"Use this when the identity must be in a specific region, e.g. due to data residency restrictions.",
+							Optional: true,
+							Validators: []validator.String{
+								stringvalidator.LengthAtLeast(1),
+							},
+						},
+
 						"non_compliance_message": schema.SetNestedAttribute{
 							MarkdownDescription: "The non-compliance messages to use for the policy assignment.",
 							Optional:            true,
@@ -223,6 +553,16 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 							},
 						},
 
+						"not_scopes": schema.SetAttribute{
+							MarkdownDescription: "The resource ids to exclude from the policy assignment, replacing the not scopes in the library. " +
+								"A value of `${subscriptions:<management group id>}` is replaced by the subscriptions in the `subscription_ids` of the `alz_archetype` data source of that management group, " +
+								"e.g. `${subscriptions:decommissioned}`, so that the exclusions stay in sync with the subscriptions. " +
+								"The same values can be used in the `notScopes` of library policy assignments. " +
+								"The referenced data source must be read first, add it to `depends_on` if it is not an ancestor of this management group.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+
 						"overrides": schema.ListNestedAttribute{
 							MarkdownDescription: "The overrides for this policy assignment. There are a maximum of 10 overrides allowed per assignment. " +
 								"If specified here the overrides will replace the existing overrides." +
@@ -350,7 +690,21 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 								"**Note:** This is a JSON string, and not a map. This is because the parameter values have different types, which confuses the type system used by the provider sdk. " +
 								"Use `jsonencode()` to construct the map. " +
 								"The map keys must be strings, the values are `any` type. " +
-								"Example: `jsonencode({\"param1\": \"value1\", \"param2\": 2})`",
+								"Example: `jsonencode({\"param1\": \"value1\", \"param2\": 2})`. " +
+								"Values can also be supplied in the ARM format, as used by the azurerm and azapi providers, e.g. `jsonencode({ param1 = { value = \"value1\" } })`, and both formats can be mixed. " +
+								"An object value whose only key is `value` is taken to be in the ARM format, so wrap such objects, e.g. `{ value = { value = ... } }`. " +
+								"String values, including those nested in arrays and objects, and those of the library and the provider `parameter_substitutions`, may contain the tokens `${management_group_name}` and `${management_group_display_name}`, " +
+								"which are replaced with the name and display name of the management group, e.g. `Contact the platform team for $${management_group_name}`. In HCL, escape the tokens with `$$` as in this example.",
+							CustomType: alztypes.PolicyParameterType{},
+							Optional:   true,
+						},
+
+						"raw_parameters": schema.StringAttribute{
+							MarkdownDescription: "Parameters to set on the policy assignment exactly as supplied, as a JSON string like `parameters`. " +
+								"Unlike `parameters`, the values are not converted to the types declared by the assigned definition, are not unwrapped from the ARM format, and numbers keep their precision. " +
+								"Use this for values the provider must not change, e.g. ARM template expressions that do not match the declared type: " +
+								"`jsonencode({ effect = \"[parameters('effect')]\" })`. " +
+								"A parameter cannot be in both `parameters` and `raw_parameters`.",
 							CustomType: alztypes.PolicyParameterType{},
 							Optional:   true,
 						},
@@ -359,18 +713,18 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 			},
 
 			"defaults": schema.SingleNestedAttribute{
-				MarkdownDescription: "Archetype default values",
-				Required:            true,
+				MarkdownDescription: "Archetype default values. The values that are not set are taken from the provider `archetype_defaults`.",
+				Optional:            true,
 				Attributes: map[string]schema.Attribute{
 					"location": schema.StringAttribute{
-						MarkdownDescription: "Default location",
-						Required:            true,
+						MarkdownDescription: "Default location. Must be set here or in the provider `archetype_defaults`.",
+						Optional:            true,
 					},
 					"log_analytics_workspace_id": schema.StringAttribute{
 						MarkdownDescription: "Default Log Analytics workspace id",
 						Optional:            true,
 						Validators: []validator.String{
-							alzvalidators.ArmTypeResourceId("Microsoft.OperationalInsights", "workspaces"),
+							alzvalidators.LogAnalyticsWorkspaceId(),
 						},
 					},
 					"private_dns_zone_resource_group_id": schema.StringAttribute{
@@ -383,10 +737,81 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 				},
 			},
 
+			"disable_default_substitution": schema.BoolAttribute{
+				MarkdownDescription: "If true, the `defaults` and `regional_defaults` are not substituted into the policy assignment parameters, e.g. the Log Analytics workspace and region parameters, so the parameter values are those of the library, " +
+					"the provider `parameter_substitutions` and `policy_assignments_to_modify`. For advanced users who manage all parameters explicitly. " +
+					"The location of policy assignments with a managed identity is still set to `defaults.location`. Default is `false`.",
+				Optional: true,
+			},
+
+			"regional_defaults": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of locations to default values, for organizations that run a platform stack in each of a pair of regions. " +
+					"The entry for `defaults.location` supplies the values that are not set in `defaults`, so the same map can be passed to every management group, with each management group declaring its region in `defaults.location`. " +
+					"Locations are matched ignoring case and spaces, e.g. `West Europe` matches `westeurope`. If set, the map **must** have an entry for `defaults.location`.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"log_analytics_workspace_id": schema.StringAttribute{
+							MarkdownDescription: "Log Analytics workspace id for the region",
+							Optional:            true,
+							Validators: []validator.String{
+								alzvalidators.LogAnalyticsWorkspaceId(),
+							},
+						},
+						"private_dns_zone_resource_group_id": schema.StringAttribute{
+							MarkdownDescription: "Resource group resource id containing private DNS zones for the region. Used in the Deploy-Private-DNS-Zones assignment.",
+							Optional:            true,
+							Validators: []validator.String{
+								alzvalidators.ArmTypeResourceId("Microsoft.Resources", "resourceGroups"),
+							},
+						},
+					},
+				},
+			},
+
 			"alz_policy_assignments": schema.MapAttribute{
-				MarkdownDescription: "A map of generated policy assignments. The values are ARM JSON policy assignments.",
-				Computed:            true,
-				ElementType:         types.StringType,
+				MarkdownDescription: "A map of generated policy assignments. The values are ARM JSON policy assignments. " +
+					"Parameter values are converted to the types declared by the assigned definition, if the definition is in the library. " +
+					"The `enforcementMode` is always set, to `Default` if the library does not set it, as ARM returns it explicitly.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+
+			"alz_policy_assignment_dependencies": schema.MapAttribute{
+				MarkdownDescription: "The names of the policy assignments that each policy assignment depends on, declared with `depends_on_assignments` in `policy_assignments_to_modify`. " +
+					"Policy assignments without dependencies are omitted. Use this to create the policy assignments in order, e.g. with a separate resource for the policy assignments that have dependencies.",
+				Computed:    true,
+				ElementType: types.SetType{ElemType: types.StringType},
+			},
+
+			"alz_policy_assignment_identities": schema.MapNestedAttribute{
+				MarkdownDescription: "The managed identity of each policy assignment in `alz_policy_assignments` that has one, in the shape of the `identity` block of the `azapi_resource` resource, " +
+					"e.g. `identity { type = each.value.type, identity_ids = each.value.identity_ids }`. " +
+					"Use this rather than the `identity` of the ARM JSON, which is rejected in the body by some API versions. Policy assignments without a managed identity are omitted.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"identity_ids": schema.ListAttribute{
+							MarkdownDescription: "The resource ids of the user assigned identities, sorted. Empty for a system assigned identity.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+
+						"type": schema.StringAttribute{
+							MarkdownDescription: "The identity type, `SystemAssigned` or `UserAssigned`.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+
+			"alz_policy_assignment_parameter_sources": schema.MapAttribute{
+				MarkdownDescription: "The source of each parameter value in `alz_policy_assignments`, keyed by policy assignment name and then parameter name. " +
+					"The sources, from lowest to highest precedence, are `definition_default` (the default value declared by the assigned definition, for parameters the assignment does not set), " +
+					"`library` (the value in the library policy assignment), `defaults` (a well-known value set from `defaults` or `regional_defaults`), " +
+					"`parameter_substitutions` (the provider `parameter_substitutions`), `policy_default_values` (the provider `policy_default_values`), `policy_assignments_to_modify`, and `policy_assignments_to_fan_out`.",
+				Computed:    true,
+				ElementType: types.MapType{ElemType: types.StringType},
 			},
 
 			"alz_policy_definitions": schema.MapAttribute{
@@ -407,98 +832,296 @@ func (d *ArchetypeDataSource) Schema(ctx context.Context, req datasource.SchemaR
 				ElementType:         types.StringType,
 			},
 
-			"alz_policy_role_assignments": schema.MapNestedAttribute{
-				MarkdownDescription: "A map of role assignments generated from the policy assignments. The values are a nested object containing the role definition ids and any additionl scopes.",
-				Computed:            true,
+			"alz_defender_pricings": schema.MapAttribute{
+				MarkdownDescription: "A map of Microsoft Defender for Cloud pricings (plans) enabled by the `enableAscFor*` parameters of the policy assignments, keyed by plan name. " +
+					"The values are ARM JSON `Microsoft.Security/pricings` bodies, for deployment to subscriptions that are in scope before the policy is remediated.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+
+			"alz_security_contacts": schema.MapAttribute{
+				MarkdownDescription: "A map of Microsoft Defender for Cloud security contacts configured by the `emailSecurityContact` parameter of the policy assignments. " +
+					"The values are ARM JSON `Microsoft.Security/securityContacts` bodies. The only key is `default`, as a subscription can have one security contact." +
+					"If more than one policy assignment sets `emailSecurityContact`, the first by name is used.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+
+			"alz_user_assigned_identities": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of the user assigned identities named in `identity_name` of `policy_assignments_to_modify`, keyed by identity name. " +
+					"Create the identities before the policy assignments, and supply their principal ids in `assignment_principal_ids` to complete `alz_policy_role_assignments`.",
+				Computed: true,
 				NestedObject: schema.NestedAttributeObject{
 					Attributes: map[string]schema.Attribute{
-						"role_definition_id": schema.StringAttribute{
-							MarkdownDescription: "The role definition id to assign with the policy assignment.",
+						"id": schema.StringAttribute{
+							MarkdownDescription: "The resource id of the identity, as used in the policy assignments.",
 							Computed:            true,
 						},
 
-						"scope": schema.StringAttribute{
-							MarkdownDescription: "The scope to assign with the policy assignment.",
+						"location": schema.StringAttribute{
+							MarkdownDescription: "The location of the identity, which is the location of the policy assignments that use it.",
 							Computed:            true,
 						},
 
-						"assignment_name": schema.StringAttribute{
-							MarkdownDescription: "The name of the policy assignment.",
+						"name": schema.StringAttribute{
+							MarkdownDescription: "The name of the identity.",
+							Computed:            true,
+						},
+
+						"policy_assignments": schema.SetAttribute{
+							MarkdownDescription: "The names of the policy assignments that use the identity.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+
+						"resource_group_id": schema.StringAttribute{
+							MarkdownDescription: "The resource id of the resource group of the identity, from `identity_resource_group_id`.",
 							Computed:            true,
 						},
 					},
 				},
 			},
-		},
-		Blocks: map[string]schema.Block{
-			"timeouts": timeouts.Block(ctx, timeouts.Opts{
-				Read: true,
-			}),
-		},
-	}
-}
-
-func (d *ArchetypeDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
 
-	data, ok := req.ProviderData.(*alzProviderData)
+			"ancestry": schema.ListAttribute{
+				MarkdownDescription: "The names of the management groups from the root of the hierarchy to this management group, inclusive. " +
+					"If the top management group in the hierarchy has a parent that is not managed by the provider, e.g. the tenant root group, the parent name is the first element.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-		return
-	}
+			"alz_role_definition_permissions": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of the permissions of the generated role definitions, keyed by the same names as `alz_role_definitions`. " +
+					"The actions from all permission blocks in the role definition are combined into sets, so that changes between library versions are easy to review in a plan.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"actions": schema.SetAttribute{
+							MarkdownDescription: "The allowed control plane actions.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
 
-	d.alz = data
-}
+						"not_actions": schema.SetAttribute{
+							MarkdownDescription: "The control plane actions excluded from `actions`.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
 
-func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	var data ArchetypeDataSourceModel
+						"data_actions": schema.SetAttribute{
+							MarkdownDescription: "The allowed data plane actions.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
 
-	if d.alz == nil {
-		resp.Diagnostics.AddError(
-			"Provider not configured",
-			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
-		)
-		return
-	}
+						"not_data_actions": schema.SetAttribute{
+							MarkdownDescription: "The data plane actions excluded from `data_actions`.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+					},
+				},
+			},
 
-	// Read Terraform configuration data into the model.
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+			"alz_pim_role_eligibility_requests": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of Privileged Identity Management role eligibility schedule requests generated from the `role_assignments_to_add` with `pim_eligible` set, with the same keys. " +
+					"Create them as `Microsoft.Authorization/roleEligibilityScheduleRequests` resources, e.g. with the `azapi_resource` resource, using the `name`, `scope` as the parent id, and `request_body` as the body.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "The name of the role eligibility schedule request, a UUID generated from the scope, principal id and role definition id.",
+							Computed:            true,
+						},
 
-	if resp.Diagnostics.HasError() {
-		return
-	}
+						"principal_id": schema.StringAttribute{
+							MarkdownDescription: "The principal id to make eligible for the role.",
+							Computed:            true,
+						},
 
-	readTimeout, diags := data.Timeouts.Create(ctx, archetypeDataSourceReadTimeoutInMins*time.Minute)
-	resp.Diagnostics.Append(diags...)
-	ctx, cancel := context.WithTimeout(ctx, readTimeout)
-	defer cancel()
+						"request_body": schema.StringAttribute{
+							MarkdownDescription: "The ARM JSON body of the role eligibility schedule request. The request type is `AdminAssign` and the schedule has no start time, so the eligibility starts when the request is created.",
+							Computed:            true,
+						},
 
-	d.alz.mu.Lock()
-	defer d.alz.mu.Unlock()
+						"role_definition_id": schema.StringAttribute{
+							MarkdownDescription: "The role definition id of the eligibility.",
+							Computed:            true,
+						},
 
-	mgname := data.Id.ValueString()
+						"scope": schema.StringAttribute{
+							MarkdownDescription: "The resource id of the scope of the eligibility.",
+							Computed:            true,
+						},
+					},
+				},
+			},
 
-	// Set well known policy values.
-	wkpv := new(alzlib.WellKnownPolicyValues)
-	defloc := to.Ptr(data.Defaults.DefaultLocation.ValueString())
-	if *defloc == "" {
-		resp.Diagnostics.AddError("Default location not set", "Unable to find default location in the archetype attributes. This should have been caught by the schema validation.")
+			"alz_role_assignments": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of role assignments generated from `role_assignments_to_add` and the provider `management_group_role_assignments` that select the management group, with the same keys. The scope is the resource id of the management group or subscription.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"role_definition_id": schema.StringAttribute{
+							MarkdownDescription: "The role definition id to assign.",
+							Computed:            true,
+						},
+
+						"scope": schema.StringAttribute{
+							MarkdownDescription: "The resource id of the scope to assign the role at.",
+							Computed:            true,
+						},
+
+						"principal_id": schema.StringAttribute{
+							MarkdownDescription: "The principal id to assign the role to.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+
+			"alz_policy_role_assignments": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of role assignments generated from the policy assignments. The values are a nested object containing the role definition ids and any additionl scopes. " +
+					"Role assignments of policy assignments with a user-assigned identity that have the same principal, role and scope as a role assignment in `alz_role_assignments` are removed, with a warning, as Azure does not allow duplicate role assignments. " +
+					"The role assignments of policy assignments with a system-assigned identity are never removed, as their principal is not known until the policy assignment is created.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"role_definition_id": schema.StringAttribute{
+							MarkdownDescription: "The role definition id to assign with the policy assignment.",
+							Computed:            true,
+						},
+
+						"scope": schema.StringAttribute{
+							MarkdownDescription: "The scope to assign with the policy assignment.",
+							Computed:            true,
+						},
+
+						"assignment_name": schema.StringAttribute{
+							MarkdownDescription: "The name of the policy assignment.",
+							Computed:            true,
+						},
+
+						"principal_id": schema.StringAttribute{
+							MarkdownDescription: "The principal id of the policy assignment's managed identity, taken from `assignment_principal_ids`. Null if not supplied.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Read: true,
+				ReadDescription: "The maximum duration of the read, as a duration, e.g. `10m`. " +
+					"Read operations occur during any refresh or planning operation when refresh is enabled. Defaults to the `read` timeout of the provider `timeouts`.",
+			}),
+		},
+	}
+}
+
+func (d *ArchetypeDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
 	}
-	wkpv.DefaultLocation = defloc
-	if isKnown(data.Defaults.DefaultLaWorkspaceId) {
-		wkpv.DefaultLogAnalyticsWorkspaceId = to.Ptr(data.Defaults.DefaultLaWorkspaceId.ValueString())
+
+	d.alz = data
+}
+
+func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ArchetypeDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
 	}
-	if isKnown(data.Defaults.PrivateDnsZoneResourceGroupId) {
-		wkpv.PrivateDnsZoneResourceGroupId = to.Ptr(data.Defaults.PrivateDnsZoneResourceGroupId.ValueString())
+
+	defer func() {
+		resp.Diagnostics = summarizeWarnings(ctx, resp.Diagnostics, d.alz.warningVerbosity)
+	}()
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	// Record the subscriptions of the management group, for the not scopes of other management groups.
+	var subscriptionIds []string
+	if isKnown(data.SubscriptionIds) {
+		resp.Diagnostics.Append(data.SubscriptionIds.ElementsAs(ctx, &subscriptionIds, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		slices.Sort(subscriptionIds)
+	}
+	d.alz.mu.Lock()
+	d.alz.managementGroupSubscriptions[data.Id.ValueString()] = subscriptionIds
+	d.alz.mu.Unlock()
+
+	readTimeout, diags := data.Timeouts.Read(ctx, d.alz.readTimeout)
+	resp.Diagnostics.Append(diags...)
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	release, err := d.alz.resolutions.Acquire(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to start archetype resolution", fmt.Sprintf("Timed out waiting for one of the `max_parallel_resolutions` slots: %s", err.Error()))
+		return
+	}
+	defer release()
+
+	// AlzLib is not safe for concurrent use, so it is locked until the values of the management group have been copied.
+	// The conversion of the copies to Terraform values runs concurrently, up to the resolution limit.
+	d.alz.mu.Lock()
+	unlock := sync.OnceFunc(d.alz.mu.Unlock)
+	defer unlock()
+
+	defer startProfile(ctx, d.alz.debugProfileDir, "read-archetype-"+data.Id.ValueString())()
+	started := time.Now()
+
+	mgname, parent, err := canaryManagementGroupNames(data.Id.ValueString(), data.ParentId.ValueString(), data.CanarySuffix.ValueString(), func(name string) bool {
+		return d.alz.Deployment.GetManagementGroup(name) != nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("canary_suffix"), "Canary parent management group not found", err.Error())
+		return
+	}
+	displayName := data.DisplayName.ValueString()
+	if displayName != "" {
+		displayName += data.CanarySuffix.ValueString()
+	}
+	data.ManagementGroupName = types.StringValue(mgname)
+	data.OutputSchemaVersion = types.StringValue(outputschema.Version)
+
+	// Set well known policy values.
+	defaults, err := resolveArchetypeDefaults(data.Defaults, data.RegionalDefaults, d.alz.archetypeDefaults)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("regional_defaults"), "Regional defaults not found", err.Error())
+		return
+	}
+	defloc := to.Ptr(defaults.DefaultLocation.ValueString())
+	if *defloc == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("defaults").AtName("location"), "Default location not set",
+			"Set the default location in the `defaults` of the archetype, or in the `archetype_defaults` of the provider.")
+		return
+	}
+	wkpv := wellKnownPolicyValues(defaults, data.DisableDefaultSubstitution.ValueBool())
+
 	// Make a copy of the archetype so we can customize it.
 	arch, err := d.alz.CopyArchetype(data.BaseArchetype.ValueString(), wkpv)
 	if err != nil {
@@ -506,6 +1129,24 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
+	data.BaseArchetypeDefinition, diags = convertBaseArchetypeDefinition(ctx, data.BaseArchetype.ValueString(), arch)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The trace is always recorded for the trace level log, and is only returned with `trace_resolution`.
+	trace := newArchetypeTrace(d.alz.libUrls, d.alz.library)
+	trace.baseArchetype(data.BaseArchetype.ValueString(), arch)
+
+	excluded := excludeArchetypePolicyAssignments(arch, d.alz.excludeDefaultAssignments)
+	for _, name := range excluded {
+		tflog.Debug(ctx, "Excluded policy assignment matching exclude_default_assignments_matching", map[string]interface{}{
+			"policy_assignment": name,
+		})
+	}
+	trace.excluded(excluded, d.alz.excludeDefaultAssignments)
+
 	checks := []checkExistsInAlzLib{
 		{arch.PolicyDefinitions, d.alz.PolicyDefinitionExists},
 		{arch.PolicySetDefinitions, d.alz.PolicySetDefinitionExists},
@@ -522,16 +1163,22 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 		}
 	}
 
+	if d.alz.offline {
+		if missing := d.alz.library.MissingDefinitions(arch.PolicyAssignments.ToSlice()); len(missing) != 0 {
+			resp.Diagnostics.AddError("Built-in definitions not available offline", offlineMissingDefinitionsError(missing))
+			return
+		}
+	}
+
 	if mg := d.alz.Deployment.GetManagementGroup(mgname); mg == nil {
 		tflog.Debug(ctx, "Add management group")
 		external := false
-		parent := data.ParentId.ValueString()
 		if mg := d.alz.Deployment.GetManagementGroup(parent); mg == nil {
 			external = true
 		}
 		req := alzlib.AlzManagementGroupAddRequest{
 			Id:               mgname,
-			DisplayName:      data.DisplayName.ValueString(),
+			DisplayName:      displayName,
 			ParentId:         parent,
 			ParentIsExternal: external,
 			Archetype:        arch,
@@ -548,12 +1195,82 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
-	for k, v := range data.PolicyAssignmentsToModify {
+	substituted := parameterSubstitutionsForAssignments(mg.GetPolicyAssignmentMap(), d.alz.parameterSubstitutions)
+	trace.parameters("parameter_substitutions", substituted)
+	for k, params := range substituted {
+		if err := mg.ModifyPolicyAssignment(k, params, nil, nil, nil, nil, nil); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("Unable to apply parameter substitutions to policy assignment %s", k), err.Error())
+			return
+		}
+	}
+
+	defaulted := policyDefaultValuesForAssignments(mg.GetPolicyAssignmentMap(), d.alz.policyDefaultValues, d.alz.library)
+	trace.parameters("policy_default_values", defaulted)
+	for k, params := range defaulted {
+		if err := mg.ModifyPolicyAssignment(k, params, nil, nil, nil, nil, nil); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("Unable to apply policy default values to policy assignment %s", k), err.Error())
+			return
+		}
+	}
+
+	idents := identityOverridesForAssignments(mg.GetPolicyAssignmentMap(), d.alz.identityOverrides)
+	for _, k := range sortedKeys(idents) {
+		ident := idents[k]
+		trace.add(traceKindPolicyAssignment, traceActionModified, "identity_overrides", "", k)
+		if err := mg.ModifyPolicyAssignment(k, nil, nil, nil, ident, nil, nil); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("Unable to apply identity override to policy assignment %s", k), err.Error())
+			return
+		}
+	}
+
+	enfs := rolloutPhaseEnforcementModes(mg.GetPolicyAssignmentMap(), data.RolloutPhase.ValueString())
+	for _, k := range sortedKeys(enfs) {
+		enf := enfs[k]
+		trace.add(traceKindPolicyAssignment, traceActionModified, "rollout_phase", fmt.Sprintf("%s: enforcement mode %s", data.RolloutPhase.ValueString(), *enf), k)
+		if err := mg.ModifyPolicyAssignment(k, nil, enf, nil, nil, nil, nil); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("Unable to apply rollout phase to policy assignment %s", k), err.Error())
+			return
+		}
+	}
+
+	mods, fanOutSrc := data.PolicyAssignmentsToModify, data.PolicyAssignmentsToFanOut
+	if d.alz.warnOnRemovedPolicyReferences {
+		var refs []removedPolicyReference
+		mods, fanOutSrc, refs, diags = withoutRemovedPolicyReferences(ctx, mods, fanOutSrc, mg.GetPolicyAssignmentMap())
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if len(refs) != 0 {
+			resp.Diagnostics.AddWarning(
+				"Configuration references policy assignments that are not in the archetype",
+				fmt.Sprintf("The following references in the configuration of management group %s are ignored. "+
+					"Make these changes to the configuration to remove them:\n%s", mgname, removedPolicyReferencesSummary(refs)),
+			)
+		}
+	}
+
+	identities, err := userAssignedIdentitiesToCreate(mods, data.IdentityResourceGroupId.ValueString(), *defloc)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("policy_assignments_to_modify"), "Invalid user assigned identities", err.Error())
+		return
+	}
+
+	modified := make(map[string]map[string]*armpolicy.ParameterValuesValue, len(mods))
+	for _, k := range sortedKeys(mods) {
+		v := mods[k]
+		trace.add(traceKindPolicyAssignment, traceActionModified, "policy_assignments_to_modify", "", k)
+		if v.IdentityName.IsUnknown() {
+			v.IdentityIds = types.SetUnknown(types.StringType)
+		} else if u, ok := identities[v.IdentityName.ValueString()]; ok {
+			v.IdentityIds = types.SetValueMust(types.StringType, []attr.Value{types.StringValue(u.id())})
+		}
 		enf, ident, noncompl, params, resourceSel, overrides, err := policyAssignmentType2ArmPolicyValues(v)
 		if err != nil {
 			resp.Diagnostics.AddError(fmt.Sprintf("Unable to convert supplied policy assignment modifications to SDK values for policy assignment %s", k), err.Error())
 			return
 		}
+		modified[k] = params
 		if err := mg.ModifyPolicyAssignment(k, params, enf, noncompl, ident, resourceSel, overrides); err != nil {
 			resp.Diagnostics.AddError(fmt.Sprintf("Unable to modify policy assignment %s", k), err.Error())
 			return
@@ -566,19 +1283,138 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
+	pas := mg.GetPolicyAssignmentMap()
+	pds := mg.GetPolicyDefinitionsMap()
+	psds := mg.GetPolicySetDefinitionsMap()
+	if data.DisableDefaultSubstitution.ValueBool() {
+		applyIdentityPolicyAssignmentLocations(pas, *defloc)
+	}
+	applyPolicyAssignmentLocations(pas, mods)
+	applyPolicyAssignmentNotScopes(pas, mods)
+	applyDefaultEnforcementModes(pas)
+	if _, err := expandNotScopesSubscriptions(pas, d.alz.managementGroupSubscriptions); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("policy_assignments_to_modify"), "Unable to expand policy assignment not scopes", err.Error())
+		return
+	}
+	allMgs := make([]*alzlib.AlzManagementGroup, 0)
+	for _, name := range d.alz.Deployment.ListManagementGroups() {
+		allMgs = append(allMgs, d.alz.Deployment.GetManagementGroup(name))
+	}
+	paramDefs := policyParameterDefinitions(allMgs)
+	parameterSources := policyAssignmentParameterSources(pas, d.alz.library, paramDefs, substituted, defaulted, modified)
+	mgDisplayName := displayName
+	if mgDisplayName == "" {
+		mgDisplayName = mgname
+	}
+	interpolateManagementGroupParameters(pas, mgname, mgDisplayName)
+	if d.alz.defaultNonComplianceMessages {
+		trace.add(traceKindPolicyAssignment, traceActionModified, "default_non_compliance_messages", "", defaultNonComplianceMessages(pas, d.alz.library.PolicyDefinitionMetadata())...)
+	}
+	if err := coercePolicyAssignmentParameters(pas, paramDefs); err != nil {
+		resp.Diagnostics.AddError("Unable to convert policy assignment parameters to the types declared in the definition", err.Error())
+		return
+	}
+	if err := applyRawPolicyAssignmentParameters(pas, mods); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("policy_assignments_to_modify"), "Unable to apply raw policy assignment parameters", err.Error())
+		return
+	}
+	unavailable := newLibraryContent()
+	if len(d.alz.unavailableResourceProviders) != 0 {
+		deploymentPds, deploymentPsds := deploymentDefinitions(allMgs, d.alz.builtInDefinitions)
+		unavailable = filterUnavailableResourceProviders(pds, psds, pas, unavailableDefinitions(deploymentPds, deploymentPsds, d.alz.unavailableResourceProviders))
+	}
+	trace.unavailable(unavailable)
+	if unavailable.Cardinality() != 0 {
+		resp.Diagnostics.AddWarning(
+			"Policies removed for unavailable resource providers",
+			unavailableResourceProvidersSummary(unavailable, d.alz.unavailableResourceProviders),
+		)
+	}
+	rds := mg.GetRoleDefinitionsMap()
+	policyRoleAssignments := mg.GetPolicyRoleAssignments()
+	var fanOuts map[string]policyAssignmentFanOut
+	if len(fanOutSrc) != 0 {
+		fanOuts, diags = policyAssignmentFanOuts(ctx, fanOutSrc, unavailable)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if policyRoleAssignments, err = fanOutPolicyAssignments(pas, policyRoleAssignments, paramDefs, fanOuts); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("policy_assignments_to_fan_out"), "Unable to fan out policy assignments", err.Error())
+			return
+		}
+		fanOutParameterSources(parameterSources, fanOuts)
+		trace.fanOuts(fanOuts)
+	}
+	ancestry := managementGroupAncestry(mg)
+	mgResourceId := mg.ResourceId()
+	unlock()
+
+	trace.log(ctx)
+	tflog.Debug(ctx, "Resolved archetype", map[string]interface{}{
+		"management_group":       mgname,
+		"base_archetype":         data.BaseArchetype.ValueString(),
+		"duration_ms":            time.Since(started).Milliseconds(),
+		"policy_assignments":     len(pas),
+		"policy_definitions":     len(pds),
+		"policy_set_definitions": len(psds),
+		"role_definitions":       len(rds),
+		"decisions":              len(trace.events),
+	})
+
+	data.ResolutionTrace = types.StringNull()
+	if data.TraceResolution.ValueBool() {
+		traceJson, err := trace.JSON()
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to marshal the resolution trace", err.Error())
+			return
+		}
+		data.ResolutionTrace = types.StringValue(traceJson)
+	}
+
+	if d.alz.checkExistingManagementGroups && d.alz.clients != nil && d.alz.clients.ManagementGroupsClient != nil {
+		resp.Diagnostics.Append(checkExistingManagementGroup(ctx, d.alz.clients.ManagementGroupsClient, mgname, displayName, parent)...)
+	}
+
 	tflog.Debug(ctx, "Converting maps from Go types to Framework types")
 	var m basetypes.MapValue
 
 	tflog.Debug(ctx, "Converting policy assignments")
-	m, diags = convertMapOfStringToMapValue(mg.GetPolicyAssignmentMap())
+	m, diags = convertMapOfStringToMapValue(pas)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 	data.AlzPolicyAssignments = m
 
+	tflog.Debug(ctx, "Converting policy assignment dependencies")
+	dependencies, diags := policyAssignmentDependencies(ctx, mods, pas, fanOuts)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.AlzPolicyAssignmentDependencies, diags = types.MapValueFrom(ctx, types.SetType{ElemType: types.StringType}, dependencies)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Record the dependencies, for the deployment waves.
+	d.alz.mu.Lock()
+	d.alz.policyAssignmentDependencies[mgname] = dependencies
+	d.alz.mu.Unlock()
+
+	tflog.Debug(ctx, "Converting policy assignment identities")
+	data.AlzPolicyAssignmentIdentities = policyAssignmentIdentities(pas)
+
+	tflog.Debug(ctx, "Converting policy assignment parameter sources")
+	data.AlzPolicyAssignmentParameterSources, diags = types.MapValueFrom(ctx, types.MapType{ElemType: types.StringType}, parameterSources)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	tflog.Debug(ctx, "Converting policy definitions")
-	m, diags = convertMapOfStringToMapValue(mg.GetPolicyDefinitionsMap())
+	m, diags = convertMapOfStringToMapValue(pds)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -586,7 +1422,7 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 	data.AlzPolicyDefinitions = m
 
 	tflog.Debug(ctx, "Converting policy set definitions")
-	m, diags = convertMapOfStringToMapValue(mg.GetPolicySetDefinitionsMap())
+	m, diags = convertMapOfStringToMapValue(psds)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -594,41 +1430,547 @@ func (d *ArchetypeDataSource) Read(ctx context.Context, req datasource.ReadReque
 	data.AlzPolicySetDefinitions = m
 
 	tflog.Debug(ctx, "Converting role definitions")
-	m, diags = convertMapOfStringToMapValue(mg.GetRoleDefinitionsMap())
+	m, diags = convertMapOfStringToMapValue(rds)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 	data.AlzRoleDefinitions = m
 
+	tflog.Debug(ctx, "Generating Defender for Cloud resources")
+	pricings, contacts, err := securityCenterResources(pas)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to generate Defender for Cloud resources", err.Error())
+		return
+	}
+	data.AlzDefenderPricings, diags = types.MapValueFrom(ctx, types.StringType, pricings)
+	resp.Diagnostics.Append(diags...)
+	data.AlzSecurityContacts, diags = types.MapValueFrom(ctx, types.StringType, contacts)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.AlzTemplateSpec = types.StringNull()
+	if data.TemplateSpecEnabled.ValueBool() {
+		tflog.Debug(ctx, "Generating template spec")
+		spec, err := archetypeTemplateSpec(mgname, data.BaseArchetype.ValueString(), *defloc, pds, psds, rds, pas)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to generate template spec", err.Error())
+			return
+		}
+		data.AlzTemplateSpec = types.StringValue(spec)
+	}
+
+	tflog.Debug(ctx, "Converting user assigned identities")
+	data.AlzUserAssignedIdentities, diags = convertUserAssignedIdentities(ctx, identities)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Converting role definition permissions")
+	data.AlzRoleDefinitionPermissions, diags = convertRoleDefinitionPermissions(ctx, rds, d.alz.library)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	principalIds := make(map[string]types.String)
+	if isKnown(data.AssignmentPrincipalIds) {
+		resp.Diagnostics.Append(data.AssignmentPrincipalIds.ElementsAs(ctx, &principalIds, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	for _, k := range sortedKeys(principalIds) {
+		if _, ok := pas[k]; !ok {
+			if d.alz.warnOnRemovedPolicyReferences {
+				resp.Diagnostics.AddAttributeWarning(
+					path.Root("assignment_principal_ids").AtMapKey(k),
+					"Policy assignment not found",
+					fmt.Sprintf("The policy assignment %s is not in management group %s, the principal id is ignored. "+
+						"Make this change to the configuration to remove it:\n  - %s", k, mgname,
+						removedPolicyReference{assignment: k, attribute: "assignment_principal_ids"}),
+				)
+				delete(principalIds, k)
+				continue
+			}
+			resp.Diagnostics.AddAttributeError(
+				path.Root("assignment_principal_ids").AtMapKey(k),
+				"Policy assignment not found",
+				fmt.Sprintf("Unable to find policy assignment %s in management group %s", k, mgname),
+			)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Ancestry, diags = types.ListValueFrom(ctx, types.StringType, ancestry)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, c := range d.alz.subscriptions.Declare(ancestry, subscriptionIds) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("subscription_ids"),
+			"Subscription declared in more than one management group",
+			fmt.Sprintf("In management group %s, %s. A subscription can only be in one management group, so the management group applied last would silently move it. Remove the subscription from one of the `alz_archetype` data sources.", mgname, c),
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if selected := selectedRoleAssignments(d.alz.managementGroupRoleAssignments, mgname, data.BaseArchetype.ValueString(), mgResourceId); len(selected) != 0 {
+		data.AlzRoleAssignments = selected
+	} else if len(data.RoleAssignmentsToAdd) != 0 {
+		data.AlzRoleAssignments = make(map[string]AlzRoleAssignmentType, len(data.RoleAssignmentsToAdd))
+	}
+	data.AlzPimRoleEligibilityRequests = nil
+	for k, v := range data.RoleAssignmentsToAdd {
+		scope, err := roleAssignmentScope(mgResourceId, subscriptionIds, v.Scope.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("role_assignments_to_add").AtMapKey(k).AtName("scope"), "Invalid role assignment scope", err.Error())
+			continue
+		}
+		ra := AlzRoleAssignmentType{
+			PrincipalId:      v.PrincipalId,
+			RoleDefinitionId: v.RoleDefinitionId,
+			Scope:            types.StringValue(scope),
+		}
+		if !v.PimEligible.ValueBool() {
+			if !v.PimDuration.IsNull() || !v.PimJustification.IsNull() {
+				resp.Diagnostics.AddAttributeError(path.Root("role_assignments_to_add").AtMapKey(k).AtName("pim_eligible"), "Invalid role assignment", "pim_duration and pim_justification require pim_eligible to be true")
+				continue
+			}
+			data.AlzRoleAssignments[k] = ra
+			continue
+		}
+		req, err := newPimRoleEligibilityRequest(ra, mgname, v.PimDuration.ValueString(), v.PimJustification.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("role_assignments_to_add").AtMapKey(k).AtName("pim_duration"), "Invalid role eligibility duration", err.Error())
+			continue
+		}
+		if data.AlzPimRoleEligibilityRequests == nil {
+			data.AlzPimRoleEligibilityRequests = make(map[string]AlzPimRoleEligibilityRequestType)
+		}
+		data.AlzPimRoleEligibilityRequests[k] = req
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Generating archetype config")
+	archetypeConfig, err := newArchetypeConfig(data.BaseArchetype.ValueString(), pas, data.AlzRoleAssignments, mgResourceId)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to generate archetype config", err.Error())
+		return
+	}
+	data.ArchetypeConfig = types.StringValue(archetypeConfig)
+
 	tflog.Debug(ctx, "Converting additional role assignments")
-	data.AlzPolicyRoleAssignments = convertAlzPolicyRoleAssignments(mg.GetPolicyRoleAssignments())
+	data.AlzPolicyRoleAssignments = convertAlzPolicyRoleAssignments(filterPolicyRoleAssignments(policyRoleAssignments, unavailable), principalIds)
+	if merged := dedupPolicyRoleAssignments(data.AlzPolicyRoleAssignments, data.AlzRoleAssignments); len(merged) != 0 {
+		resp.Diagnostics.AddWarning(
+			"Duplicate role assignments merged",
+			"These role assignments of policy assignments have the same principal, role and scope as a role assignment in `alz_role_assignments`, and are removed from `alz_policy_role_assignments`:\n  - "+strings.Join(merged, "\n  - "),
+		)
+	}
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// convertBaseArchetypeDefinition converts the archetype to the framework type.
+// It must be called before the archetype is customized.
+func convertBaseArchetypeDefinition(ctx context.Context, name string, arch *alzlib.Archetype) (*BaseArchetypeDefinitionType, diag.Diagnostics) {
+	var diags, d diag.Diagnostics
+	res := &BaseArchetypeDefinitionType{Name: types.StringValue(name)}
+	res.PolicyAssignments, d = types.SetValueFrom(ctx, types.StringType, arch.PolicyAssignments.ToSlice())
+	diags.Append(d...)
+	res.PolicyDefinitions, d = types.SetValueFrom(ctx, types.StringType, arch.PolicyDefinitions.ToSlice())
+	diags.Append(d...)
+	res.PolicySetDefinitions, d = types.SetValueFrom(ctx, types.StringType, arch.PolicySetDefinitions.ToSlice())
+	diags.Append(d...)
+	res.RoleDefinitions, d = types.SetValueFrom(ctx, types.StringType, arch.RoleDefinitions.ToSlice())
+	diags.Append(d...)
+	return res, diags
+}
+
+// excludeArchetypePolicyAssignments removes the policy assignments with names matching any of the expressions from the archetype.
+// The names of the removed policy assignments are returned.
+func excludeArchetypePolicyAssignments(arch *alzlib.Archetype, exprs []*regexp.Regexp) []string {
+	var res []string
+	for _, name := range arch.PolicyAssignments.ToSlice() {
+		for _, re := range exprs {
+			if re.MatchString(name) {
+				arch.PolicyAssignments.Remove(name)
+				res = append(res, name)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// checkExistingManagementGroup reads the management group from Azure and returns a warning if it exists
+// with a different display name or parent than declared.
+// The check is skipped if the management group does not exist or cannot be read.
+func checkExistingManagementGroup(ctx context.Context, client *ManagementGroupsClient, name, displayName, parent string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	live, err := client.Get(ctx, name)
+	if err != nil {
+		if !isNotFoundError(err) {
+			tflog.Debug(ctx, "Unable to read existing management group, skipping check", map[string]interface{}{
+				"management_group": name,
+				"error":            err.Error(),
+			})
+		}
+		return diags
+	}
+	if differences := existingManagementGroupDifferences(live, displayName, parent); len(differences) != 0 {
+		diags.AddWarning(
+			"Management group differs from Azure",
+			fmt.Sprintf("The management group %s exists in Azure with a different configuration than declared:\n\n%s\n\nApplying the configuration will rename or move the management group.", name, strings.Join(differences, "\n")),
+		)
+	}
+	return diags
+}
+
+// existingManagementGroupDifferences returns the differences between a management group in Azure and the declared display name and parent.
+// An empty display name is compared with the name, which Azure uses as the display name if none is supplied.
+func existingManagementGroupDifferences(live managementGroupInfo, displayName, parent string) []string {
+	var res []string
+	if displayName == "" {
+		displayName = live.Name
+	}
+	if live.DisplayName != displayName {
+		res = append(res, fmt.Sprintf("- display name is %q in Azure, declared %q", live.DisplayName, displayName))
+	}
+	if !strings.EqualFold(live.ParentName, parent) {
+		res = append(res, fmt.Sprintf("- parent is %q in Azure, declared %q", live.ParentName, parent))
+	}
+	return res
+}
+
+// canaryManagementGroupNames returns the management group and parent names with the canary suffix applied.
+// The parent has the suffix unless it is external, i.e. neither the parent nor the canary parent is in the deployment,
+// in which case the canary hierarchy is attached to the original parent.
+// Returns an error if the parent is in the deployment but the canary parent is not.
+func canaryManagementGroupNames(id, parentId, suffix string, exists func(string) bool) (string, string, error) {
+	if suffix == "" {
+		return id, parentId, nil
+	}
+	switch {
+	case exists(parentId + suffix):
+		return id + suffix, parentId + suffix, nil
+	case exists(parentId):
+		return "", "", fmt.Errorf("the parent management group %s is in the deployment, but its canary management group %s is not. Add the canary parent, and set `parent_id` to its `id` so that it is read first", parentId, parentId+suffix)
+	}
+	return id + suffix, parentId, nil
+}
+
+// managementGroupAncestry returns the names of the management groups from the root to the supplied management group, inclusive.
+// If the top management group has an external parent, it is the first element.
+func managementGroupAncestry(mg *alzlib.AlzManagementGroup) []string {
+	res := make([]string, 0)
+	for ; mg != nil; mg = mg.GetParentMg() {
+		res = append(res, resourceIdName(mg.ResourceId()))
+		if mg.ParentIsExternal() {
+			res = append(res, mg.GetParentId())
+		}
+	}
+	slices.Reverse(res)
+	return res
+}
+
+// roleAssignmentScope returns the resource id of the scope of a role assignment in `role_assignments_to_add`.
+// An empty scope is the management group, otherwise the scope must be one of the subscription ids,
+// with or without the `/subscriptions/` prefix.
+func roleAssignmentScope(mgResourceId string, subscriptionIds []string, scope string) (string, error) {
+	if scope == "" {
+		return mgResourceId, nil
+	}
+	id := trimSubscriptionsPrefix(scope)
+	for _, sub := range subscriptionIds {
+		if sub := trimSubscriptionsPrefix(sub); strings.EqualFold(sub, id) {
+			return "/subscriptions/" + sub, nil
+		}
+	}
+	return "", fmt.Errorf("subscription %s is not in subscription_ids", scope)
+}
+
+// trimSubscriptionsPrefix returns the subscription id without the case-insensitive `/subscriptions/` prefix.
+func trimSubscriptionsPrefix(s string) string {
+	const prefix = "/subscriptions/"
+	if len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):]
+	}
+	return s
+}
+
 // convertAlzPolicyRoleAssignments converts a map[string]alzlib.PolicyAssignmentAdditionalRoleAssignments to a map[string]AlzPolicyRoleAssignmentType.
-func convertAlzPolicyRoleAssignments(src []alzlib.PolicyRoleAssignment) map[string]AlzPolicyRoleAssignmentType {
+// The principal ids are keyed by policy assignment name, assignments without a principal id have a null value.
+func convertAlzPolicyRoleAssignments(src []alzlib.PolicyRoleAssignment, principalIds map[string]types.String) map[string]AlzPolicyRoleAssignmentType {
 	if len(src) == 0 {
 		return nil
 	}
 	res := make(map[string]AlzPolicyRoleAssignmentType, len(src))
 	for _, v := range src {
+		pid, ok := principalIds[v.AssignmentName]
+		if !ok {
+			pid = types.StringNull()
+		}
 		res[genPolicyRoleAssignmentId(v)] = AlzPolicyRoleAssignmentType{
 			RoleDefinitionId: types.StringValue(v.RoleDefinitionId),
 			Scope:            types.StringValue(v.Scope),
 			AssignmentName:   types.StringValue(v.AssignmentName),
+			PrincipalId:      pid,
+		}
+	}
+	return res
+}
+
+// parameterSubstitutionsForAssignments returns the parameter values to set for each policy assignment,
+// where the assignment has a parameter with the same name as one of the substitutions.
+func parameterSubstitutionsForAssignments(pas map[string]armpolicy.Assignment, subs map[string]*armpolicy.ParameterValuesValue) map[string]map[string]*armpolicy.ParameterValuesValue {
+	if len(subs) == 0 {
+		return nil
+	}
+	res := make(map[string]map[string]*armpolicy.ParameterValuesValue)
+	for name, pa := range pas {
+		if pa.Properties == nil {
+			continue
+		}
+		for param := range pa.Properties.Parameters {
+			v, ok := subs[param]
+			if !ok {
+				continue
+			}
+			if _, ok := res[name]; !ok {
+				res[name] = make(map[string]*armpolicy.ParameterValuesValue)
+			}
+			res[name][param] = v
+		}
+	}
+	return res
+}
+
+// identityOverridesForAssignments returns the user assigned identity for each policy assignment with a system assigned identity
+// whose name matches one of the overrides. The first matching override is used.
+func identityOverridesForAssignments(pas map[string]armpolicy.Assignment, overrides []identityOverride) map[string]*armpolicy.Identity {
+	if len(overrides) == 0 {
+		return nil
+	}
+	res := make(map[string]*armpolicy.Identity)
+	for name, pa := range pas {
+		if pa.Identity == nil || pa.Identity.Type == nil || *pa.Identity.Type != armpolicy.ResourceIdentityTypeSystemAssigned {
+			continue
+		}
+		for _, o := range overrides {
+			if !o.re.MatchString(name) {
+				continue
+			}
+			res[name] = &armpolicy.Identity{
+				Type:                   to.Ptr(armpolicy.ResourceIdentityTypeUserAssigned),
+				UserAssignedIdentities: map[string]*armpolicy.UserAssignedIdentitiesValue{o.identityId: {}},
+			}
+			break
 		}
 	}
 	return res
 }
 
+// wellKnownPolicyValues returns the values that alzlib substitutes into the policy assignments.
+// If substitution is disabled, no values are set, so alzlib leaves the parameters and locations of the library policy assignments unset.
+func wellKnownPolicyValues(defaults ArchetypeDataSourceModelDefaults, disabled bool) *alzlib.WellKnownPolicyValues {
+	wkpv := new(alzlib.WellKnownPolicyValues)
+	if disabled {
+		return wkpv
+	}
+	wkpv.DefaultLocation = to.Ptr(defaults.DefaultLocation.ValueString())
+	if isKnown(defaults.DefaultLaWorkspaceId) {
+		wkpv.DefaultLogAnalyticsWorkspaceId = to.Ptr(defaults.DefaultLaWorkspaceId.ValueString())
+	}
+	if isKnown(defaults.PrivateDnsZoneResourceGroupId) {
+		wkpv.PrivateDnsZoneResourceGroupId = to.Ptr(defaults.PrivateDnsZoneResourceGroupId.ValueString())
+	}
+	return wkpv
+}
+
+// applyIdentityPolicyAssignmentLocations sets the location of the policy assignments with a managed identity that have no location.
+// A location is required for a managed identity, and alzlib only sets it from the well known values, which are not set when default substitution is disabled.
+func applyIdentityPolicyAssignmentLocations(pas map[string]armpolicy.Assignment, location string) {
+	for k, pa := range pas {
+		if pa.Location != nil || pa.Identity == nil || pa.Identity.Type == nil || *pa.Identity.Type == armpolicy.ResourceIdentityTypeNone {
+			continue
+		}
+		pa.Location = to.Ptr(location)
+		pas[k] = pa
+	}
+}
+
+// applyPolicyAssignmentLocations sets the location of the policy assignments that have a location in the modifications.
+// The location is not supported by alzlib's ModifyPolicyAssignment, so it is applied to the copy of the policy assignments used for the output.
+func applyPolicyAssignmentLocations(pas map[string]armpolicy.Assignment, mods map[string]PolicyAssignmentType) {
+	for k, v := range mods {
+		pa, ok := pas[k]
+		if !ok || !isKnown(v.Location) {
+			continue
+		}
+		pa.Location = to.Ptr(v.Location.ValueString())
+		pas[k] = pa
+	}
+}
+
+// applyDefaultEnforcementModes sets the enforcement mode of the policy assignments that do not have one to `Default`,
+// the value that ARM returns when it is omitted, so the output does not differ from the deployed assignments.
+func applyDefaultEnforcementModes(pas map[string]armpolicy.Assignment) {
+	for k, pa := range pas {
+		if pa.Properties == nil {
+			pa.Properties = new(armpolicy.AssignmentProperties)
+		}
+		if pa.Properties.EnforcementMode != nil {
+			continue
+		}
+		pa.Properties.EnforcementMode = to.Ptr(armpolicy.EnforcementModeDefault)
+		pas[k] = pa
+	}
+}
+
+// resolveArchetypeDefaults returns the defaults of the archetype, which may be nil.
+// The location that is not set is taken from the provider defaults, which may be nil, then the other values that are not set are taken from
+// the regional defaults for the location, and then from the provider defaults, so the values of the data source override those of the provider.
+func resolveArchetypeDefaults(defaults *ArchetypeDataSourceModelDefaults, regional map[string]ArchetypeRegionalDefaultsType, provider *ArchetypeDefaultsType) (ArchetypeDataSourceModelDefaults, error) {
+	var res ArchetypeDataSourceModelDefaults
+	if defaults != nil {
+		res = *defaults
+	}
+	if provider != nil && res.DefaultLocation.IsNull() {
+		res.DefaultLocation = provider.Location
+	}
+	res, err := resolveRegionalDefaults(res, regional)
+	if err != nil {
+		return res, err
+	}
+	if provider != nil && res.DefaultLaWorkspaceId.IsNull() {
+		res.DefaultLaWorkspaceId = provider.LogAnalyticsWorkspaceId
+	}
+	return res, nil
+}
+
+// resolveRegionalDefaults returns the defaults, with the values that are not set taken from the regional defaults
+// for the default location. Locations are compared ignoring case and spaces.
+// An error is returned if there are regional defaults but none for the default location.
+func resolveRegionalDefaults(defaults ArchetypeDataSourceModelDefaults, regional map[string]ArchetypeRegionalDefaultsType) (ArchetypeDataSourceModelDefaults, error) {
+	if len(regional) == 0 {
+		return defaults, nil
+	}
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "")) }
+	loc := normalize(defaults.DefaultLocation.ValueString())
+	keys := make([]string, 0, len(regional))
+	for k, v := range regional {
+		keys = append(keys, k)
+		if normalize(k) != loc {
+			continue
+		}
+		if defaults.DefaultLaWorkspaceId.IsNull() {
+			defaults.DefaultLaWorkspaceId = v.DefaultLaWorkspaceId
+		}
+		if defaults.PrivateDnsZoneResourceGroupId.IsNull() {
+			defaults.PrivateDnsZoneResourceGroupId = v.PrivateDnsZoneResourceGroupId
+		}
+		return defaults, nil
+	}
+	slices.Sort(keys)
+	return defaults, fmt.Errorf("no regional defaults for location %s, regions are: %s", defaults.DefaultLocation.ValueString(), strings.Join(keys, ", "))
+}
+
+// rolloutPhaseEnforcementModes returns the enforcement mode for each policy assignment in the rollout phase.
+// Only the enforcement mode is set by a phase. In the `enforce-new` phase, assignments with a managed identity are not enforced,
+// as they are the ones that deploy or modify resources. Returns nil if the phase is empty.
+func rolloutPhaseEnforcementModes(pas map[string]armpolicy.Assignment, phase string) map[string]*armpolicy.EnforcementMode {
+	if phase == "" {
+		return nil
+	}
+	res := make(map[string]*armpolicy.EnforcementMode, len(pas))
+	for name, pa := range pas {
+		switch phase {
+		case rolloutPhaseAudit:
+			res[name] = to.Ptr(armpolicy.EnforcementModeDoNotEnforce)
+		case rolloutPhaseEnforceNew:
+			if pa.Identity != nil && pa.Identity.Type != nil && *pa.Identity.Type != armpolicy.ResourceIdentityTypeNone {
+				res[name] = to.Ptr(armpolicy.EnforcementModeDoNotEnforce)
+				continue
+			}
+			res[name] = to.Ptr(armpolicy.EnforcementModeDefault)
+		case rolloutPhaseEnforceAll:
+			res[name] = to.Ptr(armpolicy.EnforcementModeDefault)
+		}
+	}
+	return res
+}
+
+// convertRoleDefinitionPermissions converts the permissions of the role definitions to sets of actions.
+// The data actions are read from the library index, as they are not present in the Azure SDK role definition type.
+func convertRoleDefinitionPermissions(ctx context.Context, src map[string]armauthorization.RoleDefinition, library *libraryIndex) (map[string]AlzRoleDefinitionPermissionsType, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if len(src) == 0 {
+		return nil, diags
+	}
+	res := make(map[string]AlzRoleDefinitionPermissionsType, len(src))
+	for k, rd := range src {
+		actions := mapset.NewThreadUnsafeSet[string]()
+		notActions := mapset.NewThreadUnsafeSet[string]()
+		dataActions := mapset.NewThreadUnsafeSet[string]()
+		notDataActions := mapset.NewThreadUnsafeSet[string]()
+		if rd.Properties != nil {
+			for _, p := range rd.Properties.Permissions {
+				if p == nil {
+					continue
+				}
+				addStringPtrsToSet(actions, p.Actions)
+				addStringPtrsToSet(notActions, p.NotActions)
+			}
+			if rd.Properties.RoleName != nil {
+				perms, _ := library.RoleDefinitionPermissions(*rd.Properties.RoleName)
+				for _, p := range perms {
+					dataActions.Append(p.DataActions...)
+					notDataActions.Append(p.NotDataActions...)
+				}
+			}
+		}
+		var v AlzRoleDefinitionPermissionsType
+		var d diag.Diagnostics
+		v.Actions, d = types.SetValueFrom(ctx, types.StringType, actions.ToSlice())
+		diags.Append(d...)
+		v.NotActions, d = types.SetValueFrom(ctx, types.StringType, notActions.ToSlice())
+		diags.Append(d...)
+		v.DataActions, d = types.SetValueFrom(ctx, types.StringType, dataActions.ToSlice())
+		diags.Append(d...)
+		v.NotDataActions, d = types.SetValueFrom(ctx, types.StringType, notDataActions.ToSlice())
+		diags.Append(d...)
+		res[k] = v
+	}
+	return res, diags
+}
+
+// addStringPtrsToSet adds the non-nil values to the set.
+func addStringPtrsToSet(set mapset.Set[string], src []*string) {
+	for _, v := range src {
+		if v != nil {
+			set.Add(*v)
+		}
+	}
+}
+
 // convertMapOfStringToMapValue converts a map[string]armTypes to a map[string]attr.Value, using types.StringType as the value type.
 func convertMapOfStringToMapValue[T mapTypes](m map[string]T) (basetypes.MapValue, diag.Diagnostics) {
 	result := make(map[string]attr.Value, len(m))
 	for k, v := range m {
-		b, err := json.Marshal(v)
+		b, err := marshalArmJSON(v)
 		if err != nil {
 			var diags diag.Diagnostics
 			diags.AddError("Unable to marshal ARM object", err.Error())
@@ -670,6 +2012,9 @@ func policyAssignmentType2ArmPolicyValues(pa PolicyAssignmentType) (
 	if err != nil {
 		return nil, nil, nil, nil, nil, nil, fmt.Errorf("unable to convert policy assignment parameters to sdk type: %w", err)
 	}
+	if parameters, err = mergeRawPolicyAssignmentParameters(parameters, pa.RawParameters); err != nil {
+		return nil, nil, nil, nil, nil, nil, fmt.Errorf("unable to convert policy assignment raw parameters to sdk type: %w", err)
+	}
 
 	resourceSelectors, err = convertPolicyAssignmentResourceSelectorsToSdkType(pa.ResourceSelectors)
 	if err != nil {
@@ -745,14 +2090,14 @@ func convertPolicyAssignmentResourceSelectorsToSdkType(src []ResourceSelectorTyp
 	return res, nil
 }
 
-func convertPolicyAssignmentEnforcementModeToSdkType(src types.String) *armpolicy.EnforcementMode {
+func convertPolicyAssignmentEnforcementModeToSdkType(src alztypes.EnforcementModeValue) *armpolicy.EnforcementMode {
 	if !isKnown(src) {
 		return nil
 	}
-	switch src.ValueString() {
-	case "DoNotEnforce":
+	switch src.ValueEnforcementMode() {
+	case alztypes.EnforcementModeDoNotEnforce:
 		return to.Ptr(armpolicy.EnforcementModeDoNotEnforce)
-	case "Default":
+	case alztypes.EnforcementModeDefault:
 		return to.Ptr(armpolicy.EnforcementModeDefault)
 	}
 	return nil
@@ -810,6 +2155,7 @@ func convertPolicyAssignmentIdentityToSdkType(typ types.String, ids types.Set) (
 }
 
 // convertPolicyAssignmentParametersToSdkType converts a map[string]any to a map[string]*armpolicy.ParameterValuesValue.
+// Values in the ARM format, `{ "value": ... }`, are unwrapped.
 func convertPolicyAssignmentParametersToSdkType(src alztypes.PolicyParameterValue) (map[string]*armpolicy.ParameterValuesValue, error) {
 	if !isKnown(src) {
 		return nil, nil
@@ -824,7 +2170,7 @@ func convertPolicyAssignmentParametersToSdkType(src alztypes.PolicyParameterValu
 	res := make(map[string]*armpolicy.ParameterValuesValue, len(params))
 	for k, v := range params {
 		val := new(armpolicy.ParameterValuesValue)
-		val.Value = v
+		val.Value = unwrapParameterValue(v)
 		res[k] = val
 	}
 	return res, nil
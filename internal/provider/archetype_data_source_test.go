@@ -6,27 +6,36 @@ package provider
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/Azure/alzlib"
 	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
 	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+	"github.com/Azure/terraform-provider-alz/internal/synthlib"
 
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestAccAlzArchetypeDataSource tests the data source for alz_archetype.
 // It checks that the policy parameter substitution & location defaults are applied.
+// The requests to Azure are replayed from testdata/fixtures/archetype.json, so no tenant is required.
 func TestAccAlzArchetypeDataSource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesUnique(),
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesFixture(t, "testdata/fixtures/archetype.json"),
 		ExternalProviders: map[string]resource.ExternalProvider{
 			"random": {
 				Source: "hashicorp/random",
@@ -39,6 +48,7 @@ func TestAccAlzArchetypeDataSource(t *testing.T) {
 					resource.TestCheckResourceAttr("data.alz_archetype.test", "id", "example"),
 					resource.TestCheckOutput("test_location_replacement", "westeurope"),
 					resource.TestCheckOutput("test_parameter_replacement", "test"),
+					resource.TestCheckOutput("test_enforcement_mode_default", "Default"),
 				),
 			},
 		},
@@ -72,7 +82,10 @@ func testAccExampleDataSourceConfig() string {
 
 	return fmt.Sprintf(`
 provider "alz" {
-  use_alz_lib = false
+  auth_method                      = "client_secret"
+  check_existing_management_groups = true
+  metadata_host                    = "management.azure.com"
+  use_alz_lib                      = false
   lib_urls = [
     "%s",
   ]
@@ -106,6 +119,11 @@ output "test_location_replacement" {
 output "test_parameter_replacement" {
   value = jsondecode(data.alz_archetype.test.alz_policy_assignments["BlobServicesDiagnosticsLogsToWorkspace"]).properties.parameters.logAnalytics.value
 }
+
+# Test that the null enforcement mode of the library is returned as Default
+output "test_enforcement_mode_default" {
+  value = jsondecode(data.alz_archetype.test.alz_policy_assignments["BlobServicesDiagnosticsLogsToWorkspace"]).properties.enforcementMode
+}
 `, libPath)
 }
 
@@ -247,12 +265,12 @@ output "test" {
 
 func TestConvertAlzPolicyRoleAssignments(t *testing.T) {
 	// Test with nil input
-	res := convertAlzPolicyRoleAssignments(nil)
+	res := convertAlzPolicyRoleAssignments(nil, nil)
 	assert.Nil(t, res)
 	assert.Empty(t, res)
 
 	// Test with empty input
-	res = convertAlzPolicyRoleAssignments(make([]alzlib.PolicyRoleAssignment, 0))
+	res = convertAlzPolicyRoleAssignments(make([]alzlib.PolicyRoleAssignment, 0), nil)
 	assert.Nil(t, res)
 	assert.Empty(t, res)
 
@@ -264,7 +282,7 @@ func TestConvertAlzPolicyRoleAssignments(t *testing.T) {
 			AssignmentName:   "test1",
 		},
 	}
-	res = convertAlzPolicyRoleAssignments(src)
+	res = convertAlzPolicyRoleAssignments(src, nil)
 	assert.NotNil(t, res)
 	assert.Len(t, res, len(src))
 	for _, v := range src {
@@ -272,7 +290,22 @@ func TestConvertAlzPolicyRoleAssignments(t *testing.T) {
 		assert.Equal(t, v.RoleDefinitionId, res[key].RoleDefinitionId.ValueString())
 		assert.Equal(t, v.Scope, res[key].Scope.ValueString())
 		assert.Equal(t, v.AssignmentName, res[key].AssignmentName.ValueString())
+		assert.True(t, res[key].PrincipalId.IsNull())
 	}
+
+	// Test with principal ids, including an unknown value
+	src = append(src, alzlib.PolicyRoleAssignment{
+		RoleDefinitionId: "test2",
+		Scope:            "test2",
+		AssignmentName:   "test2",
+	})
+	principalIds := map[string]types.String{
+		"test1": types.StringValue("00000000-0000-0000-0000-000000000001"),
+		"test2": types.StringUnknown(),
+	}
+	res = convertAlzPolicyRoleAssignments(src, principalIds)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", res[genPolicyRoleAssignmentId(src[0])].PrincipalId.ValueString())
+	assert.True(t, res[genPolicyRoleAssignmentId(src[1])].PrincipalId.IsUnknown())
 }
 
 // TestPolicyAssignmentType2ArmPolicyValues tests the policyAssignmentType2ArmPolicyValues function.
@@ -283,7 +316,7 @@ func TestPolicyAssignmentType2ArmPolicyValues(t *testing.T) {
 		"param3": true
 	}`))
 	pa := PolicyAssignmentType{ //nolint:forcetypeassert
-		EnforcementMode: types.StringValue("DoNotEnforce"),
+		EnforcementMode: alztypes.NewEnforcementModeValue("DoNotEnforce"),
 		NonComplianceMessage: []PolicyAssignmentNonComplianceMessage{
 			{
 				Message:                     types.StringValue("Non-compliance message 1"),
@@ -347,18 +380,27 @@ func TestConvertPolicyAssignmentParametersToSdkType(t *testing.T) {
 
 func TestConvertPolicyAssignmentEnforcementModeToSdkType(t *testing.T) {
 	// Test with unknown enforcement mode
-	src := types.StringValue("Unknown")
+	src := alztypes.NewEnforcementModeValue("Unknown")
 	res := convertPolicyAssignmentEnforcementModeToSdkType(src)
 	assert.Nil(t, res)
 
+	// Test with null enforcement mode, which leaves the library value unchanged
+	res = convertPolicyAssignmentEnforcementModeToSdkType(alztypes.NewEnforcementModeNull())
+	assert.Nil(t, res)
+
+	// Test with empty enforcement mode, which is equivalent to Default
+	res = convertPolicyAssignmentEnforcementModeToSdkType(alztypes.NewEnforcementModeValue(""))
+	assert.NotNil(t, res)
+	assert.Equal(t, armpolicy.EnforcementModeDefault, *res)
+
 	// Test with DoNotEnforce enforcement mode
-	src = types.StringValue("DoNotEnforce")
+	src = alztypes.NewEnforcementModeValue("DoNotEnforce")
 	res = convertPolicyAssignmentEnforcementModeToSdkType(src)
 	assert.NotNil(t, res)
 	assert.Equal(t, armpolicy.EnforcementModeDoNotEnforce, *res)
 
 	// Test with Default enforcement mode
-	src = types.StringValue("Default")
+	src = alztypes.NewEnforcementModeValue("Default")
 	res = convertPolicyAssignmentEnforcementModeToSdkType(src)
 	assert.NotNil(t, res)
 	assert.Equal(t, armpolicy.EnforcementModeDefault, *res)
@@ -530,3 +572,432 @@ func TestConvertPolicyAssignmentResourceSelectorsToSdkType(t *testing.T) {
 		assert.Nil(t, res)
 	})
 }
+
+// BenchmarkArchetypeResolution measures copying an archetype and adding a management group to the deployment,
+// which is the work done by each read of the archetype data source.
+func BenchmarkArchetypeResolution(b *testing.B) {
+	alz := alzlib.NewAlzLib()
+	require.NoError(b, alz.Init(context.Background(), os.DirFS("testdata/testacc_lib")))
+	wkpv := &alzlib.WellKnownPolicyValues{DefaultLocation: to.Ptr("westeurope")}
+	arch, err := alz.CopyArchetype("test", wkpv)
+	require.NoError(b, err)
+	require.NoError(b, alz.AddManagementGroupToDeployment(context.Background(), alzlib.AlzManagementGroupAddRequest{
+		Id:               "root",
+		ParentId:         "00000000-0000-0000-0000-000000000000",
+		ParentIsExternal: true,
+		Archetype:        arch,
+	}))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		arch, err := alz.CopyArchetype("test", wkpv)
+		require.NoError(b, err)
+		require.NoError(b, alz.AddManagementGroupToDeployment(context.Background(), alzlib.AlzManagementGroupAddRequest{
+			Id:        fmt.Sprintf("bench%d", i),
+			ParentId:  "root",
+			Archetype: arch,
+		}))
+	}
+}
+
+// BenchmarkLibraryInit measures processing the library, which is done once by the provider configuration.
+func BenchmarkLibraryInit(b *testing.B) {
+	libs := []fs.FS{os.DirFS("testdata/testacc_lib")}
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, alzlib.NewAlzLib().Init(context.Background(), libs...))
+		_, err := newLibraryIndex(libs)
+		require.NoError(b, err)
+	}
+}
+
+// BenchmarkSyntheticLibraryInit measures processing synthetic libraries of increasing size.
+func BenchmarkSyntheticLibraryInit(b *testing.B) {
+	for _, o := range []synthlib.Options{
+		{Archetypes: 5, PolicyDefinitions: 100, PolicySetDefinitions: 10},
+		{Archetypes: 20, PolicyDefinitions: 1000, PolicySetDefinitions: 100},
+	} {
+		lib, err := synthlib.FS(o)
+		require.NoError(b, err)
+		b.Run(fmt.Sprintf("%d_%d_%d", o.Archetypes, o.PolicyDefinitions, o.PolicySetDefinitions), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, alzlib.NewAlzLib().Init(context.Background(), lib))
+			}
+		})
+	}
+}
+
+func TestConvertBaseArchetypeDefinition(t *testing.T) {
+	arch := &alzlib.Archetype{
+		PolicyAssignments:    mapset.NewSet("pa"),
+		PolicyDefinitions:    mapset.NewSet("pd1", "pd2"),
+		PolicySetDefinitions: mapset.NewSet[string](),
+		RoleDefinitions:      mapset.NewSet("rd"),
+	}
+	res, diags := convertBaseArchetypeDefinition(context.Background(), "test", arch)
+	require.False(t, diags.HasError())
+	assert.Equal(t, "test", res.Name.ValueString())
+	var pds []string
+	require.False(t, res.PolicyDefinitions.ElementsAs(context.Background(), &pds, false).HasError())
+	assert.ElementsMatch(t, []string{"pd1", "pd2"}, pds)
+	assert.Empty(t, res.PolicySetDefinitions.Elements())
+	assert.Len(t, res.RoleDefinitions.Elements(), 1)
+}
+
+func TestExcludeArchetypePolicyAssignments(t *testing.T) {
+	arch := &alzlib.Archetype{PolicyAssignments: mapset.NewSet("Enable-DDoS-VNET", "Deny-Public-IP", "Deploy-MDFC-Config")}
+	res := excludeArchetypePolicyAssignments(arch, []*regexp.Regexp{regexp.MustCompile("DDoS"), regexp.MustCompile("^Deny-")})
+	assert.ElementsMatch(t, []string{"Enable-DDoS-VNET", "Deny-Public-IP"}, res)
+	assert.ElementsMatch(t, []string{"Deploy-MDFC-Config"}, arch.PolicyAssignments.ToSlice())
+
+	assert.Empty(t, excludeArchetypePolicyAssignments(arch, nil))
+}
+
+func TestCanaryManagementGroupNames(t *testing.T) {
+	exists := func(name string) bool { return name == "alz" || name == "alz-canary" || name == "platform" }
+
+	id, parent, err := canaryManagementGroupNames("landingzones", "alz", "", exists)
+	require.NoError(t, err)
+	assert.Equal(t, "landingzones", id)
+	assert.Equal(t, "alz", parent)
+
+	id, parent, err = canaryManagementGroupNames("landingzones", "alz", "-canary", exists)
+	require.NoError(t, err)
+	assert.Equal(t, "landingzones-canary", id)
+	assert.Equal(t, "alz-canary", parent)
+
+	id, parent, err = canaryManagementGroupNames("alz", "tenant-root", "-canary", exists)
+	require.NoError(t, err)
+	assert.Equal(t, "alz-canary", id)
+	assert.Equal(t, "tenant-root", parent, "an external parent does not have the suffix")
+
+	_, _, err = canaryManagementGroupNames("connectivity", "platform", "-canary", exists)
+	assert.ErrorContains(t, err, "platform-canary")
+}
+
+// TestManagementGroupAncestry tests that the ancestry starts with the external parent and ends with the management group.
+func TestManagementGroupAncestry(t *testing.T) {
+	alz := newTestAlzProviderData(t)
+	arch, err := alz.CopyArchetype("test", &alzlib.WellKnownPolicyValues{DefaultLocation: to.Ptr("westeurope")})
+	require.NoError(t, err)
+	require.NoError(t, alz.AddManagementGroupToDeployment(context.Background(), alzlib.AlzManagementGroupAddRequest{
+		Id:        "child",
+		ParentId:  "test",
+		Archetype: arch,
+	}))
+
+	assert.Equal(t, []string{"00000000-0000-0000-0000-000000000000", "test"}, managementGroupAncestry(alz.Deployment.GetManagementGroup("test")))
+	assert.Equal(t, []string{"00000000-0000-0000-0000-000000000000", "test", "child"}, managementGroupAncestry(alz.Deployment.GetManagementGroup("child")))
+	assert.Empty(t, managementGroupAncestry(nil))
+}
+
+// TestConvertRoleDefinitionPermissions tests that permissions are combined into sets,
+// and that data actions are read from the library index.
+func TestConvertRoleDefinitionPermissions(t *testing.T) {
+	res, diags := convertRoleDefinitionPermissions(context.Background(), nil, nil)
+	assert.False(t, diags.HasError())
+	assert.Nil(t, res)
+
+	src := map[string]armauthorization.RoleDefinition{
+		"test": {
+			Properties: &armauthorization.RoleDefinitionProperties{
+				RoleName: to.Ptr("Test-Role"),
+				Permissions: []*armauthorization.Permission{
+					{
+						Actions:    to.SliceOfPtrs("*/read", "Microsoft.Storage/*"),
+						NotActions: to.SliceOfPtrs("Microsoft.Storage/delete"),
+					},
+					{
+						Actions: to.SliceOfPtrs("*/read"),
+					},
+				},
+			},
+		},
+	}
+	library := &libraryIndex{
+		roleDefinitionPermissions: map[string][]libraryRoleDefinitionPermission{
+			"Test-Role": {
+				{DataActions: []string{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read"}},
+			},
+		},
+	}
+	res, diags = convertRoleDefinitionPermissions(context.Background(), src, library)
+	assert.False(t, diags.HasError())
+	assert.Len(t, res, 1)
+	assert.Len(t, res["test"].Actions.Elements(), 2)
+	assert.Contains(t, res["test"].Actions.Elements(), types.StringValue("Microsoft.Storage/*"))
+	assert.Equal(t, []attr.Value{types.StringValue("Microsoft.Storage/delete")}, res["test"].NotActions.Elements())
+	assert.Equal(t, []attr.Value{types.StringValue("Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read")}, res["test"].DataActions.Elements())
+	assert.Empty(t, res["test"].NotDataActions.Elements())
+	assert.False(t, res["test"].NotDataActions.IsNull())
+
+	// Without the library index, data actions are empty.
+	res, diags = convertRoleDefinitionPermissions(context.Background(), src, nil)
+	assert.False(t, diags.HasError())
+	assert.Empty(t, res["test"].DataActions.Elements())
+}
+
+// TestParameterSubstitutionsForAssignments tests that substitutions are only applied to assignments with a matching parameter.
+func TestParameterSubstitutionsForAssignments(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"pa1": {
+			Properties: &armpolicy.AssignmentProperties{
+				Parameters: map[string]*armpolicy.ParameterValuesValue{
+					"emailSecurityContact": {Value: "old@example.com"},
+					"effect":               {Value: "Audit"},
+				},
+			},
+		},
+		"pa2": {
+			Properties: &armpolicy.AssignmentProperties{
+				Parameters: map[string]*armpolicy.ParameterValuesValue{
+					"effect": {Value: "Audit"},
+				},
+			},
+		},
+		"pa3": {},
+	}
+
+	assert.Nil(t, parameterSubstitutionsForAssignments(pas, nil))
+
+	subs := map[string]*armpolicy.ParameterValuesValue{
+		"emailSecurityContact": {Value: "security@example.com"},
+		"logAnalytics":         {Value: "la"},
+	}
+	res := parameterSubstitutionsForAssignments(pas, subs)
+	assert.Len(t, res, 1)
+	assert.Len(t, res["pa1"], 1)
+	assert.Equal(t, "security@example.com", res["pa1"]["emailSecurityContact"].Value)
+}
+
+func TestApplyPolicyAssignmentLocations(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"pa1": {Location: to.Ptr("westeurope")},
+		"pa2": {Location: to.Ptr("westeurope")},
+	}
+	applyPolicyAssignmentLocations(pas, map[string]PolicyAssignmentType{
+		"pa1":     {Location: types.StringValue("swedencentral")},
+		"pa2":     {Location: types.StringNull()},
+		"missing": {Location: types.StringValue("swedencentral")},
+	})
+	assert.Equal(t, "swedencentral", *pas["pa1"].Location)
+	assert.Equal(t, "westeurope", *pas["pa2"].Location)
+	assert.NotContains(t, pas, "missing")
+}
+
+func TestApplyDefaultEnforcementModes(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"nil":          {Properties: &armpolicy.AssignmentProperties{}},
+		"noProperties": {},
+		"doNotEnforce": {Properties: &armpolicy.AssignmentProperties{EnforcementMode: to.Ptr(armpolicy.EnforcementModeDoNotEnforce)}},
+	}
+	applyDefaultEnforcementModes(pas)
+	assert.Equal(t, armpolicy.EnforcementModeDefault, *pas["nil"].Properties.EnforcementMode)
+	assert.Equal(t, armpolicy.EnforcementModeDefault, *pas["noProperties"].Properties.EnforcementMode)
+	assert.Equal(t, armpolicy.EnforcementModeDoNotEnforce, *pas["doNotEnforce"].Properties.EnforcementMode)
+}
+
+func TestApplyIdentityPolicyAssignmentLocations(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"deny":     {},
+		"dine":     {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}},
+		"none":     {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeNone)}},
+		"location": {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}, Location: to.Ptr("swedencentral")},
+	}
+	applyIdentityPolicyAssignmentLocations(pas, "westeurope")
+	assert.Nil(t, pas["deny"].Location)
+	assert.Equal(t, "westeurope", *pas["dine"].Location)
+	assert.Nil(t, pas["none"].Location)
+	assert.Equal(t, "swedencentral", *pas["location"].Location)
+}
+
+func TestWellKnownPolicyValues(t *testing.T) {
+	defaults := ArchetypeDataSourceModelDefaults{
+		DefaultLocation:               types.StringValue("westeurope"),
+		DefaultLaWorkspaceId:          types.StringValue("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/la"),
+		PrivateDnsZoneResourceGroupId: types.StringNull(),
+	}
+	wkpv := wellKnownPolicyValues(defaults, false)
+	assert.Equal(t, "westeurope", *wkpv.DefaultLocation)
+	assert.Equal(t, defaults.DefaultLaWorkspaceId.ValueString(), *wkpv.DefaultLogAnalyticsWorkspaceId)
+	assert.Nil(t, wkpv.PrivateDnsZoneResourceGroupId)
+
+	wkpv = wellKnownPolicyValues(defaults, true)
+	require.NotNil(t, wkpv, "alzlib requires the well known values to be set")
+	assert.Equal(t, alzlib.WellKnownPolicyValues{}, *wkpv)
+}
+
+func TestRolloutPhaseEnforcementModes(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"deny": {},
+		"dine": {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}},
+		"none": {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeNone)}},
+	}
+
+	assert.Nil(t, rolloutPhaseEnforcementModes(pas, ""))
+
+	res := rolloutPhaseEnforcementModes(pas, rolloutPhaseAudit)
+	assert.Len(t, res, 3)
+	for _, v := range res {
+		assert.Equal(t, armpolicy.EnforcementModeDoNotEnforce, *v)
+	}
+
+	res = rolloutPhaseEnforcementModes(pas, rolloutPhaseEnforceNew)
+	assert.Equal(t, armpolicy.EnforcementModeDefault, *res["deny"])
+	assert.Equal(t, armpolicy.EnforcementModeDoNotEnforce, *res["dine"])
+	assert.Equal(t, armpolicy.EnforcementModeDefault, *res["none"])
+
+	res = rolloutPhaseEnforcementModes(pas, rolloutPhaseEnforceAll)
+	assert.Len(t, res, 3)
+	for _, v := range res {
+		assert.Equal(t, armpolicy.EnforcementModeDefault, *v)
+	}
+}
+
+func TestRoleAssignmentScope(t *testing.T) {
+	mgId := "/providers/Microsoft.Management/managementGroups/corp"
+	subs := []string{"00000000-0000-0000-0000-000000000001", "/subscriptions/00000000-0000-0000-0000-000000000002"}
+
+	res, err := roleAssignmentScope(mgId, subs, "")
+	require.NoError(t, err)
+	assert.Equal(t, mgId, res)
+
+	res, err = roleAssignmentScope(mgId, subs, "00000000-0000-0000-0000-000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, "/subscriptions/00000000-0000-0000-0000-000000000001", res)
+
+	res, err = roleAssignmentScope(mgId, subs, "/Subscriptions/00000000-0000-0000-0000-000000000002")
+	require.NoError(t, err)
+	assert.Equal(t, "/subscriptions/00000000-0000-0000-0000-000000000002", res)
+
+	_, err = roleAssignmentScope(mgId, subs, "00000000-0000-0000-0000-000000000003")
+	assert.ErrorContains(t, err, "is not in subscription_ids")
+}
+
+func TestIdentityOverridesForAssignments(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-VM-Monitoring":   {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}},
+		"Deploy-VMSS-Monitoring": {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeUserAssigned)}},
+		"Deny-Monitoring":        {},
+		"Deploy-Other":           {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}},
+	}
+	assert.Nil(t, identityOverridesForAssignments(pas, nil))
+
+	res := identityOverridesForAssignments(pas, []identityOverride{
+		{re: regexp.MustCompile("-Monitoring$"), identityId: "id1"},
+		{re: regexp.MustCompile("^Deploy-VM-"), identityId: "id2"},
+	})
+	require.Len(t, res, 1)
+	ident := res["Deploy-VM-Monitoring"]
+	require.NotNil(t, ident)
+	assert.Equal(t, armpolicy.ResourceIdentityTypeUserAssigned, *ident.Type)
+	assert.Contains(t, ident.UserAssignedIdentities, "id1")
+}
+
+// TestResolveRegionalDefaults tests that unset defaults are taken from the regional defaults for the location.
+func TestResolveRegionalDefaults(t *testing.T) {
+	laWeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/weu"
+	laNeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/neu"
+	dnsNeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/dns-neu"
+	regional := map[string]ArchetypeRegionalDefaultsType{
+		"West Europe": {DefaultLaWorkspaceId: types.StringValue(laWeu), PrivateDnsZoneResourceGroupId: types.StringNull()},
+		"northeurope": {DefaultLaWorkspaceId: types.StringValue(laNeu), PrivateDnsZoneResourceGroupId: types.StringValue(dnsNeu)},
+	}
+	defaults := ArchetypeDataSourceModelDefaults{
+		DefaultLocation:               types.StringValue("westeurope"),
+		DefaultLaWorkspaceId:          types.StringNull(),
+		PrivateDnsZoneResourceGroupId: types.StringNull(),
+	}
+
+	res, err := resolveRegionalDefaults(defaults, regional)
+	require.NoError(t, err)
+	assert.Equal(t, laWeu, res.DefaultLaWorkspaceId.ValueString())
+	assert.True(t, res.PrivateDnsZoneResourceGroupId.IsNull())
+
+	// Values in defaults take precedence.
+	defaults.DefaultLocation = types.StringValue("northeurope")
+	defaults.DefaultLaWorkspaceId = types.StringValue(laWeu)
+	res, err = resolveRegionalDefaults(defaults, regional)
+	require.NoError(t, err)
+	assert.Equal(t, laWeu, res.DefaultLaWorkspaceId.ValueString())
+	assert.Equal(t, dnsNeu, res.PrivateDnsZoneResourceGroupId.ValueString())
+
+	defaults.DefaultLocation = types.StringValue("uksouth")
+	_, err = resolveRegionalDefaults(defaults, regional)
+	assert.ErrorContains(t, err, "no regional defaults for location uksouth, regions are: West Europe, northeurope")
+
+	res, err = resolveRegionalDefaults(defaults, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaults, res)
+}
+
+func TestResolveArchetypeDefaults(t *testing.T) {
+	laProvider := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/provider"
+	laNeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/neu"
+	provider := &ArchetypeDefaultsType{
+		Location:                types.StringValue("westeurope"),
+		LogAnalyticsWorkspaceId: types.StringValue(laProvider),
+	}
+
+	// No data source defaults.
+	res, err := resolveArchetypeDefaults(nil, nil, provider)
+	require.NoError(t, err)
+	assert.Equal(t, "westeurope", res.DefaultLocation.ValueString())
+	assert.Equal(t, laProvider, res.DefaultLaWorkspaceId.ValueString())
+	assert.True(t, res.PrivateDnsZoneResourceGroupId.IsNull())
+
+	// Data source and regional defaults take precedence.
+	defaults := &ArchetypeDataSourceModelDefaults{DefaultLocation: types.StringValue("northeurope")}
+	regional := map[string]ArchetypeRegionalDefaultsType{
+		"northeurope": {DefaultLaWorkspaceId: types.StringValue(laNeu)},
+	}
+	res, err = resolveArchetypeDefaults(defaults, regional, provider)
+	require.NoError(t, err)
+	assert.Equal(t, "northeurope", res.DefaultLocation.ValueString())
+	assert.Equal(t, laNeu, res.DefaultLaWorkspaceId.ValueString())
+
+	// The provider location selects the regional defaults.
+	_, err = resolveArchetypeDefaults(nil, regional, provider)
+	assert.ErrorContains(t, err, "no regional defaults for location westeurope")
+
+	// No defaults.
+	res, err = resolveArchetypeDefaults(nil, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, res.DefaultLocation.IsNull())
+}
+
+func TestExistingManagementGroupDifferences(t *testing.T) {
+	live := managementGroupInfo{Name: "alz", DisplayName: "ALZ", ParentName: "tenant"}
+	assert.Empty(t, existingManagementGroupDifferences(live, "ALZ", "Tenant"))
+	assert.Equal(t, []string{
+		`- display name is "ALZ" in Azure, declared "Azure Landing Zones"`,
+		`- parent is "tenant" in Azure, declared "other"`,
+	}, existingManagementGroupDifferences(live, "Azure Landing Zones", "other"))
+
+	// An empty display name is compared with the name.
+	assert.Len(t, existingManagementGroupDifferences(live, "", "tenant"), 1)
+	assert.Empty(t, existingManagementGroupDifferences(managementGroupInfo{Name: "alz", DisplayName: "alz", ParentName: "tenant"}, "", "tenant"))
+}
+
+// TestCheckExistingManagementGroup tests that a warning is returned for a different management group,
+// and that missing or unreadable management groups are skipped.
+func TestCheckExistingManagementGroup(t *testing.T) {
+	c := testManagementGroupsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/providers/Microsoft.Management/managementGroups/alz":
+			_, _ = w.Write([]byte(`{"name":"alz","properties":{"displayName":"ALZ","details":{"parent":{"id":"/providers/Microsoft.Management/managementGroups/tenant"}}}}`))
+		case "/providers/Microsoft.Management/managementGroups/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	diags := checkExistingManagementGroup(context.Background(), c, "alz", "ALZ", "tenant")
+	assert.Empty(t, diags)
+	diags = checkExistingManagementGroup(context.Background(), c, "alz", "ALZ", "other")
+	require.Len(t, diags, 1)
+	assert.Equal(t, "Management group differs from Azure", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), `parent is "tenant" in Azure, declared "other"`)
+
+	assert.Empty(t, checkExistingManagementGroup(context.Background(), c, "missing", "", "tenant"))
+	assert.Empty(t, checkExistingManagementGroup(context.Background(), c, "forbidden", "", "tenant"))
+}
@@ -0,0 +1,105 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+const (
+	// templateSpecSchema is the schema of the main template, which is deployed at management group scope.
+	templateSpecSchema = "https://schema.management.azure.com/schemas/2019-08-01/managementGroupDeploymentTemplate.json#"
+	// templateSpecPolicyApiVersion is the API version of the policy resources in the main template.
+	templateSpecPolicyApiVersion = "2023-04-01"
+	// templateSpecRoleDefinitionApiVersion is the API version of the role definitions in the main template.
+	templateSpecRoleDefinitionApiVersion = "2022-04-01"
+)
+
+// archetypeTemplateSpec returns the body of a template spec version, as JSON, whose main template deploys the
+// policy definitions, policy set definitions, role definitions and policy assignments of the management group.
+// The resources keep the ids of the management group, so the template must be deployed at that management group.
+// Policy set definitions depend on the policy definitions, and policy assignments depend on both.
+func archetypeTemplateSpec(
+	mgName, baseArchetype, location string,
+	pds map[string]armpolicy.Definition,
+	psds map[string]armpolicy.SetDefinition,
+	rds map[string]armauthorization.RoleDefinition,
+	pas map[string]armpolicy.Assignment,
+) (string, error) {
+	resources := make([]any, 0, len(pds)+len(psds)+len(rds)+len(pas))
+	pdRes, pdIds, err := templateSpecResources(pds, "Microsoft.Authorization/policyDefinitions", templateSpecPolicyApiVersion, nil)
+	if err != nil {
+		return "", fmt.Errorf("policy definition %w", err)
+	}
+	psdRes, psdIds, err := templateSpecResources(psds, "Microsoft.Authorization/policySetDefinitions", templateSpecPolicyApiVersion, pdIds)
+	if err != nil {
+		return "", fmt.Errorf("policy set definition %w", err)
+	}
+	rdRes, _, err := templateSpecResources(rds, "Microsoft.Authorization/roleDefinitions", templateSpecRoleDefinitionApiVersion, nil)
+	if err != nil {
+		return "", fmt.Errorf("role definition %w", err)
+	}
+	paRes, _, err := templateSpecResources(pas, "Microsoft.Authorization/policyAssignments", templateSpecPolicyApiVersion, append(append([]string{}, pdIds...), psdIds...))
+	if err != nil {
+		return "", fmt.Errorf("policy assignment %w", err)
+	}
+	resources = append(resources, pdRes...)
+	resources = append(resources, psdRes...)
+	resources = append(resources, rdRes...)
+	resources = append(resources, paRes...)
+	b, err := marshalArmJSON(map[string]any{
+		"location": location,
+		"properties": map[string]any{
+			"description": fmt.Sprintf("Azure Landing Zones archetype %s for management group %s.", baseArchetype, mgName),
+			"mainTemplate": map[string]any{
+				"$schema":        templateSpecSchema,
+				"contentVersion": "1.0.0.0",
+				"resources":      resources,
+			},
+			"metadata": map[string]any{
+				"base_archetype":        baseArchetype,
+				"management_group_name": mgName,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal template spec: %w", err)
+	}
+	return string(b), nil
+}
+
+// templateSpecResources returns the ARM JSON objects as template resources, sorted by name, and their resource ids.
+// The read-only `id` is removed and the resource type, API version and dependencies are set.
+func templateSpecResources[T mapTypes](m map[string]T, resourceType, apiVersion string, dependsOn []string) ([]any, []string, error) {
+	res := make([]any, 0, len(m))
+	ids := make([]string, 0, len(m))
+	for _, name := range sortedKeys(m) {
+		b, err := json.Marshal(m[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: unable to marshal: %w", name, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			return nil, nil, fmt.Errorf("%s: unable to unmarshal: %w", name, err)
+		}
+		if id, ok := r["id"].(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+		delete(r, "id")
+		r["type"] = resourceType
+		r["apiVersion"] = apiVersion
+		if len(dependsOn) != 0 {
+			r["dependsOn"] = dependsOn
+		}
+		res = append(res, r)
+	}
+	return res, ids, nil
+}
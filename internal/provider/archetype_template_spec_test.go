@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchetypeTemplateSpec(t *testing.T) {
+	mg := "/providers/Microsoft.Management/managementGroups/test"
+	pdId := mg + "/providers/Microsoft.Authorization/policyDefinitions/Deny-Public"
+	psdId := mg + "/providers/Microsoft.Authorization/policySetDefinitions/Enforce-Tags"
+	pds := map[string]armpolicy.Definition{
+		"Deny-Public": {ID: to.Ptr(pdId), Name: to.Ptr("Deny-Public"), Properties: &armpolicy.DefinitionProperties{DisplayName: to.Ptr("Deny public access")}},
+	}
+	psds := map[string]armpolicy.SetDefinition{
+		"Enforce-Tags": {ID: to.Ptr(psdId), Name: to.Ptr("Enforce-Tags")},
+	}
+	rds := map[string]armauthorization.RoleDefinition{
+		"Network-Contributor": {Name: to.Ptr("00000000-0000-0000-0000-000000000001")},
+	}
+	pas := map[string]armpolicy.Assignment{
+		"Deny-Public": {
+			Name:     to.Ptr("Deny-Public"),
+			Location: to.Ptr("westeurope"),
+			Properties: &armpolicy.AssignmentProperties{
+				PolicyDefinitionID: to.Ptr(pdId),
+				Parameters:         map[string]*armpolicy.ParameterValuesValue{"limit": {Value: json.Number("10")}},
+			},
+		},
+	}
+
+	s, err := archetypeTemplateSpec("test", "root", "westeurope", pds, psds, rds, pas)
+	require.NoError(t, err)
+	var spec struct {
+		Location   string `json:"location"`
+		Properties struct {
+			Description  string         `json:"description"`
+			Metadata     map[string]any `json:"metadata"`
+			MainTemplate struct {
+				Schema    string           `json:"$schema"`
+				Resources []map[string]any `json:"resources"`
+			} `json:"mainTemplate"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(s), &spec))
+	assert.Equal(t, "westeurope", spec.Location)
+	assert.Equal(t, "Azure Landing Zones archetype root for management group test.", spec.Properties.Description)
+	assert.Equal(t, map[string]any{"base_archetype": "root", "management_group_name": "test"}, spec.Properties.Metadata)
+	assert.Equal(t, templateSpecSchema, spec.Properties.MainTemplate.Schema)
+
+	res := spec.Properties.MainTemplate.Resources
+	require.Len(t, res, 4)
+	kinds := make([]any, len(res))
+	for i, r := range res {
+		kinds[i] = r["type"]
+		assert.NotContains(t, r, "id")
+	}
+	assert.Equal(t, []any{
+		"Microsoft.Authorization/policyDefinitions",
+		"Microsoft.Authorization/policySetDefinitions",
+		"Microsoft.Authorization/roleDefinitions",
+		"Microsoft.Authorization/policyAssignments",
+	}, kinds)
+	assert.Equal(t, templateSpecRoleDefinitionApiVersion, res[2]["apiVersion"])
+	assert.NotContains(t, res[0], "dependsOn")
+	assert.Equal(t, []any{pdId}, res[1]["dependsOn"])
+	assert.Equal(t, []any{pdId, psdId}, res[3]["dependsOn"])
+	assert.Equal(t, "westeurope", res[3]["location"])
+	assert.Equal(t, map[string]any{"value": float64(10)}, res[3]["properties"].(map[string]any)["parameters"].(map[string]any)["limit"])
+}
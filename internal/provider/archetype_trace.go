@@ -0,0 +1,203 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// The kinds of object in an archetype resolution trace.
+const (
+	traceKindPolicyAssignment    = "policy_assignment"
+	traceKindPolicyDefinition    = "policy_definition"
+	traceKindPolicySetDefinition = "policy_set_definition"
+	traceKindRoleDefinition      = "role_definition"
+)
+
+// The actions in an archetype resolution trace.
+const (
+	traceActionAdded    = "added"
+	traceActionModified = "modified"
+	traceActionRemoved  = "removed"
+)
+
+// archetypeTrace records each decision made while resolving an archetype, in the order they were made.
+// A nil trace records nothing, so that tracing can be disabled without checks at each step.
+type archetypeTrace struct {
+	libUrls []string
+	library *libraryIndex
+	events  []archetypeTraceEvent
+}
+
+// archetypeTraceEvent is a decision that added, modified or removed an object.
+type archetypeTraceEvent struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Action    string   `json:"action"`
+	Reason    string   `json:"reason"`
+	Detail    string   `json:"detail,omitempty"`
+	Library   string   `json:"library,omitempty"`
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// newArchetypeTrace returns a trace that reports the library sources of the objects.
+func newArchetypeTrace(libUrls []string, library *libraryIndex) *archetypeTrace {
+	return &archetypeTrace{libUrls: libUrls, library: library, events: make([]archetypeTraceEvent, 0)}
+}
+
+// add records an event. The names are sorted so that the trace is stable.
+func (t *archetypeTrace) add(kind, action, reason, detail string, names ...string) {
+	if t == nil {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t.events = append(t.events, archetypeTraceEvent{Kind: kind, Name: name, Action: action, Reason: reason, Detail: detail})
+	}
+}
+
+// baseArchetype records the objects of the base archetype and the library that contributed each one.
+// If more than one library defines an object, the earlier libraries are reported as overridden.
+func (t *archetypeTrace) baseArchetype(name string, arch *alzlib.Archetype) {
+	if t == nil {
+		return
+	}
+	for _, c := range []struct {
+		kind, prefix string
+		names        []string
+	}{
+		{traceKindPolicyDefinition, policyDefinitionFilePrefix, arch.PolicyDefinitions.ToSlice()},
+		{traceKindPolicySetDefinition, policySetDefinitionFilePrefix, arch.PolicySetDefinitions.ToSlice()},
+		{traceKindRoleDefinition, roleDefinitionFilePrefix, arch.RoleDefinitions.ToSlice()},
+		{traceKindPolicyAssignment, policyAssignmentFilePrefix, arch.PolicyAssignments.ToSlice()},
+	} {
+		sort.Strings(c.names)
+		for _, n := range c.names {
+			e := archetypeTraceEvent{Kind: c.kind, Name: n, Action: traceActionAdded, Reason: "base_archetype", Detail: name}
+			if origins := t.library.Origins(c.prefix, n); len(origins) != 0 {
+				e.Library = t.libUrl(origins[len(origins)-1])
+				for _, o := range origins[:len(origins)-1] {
+					e.Overrides = append(e.Overrides, t.libUrl(o))
+				}
+			}
+			t.events = append(t.events, e)
+		}
+	}
+}
+
+// excluded records the policy assignments removed by `exclude_default_assignments_matching`, with the expression that matched.
+func (t *archetypeTrace) excluded(names []string, exprs []*regexp.Regexp) {
+	if t == nil {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, re := range exprs {
+			if re.MatchString(name) {
+				t.add(traceKindPolicyAssignment, traceActionRemoved, "exclude_default_assignments_matching", re.String(), name)
+				break
+			}
+		}
+	}
+}
+
+// parameters records the policy assignments whose parameters were set, with the names of the parameters.
+func (t *archetypeTrace) parameters(reason string, params map[string]map[string]*armpolicy.ParameterValuesValue) {
+	if t == nil {
+		return
+	}
+	for _, name := range sortedKeys(params) {
+		t.add(traceKindPolicyAssignment, traceActionModified, reason, "parameters: "+strings.Join(sortedKeys(params[name]), ", "), name)
+	}
+}
+
+// unavailable records the objects removed by `unavailable_resource_providers`.
+func (t *archetypeTrace) unavailable(removed libraryContent) {
+	const reason = "unavailable_resource_providers"
+	t.add(traceKindPolicyDefinition, traceActionRemoved, reason, "", removed.policyDefinitions.ToSlice()...)
+	t.add(traceKindPolicySetDefinition, traceActionRemoved, reason, "", removed.policySetDefinitions.ToSlice()...)
+	t.add(traceKindPolicyAssignment, traceActionRemoved, reason, "", removed.policyAssignments.ToSlice()...)
+}
+
+// fanOuts records the policy assignments replaced by `policy_assignments_to_fan_out`, and the instances that replaced them.
+func (t *archetypeTrace) fanOuts(fanOuts map[string]policyAssignmentFanOut) {
+	if t == nil {
+		return
+	}
+	const reason = "policy_assignments_to_fan_out"
+	for _, name := range sortedKeys(fanOuts) {
+		fo := fanOuts[name]
+		t.add(traceKindPolicyAssignment, traceActionRemoved, reason, "replaced by one assignment per value of parameter "+fo.parameter, name)
+		for _, suffix := range sortedKeys(fo.destinations) {
+			instance, err := policyAssignmentInstanceName(name, suffix)
+			if err != nil {
+				continue
+			}
+			t.add(traceKindPolicyAssignment, traceActionAdded, reason, "instance of "+name, instance)
+		}
+	}
+}
+
+// libUrl returns the source of the library at the position.
+func (t *archetypeTrace) libUrl(n int) string {
+	if n < len(t.libUrls) {
+		return t.libUrls[n]
+	}
+	return fmt.Sprintf("library %d", n)
+}
+
+// log writes each event to the trace level log, so that the decisions can be diagnosed without `trace_resolution`.
+func (t *archetypeTrace) log(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	for _, e := range t.events {
+		fields := map[string]interface{}{
+			"kind":   e.Kind,
+			"name":   e.Name,
+			"action": e.Action,
+			"reason": e.Reason,
+		}
+		if e.Detail != "" {
+			fields["detail"] = e.Detail
+		}
+		if e.Library != "" {
+			fields["library"] = e.Library
+		}
+		if len(e.Overrides) != 0 {
+			fields["overrides"] = e.Overrides
+		}
+		tflog.Trace(ctx, "Archetype resolution decision", fields)
+	}
+}
+
+// JSON returns the trace as a JSON document.
+func (t *archetypeTrace) JSON() (string, error) {
+	b, err := json.Marshal(struct {
+		Events []archetypeTraceEvent `json:"events"`
+	}{t.events})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// sortedKeys returns the sorted keys of the map.
+func sortedKeys[T any](m map[string]T) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"io/fs"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchetypeTrace(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"policy_assignment_deny.json":  &fstest.MapFile{Data: []byte(`{"name": "Deny-Public"}`)},
+		"policy_assignment_audit.json": &fstest.MapFile{Data: []byte(`{"name": "Audit-Tags"}`)},
+	}
+	lib2 := fstest.MapFS{
+		"policy_assignment_deny.json": &fstest.MapFile{Data: []byte(`{"name": "Deny-Public", "properties": {}}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib1, lib2})
+	require.NoError(t, err)
+
+	trace := newArchetypeTrace([]string{"alz", "./lib"}, idx)
+	trace.baseArchetype("root", &alzlib.Archetype{
+		PolicyAssignments:    mapset.NewThreadUnsafeSet("Deny-Public", "Audit-Tags"),
+		PolicyDefinitions:    mapset.NewThreadUnsafeSet[string](),
+		PolicySetDefinitions: mapset.NewThreadUnsafeSet[string](),
+		RoleDefinitions:      mapset.NewThreadUnsafeSet[string](),
+	})
+	trace.excluded([]string{"Audit-Tags"}, []*regexp.Regexp{regexp.MustCompile("^Deny-"), regexp.MustCompile("^Audit-")})
+	trace.parameters("parameter_substitutions", map[string]map[string]*armpolicy.ParameterValuesValue{
+		"Deny-Public": {"effect": nil, "allowed": nil},
+	})
+	trace.fanOuts(map[string]policyAssignmentFanOut{
+		"Deny-Public": {parameter: "location", destinations: map[string]string{"weu": "westeurope"}},
+	})
+
+	s, err := trace.JSON()
+	require.NoError(t, err)
+	var res struct {
+		Events []archetypeTraceEvent `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(s), &res))
+	assert.Equal(t, []archetypeTraceEvent{
+		{Kind: "policy_assignment", Name: "Audit-Tags", Action: "added", Reason: "base_archetype", Detail: "root", Library: "alz"},
+		{Kind: "policy_assignment", Name: "Deny-Public", Action: "added", Reason: "base_archetype", Detail: "root", Library: "./lib", Overrides: []string{"alz"}},
+		{Kind: "policy_assignment", Name: "Audit-Tags", Action: "removed", Reason: "exclude_default_assignments_matching", Detail: "^Audit-"},
+		{Kind: "policy_assignment", Name: "Deny-Public", Action: "modified", Reason: "parameter_substitutions", Detail: "parameters: allowed, effect"},
+		{Kind: "policy_assignment", Name: "Deny-Public", Action: "removed", Reason: "policy_assignments_to_fan_out", Detail: "replaced by one assignment per value of parameter location"},
+		{Kind: "policy_assignment", Name: "Deny-Public-weu", Action: "added", Reason: "policy_assignments_to_fan_out", Detail: "instance of Deny-Public"},
+	}, res.Events)
+}
+
+// TestArchetypeTraceNil tests that a nil trace, used when tracing is disabled, records nothing.
+func TestArchetypeTraceNil(t *testing.T) {
+	var trace *archetypeTrace
+	trace.add(traceKindPolicyAssignment, traceActionModified, "rollout_phase", "", "a")
+	trace.excluded([]string{"a"}, []*regexp.Regexp{regexp.MustCompile("a")})
+	trace.unavailable(newLibraryContent())
+	assert.Nil(t, trace)
+}
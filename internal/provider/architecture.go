@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// architectureDefaultNames are the names of the archetype defaults that a management group of an architecture can set.
+var architectureDefaultNames = []string{"location", "log_analytics_workspace_id", "private_dns_zone_resource_group_id"}
+
+// architectureManagementGroup is a management group of an architecture, with its position in the hierarchy.
+type architectureManagementGroup struct {
+	libraryArchitectureManagementGroup
+	Level            int               // the number of ancestors in the architecture, zero for the management groups at the top
+	ResolvedDefaults map[string]string // the defaults of the management group, merged with those of its ancestors
+}
+
+// architectureManagementGroups checks the management groups of the architecture and returns them keyed by id.
+// The ids must be unique, the parents must be in the architecture, the hierarchy must not have a cycle,
+// and each management group must have at least one archetype, which must be in the archetypes.
+// The defaults must be archetype defaults, and the policy assignments whose parameters are set must exist.
+// A management group inherits the defaults of its ancestors that it does not set itself.
+func architectureManagementGroups(ad libraryArchitectureDefinition, archetypes []string, assignmentExists func(string) bool) (map[string]architectureManagementGroup, error) {
+	known := make(map[string]bool, len(archetypes))
+	for _, a := range archetypes {
+		known[a] = true
+	}
+	mgs := make(map[string]libraryArchitectureManagementGroup, len(ad.ManagementGroups))
+	var errs []error
+	for i, mg := range ad.ManagementGroups {
+		if mg.Id == "" {
+			errs = append(errs, fmt.Errorf("management group %d has no id", i))
+			continue
+		}
+		if _, ok := mgs[mg.Id]; ok {
+			errs = append(errs, fmt.Errorf("management group %s is defined more than once", mg.Id))
+			continue
+		}
+		mgs[mg.Id] = mg
+		if len(mg.Archetypes) == 0 {
+			errs = append(errs, fmt.Errorf("management group %s has no archetypes", mg.Id))
+		}
+		for _, a := range mg.Archetypes {
+			if !known[a] {
+				errs = append(errs, fmt.Errorf("management group %s has archetype %s, which is not in the libraries", mg.Id, a))
+			}
+		}
+		for _, k := range sortedKeys(mg.Defaults) {
+			if !slices.Contains(architectureDefaultNames, k) {
+				errs = append(errs, fmt.Errorf("management group %s has default %s, which is not one of: %s", mg.Id, k, strings.Join(architectureDefaultNames, ", ")))
+			}
+		}
+		for _, k := range sortedKeys(mg.PolicyAssignmentParameters) {
+			if !assignmentExists(k) {
+				errs = append(errs, fmt.Errorf("management group %s has parameters for policy assignment %s, which is not in the libraries", mg.Id, k))
+			}
+		}
+	}
+	for _, id := range sortedKeys(mgs) {
+		if p := mgs[id].ParentId; p != nil {
+			if _, ok := mgs[*p]; !ok {
+				errs = append(errs, fmt.Errorf("management group %s has parent %s, which is not in the architecture", id, *p))
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	res := make(map[string]architectureManagementGroup, len(mgs))
+	for _, id := range sortedKeys(mgs) {
+		path := []string{id}
+		seen := map[string]bool{id: true}
+		for p := mgs[id].ParentId; p != nil; p = mgs[*p].ParentId {
+			path = append(path, *p)
+			if seen[*p] {
+				return nil, fmt.Errorf("the parents of management group %s form a cycle: %s", id, strings.Join(path, " -> "))
+			}
+			seen[*p] = true
+		}
+		// The path runs from the management group to the top, so apply the defaults from the top down.
+		defaults := make(map[string]string)
+		for i := len(path) - 1; i >= 0; i-- {
+			maps.Copy(defaults, mgs[path[i]].Defaults)
+		}
+		res[id] = architectureManagementGroup{libraryArchitectureManagementGroup: mgs[id], Level: len(path) - 1, ResolvedDefaults: defaults}
+	}
+	return res, nil
+}
+
+// architectureHierarchyMaps returns the children and parent of each management group of the architecture, as hierarchyMaps does.
+// The parent of the management groups at the top of the architecture is the root parent id, if it is not empty.
+func architectureHierarchyMaps(mgs map[string]architectureManagementGroup, rootParentId string) (children map[string][]string, parents map[string]string) {
+	infos := make([]managementGroupInfo, 0, len(mgs))
+	for id, mg := range mgs {
+		parent := rootParentId
+		if mg.ParentId != nil {
+			parent = *mg.ParentId
+		}
+		infos = append(infos, managementGroupInfo{Name: id, DisplayName: mg.DisplayName, ParentName: parent})
+	}
+	children, parents, _ = hierarchyMaps(infos)
+	return children, parents
+}
@@ -0,0 +1,262 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &ArchitectureDataSource{}
+
+func NewArchitectureDataSource() datasource.DataSource {
+	return &ArchitectureDataSource{}
+}
+
+// ArchitectureDataSource defines the data source implementation.
+type ArchitectureDataSource struct {
+	alz *alzProviderData
+}
+
+// ArchitectureDataSourceModel describes the data source data model.
+type ArchitectureDataSourceModel struct {
+	Children         types.Map                                  `tfsdk:"children"` // map of list of string
+	Id               types.String                               `tfsdk:"id"`
+	ManagementGroups map[string]ArchitectureManagementGroupType `tfsdk:"management_groups"`
+	Name             types.String                               `tfsdk:"name"`
+	Parents          types.Map                                  `tfsdk:"parents"` // map of string
+	RootParentId     types.String                               `tfsdk:"root_parent_id"`
+}
+
+// ArchitectureManagementGroupType describes a management group of the architecture.
+type ArchitectureManagementGroupType struct {
+	Archetypes                 types.List   `tfsdk:"archetypes"` // list of string
+	Defaults                   types.Map    `tfsdk:"defaults"`   // map of string
+	DisplayName                types.String `tfsdk:"display_name"`
+	Exists                     types.Bool   `tfsdk:"exists"`
+	Level                      types.Int64  `tfsdk:"level"`
+	ParentId                   types.String `tfsdk:"parent_id"`
+	PolicyAssignmentParameters types.Map    `tfsdk:"policy_assignment_parameters"` // map of JSON string
+}
+
+func (d *ArchitectureDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_architecture"
+}
+
+func (d *ArchitectureDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Architecture data source. Reads a management group hierarchy from an architecture definition file in the libraries, " +
+			"e.g. `architecture_definition_alz.json`, so that the hierarchy is versioned with the library rather than written in HCL. " +
+			"Use `management_groups` in `for_each` of the `alz_archetype` data source, with the `id`, `parent_id` and `base_archetype` of each management group. " +
+			"The architecture is checked when it is read: the management group ids must be unique, the parents must be in the architecture and must not form a cycle, " +
+			"and the archetypes must be in the libraries. " +
+			"A management group can set archetype defaults and policy assignment parameters, " +
+			"so that one library can describe several hierarchies, e.g. production and development, that differ only in a few values.",
+
+		Attributes: map[string]schema.Attribute{
+			"children": schema.MapAttribute{
+				MarkdownDescription: "The ids of the child management groups of each management group of the architecture, sorted by id. " +
+					"Every management group is a key, with an empty list if it has no children. " +
+					"Use this to apply an operation to a subtree, e.g. everything under `landingzones`, with lookups.",
+				Computed:    true,
+				ElementType: types.ListType{ElemType: types.StringType},
+			},
+
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The name of the architecture.",
+				Computed:            true,
+			},
+
+			"management_groups": schema.MapNestedAttribute{
+				MarkdownDescription: "The management groups of the architecture, keyed by management group id.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"archetypes": schema.ListAttribute{
+							MarkdownDescription: "The names of the archetypes of the management group. " +
+								"The `alz_archetype` data source has one `base_archetype`, use `one(each.value.archetypes)` for architectures with one archetype per management group.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+
+						"defaults": schema.MapAttribute{
+							MarkdownDescription: "The archetype defaults of the management group, " +
+								"i.e. `location`, `log_analytics_workspace_id` and `private_dns_zone_resource_group_id`. " +
+								"A management group inherits the defaults of its ancestors that it does not set itself. " +
+								"Use it in `defaults` of the `alz_archetype` data source, e.g. `merge({ location = \"westeurope\" }, each.value.defaults)`.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+
+						"display_name": schema.StringAttribute{
+							MarkdownDescription: "The display name of the management group.",
+							Computed:            true,
+						},
+
+						"exists": schema.BoolAttribute{
+							MarkdownDescription: "Whether the management group already exists, and so is not created.",
+							Computed:            true,
+						},
+
+						"level": schema.Int64Attribute{
+							MarkdownDescription: "The number of ancestors of the management group in the architecture. The management groups at the top of the architecture are at level 0.",
+							Computed:            true,
+						},
+
+						"parent_id": schema.StringAttribute{
+							MarkdownDescription: "The id of the parent management group. For the management groups at the top of the architecture, this is `root_parent_id`.",
+							Computed:            true,
+						},
+
+						"policy_assignment_parameters": schema.MapAttribute{
+							MarkdownDescription: "The policy assignment parameters of the management group, as a JSON object of parameter values, keyed by policy assignment name. " +
+								"Use it in `policy_assignments_to_modify` of the `alz_archetype` data source, " +
+								"e.g. `{ for k, v in each.value.policy_assignment_parameters : k => { parameters = v } }`.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+					},
+				},
+			},
+
+			"name": schema.StringAttribute{
+				MarkdownDescription: "The name of the architecture definition.",
+				Required:            true,
+			},
+
+			"parents": schema.MapAttribute{
+				MarkdownDescription: "The id of the parent management group of each management group of the architecture. " +
+					"The management groups at the top of the architecture are included only if `root_parent_id` is set.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+
+			"root_parent_id": schema.StringAttribute{
+				MarkdownDescription: "The id of the parent of the management groups at the top of the architecture, e.g. the tenant root group. " +
+					"If not set, their `parent_id` is null.",
+				Optional: true,
+			},
+		},
+	}
+}
+
+func (d *ArchitectureDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *ArchitectureDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ArchitectureDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := data.Name.ValueString()
+	ad, ok := d.alz.library.Architecture(name)
+	if !ok {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"Architecture not found",
+			fmt.Sprintf("The architecture %s is not in the libraries. The architectures are: %s.", name, strings.Join(d.alz.library.ArchitectureNames(), ", ")),
+		)
+		return
+	}
+	d.alz.mu.Lock()
+	archetypes := d.alz.ListArchetypes()
+	mgs, err := architectureManagementGroups(ad, archetypes, d.alz.PolicyAssignmentExists)
+	d.alz.mu.Unlock()
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), fmt.Sprintf("Invalid architecture %s", name), err.Error())
+		return
+	}
+
+	data.Id = types.StringValue(name)
+	data.ManagementGroups = make(map[string]ArchitectureManagementGroupType, len(mgs))
+	for id, mg := range mgs {
+		var diags diag.Diagnostics
+		data.ManagementGroups[id], diags = mg.convert(ctx, data.RootParentId)
+		resp.Diagnostics.Append(diags...)
+	}
+	childMap, parentMap := architectureHierarchyMaps(mgs, data.RootParentId.ValueString())
+	var diags diag.Diagnostics
+	data.Children, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, childMap)
+	resp.Diagnostics.Append(diags...)
+	data.Parents, diags = types.MapValueFrom(ctx, types.StringType, parentMap)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// convert converts the management group to the framework type, using the root parent id for the management groups at the top.
+// The parameters of each policy assignment are encoded as a JSON object.
+func (mg architectureManagementGroup) convert(ctx context.Context, rootParentId types.String) (ArchitectureManagementGroupType, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	archetypes, d := types.ListValueFrom(ctx, types.StringType, mg.Archetypes)
+	diags.Append(d...)
+	defaults, d := types.MapValueFrom(ctx, types.StringType, mg.ResolvedDefaults)
+	diags.Append(d...)
+	params := make(map[string]string, len(mg.PolicyAssignmentParameters))
+	for name, v := range mg.PolicyAssignmentParameters {
+		b, err := json.Marshal(v)
+		if err != nil {
+			diags.AddError(fmt.Sprintf("Unable to encode the parameters of policy assignment %s", name), err.Error())
+			continue
+		}
+		params[name] = string(b)
+	}
+	paramsVal, d := types.MapValueFrom(ctx, types.StringType, params)
+	diags.Append(d...)
+	res := ArchitectureManagementGroupType{
+		Archetypes:                 archetypes,
+		Defaults:                   defaults,
+		DisplayName:                types.StringValue(mg.DisplayName),
+		Exists:                     types.BoolValue(mg.Exists),
+		Level:                      types.Int64Value(int64(mg.Level)),
+		ParentId:                   types.StringPointerValue(mg.ParentId),
+		PolicyAssignmentParameters: paramsVal,
+	}
+	if mg.ParentId == nil {
+		res.ParentId = rootParentId
+	}
+	return res, diags
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchitectureManagementGroups(t *testing.T) {
+	lib := fstest.MapFS{
+		"architecture_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "test",
+  "management_groups": [
+    {"id": "alz", "display_name": "Azure Landing Zones", "parent_id": null, "exists": false, "archetypes": ["root"], "defaults": {"location": "westeurope"}},
+    {"id": "landingzones", "display_name": "Landing Zones", "parent_id": "alz", "exists": false, "archetypes": ["landing_zones"], "defaults": {"location": "northeurope", "log_analytics_workspace_id": "law"}},
+    {
+      "id": "corp", "display_name": "Corp", "parent_id": "landingzones", "exists": true, "archetypes": ["corp"],
+      "policy_assignment_parameters": {"Deny-Public-Endpoints": {"effect": "Audit", "count": 1}}
+    }
+  ]
+}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, idx.ArchitectureNames())
+	ad, ok := idx.Architecture("test")
+	require.True(t, ok)
+	assert.Equal(t, 1, idx.ObjectCounts(0)["architecture_definition"])
+
+	archetypes := []string{"root", "landing_zones", "corp"}
+	assignmentExists := func(name string) bool { return name == "Deny-Public-Endpoints" }
+	mgs, err := architectureManagementGroups(ad, archetypes, assignmentExists)
+	require.NoError(t, err)
+	require.Len(t, mgs, 3)
+	assert.Equal(t, 0, mgs["alz"].Level)
+	assert.Equal(t, 1, mgs["landingzones"].Level)
+	assert.Equal(t, 2, mgs["corp"].Level)
+	assert.True(t, mgs["corp"].Exists)
+	assert.Equal(t, map[string]string{"location": "westeurope"}, mgs["alz"].ResolvedDefaults)
+	assert.Equal(t, map[string]string{"location": "northeurope", "log_analytics_workspace_id": "law"}, mgs["corp"].ResolvedDefaults)
+
+	root, diags := mgs["alz"].convert(context.Background(), types.StringValue("tenant"))
+	require.False(t, diags.HasError())
+	assert.Equal(t, types.StringValue("tenant"), root.ParentId)
+	assert.Equal(t, types.StringValue("Azure Landing Zones"), root.DisplayName)
+	corp, diags := mgs["corp"].convert(context.Background(), types.StringNull())
+	require.False(t, diags.HasError())
+	assert.Equal(t, types.StringValue("landingzones"), corp.ParentId)
+	assert.Equal(t, types.Int64Value(2), corp.Level)
+	assert.Equal(t, types.MapValueMust(types.StringType, map[string]attr.Value{
+		"location":                   types.StringValue("northeurope"),
+		"log_analytics_workspace_id": types.StringValue("law"),
+	}), corp.Defaults)
+	assert.Equal(t, types.MapValueMust(types.StringType, map[string]attr.Value{
+		"Deny-Public-Endpoints": types.StringValue(`{"count":1,"effect":"Audit"}`),
+	}), corp.PolicyAssignmentParameters)
+	assert.Empty(t, root.PolicyAssignmentParameters.Elements())
+
+	children, parents := architectureHierarchyMaps(mgs, "tenant")
+	assert.Equal(t, map[string][]string{"alz": {"landingzones"}, "landingzones": {"corp"}, "corp": {}}, children)
+	assert.Equal(t, map[string]string{"alz": "tenant", "landingzones": "alz", "corp": "landingzones"}, parents)
+	_, parents = architectureHierarchyMaps(mgs, "")
+	assert.Equal(t, map[string]string{"landingzones": "alz", "corp": "landingzones"}, parents)
+
+	_, err = architectureManagementGroups(ad, []string{"root"}, assignmentExists)
+	assert.ErrorContains(t, err, "management group corp has archetype corp, which is not in the libraries")
+
+	invalid := libraryArchitectureDefinition{ManagementGroups: []libraryArchitectureManagementGroup{
+		{Id: "a", ParentId: to.Ptr("missing"), Archetypes: []string{"root"}},
+		{Id: "a", Archetypes: []string{"root"}},
+		{Id: "b"},
+		{},
+		{Id: "c", Archetypes: []string{"root"}, Defaults: map[string]string{"region": "westeurope"}},
+		{Id: "d", Archetypes: []string{"root"}, PolicyAssignmentParameters: map[string]map[string]any{"Missing": {"effect": "Audit"}}},
+	}}
+	_, err = architectureManagementGroups(invalid, archetypes, assignmentExists)
+	assert.ErrorContains(t, err, "management group a is defined more than once")
+	assert.ErrorContains(t, err, "management group b has no archetypes")
+	assert.ErrorContains(t, err, "management group 3 has no id")
+	assert.ErrorContains(t, err, "management group a has parent missing, which is not in the architecture")
+	assert.ErrorContains(t, err, "management group c has default region, which is not one of: location, log_analytics_workspace_id, private_dns_zone_resource_group_id")
+	assert.ErrorContains(t, err, "management group d has parameters for policy assignment Missing, which is not in the libraries")
+
+	cycle := libraryArchitectureDefinition{ManagementGroups: []libraryArchitectureManagementGroup{
+		{Id: "a", ParentId: to.Ptr("b"), Archetypes: []string{"root"}},
+		{Id: "b", ParentId: to.Ptr("a"), Archetypes: []string{"root"}},
+	}}
+	_, err = architectureManagementGroups(cycle, archetypes, assignmentExists)
+	assert.ErrorContains(t, err, "the parents of management group a form a cycle: a -> b -> a")
+}
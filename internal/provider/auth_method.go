@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// The values of the auth_method provider attribute.
+const (
+	authMethodClientCertificate = "client_certificate"
+	authMethodClientSecret      = "client_secret"
+	authMethodCli               = "cli"
+	authMethodMsi               = "msi"
+	authMethodOidc              = "oidc"
+)
+
+// authMethods are the valid values of the auth_method provider attribute.
+var authMethods = []string{authMethodClientCertificate, authMethodClientSecret, authMethodCli, authMethodMsi, authMethodOidc}
+
+// newAuthMethodCredential returns a chain with only the credential of the auth_method attribute, created from the provider attributes.
+// Unlike the default chain, the environment is not used and there is no fallback to other credentials,
+// so that the provider authenticates with exactly the configured identity or fails.
+func newAuthMethodCredential(data AlzProviderModel, options *azidentity.DefaultAzureCredentialOptions) (*azidentity.ChainedTokenCredential, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	method := data.AuthMethod.ValueString()
+	cred, err := authMethodCredential(method, data, options)
+	if err != nil {
+		diags.AddAttributeError(path.Root("auth_method"), "Failed to create credential", fmt.Sprintf("Unable to create the %s credential: %s", method, err))
+		return nil, diags
+	}
+	chain, err := azidentity.NewChainedTokenCredential([]azcore.TokenCredential{cred}, nil)
+	if err != nil {
+		diags.AddAttributeError(path.Root("auth_method"), "Failed to create credential", err.Error())
+		return nil, diags
+	}
+	return chain, diags
+}
+
+// authMethodCredential creates the credential of the auth method. The attributes required by the method must be set.
+func authMethodCredential(method string, data AlzProviderModel, options *azidentity.DefaultAzureCredentialOptions) (azcore.TokenCredential, error) {
+	tenantId := data.TenantId.ValueString()
+	clientId := data.ClientId.ValueString()
+	requireIds := func() error {
+		if tenantId == "" || clientId == "" {
+			return errors.New("`tenant_id` and `client_id` must be set")
+		}
+		return nil
+	}
+	switch method {
+	case authMethodClientSecret:
+		if err := requireIds(); err != nil {
+			return nil, err
+		}
+		if data.ClientSecret.ValueString() == "" {
+			return nil, errors.New("`client_secret` must be set")
+		}
+		return azidentity.NewClientSecretCredential(tenantId, clientId, data.ClientSecret.ValueString(), &azidentity.ClientSecretCredentialOptions{
+			ClientOptions:              options.ClientOptions,
+			AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
+		})
+	case authMethodClientCertificate:
+		if err := requireIds(); err != nil {
+			return nil, err
+		}
+		if data.ClientCertificatePath.ValueString() == "" {
+			return nil, errors.New("`client_certificate_path` must be set")
+		}
+		b, err := os.ReadFile(data.ClientCertificatePath.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client certificate: %w", err)
+		}
+		var password []byte
+		if !data.ClientCertificatePassword.IsNull() {
+			password = []byte(data.ClientCertificatePassword.ValueString())
+		}
+		certs, key, err := azidentity.ParseCertificates(b, password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse client certificate: %w", err)
+		}
+		return azidentity.NewClientCertificateCredential(tenantId, clientId, certs, key, &azidentity.ClientCertificateCredentialOptions{
+			ClientOptions:              options.ClientOptions,
+			AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
+		})
+	case authMethodOidc:
+		if err := requireIds(); err != nil {
+			return nil, err
+		}
+		return NewOidcCredential(&OidcCredentialOptions{
+			ClientOptions:              options.ClientOptions,
+			AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
+			TenantID:                   tenantId,
+			ClientID:                   clientId,
+			RequestToken:               data.OidcRequestToken.ValueString(),
+			RequestUrl:                 data.OidcRequestUrl.ValueString(),
+			Token:                      data.OidcToken.ValueString(),
+			TokenFilePath:              data.OidcTokenFilePath.ValueString(),
+		})
+	case authMethodMsi:
+		o := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: options.ClientOptions}
+		if clientId != "" {
+			o.ID = azidentity.ClientID(clientId)
+		}
+		return azidentity.NewManagedIdentityCredential(o)
+	case authMethodCli:
+		return azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{
+			AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
+			TenantID:                   tenantId,
+		})
+	}
+	return nil, fmt.Errorf("unknown auth method %q", method)
+}
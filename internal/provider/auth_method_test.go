@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMethodCredential(t *testing.T) {
+	const (
+		tenantId = "00000000-0000-0000-0000-000000000000"
+		clientId = "11111111-1111-1111-1111-111111111111"
+	)
+	opts := &azidentity.DefaultAzureCredentialOptions{}
+	ids := AlzProviderModel{TenantId: types.StringValue(tenantId), ClientId: types.StringValue(clientId)}
+
+	for name, tc := range map[string]struct {
+		method  string
+		data    AlzProviderModel
+		wantErr string
+	}{
+		"client secret": {authMethodClientSecret, AlzProviderModel{
+			TenantId: ids.TenantId, ClientId: ids.ClientId, ClientSecret: types.StringValue("secret"),
+		}, ""},
+		"client secret missing secret":    {authMethodClientSecret, ids, "`client_secret` must be set"},
+		"client secret missing ids":       {authMethodClientSecret, AlzProviderModel{ClientSecret: types.StringValue("secret")}, "`tenant_id` and `client_id` must be set"},
+		"client certificate missing path": {authMethodClientCertificate, ids, "`client_certificate_path` must be set"},
+		"client certificate missing file": {authMethodClientCertificate, AlzProviderModel{
+			TenantId: ids.TenantId, ClientId: ids.ClientId, ClientCertificatePath: types.StringValue(filepath.Join(t.TempDir(), "missing.pfx")),
+		}, "unable to read client certificate"},
+		"oidc missing ids": {authMethodOidc, AlzProviderModel{}, "`tenant_id` and `client_id` must be set"},
+		"oidc": {authMethodOidc, AlzProviderModel{
+			TenantId: ids.TenantId, ClientId: ids.ClientId, OidcToken: types.StringValue("token"),
+		}, ""},
+		"msi":     {authMethodMsi, ids, ""},
+		"cli":     {authMethodCli, AlzProviderModel{}, ""},
+		"unknown": {"browser", ids, `unknown auth method "browser"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cred, err := authMethodCredential(tc.method, tc.data, opts)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, cred)
+		})
+	}
+
+	chain, diags := newAuthMethodCredential(AlzProviderModel{AuthMethod: types.StringValue(authMethodCli)}, opts)
+	require.False(t, diags.HasError(), diags)
+	assert.NotNil(t, chain)
+	_, diags = newAuthMethodCredential(AlzProviderModel{AuthMethod: types.StringValue(authMethodClientSecret)}, opts)
+	assert.True(t, diags.HasError())
+}
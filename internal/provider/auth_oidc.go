@@ -23,6 +23,7 @@ type OidcCredential struct {
 	requestUrl    string
 	token         string
 	tokenFilePath string
+	transport     policy.Transporter
 	cred          *azidentity.ClientAssertionCredential
 }
 
@@ -43,6 +44,11 @@ func NewOidcCredential(options *OidcCredentialOptions) (*OidcCredential, error)
 		requestUrl:    options.RequestUrl,
 		token:         options.Token,
 		tokenFilePath: options.TokenFilePath,
+		transport:     options.Transport,
+	}
+
+	if w.transport == nil {
+		w.transport = http.DefaultClient
 	}
 
 	cred, err := azidentity.NewClientAssertionCredential(options.TenantID, options.ClientID, w.getAssertion,
@@ -95,7 +101,7 @@ func (w *OidcCredential) getAssertion(ctx context.Context) (string, error) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", w.requestToken))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := w.transport.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("getAssertion: cannot request token: %v", err)
 	}
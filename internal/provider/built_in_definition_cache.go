@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// builtInDefinitionPathRegex matches the path of a built-in policy definition or policy set definition, or one of its versions, in lower case.
+var builtInDefinitionPathRegex = regexp.MustCompile(`^/providers/microsoft\.authorization/(policydefinitions|policysetdefinitions)/[^/]+(/versions/[^/]+)?$`)
+
+// BuiltInDefinitionCachePolicy is a read-through disk cache of the built-in policy definitions and policy set definitions read from Azure.
+// The responses are cached by host, definition id and api-version, so that runs on the same machine, e.g. CI plans with a kept cache directory,
+// do not read the same definitions again. Other requests are sent unchanged.
+type BuiltInDefinitionCachePolicy struct {
+	Dir     string        // Dir is the cache directory, which is created if it does not exist
+	TTL     time.Duration // TTL is how long a cached definition is used before it is read again, zero means it never expires
+	Refresh bool          // Refresh reads every definition, replacing the cached copy
+
+	now func() time.Time
+}
+
+var _ policy.Policy = &BuiltInDefinitionCachePolicy{}
+
+func (c *BuiltInDefinitionCachePolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	key, ok := builtInDefinitionCacheKey(raw.Method, raw.URL)
+	if !ok {
+		return req.Next()
+	}
+	path := filepath.Join(c.Dir, key+".json")
+	if b, ok := c.read(path); ok {
+		tflog.Trace(raw.Context(), "Using cached built-in definition", map[string]interface{}{"path": raw.URL.Path})
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Status:        http.StatusText(http.StatusOK),
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(b)),
+			ContentLength: int64(len(b)),
+			Request:       raw,
+		}, nil
+	}
+	resp, err := req.Next()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err := c.write(path, b); err != nil {
+		// A cache that cannot be written only costs another read on the next run.
+		tflog.Warn(raw.Context(), "Unable to cache built-in definition", map[string]interface{}{"path": raw.URL.Path, "error": err.Error()})
+	}
+	return resp, nil
+}
+
+// read returns the cached response if it exists and has not expired.
+func (c *BuiltInDefinitionCachePolicy) read(path string) ([]byte, bool) {
+	if c.Refresh {
+		return nil, false
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	if c.TTL != 0 && now().Sub(fi.ModTime()) >= c.TTL {
+		return nil, false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// write writes the response to a temporary file first, so that concurrent runs do not read a partial response.
+func (c *BuiltInDefinitionCachePolicy) write(path string, b []byte) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// builtInDefinitionCacheKey returns the cache key of a read of a built-in definition, the sha256 of its host, id and api-version.
+// The host is included, as the built-in definitions differ between clouds, e.g. Azure Public and Azure Government.
+// It returns false for any other request.
+func builtInDefinitionCacheKey(method string, u *url.URL) (string, bool) {
+	if method != http.MethodGet {
+		return "", false
+	}
+	id := strings.ToLower(strings.TrimSuffix(u.Path, "/"))
+	if !builtInDefinitionPathRegex.MatchString(id) {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(u.Host) + id + "?api-version=" + u.Query().Get("api-version")))
+	return hex.EncodeToString(sum[:]), true
+}
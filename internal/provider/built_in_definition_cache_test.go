@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltInDefinitionCacheKey(t *testing.T) {
+	parse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+	k1, ok := builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/Microsoft.Authorization/policyDefinitions/abc?api-version=2023-04-01"))
+	require.True(t, ok)
+	k2, ok := builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/microsoft.authorization/policydefinitions/ABC?api-version=2023-04-01"))
+	require.True(t, ok)
+	assert.Equal(t, k1, k2)
+	k3, ok := builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/Microsoft.Authorization/policyDefinitions/abc?api-version=2021-06-01"))
+	require.True(t, ok)
+	assert.NotEqual(t, k1, k3)
+	k4, ok := builtInDefinitionCacheKey(http.MethodGet, parse("https://management.usgovcloudapi.net/providers/Microsoft.Authorization/policyDefinitions/abc?api-version=2023-04-01"))
+	require.True(t, ok)
+	assert.NotEqual(t, k1, k4, "the definitions of other clouds are cached separately")
+	_, ok = builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/Microsoft.Authorization/policySetDefinitions/abc?api-version=2023-04-01"))
+	assert.True(t, ok)
+
+	_, ok = builtInDefinitionCacheKey(http.MethodPut, parse("https://management.azure.com/providers/Microsoft.Authorization/policyDefinitions/abc?api-version=2023-04-01"))
+	assert.False(t, ok, "write")
+	_, ok = builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/abc?api-version=2023-04-01"))
+	assert.False(t, ok, "custom definition")
+	_, ok = builtInDefinitionCacheKey(http.MethodGet, parse("https://management.azure.com/providers/Microsoft.Authorization/policyDefinitions?api-version=2023-04-01"))
+	assert.False(t, ok, "list")
+}
+
+func TestBuiltInDefinitionCachePolicy(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "builtins")
+	now := time.Now()
+	cache := &BuiltInDefinitionCachePolicy{Dir: dir, TTL: time.Hour, now: func() time.Time { return now }}
+	transport := new(recordingTransport)
+	client, err := armpolicy.NewDefinitionsClient("", staticTokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:           cloud.AzurePublic,
+			Transport:       &http.Client{Transport: transport},
+			PerCallPolicies: []policy.Policy{cache},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	assert.Len(t, transport.methods, 1)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	assert.Len(t, transport.methods, 1, "cached")
+
+	now = now.Add(2 * time.Hour)
+	_, err = client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	assert.Len(t, transport.methods, 2, "expired")
+
+	cache.Refresh = true
+	_, err = client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	assert.Len(t, transport.methods, 3, "refresh")
+}
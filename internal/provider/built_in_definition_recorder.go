@@ -0,0 +1,202 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// builtInDefinitionRecorder records the built-in policy definitions and policy set definitions read from Azure by alzlib,
+// which does not expose them, so that their policy rules and the definitions they reference can be inspected.
+// It also serves the recorded responses to later reads of the same definitions, so that definitions prefetched concurrently
+// by the provider are not read again by alzlib, which reads one definition at a time.
+// It must be the first per call policy, so that it records the responses of the other policies, e.g. cached or pinned definitions.
+// A nil recorder records nothing.
+type builtInDefinitionRecorder struct {
+	mu     sync.Mutex
+	pds    map[string]armpolicy.Definition    // keyed by name, as in the requested id
+	psds   map[string]armpolicy.SetDefinition // keyed by name, as in the requested id
+	bodies map[string][]byte                  // keyed by lower case path and api-version
+}
+
+var _ policy.Policy = &builtInDefinitionRecorder{}
+
+// newBuiltInDefinitionRecorder returns an empty recorder.
+func newBuiltInDefinitionRecorder() *builtInDefinitionRecorder {
+	return &builtInDefinitionRecorder{
+		pds:    make(map[string]armpolicy.Definition),
+		psds:   make(map[string]armpolicy.SetDefinition),
+		bodies: make(map[string][]byte),
+	}
+}
+
+func (r *builtInDefinitionRecorder) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	// The path is read before the request is sent, as later policies may change it.
+	id := strings.TrimSuffix(raw.URL.Path, "/")
+	m := builtInDefinitionPathRegex.FindStringSubmatch(strings.ToLower(id))
+	key := strings.ToLower(id) + "?api-version=" + raw.URL.Query().Get("api-version")
+	if raw.Method == http.MethodGet && m != nil && m[2] == "" {
+		if b, ok := r.body(key); ok {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Status:        http.StatusText(http.StatusOK),
+				Header:        http.Header{"Content-Type": []string{"application/json"}},
+				Body:          io.NopCloser(bytes.NewReader(b)),
+				ContentLength: int64(len(b)),
+				Request:       raw,
+			}, nil
+		}
+	}
+	resp, err := req.Next()
+	if err != nil || raw.Method != http.MethodGet || m == nil || m[2] != "" || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	// A response that cannot be decoded is left to the caller to report.
+	_ = r.add(m[1], resourceIdName(id), key, b)
+	return resp, nil
+}
+
+// body returns the recorded response of the read with the key.
+func (r *builtInDefinitionRecorder) body(key string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.bodies[key]
+	return b, ok
+}
+
+// add records the response of a read of the built-in definition, the kind is the lower case resource type in the path.
+func (r *builtInDefinitionRecorder) add(kind, name, key string, b []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch kind {
+	case "policydefinitions":
+		var pd armpolicy.Definition
+		if err := json.Unmarshal(b, &pd); err != nil {
+			return err
+		}
+		r.pds[name] = pd
+	case "policysetdefinitions":
+		var psd armpolicy.SetDefinition
+		if err := json.Unmarshal(b, &psd); err != nil {
+			return err
+		}
+		r.psds[name] = psd
+	}
+	r.bodies[key] = b
+	return nil
+}
+
+// Definitions returns copies of the recorded policy definitions and policy set definitions, keyed by name.
+func (r *builtInDefinitionRecorder) Definitions() (map[string]armpolicy.Definition, map[string]armpolicy.SetDefinition) {
+	if r == nil {
+		return make(map[string]armpolicy.Definition), make(map[string]armpolicy.SetDefinition)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.pds), maps.Clone(r.psds)
+}
+
+// Prefetch reads the built-in policy definitions and policy set definitions with the ids, and the policy definitions that the
+// policy set definitions reference, from Azure concurrently, at most parallelism at a time.
+// The client factory must have the recorder in its pipeline, so that the definitions are recorded and alzlib reads them from the recorder.
+// Definitions that exist in alzlib, e.g. those in the libraries, are not read.
+func (r *builtInDefinitionRecorder) Prefetch(ctx context.Context, cf *armpolicy.ClientFactory, alz *alzlib.AlzLib, ids []string, parallelism int) error {
+	pdNames := mapset.NewThreadUnsafeSet[string]()
+	psdNames := mapset.NewThreadUnsafeSet[string]()
+	for _, id := range ids {
+		name := resourceIdName(id)
+		if strings.Contains(strings.ToLower(id), "/policysetdefinitions/") {
+			if !alz.PolicySetDefinitionExists(name) {
+				psdNames.Add(name)
+			}
+			continue
+		}
+		if !alz.PolicyDefinitionExists(name) {
+			pdNames.Add(name)
+		}
+	}
+	psdClient := cf.NewSetDefinitionsClient()
+	if err := readConcurrently(ctx, psdNames.ToSlice(), parallelism, func(ctx context.Context, name string) error {
+		_, err := psdClient.GetBuiltIn(ctx, name, nil)
+		return err
+	}); err != nil {
+		return err
+	}
+	_, psds := r.Definitions()
+	for _, psd := range psds {
+		if psd.Properties == nil {
+			continue
+		}
+		for _, ref := range psd.Properties.PolicyDefinitions {
+			if ref == nil || ref.PolicyDefinitionID == nil {
+				continue
+			}
+			if name := resourceIdName(*ref.PolicyDefinitionID); !alz.PolicyDefinitionExists(name) {
+				pdNames.Add(name)
+			}
+		}
+	}
+	pdClient := cf.NewDefinitionsClient()
+	return readConcurrently(ctx, pdNames.ToSlice(), parallelism, func(ctx context.Context, name string) error {
+		_, err := pdClient.GetBuiltIn(ctx, name, nil)
+		return err
+	})
+}
+
+// readConcurrently calls read for each name, at most parallelism at a time, and returns the first error.
+// The context passed to read is cancelled when a read fails.
+func readConcurrently(ctx context.Context, names []string, parallelism int, read func(ctx context.Context, name string) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	slots := make(chan struct{}, max(parallelism, 1))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, name := range names {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := read(ctx, name); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
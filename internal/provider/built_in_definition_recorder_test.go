@@ -0,0 +1,162 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// builtInDefinitionTransport responds to reads of built-in definitions with the body of the definition, keyed by name.
+type builtInDefinitionTransport struct {
+	bodies map[string]string
+}
+
+func (t *builtInDefinitionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := t.bodies[resourceIdName(req.URL.Path)]
+	status := http.StatusOK
+	if !ok {
+		body, status = `{"error": {"code": "PolicyDefinitionNotFound"}}`, http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// testBuiltInDefinitionRecorder returns a recorder that has recorded the built-in definitions, read through a client.
+func testBuiltInDefinitionRecorder(t *testing.T, pdBodies, psdBodies map[string]string) *builtInDefinitionRecorder {
+	ctx := context.Background()
+	rec := newBuiltInDefinitionRecorder()
+	bodies := make(map[string]string)
+	for k, v := range pdBodies {
+		bodies[k] = v
+	}
+	for k, v := range psdBodies {
+		bodies[k] = v
+	}
+	opts := &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:           cloud.AzurePublic,
+			Transport:       &http.Client{Transport: &builtInDefinitionTransport{bodies: bodies}},
+			PerCallPolicies: []policy.Policy{rec},
+		},
+	}
+	cf, err := armpolicy.NewClientFactory("", staticTokenCredential{}, opts)
+	require.NoError(t, err)
+	for name := range pdBodies {
+		_, err := cf.NewDefinitionsClient().GetBuiltIn(ctx, name, nil)
+		require.NoError(t, err)
+	}
+	for name := range psdBodies {
+		_, err := cf.NewSetDefinitionsClient().GetBuiltIn(ctx, name, nil)
+		require.NoError(t, err)
+	}
+	return rec
+}
+
+// TestBuiltInDefinitionRecorder tests that reads of built-in definitions are recorded by name, and that failed reads are not.
+func TestBuiltInDefinitionRecorder(t *testing.T) {
+	rec := testBuiltInDefinitionRecorder(t,
+		map[string]string{"pd1": `{"name": "pd1", "properties": {"policyRule": {"if": {}, "then": {"effect": "audit"}}}}`},
+		map[string]string{"psd1": `{"name": "psd1", "properties": {"policyDefinitions": [{"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/pd1"}]}}`},
+	)
+
+	pds, psds := rec.Definitions()
+	require.Contains(t, pds, "pd1")
+	assert.Equal(t, "pd1", *pds["pd1"].Name)
+	require.Contains(t, psds, "psd1")
+	assert.Len(t, psds["psd1"].Properties.PolicyDefinitions, 1)
+
+	delete(pds, "pd1")
+	pds, _ = rec.Definitions()
+	assert.Contains(t, pds, "pd1", "copies are returned")
+
+	var nilRec *builtInDefinitionRecorder
+	pds, psds = nilRec.Definitions()
+	assert.Empty(t, pds)
+	assert.Empty(t, psds)
+}
+
+// concurrentTransport counts the reads of each definition and the maximum number of concurrent reads.
+type concurrentTransport struct {
+	builtInDefinitionTransport
+	mu       sync.Mutex
+	reads    map[string]int
+	inFlight int
+	maxIn    int
+}
+
+func (t *concurrentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.reads[resourceIdName(req.URL.Path)]++
+	t.inFlight++
+	t.maxIn = max(t.maxIn, t.inFlight)
+	t.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	t.mu.Lock()
+	t.inFlight--
+	t.mu.Unlock()
+	return t.builtInDefinitionTransport.RoundTrip(req)
+}
+
+// TestBuiltInDefinitionRecorderPrefetch tests that the definitions and the policy definitions referenced by the policy set
+// definitions are read concurrently, and that later reads are served by the recorder.
+func TestBuiltInDefinitionRecorderPrefetch(t *testing.T) {
+	ctx := context.Background()
+	rec := newBuiltInDefinitionRecorder()
+	transport := &concurrentTransport{
+		builtInDefinitionTransport: builtInDefinitionTransport{bodies: map[string]string{
+			"pd1":  `{"name": "pd1"}`,
+			"pd2":  `{"name": "pd2"}`,
+			"pd3":  `{"name": "pd3"}`,
+			"pd4":  `{"name": "pd4"}`,
+			"psd1": `{"name": "psd1", "properties": {"policyDefinitions": [{"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/pd4"}]}}`,
+		}},
+		reads: make(map[string]int),
+	}
+	cf, err := armpolicy.NewClientFactory("", staticTokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:           cloud.AzurePublic,
+			Transport:       &http.Client{Transport: transport},
+			PerCallPolicies: []policy.Policy{rec},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, rec.Prefetch(ctx, cf, alzlib.NewAlzLib(), []string{
+		"/providers/Microsoft.Authorization/policyDefinitions/pd1",
+		"/providers/Microsoft.Authorization/policyDefinitions/pd2",
+		"/providers/Microsoft.Authorization/policyDefinitions/pd3",
+		"/providers/Microsoft.Authorization/policySetDefinitions/psd1",
+	}, 2))
+	assert.Equal(t, map[string]int{"pd1": 1, "pd2": 1, "pd3": 1, "pd4": 1, "psd1": 1}, transport.reads)
+	assert.Equal(t, 2, transport.maxIn, "the reads are concurrent, at most parallelism at a time")
+	pds, psds := rec.Definitions()
+	assert.Len(t, pds, 4)
+	assert.Len(t, psds, 1)
+
+	_, err = cf.NewDefinitionsClient().GetBuiltIn(ctx, "pd4", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, transport.reads["pd4"], "the recorded response is served")
+
+	err = rec.Prefetch(ctx, cf, alzlib.NewAlzLib(), []string{"/providers/Microsoft.Authorization/policyDefinitions/missing"}, 2)
+	assert.ErrorContains(t, err, "PolicyDefinitionNotFound")
+}
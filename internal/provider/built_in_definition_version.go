@@ -0,0 +1,96 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// builtInDefinitionVersionApiVersion is the first api version of the built-in definition versions.
+const builtInDefinitionVersionApiVersion = "2023-04-01"
+
+// builtInDefinitionVersionRegex matches the version of a built-in definition, e.g. `1.2.0`.
+var builtInDefinitionVersionRegex = regexp.MustCompile(`^\d+\.\d+\.\d+(-preview|-deprecated)?$`)
+
+// BuiltInDefinitionVersionPolicy reads pinned versions of the built-in policy definitions and policy set definitions,
+// so that a new version published by Microsoft does not change the plan.
+// A read of a pinned definition is sent to its `versions` endpoint, and the id and name of the version in the response
+// are replaced by those of the definition, as the callers expect the definition. Other requests are sent unchanged.
+type BuiltInDefinitionVersionPolicy struct {
+	Versions map[string]string // Versions are the pinned versions, keyed by the lower case name of the definition
+}
+
+var _ policy.Policy = &BuiltInDefinitionVersionPolicy{}
+
+func (v *BuiltInDefinitionVersionPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	if raw.Method != http.MethodGet {
+		return req.Next()
+	}
+	id := strings.TrimSuffix(raw.URL.Path, "/")
+	if lower := strings.ToLower(id); !builtInDefinitionPathRegex.MatchString(lower) || strings.Contains(lower, "/versions/") {
+		return req.Next()
+	}
+	version, ok := v.Versions[strings.ToLower(resourceIdName(id))]
+	if !ok {
+		return req.Next()
+	}
+	tflog.Trace(raw.Context(), "Reading pinned built-in definition version", map[string]interface{}{"path": id, "version": version})
+	raw.URL.Path = id + "/versions/" + version
+	raw.URL.RawPath = ""
+	q := raw.URL.Query()
+	q.Set("api-version", builtInDefinitionVersionApiVersion)
+	raw.URL.RawQuery = q.Encode()
+	resp, err := req.Next()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if b, err = unversionedDefinition(b, id); err != nil {
+		return nil, fmt.Errorf("unable to read version %s of built-in definition %s: %w", version, id, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// unversionedDefinition replaces the id, name and type of a definition version with those of the definition.
+func unversionedDefinition(b []byte, id string) ([]byte, error) {
+	var res map[string]any
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	res["id"] = id
+	res["name"] = resourceIdName(id)
+	if typ, ok := res["type"].(string); ok {
+		res["type"] = strings.TrimSuffix(typ, "/versions")
+	}
+	return json.Marshal(res)
+}
+
+// builtInDefinitionVersions returns the known versions of the map, keyed by the lower case name of the definition.
+func builtInDefinitionVersions(src types.Map) map[string]string {
+	res := make(map[string]string, len(src.Elements()))
+	for name, v := range src.Elements() {
+		if sv, ok := v.(types.String); ok && !sv.IsNull() && !sv.IsUnknown() {
+			res[strings.ToLower(name)] = sv.ValueString()
+		}
+	}
+	return res
+}
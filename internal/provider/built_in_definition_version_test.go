@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// definitionVersionTransport responds to reads of definition versions with a version of the definition.
+type definitionVersionTransport struct {
+	urls []string
+}
+
+func (t *definitionVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.Path+"?"+req.URL.RawQuery)
+	body := `{"name": "` + resourceIdName(req.URL.Path) + `", "type": "Microsoft.Authorization/policyDefinitions", "properties": {"version": "2.0.0"}}`
+	if _, version, ok := strings.Cut(req.URL.Path, "/versions/"); ok {
+		body = `{"id": "` + req.URL.Path + `", "name": "` + version + `", "type": "Microsoft.Authorization/policyDefinitions/versions", "properties": {"version": "` + version + `"}}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestBuiltInDefinitionVersionPolicy(t *testing.T) {
+	ctx := context.Background()
+	versions := builtInDefinitionVersions(types.MapValueMust(types.StringType, map[string]attr.Value{
+		"ABC": types.StringValue("1.0.0"),
+	}))
+	assert.Equal(t, map[string]string{"abc": "1.0.0"}, versions)
+	transport := new(definitionVersionTransport)
+	cache := &BuiltInDefinitionCachePolicy{Dir: filepath.Join(t.TempDir(), "builtins")}
+	client, err := armpolicy.NewDefinitionsClient("", staticTokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:           cloud.AzurePublic,
+			Transport:       &http.Client{Transport: transport},
+			PerCallPolicies: []policy.Policy{&BuiltInDefinitionVersionPolicy{Versions: versions}, cache},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	require.Len(t, transport.urls, 1)
+	assert.Equal(t, "/providers/Microsoft.Authorization/policyDefinitions/abc/versions/1.0.0?api-version=2023-04-01", transport.urls[0])
+	assert.Equal(t, "/providers/Microsoft.Authorization/policyDefinitions/abc", *resp.ID)
+	assert.Equal(t, "abc", *resp.Name)
+	assert.Equal(t, "Microsoft.Authorization/policyDefinitions", *resp.Type)
+
+	// The version is cached.
+	resp, err = client.GetBuiltIn(ctx, "abc", nil)
+	require.NoError(t, err)
+	assert.Len(t, transport.urls, 1)
+	assert.Equal(t, "abc", *resp.Name)
+
+	// Definitions that are not pinned are read unchanged.
+	resp, err = client.GetBuiltIn(ctx, "def", nil)
+	require.NoError(t, err)
+	require.Len(t, transport.urls, 2)
+	assert.True(t, strings.HasPrefix(transport.urls[1], "/providers/Microsoft.Authorization/policyDefinitions/def?"))
+	assert.Equal(t, "def", *resp.Name)
+
+	assert.True(t, builtInDefinitionVersionRegex.MatchString("1.2.0"))
+	assert.True(t, builtInDefinitionVersionRegex.MatchString("1.0.0-preview"))
+	assert.False(t, builtInDefinitionVersionRegex.MatchString("1.*.*"))
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// armMetadataApiVersion is the api version of the Azure Resource Manager metadata endpoint.
+const armMetadataApiVersion = "2022-09-01"
+
+// armMetadata is an entry of the Azure Resource Manager metadata endpoint response.
+// Public clouds return a list of entries, Azure Stack Hub returns a single entry without the resource manager endpoint.
+type armMetadata struct {
+	Name            string `json:"name"`
+	ResourceManager string `json:"resourceManager"`
+	Authentication  struct {
+		LoginEndpoint string   `json:"loginEndpoint"`
+		Audiences     []string `json:"audiences"`
+	} `json:"authentication"`
+}
+
+// cloudConfiguration returns the cloud configuration of the Azure SDK clients and credentials.
+// If `metadata_host` is set, the endpoints are read from its metadata endpoint, otherwise they are those of the `environment`.
+func cloudConfiguration(ctx context.Context, data AlzProviderModel, httpClient *http.Client) (cloud.Configuration, error) {
+	if host := data.MetadataHost.ValueString(); host != "" {
+		return cloudConfigurationFromMetadata(ctx, host, httpClient)
+	}
+	return environmentCloudConfiguration(data.Environment.ValueString())
+}
+
+// cloudConfigurationFromMetadata reads the endpoints of the Azure Resource Manager at the host, e.g. `management.local.azurestack.external`,
+// from its metadata endpoint. The host may also be an https URL of the resource manager.
+func cloudConfigurationFromMetadata(ctx context.Context, host string, httpClient *http.Client) (cloud.Configuration, error) {
+	base := host
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return cloud.Configuration{}, fmt.Errorf("the metadata host %s must be a host name or an https URL", host)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/metadata/endpoints?api-version=%s", u.Host, armMetadataApiVersion), nil)
+	if err != nil {
+		return cloud.Configuration{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("unable to read the metadata of %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("unable to read the metadata of %s: %w", u.Host, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cloud.Configuration{}, fmt.Errorf("unable to read the metadata of %s: unexpected status %s", u.Host, resp.Status)
+	}
+	md, err := selectArmMetadata(b, u.Host)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("invalid metadata of %s: %w", u.Host, err)
+	}
+	if md.Authentication.LoginEndpoint == "" || len(md.Authentication.Audiences) == 0 {
+		return cloud.Configuration{}, fmt.Errorf("invalid metadata of %s: the login endpoint and audiences must be set", u.Host)
+	}
+	endpoint := md.ResourceManager
+	if endpoint == "" {
+		endpoint = "https://" + u.Host + "/"
+	}
+	return cloud.Configuration{
+		ActiveDirectoryAuthorityHost: strings.TrimSuffix(md.Authentication.LoginEndpoint, "/") + "/",
+		Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {
+				Audience: md.Authentication.Audiences[0],
+				Endpoint: endpoint,
+			},
+		},
+	}, nil
+}
+
+// selectArmMetadata returns the entry of the metadata response whose resource manager is the host.
+// A response with a single entry, as returned by Azure Stack Hub, is used regardless of its resource manager.
+func selectArmMetadata(b []byte, host string) (armMetadata, error) {
+	var entries []armMetadata
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		if err := json.Unmarshal(b, &entries); err != nil {
+			return armMetadata{}, err
+		}
+	} else {
+		var md armMetadata
+		if err := json.Unmarshal(b, &md); err != nil {
+			return armMetadata{}, err
+		}
+		entries = []armMetadata{md}
+	}
+	if len(entries) == 1 {
+		return entries[0], nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, md := range entries {
+		if u, err := url.Parse(md.ResourceManager); err == nil && strings.EqualFold(u.Host, host) {
+			return md, nil
+		}
+		names = append(names, md.Name)
+	}
+	return armMetadata{}, fmt.Errorf("no cloud has the resource manager %s, the clouds are %s", host, strings.Join(names, ", "))
+}
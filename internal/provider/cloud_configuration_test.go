@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCloudConfiguration tests that the cloud configuration is that of the environment if no metadata host is set.
+func TestCloudConfiguration(t *testing.T) {
+	data := AlzProviderModel{Environment: types.StringValue("usgovernment"), MetadataHost: types.StringNull()}
+	cfg, err := cloudConfiguration(context.Background(), data, http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, cloud.AzureGovernment, cfg)
+
+	data.Environment = types.StringValue("mars")
+	_, err = cloudConfiguration(context.Background(), data, http.DefaultClient)
+	assert.ErrorContains(t, err, "unknown environment")
+}
+
+// TestCloudConfigurationFromMetadata tests reading the endpoints from the metadata of an Azure Stack Hub resource manager,
+// which returns a single cloud, and from a resource manager that returns a list of clouds.
+func TestCloudConfigurationFromMetadata(t *testing.T) {
+	var body string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metadata/endpoints", r.URL.Path)
+		assert.Equal(t, armMetadataApiVersion, r.URL.Query().Get("api-version"))
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	body = `{
+  "galleryEndpoint": "https://providers.local.azurestack.external:30016/",
+  "graphEndpoint": "https://graph.windows.net/",
+  "authentication": {
+    "loginEndpoint": "https://login.microsoftonline.com/",
+    "audiences": ["https://management.contoso.onmicrosoft.com/00000000-0000-0000-0000-000000000000"]
+  }
+}`
+	cfg, err := cloudConfigurationFromMetadata(context.Background(), host, srv.Client())
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.microsoftonline.com/", cfg.ActiveDirectoryAuthorityHost)
+	assert.Equal(t, cloud.ServiceConfiguration{
+		Audience: "https://management.contoso.onmicrosoft.com/00000000-0000-0000-0000-000000000000",
+		Endpoint: "https://" + host + "/",
+	}, cfg.Services[cloud.ResourceManager])
+
+	body = `[
+  {"name": "AzureCloud", "resourceManager": "https://management.azure.com/", "authentication": {"loginEndpoint": "https://login.microsoftonline.com", "audiences": ["https://management.core.windows.net/"]}},
+  {"name": "Private", "resourceManager": "https://` + host + `/", "authentication": {"loginEndpoint": "https://login.private.example", "audiences": ["https://management.private.example/"]}}
+]`
+	cfg, err = cloudConfigurationFromMetadata(context.Background(), "https://"+host+"/", srv.Client())
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.private.example/", cfg.ActiveDirectoryAuthorityHost)
+	assert.Equal(t, "https://management.private.example/", cfg.Services[cloud.ResourceManager].Audience)
+
+	body = `[{"name": "A", "resourceManager": "https://a.example/"}, {"name": "B", "resourceManager": "https://b.example/"}]`
+	_, err = cloudConfigurationFromMetadata(context.Background(), host, srv.Client())
+	assert.ErrorContains(t, err, "the clouds are A, B")
+
+	_, err = cloudConfigurationFromMetadata(context.Background(), "http://"+host, srv.Client())
+	assert.ErrorContains(t, err, "must be a host name or an https URL")
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// defaultUnavailableResourceProviders are the resource provider namespaces known to be unavailable in each cloud environment.
+// The list is not exhaustive, it can be replaced using the `unavailable_resource_providers` provider attribute.
+var defaultUnavailableResourceProviders = map[string][]string{
+	"china": {
+		"Microsoft.Chaos",
+		"Microsoft.Easm",
+	},
+	"usgovernment": {
+		"Microsoft.Chaos",
+		"Microsoft.Easm",
+	},
+}
+
+// environmentCloudConfiguration returns the Azure SDK cloud configuration of the `environment` provider attribute,
+// which sets the Entra ID authority and the ARM endpoint used to read built-in policies and management groups.
+func environmentCloudConfiguration(environment string) (cloud.Configuration, error) {
+	switch strings.ToLower(environment) {
+	case "public":
+		return cloud.AzurePublic, nil
+	case "usgovernment":
+		return cloud.AzureGovernment, nil
+	case "china":
+		return cloud.AzureChina, nil
+	}
+	return cloud.Configuration{}, fmt.Errorf("unknown environment %q, valid values are 'public', 'usgovernment', or 'china'", environment)
+}
+
+// unavailableResourceProvidersForEnvironment returns the configured namespaces, or the defaults for the environment if none are configured.
+func unavailableResourceProvidersForEnvironment(environment string, configured []string) []string {
+	if configured != nil {
+		return configured
+	}
+	return defaultUnavailableResourceProviders[environment]
+}
+
+// deploymentDefinitions returns the policy definitions and policy set definitions of every management group of the deployment,
+// together with the built-in definitions read from Azure, keyed by name.
+func deploymentDefinitions(mgs []*alzlib.AlzManagementGroup, builtIns *builtInDefinitionRecorder) (map[string]armpolicy.Definition, map[string]armpolicy.SetDefinition) {
+	pds, psds := builtIns.Definitions()
+	for _, mg := range mgs {
+		maps.Copy(pds, mg.GetPolicyDefinitionsMap())
+		maps.Copy(psds, mg.GetPolicySetDefinitionsMap())
+	}
+	return pds, psds
+}
+
+// unavailableDefinitions returns the names of the policy definitions that reference the namespaces, and of the policy set definitions that use them.
+// The definitions are those of the whole deployment, including the built-in definitions, as returned by deploymentDefinitions,
+// so that a definition is removed from every management group that uses it, not only from the one that defines it.
+func unavailableDefinitions(pds map[string]armpolicy.Definition, psds map[string]armpolicy.SetDefinition, namespaces []string) libraryContent {
+	res := newLibraryContent()
+	if len(namespaces) == 0 {
+		return res
+	}
+
+	for name, pd := range pds {
+		if pd.Properties != nil && policyRuleReferencesNamespace(pd.Properties.PolicyRule, namespaces) {
+			res.policyDefinitions.Add(name)
+		}
+	}
+
+	for name, psd := range psds {
+		if psd.Properties == nil {
+			continue
+		}
+		for _, ref := range psd.Properties.PolicyDefinitions {
+			if ref != nil && ref.PolicyDefinitionID != nil && res.policyDefinitions.Contains(resourceIdName(*ref.PolicyDefinitionID)) {
+				res.policySetDefinitions.Add(name)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// filterUnavailableResourceProviders removes the unavailable policy definitions and policy set definitions,
+// as returned by unavailableDefinitions, and the policy assignments that assign them.
+// The maps are modified in place and the names of the removed objects are returned.
+func filterUnavailableResourceProviders(
+	pds map[string]armpolicy.Definition,
+	psds map[string]armpolicy.SetDefinition,
+	pas map[string]armpolicy.Assignment,
+	unavailable libraryContent,
+) libraryContent {
+	removed := newLibraryContent()
+	if unavailable.Cardinality() == 0 {
+		return removed
+	}
+
+	for name := range pds {
+		if unavailable.policyDefinitions.Contains(name) {
+			removed.policyDefinitions.Add(name)
+			delete(pds, name)
+		}
+	}
+
+	for name := range psds {
+		if unavailable.policySetDefinitions.Contains(name) {
+			removed.policySetDefinitions.Add(name)
+			delete(psds, name)
+		}
+	}
+
+	for name, pa := range pas {
+		if pa.Properties == nil || pa.Properties.PolicyDefinitionID == nil {
+			continue
+		}
+		defName := resourceIdName(*pa.Properties.PolicyDefinitionID)
+		if !unavailable.policyDefinitions.Contains(defName) && !unavailable.policySetDefinitions.Contains(defName) {
+			continue
+		}
+		removed.policyAssignments.Add(name)
+		delete(pas, name)
+	}
+	return removed
+}
+
+// filterPolicyRoleAssignments removes the role assignments of the supplied policy assignments.
+func filterPolicyRoleAssignments(src []alzlib.PolicyRoleAssignment, removed libraryContent) []alzlib.PolicyRoleAssignment {
+	res := make([]alzlib.PolicyRoleAssignment, 0, len(src))
+	for _, v := range src {
+		if removed.policyAssignments.Contains(v.AssignmentName) {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// unavailableResourceProvidersSummary returns a sorted, human readable list of the removed objects.
+func unavailableResourceProvidersSummary(removed libraryContent, namespaces []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The following policies reference resource providers that are not available in the cloud environment (%s) and have been removed:\n", strings.Join(namespaces, ", ")))
+	sections := []struct {
+		name string
+		set  mapset.Set[string]
+	}{
+		{"Policy assignments", removed.policyAssignments},
+		{"Policy definitions", removed.policyDefinitions},
+		{"Policy set definitions", removed.policySetDefinitions},
+	}
+	for _, s := range sections {
+		if s.set.Cardinality() == 0 {
+			continue
+		}
+		names := s.set.ToSlice()
+		sort.Strings(names)
+		sb.WriteString(fmt.Sprintf("\n%s:\n", s.name))
+		for _, n := range names {
+			sb.WriteString(fmt.Sprintf("  - %s\n", n))
+		}
+	}
+	return sb.String()
+}
+
+// policyRuleReferencesNamespace returns true if the policy rule references a resource type or alias in any of the namespaces.
+// The comparison is case insensitive, as resource types are in Azure.
+func policyRuleReferencesNamespace(rule any, namespaces []string) bool {
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return false
+	}
+	s := strings.ToLower(string(b))
+	for _, ns := range namespaces {
+		ns = strings.ToLower(ns)
+		if strings.Contains(s, `"`+ns+`/`) || strings.Contains(s, `"`+ns+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceIdName returns the last segment of a resource id, which is the resource name.
+func resourceIdName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
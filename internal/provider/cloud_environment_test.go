@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEnvironmentCloudConfiguration tests that the environment selects the ARM endpoint used by the Azure clients.
+func TestEnvironmentCloudConfiguration(t *testing.T) {
+	for env, want := range map[string]string{
+		"public":       "https://management.azure.com",
+		"usgovernment": "https://management.usgovcloudapi.net",
+		"china":        "https://management.chinacloudapi.cn",
+	} {
+		cc, err := environmentCloudConfiguration(env)
+		require.NoError(t, err)
+		assert.Equal(t, want, cc.Services[cloud.ResourceManager].Endpoint, env)
+		assert.Equal(t, want, azureClientOptions(AlzProviderModel{}, azcore.ClientOptions{Cloud: cc}, "test").Cloud.Services[cloud.ResourceManager].Endpoint, env)
+	}
+	_, err := environmentCloudConfiguration("germany")
+	assert.ErrorContains(t, err, `unknown environment "germany"`)
+}
+
+func TestUnavailableResourceProvidersForEnvironment(t *testing.T) {
+	assert.Empty(t, unavailableResourceProvidersForEnvironment("public", nil))
+	assert.Contains(t, unavailableResourceProvidersForEnvironment("usgovernment", nil), "Microsoft.Chaos")
+	assert.Equal(t, []string{"Microsoft.Test"}, unavailableResourceProvidersForEnvironment("usgovernment", []string{"Microsoft.Test"}))
+	assert.Empty(t, unavailableResourceProvidersForEnvironment("china", []string{}))
+}
+
+// TestFilterUnavailableResourceProviders tests that the filter cascades from policy definitions
+// to the policy set definitions and policy assignments that use them, across the deployment and including built-in definitions.
+func TestFilterUnavailableResourceProviders(t *testing.T) {
+	builtIns := testBuiltInDefinitionRecorder(t, map[string]string{
+		"00000000-0000-0000-0000-000000000001": `{"name": "00000000-0000-0000-0000-000000000001", "properties": {"policyType": "BuiltIn", "policyRule": {"if": {"field": "type", "equals": "Microsoft.Chaos/experiments"}, "then": {"effect": "audit"}}}}`,
+		"00000000-0000-0000-0000-000000000002": `{"name": "00000000-0000-0000-0000-000000000002", "properties": {"policyType": "BuiltIn", "policyRule": {"if": {"field": "type", "equals": "Microsoft.Storage/storageAccounts"}, "then": {"effect": "audit"}}}}`,
+	}, nil)
+	// The chaos definition is inherited from the parent management group, so it is not in the definitions of this one.
+	parentPds := map[string]armpolicy.Definition{
+		"chaos": {Properties: &armpolicy.DefinitionProperties{PolicyRule: map[string]any{
+			"if":   map[string]any{"field": "type", "equals": "microsoft.chaos/experiments"},
+			"then": map[string]any{"effect": "deny"},
+		}}},
+	}
+	pds := map[string]armpolicy.Definition{
+		"storage": {Properties: &armpolicy.DefinitionProperties{PolicyRule: map[string]any{
+			"if":   map[string]any{"field": "type", "equals": "Microsoft.Storage/storageAccounts"},
+			"then": map[string]any{"effect": "deny"},
+		}}},
+	}
+	psds := map[string]armpolicy.SetDefinition{
+		"set-chaos": {Properties: &armpolicy.SetDefinitionProperties{PolicyDefinitions: []*armpolicy.DefinitionReference{
+			{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policyDefinitions/storage")},
+			{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/parent/providers/Microsoft.Authorization/policyDefinitions/chaos")},
+		}}},
+		"set-built-in-chaos": {Properties: &armpolicy.SetDefinitionProperties{PolicyDefinitions: []*armpolicy.DefinitionReference{
+			{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001")},
+		}}},
+		"set-storage": {Properties: &armpolicy.SetDefinitionProperties{PolicyDefinitions: []*armpolicy.DefinitionReference{
+			{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policyDefinitions/storage")},
+			{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000002")},
+		}}},
+	}
+	pas := map[string]armpolicy.Assignment{
+		"pa-chaos":          {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/parent/providers/Microsoft.Authorization/policyDefinitions/chaos")}},
+		"pa-set-chaos":      {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policySetDefinitions/set-chaos")}},
+		"pa-built-in-chaos": {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001")}},
+		"pa-set-built-in":   {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policySetDefinitions/set-built-in-chaos")}},
+		"pa-storage":        {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policySetDefinitions/set-storage")}},
+		"pa-built-in-stor":  {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000002")}},
+	}
+
+	deploymentPds, deploymentPsds := deploymentDefinitions(nil, builtIns)
+	maps.Copy(deploymentPds, parentPds)
+	maps.Copy(deploymentPds, pds)
+	maps.Copy(deploymentPsds, psds)
+	unavailable := unavailableDefinitions(deploymentPds, deploymentPsds, []string{"Microsoft.Chaos"})
+	assert.ElementsMatch(t, []string{"chaos", "00000000-0000-0000-0000-000000000001"}, unavailable.policyDefinitions.ToSlice())
+	assert.ElementsMatch(t, []string{"set-chaos", "set-built-in-chaos"}, unavailable.policySetDefinitions.ToSlice())
+
+	removed := filterUnavailableResourceProviders(pds, psds, pas, unavailable)
+	assert.Empty(t, removed.policyDefinitions.ToSlice(), "the inherited and built-in definitions are not in this management group")
+	assert.ElementsMatch(t, []string{"set-chaos", "set-built-in-chaos"}, removed.policySetDefinitions.ToSlice())
+	assert.ElementsMatch(t, []string{"pa-chaos", "pa-set-chaos", "pa-built-in-chaos", "pa-set-built-in"}, removed.policyAssignments.ToSlice())
+	assert.Contains(t, pds, "storage")
+	assert.Equal(t, []string{"set-storage"}, sortedKeys(psds))
+	assert.Equal(t, []string{"pa-built-in-stor", "pa-storage"}, sortedKeys(pas))
+
+	ras := filterPolicyRoleAssignments([]alzlib.PolicyRoleAssignment{
+		{AssignmentName: "pa-chaos"},
+		{AssignmentName: "pa-storage"},
+	}, removed)
+	assert.Equal(t, []alzlib.PolicyRoleAssignment{{AssignmentName: "pa-storage"}}, ras)
+
+	summary := unavailableResourceProvidersSummary(removed, []string{"Microsoft.Chaos"})
+	assert.Contains(t, summary, "(Microsoft.Chaos)")
+	assert.Contains(t, summary, "Policy assignments:\n  - pa-built-in-chaos\n  - pa-chaos\n  - pa-set-built-in\n  - pa-set-chaos\n")
+
+	assert.Equal(t, 0, unavailableDefinitions(deploymentPds, deploymentPsds, nil).Cardinality())
+}
+
+func TestPolicyRuleReferencesNamespace(t *testing.T) {
+	rule := map[string]any{"if": map[string]any{"field": "Microsoft.Network/virtualNetworks/subnets[*].name", "exists": true}}
+	assert.True(t, policyRuleReferencesNamespace(rule, []string{"microsoft.network"}))
+	assert.False(t, policyRuleReferencesNamespace(rule, []string{"Microsoft.Net"}))
+	assert.True(t, policyRuleReferencesNamespace(map[string]any{"equals": "Microsoft.Chaos"}, []string{"Microsoft.Chaos"}))
+}
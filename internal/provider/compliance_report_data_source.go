@@ -0,0 +1,287 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/Azure/terraform-provider-alz/internal/outputschema"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// The effect categories of the compliance report.
+const (
+	complianceEffectAudit             = "audit"
+	complianceEffectDeny              = "deny"
+	complianceEffectDeployIfNotExists = "deploy_if_not_exists"
+	complianceEffectDisabled          = "disabled"
+	complianceEffectOther             = "other"
+	complianceEffectUnknown           = "unknown"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &ComplianceReportDataSource{}
+
+func NewComplianceReportDataSource() datasource.DataSource {
+	return &ComplianceReportDataSource{}
+}
+
+// ComplianceReportDataSource defines the data source implementation.
+type ComplianceReportDataSource struct {
+	alz *alzProviderData
+}
+
+// ComplianceReportDataSourceModel describes the data source data model.
+type ComplianceReportDataSourceModel struct {
+	Id               types.String `tfsdk:"id"`
+	PolicyExemptions types.Map    `tfsdk:"policy_exemptions"` // map of string
+	Report           types.String `tfsdk:"report"`
+}
+
+// complianceReport is the JSON report, described by the `alz_compliance_report` output schema.
+type complianceReport struct {
+	OutputSchemaVersion       string                            `json:"output_schema_version"`
+	ManagementGroups          int                               `json:"management_groups"`
+	PolicyAssignments         complianceReportPolicyAssignments `json:"policy_assignments"`
+	PolicyExemptions          complianceReportPolicyExemptions  `json:"policy_exemptions"`
+	UnassignedRecommendations []string                          `json:"unassigned_recommendations"`
+}
+
+type complianceReportPolicyAssignments struct {
+	Total        int            `json:"total"`
+	Enforced     int            `json:"enforced"`
+	DoNotEnforce int            `json:"do_not_enforce"`
+	Effects      map[string]int `json:"effects"`
+}
+
+type complianceReportPolicyExemptions struct {
+	Total     int `json:"total"`
+	Mitigated int `json:"mitigated"`
+	Waiver    int `json:"waiver"`
+}
+
+func (d *ComplianceReportDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_compliance_report"
+}
+
+func (d *ComplianceReportDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Compliance report data source. Summarizes the policy assignments of the management groups added by the `alz_archetype` data sources in a JSON report, " +
+			"so that Terraform Cloud run tasks or OPA policies can evaluate it to gate applies, e.g. to fail if any `Deny` policy is not enforced. " +
+			"The report is described by the `alz_compliance_report` schema of the `output_schema` function. " +
+			"Use `depends_on` to ensure that this data source is read after all of the `alz_archetype` data sources.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"policy_exemptions": schema.MapAttribute{
+				MarkdownDescription: "The ARM JSON policy exemptions to count in the report, e.g. the `policy_exemption` values of the `alz_policy_exemptions` data source. The map keys are not used.",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+
+			"report": schema.StringAttribute{
+				MarkdownDescription: "The JSON report. It has the number of `management_groups`, and the number of `policy_assignments` in `total`, `enforced` and set to `do_not_enforce`, " +
+					"with the number of each category of `effects`: `audit` (`Audit` and `AuditIfNotExists`), `deny` (`Deny` and `DenyAction`), `deploy_if_not_exists` (`DeployIfNotExists` and `Modify`), `disabled`, `other`, and `unknown`. " +
+					"The effect of a policy assignment is its `effect` parameter, or the default effect of the library policy definition it assigns. " +
+					"Other policy assignments with a managed identity are counted as `deploy_if_not_exists`, as the identity is only used for remediation, and the rest as `unknown`, e.g. policy set definitions. " +
+					"It also has the number of `policy_exemptions` in `total` and of each category, `mitigated` and `waiver`, " +
+					"and the `unassigned_recommendations`, which are the sorted names of the library policy assignments that are not assigned to any management group.",
+				Computed: true,
+			},
+		},
+	}
+}
+
+func (d *ComplianceReportDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *ComplianceReportDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ComplianceReportDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	exemptions := make(map[string]string, len(data.PolicyExemptions.Elements()))
+	resp.Diagnostics.Append(data.PolicyExemptions.ElementsAs(ctx, &exemptions, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.alz.mu.Lock()
+	mgs := make([]*alzlib.AlzManagementGroup, 0)
+	for _, name := range d.alz.Deployment.ListManagementGroups() {
+		mgs = append(mgs, d.alz.Deployment.GetManagementGroup(name))
+	}
+	report := newComplianceReport(mgs, d.alz.library)
+	d.alz.mu.Unlock()
+
+	for _, k := range sortedKeys(exemptions) {
+		if err := report.addPolicyExemption(exemptions[k]); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("policy_exemptions").AtMapKey(k), "Invalid policy exemption", err.Error())
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to marshal compliance report", err.Error())
+		return
+	}
+	data.Id = types.StringValue("compliance_report")
+	data.Report = types.StringValue(string(b))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// newComplianceReport returns the report of the policy assignments of the management groups, without exemptions.
+func newComplianceReport(mgs []*alzlib.AlzManagementGroup, idx *libraryIndex) *complianceReport {
+	res := &complianceReport{
+		OutputSchemaVersion: outputschema.Version,
+		PolicyAssignments: complianceReportPolicyAssignments{
+			Effects: map[string]int{
+				complianceEffectAudit:             0,
+				complianceEffectDeny:              0,
+				complianceEffectDeployIfNotExists: 0,
+				complianceEffectDisabled:          0,
+				complianceEffectOther:             0,
+				complianceEffectUnknown:           0,
+			},
+		},
+		UnassignedRecommendations: make([]string, 0),
+	}
+	metadata := idx.PolicyDefinitionMetadata()
+	for _, mg := range mgs {
+		if mg == nil {
+			continue
+		}
+		res.ManagementGroups++
+		for _, pa := range mg.GetPolicyAssignmentMap() {
+			res.PolicyAssignments.Total++
+			if pa.Properties != nil && pa.Properties.EnforcementMode != nil && *pa.Properties.EnforcementMode == armpolicy.EnforcementModeDoNotEnforce {
+				res.PolicyAssignments.DoNotEnforce++
+			} else {
+				res.PolicyAssignments.Enforced++
+			}
+			res.PolicyAssignments.Effects[complianceEffectCategory(policyAssignmentEffect(pa, metadata), pa)]++
+		}
+	}
+	if idx != nil {
+		res.UnassignedRecommendations = idx.Difference(usedLibraryContent(mgs)).policyAssignments.ToSlice()
+		sort.Strings(res.UnassignedRecommendations)
+	}
+	return res
+}
+
+// addPolicyExemption counts the ARM JSON policy exemption.
+func (r *complianceReport) addPolicyExemption(s string) error {
+	var exemption armpolicy.Exemption
+	if err := json.Unmarshal([]byte(s), &exemption); err != nil {
+		return fmt.Errorf("unable to unmarshal policy exemption: %w", err)
+	}
+	if exemption.Properties == nil || exemption.Properties.ExemptionCategory == nil {
+		return fmt.Errorf("policy exemption has no exemption category")
+	}
+	r.PolicyExemptions.Total++
+	switch *exemption.Properties.ExemptionCategory {
+	case armpolicy.ExemptionCategoryMitigated:
+		r.PolicyExemptions.Mitigated++
+	case armpolicy.ExemptionCategoryWaiver:
+		r.PolicyExemptions.Waiver++
+	}
+	return nil
+}
+
+// policyAssignmentEffect returns the effect of the policy assignment, or an empty string if it is not known.
+// If the assignment assigns a library policy definition, the effect is the value of the definition's effect parameter,
+// or the definition's default effect. Otherwise it is the value of the assignment's `effect` parameter, if any.
+func policyAssignmentEffect(pa armpolicy.Assignment, metadata map[string]libraryPolicyDefinitionMetadata) string {
+	if pa.Properties == nil {
+		return ""
+	}
+	param := "effect"
+	def := ""
+	if id := pa.Properties.PolicyDefinitionID; id != nil && strings.Contains(strings.ToLower(*id), "/policydefinitions/") {
+		if md, ok := metadata[resourceIdName(*id)]; ok {
+			param, def = md.EffectParameter, md.DefaultEffect
+		}
+	}
+	if param == "" {
+		return def
+	}
+	for k, v := range pa.Properties.Parameters {
+		if !strings.EqualFold(k, param) || v == nil {
+			continue
+		}
+		if s, ok := v.Value.(string); ok {
+			return s
+		}
+	}
+	return def
+}
+
+// complianceEffectCategory returns the report category of the effect.
+// If the effect is not known, assignments with a managed identity are categorized as `deploy_if_not_exists`.
+func complianceEffectCategory(effect string, pa armpolicy.Assignment) string {
+	switch strings.ToLower(effect) {
+	case "audit", "auditifnotexists":
+		return complianceEffectAudit
+	case "deny", "denyaction":
+		return complianceEffectDeny
+	case "deployifnotexists", "modify":
+		return complianceEffectDeployIfNotExists
+	case "disabled":
+		return complianceEffectDisabled
+	case "":
+		if pa.Identity != nil && pa.Identity.Type != nil && *pa.Identity.Type != armpolicy.ResourceIdentityTypeNone {
+			return complianceEffectDeployIfNotExists
+		}
+		return complianceEffectUnknown
+	default:
+		return complianceEffectOther
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/Azure/terraform-provider-alz/internal/outputschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewComplianceReport tests the report of the test management group and the counting of exemptions.
+func TestNewComplianceReport(t *testing.T) {
+	data := newTestAlzProviderData(t)
+	mg := data.Deployment.GetManagementGroup("test")
+
+	report := newComplianceReport([]*alzlib.AlzManagementGroup{mg, nil}, data.library)
+	assert.Equal(t, 1, report.ManagementGroups)
+	assert.Equal(t, 1, report.PolicyAssignments.Total)
+	assert.Equal(t, 1, report.PolicyAssignments.Enforced)
+	assert.Equal(t, 1, report.PolicyAssignments.Effects[complianceEffectDeployIfNotExists])
+	assert.Empty(t, report.UnassignedRecommendations)
+
+	require.NoError(t, report.addPolicyExemption(`{"properties": {"exemptionCategory": "Waiver"}}`))
+	require.NoError(t, report.addPolicyExemption(`{"properties": {"exemptionCategory": "Mitigated"}}`))
+	assert.ErrorContains(t, report.addPolicyExemption(`{"properties": {}}`), "no exemption category")
+	assert.ErrorContains(t, report.addPolicyExemption(`{`), "unable to unmarshal policy exemption")
+	assert.Equal(t, complianceReportPolicyExemptions{Total: 2, Mitigated: 1, Waiver: 1}, report.PolicyExemptions)
+
+	b, err := json.Marshal(report)
+	require.NoError(t, err)
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(b, &res))
+	assert.Equal(t, outputschema.Version, res["output_schema_version"])
+	assert.Equal(t, []any{}, res["unassigned_recommendations"])
+	assert.Len(t, res["policy_assignments"].(map[string]any)["effects"], 6)
+
+	report = newComplianceReport(nil, data.library)
+	assert.Equal(t, []string{"BlobServicesDiagnosticsLogsToWorkspace"}, report.UnassignedRecommendations)
+}
+
+func TestPolicyAssignmentEffect(t *testing.T) {
+	metadata := map[string]libraryPolicyDefinitionMetadata{
+		"Deploy-Test": {DefaultEffect: "DeployIfNotExists", EffectParameter: "effectParam"},
+		"Deny-Test":   {DefaultEffect: "Deny"},
+	}
+	pa := func(id string, params map[string]any) armpolicy.Assignment {
+		res := armpolicy.Assignment{Properties: &armpolicy.AssignmentProperties{
+			PolicyDefinitionID: to.Ptr(id),
+			Parameters:         make(map[string]*armpolicy.ParameterValuesValue),
+		}}
+		for k, v := range params {
+			res.Properties.Parameters[k] = &armpolicy.ParameterValuesValue{Value: v}
+		}
+		return res
+	}
+	const pdPrefix = "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/"
+
+	assert.Equal(t, "DeployIfNotExists", policyAssignmentEffect(pa(pdPrefix+"Deploy-Test", nil), metadata))
+	assert.Equal(t, "Disabled", policyAssignmentEffect(pa(pdPrefix+"Deploy-Test", map[string]any{"EffectParam": "Disabled"}), metadata))
+	assert.Equal(t, "Deny", policyAssignmentEffect(pa(pdPrefix+"Deny-Test", map[string]any{"effect": "Audit"}), metadata), "the effect is not a parameter")
+	assert.Equal(t, "Audit", policyAssignmentEffect(pa("/providers/Microsoft.Authorization/policySetDefinitions/Builtin", map[string]any{"effect": "Audit"}), metadata))
+	assert.Empty(t, policyAssignmentEffect(pa("/providers/Microsoft.Authorization/policyDefinitions/Builtin", nil), metadata))
+	assert.Empty(t, policyAssignmentEffect(armpolicy.Assignment{}, metadata))
+}
+
+func TestComplianceEffectCategory(t *testing.T) {
+	none := armpolicy.Assignment{}
+	identity := armpolicy.Assignment{Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}}
+	assert.Equal(t, complianceEffectAudit, complianceEffectCategory("AuditIfNotExists", none))
+	assert.Equal(t, complianceEffectDeny, complianceEffectCategory("deny", none))
+	assert.Equal(t, complianceEffectDeployIfNotExists, complianceEffectCategory("Modify", none))
+	assert.Equal(t, complianceEffectDisabled, complianceEffectCategory("Disabled", identity))
+	assert.Equal(t, complianceEffectOther, complianceEffectCategory("Append", none))
+	assert.Equal(t, complianceEffectDeployIfNotExists, complianceEffectCategory("", identity))
+	assert.Equal(t, complianceEffectUnknown, complianceEffectCategory("", none))
+}
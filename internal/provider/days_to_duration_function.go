@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &DaysToDurationFunction{}
+
+func NewDaysToDurationFunction() function.Function {
+	return &DaysToDurationFunction{}
+}
+
+// DaysToDurationFunction converts a number of days to an ISO 8601 duration.
+type DaysToDurationFunction struct{}
+
+func (f *DaysToDurationFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "days_to_duration"
+}
+
+func (f *DaysToDurationFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Convert a number of days to an ISO 8601 duration",
+		MarkdownDescription: "Converts a number of days to an ISO 8601 duration, e.g. `30` to `P30D`. " +
+			"Use this to set the retention parameters of the policy assignments that expect a duration, rather than a number of days.",
+		Parameters: []function.Parameter{
+			function.Int64Parameter{
+				Name:                "days",
+				MarkdownDescription: "The number of days. Must not be negative.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *DaysToDurationFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var days int64
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &days))
+	if resp.Error != nil {
+		return
+	}
+
+	if days < 0 {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("days must not be negative, got %d", days))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, fmt.Sprintf("P%dD", days)))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runDaysToDurationFunction(days int64) *function.RunResponse {
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.Int64Value(days)}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	NewDaysToDurationFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestDaysToDurationFunction(t *testing.T) {
+	resp := runDaysToDurationFunction(30)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, types.StringValue("P30D"), resp.Result.Value())
+
+	resp = runDaysToDurationFunction(0)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, types.StringValue("P0D"), resp.Result.Value())
+
+	resp = runDaysToDurationFunction(-1)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "must not be negative")
+}
@@ -0,0 +1,330 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &DeploymentWavesDataSource{}
+
+func NewDeploymentWavesDataSource() datasource.DataSource {
+	return &DeploymentWavesDataSource{}
+}
+
+// DeploymentWavesDataSource defines the data source implementation.
+type DeploymentWavesDataSource struct {
+	alz *alzProviderData
+}
+
+// DeploymentWavesDataSourceModel describes the data source data model.
+type DeploymentWavesDataSourceModel struct {
+	Id    types.String         `tfsdk:"id"`
+	Waves []DeploymentWaveType `tfsdk:"waves"`
+}
+
+// DeploymentWaveType describes the objects that can be deployed in parallel once the earlier waves are deployed.
+type DeploymentWaveType struct {
+	ManagementGroups      types.Set `tfsdk:"management_groups"`       // set of string
+	PolicyAssignments     types.Set `tfsdk:"policy_assignments"`      // set of string
+	PolicyDefinitions     types.Set `tfsdk:"policy_definitions"`      // set of string
+	PolicyRoleAssignments types.Set `tfsdk:"policy_role_assignments"` // set of string
+	PolicySetDefinitions  types.Set `tfsdk:"policy_set_definitions"`  // set of string
+	RoleDefinitions       types.Set `tfsdk:"role_definitions"`        // set of string
+}
+
+// deploymentWave is the keys of the objects in a wave.
+type deploymentWave struct {
+	managementGroups      []string
+	policyAssignments     []string
+	policyDefinitions     []string
+	policyRoleAssignments []string
+	policySetDefinitions  []string
+	roleDefinitions       []string
+}
+
+func (d *DeploymentWavesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_deployment_waves"
+}
+
+func (d *DeploymentWavesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	setAttribute := func(description string) schema.SetAttribute {
+		return schema.SetAttribute{
+			MarkdownDescription: description,
+			Computed:            true,
+			ElementType:         types.StringType,
+		}
+	}
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Deployment waves data source. Orders the management groups added by the `alz_archetype` data sources, and their policy and role objects, into waves. " +
+			"Every object depends only on objects in earlier waves, so each wave can be applied with maximum parallelism, e.g. with one resource per wave using `for_each` and `depends_on` on the resource of the previous wave. " +
+			"Use `depends_on` to ensure that this data source is read after all of the `alz_archetype` data sources.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"waves": schema.ListNestedAttribute{
+				MarkdownDescription: "The waves, in the order they must be applied. " +
+					"A management group depends on its parent, a definition on its management group, a policy set definition on the custom policy definitions it references, " +
+					"a policy assignment on its management group, custom definition and the policy assignments in its `depends_on_assignments`, " +
+					"and a policy role assignment on its policy assignment and custom role definition. " +
+					"Objects of a management group are keyed by the management group name and the object name, joined by a slash, e.g. `corp/Deny-Public-Endpoints`.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"management_groups":       setAttribute("The names of the management groups."),
+						"policy_assignments":      setAttribute("The keys of the policy assignments."),
+						"policy_definitions":      setAttribute("The keys of the policy definitions."),
+						"policy_role_assignments": setAttribute("The keys of the policy role assignments, as in `alz_policy_role_assignments` of the `alz_archetype` data source."),
+						"policy_set_definitions":  setAttribute("The keys of the policy set definitions."),
+						"role_definitions":        setAttribute("The keys of the role definitions."),
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *DeploymentWavesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *DeploymentWavesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data DeploymentWavesDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.alz.mu.Lock()
+	mgs := make([]*alzlib.AlzManagementGroup, 0)
+	for _, name := range d.alz.Deployment.ListManagementGroups() {
+		mgs = append(mgs, d.alz.Deployment.GetManagementGroup(name))
+	}
+	waves := deploymentWaves(mgs, d.alz.policyAssignmentDependencies)
+	d.alz.mu.Unlock()
+
+	data.Id = types.StringValue("deployment_waves")
+	data.Waves = make([]DeploymentWaveType, len(waves))
+	for i, w := range waves {
+		var diags diag.Diagnostics
+		data.Waves[i], diags = w.convert(ctx)
+		resp.Diagnostics.Append(diags...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// convert converts the wave to the framework type.
+func (w deploymentWave) convert(ctx context.Context) (DeploymentWaveType, diag.Diagnostics) {
+	var res DeploymentWaveType
+	var diags diag.Diagnostics
+	for _, v := range []struct {
+		dst *types.Set
+		src []string
+	}{
+		{&res.ManagementGroups, w.managementGroups},
+		{&res.PolicyAssignments, w.policyAssignments},
+		{&res.PolicyDefinitions, w.policyDefinitions},
+		{&res.PolicyRoleAssignments, w.policyRoleAssignments},
+		{&res.PolicySetDefinitions, w.policySetDefinitions},
+		{&res.RoleDefinitions, w.roleDefinitions},
+	} {
+		set, d := types.SetValueFrom(ctx, types.StringType, v.src)
+		diags.Append(d...)
+		*v.dst = set
+	}
+	return res, diags
+}
+
+// deploymentWaves orders the management groups and their objects into waves, so that every object depends only on objects
+// in earlier waves. Each object is in the wave after the latest of its dependencies.
+// The dependencies between the policy assignments of each management group are keyed by management group name, as declared
+// with `depends_on_assignments`.
+func deploymentWaves(mgs []*alzlib.AlzManagementGroup, dependencies map[string]map[string][]string) []deploymentWave {
+	// The waves of the management groups and their policy definitions, policy set definitions and role definitions,
+	// used to resolve the waves of the objects that reference them. Role definitions are keyed by their lower case name, the
+	// last segment of their id, as alzlib normalizes the role definition ids of the policy role assignments to it.
+	mgWaves := make(map[string]int)
+	defWaves := make(map[string]int)
+	roleWaves := make(map[string]int)
+	var waves []deploymentWave
+	at := func(n int) *deploymentWave {
+		for len(waves) <= n {
+			waves = append(waves, deploymentWave{})
+		}
+		return &waves[n]
+	}
+	// referenceWave returns the wave of the definition with the resource id, or -1 if it is not in the deployment, e.g. a built-in definition.
+	referenceWave := func(id string) int {
+		if key, ok := managementGroupObjectKey(id); ok {
+			if n, ok := defWaves[key]; ok {
+				return n
+			}
+		}
+		return -1
+	}
+
+	// Sort by ancestry, so that a parent is processed before its children.
+	byDepth := make([]*alzlib.AlzManagementGroup, 0, len(mgs))
+	for _, mg := range mgs {
+		if mg != nil {
+			byDepth = append(byDepth, mg)
+		}
+	}
+	sort.SliceStable(byDepth, func(i, j int) bool {
+		return len(managementGroupAncestry(byDepth[i])) < len(managementGroupAncestry(byDepth[j]))
+	})
+	for _, mg := range byDepth {
+		name := resourceIdName(mg.ResourceId())
+		n := 0
+		if parent := mg.GetParentMg(); parent != nil && !mg.ParentIsExternal() {
+			if p, ok := mgWaves[resourceIdName(parent.ResourceId())]; ok {
+				n = p + 1
+			}
+		}
+		mgWaves[name] = n
+		w := at(n)
+		w.managementGroups = append(w.managementGroups, name)
+	}
+
+	// Definitions are processed in dependency order: policy definitions, policy set definitions, then policy assignments.
+	for _, mg := range byDepth {
+		name := resourceIdName(mg.ResourceId())
+		for _, k := range sortedKeys(mg.GetPolicyDefinitionsMap()) {
+			key := name + "/" + k
+			defWaves[key] = mgWaves[name] + 1
+			w := at(mgWaves[name] + 1)
+			w.policyDefinitions = append(w.policyDefinitions, key)
+		}
+		rds := mg.GetRoleDefinitionsMap()
+		for _, k := range sortedKeys(rds) {
+			if rds[k].ID != nil {
+				roleWaves[strings.ToLower(resourceIdName(*rds[k].ID))] = mgWaves[name] + 1
+			}
+			w := at(mgWaves[name] + 1)
+			w.roleDefinitions = append(w.roleDefinitions, name+"/"+k)
+		}
+	}
+	for _, mg := range byDepth {
+		name := resourceIdName(mg.ResourceId())
+		psds := mg.GetPolicySetDefinitionsMap()
+		for _, k := range sortedKeys(psds) {
+			n := mgWaves[name]
+			if psds[k].Properties != nil {
+				for _, ref := range psds[k].Properties.PolicyDefinitions {
+					if ref != nil && ref.PolicyDefinitionID != nil {
+						n = max(n, referenceWave(*ref.PolicyDefinitionID))
+					}
+				}
+			}
+			key := name + "/" + k
+			defWaves[key] = n + 1
+			w := at(n + 1)
+			w.policySetDefinitions = append(w.policySetDefinitions, key)
+		}
+	}
+	paWaves := make(map[string]int)
+	for _, mg := range byDepth {
+		name := resourceIdName(mg.ResourceId())
+		pas := mg.GetPolicyAssignmentMap()
+		deps := dependencies[name]
+		// paWave returns the wave of the policy assignment, after its definition and the policy assignments it depends on.
+		// A dependency that closes a cycle is ignored, rather than recursing forever.
+		visiting := make(map[string]bool)
+		var paWave func(k string) int
+		paWave = func(k string) int {
+			if n, ok := paWaves[name+"/"+k]; ok {
+				return n
+			}
+			visiting[k] = true
+			defer delete(visiting, k)
+			n := mgWaves[name]
+			if pas[k].Properties != nil && pas[k].Properties.PolicyDefinitionID != nil {
+				n = max(n, referenceWave(*pas[k].Properties.PolicyDefinitionID))
+			}
+			for _, dep := range deps[k] {
+				if _, ok := pas[dep]; ok && !visiting[dep] {
+					n = max(n, paWave(dep))
+				}
+			}
+			paWaves[name+"/"+k] = n + 1
+			return n + 1
+		}
+		for _, k := range sortedKeys(pas) {
+			w := at(paWave(k))
+			w.policyAssignments = append(w.policyAssignments, name+"/"+k)
+		}
+		for _, pra := range mg.GetPolicyRoleAssignments() {
+			n, ok := paWaves[name+"/"+pra.AssignmentName]
+			if !ok {
+				continue
+			}
+			if r, ok := roleWaves[strings.ToLower(resourceIdName(pra.RoleDefinitionId))]; ok {
+				n = max(n, r)
+			}
+			w := at(n + 1)
+			w.policyRoleAssignments = append(w.policyRoleAssignments, genPolicyRoleAssignmentId(pra))
+		}
+	}
+	return waves
+}
+
+// managementGroupObjectKey returns the key of an object at management group scope from its resource id,
+// e.g. `alz/Deny-Public-Endpoints`, or false if the resource is not at management group scope.
+func managementGroupObjectKey(id string) (string, bool) {
+	const scope = "/providers/microsoft.management/managementgroups/"
+	lower := strings.ToLower(id)
+	if !strings.HasPrefix(lower, scope) {
+		return "", false
+	}
+	mg, _, ok := strings.Cut(id[len(scope):], "/")
+	if !ok {
+		return "", false
+	}
+	return mg + "/" + resourceIdName(id), true
+}
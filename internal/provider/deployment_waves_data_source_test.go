@@ -0,0 +1,127 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeploymentWaves tests that each object is in the wave after its dependencies.
+func TestDeploymentWaves(t *testing.T) {
+	data := newTestAlzProviderData(t)
+	mg := data.Deployment.GetManagementGroup("test")
+	require.NoError(t, mg.GeneratePolicyAssignmentAdditionalRoleAssignments(data.AlzLib))
+	require.NotEmpty(t, mg.GetPolicyRoleAssignments())
+
+	waves := deploymentWaves([]*alzlib.AlzManagementGroup{mg, nil}, nil)
+	require.Len(t, waves, 4)
+	assert.Equal(t, []string{"test"}, waves[0].managementGroups)
+	assert.Equal(t, []string{"test/BlobServicesDiagnosticsLogsToWorkspace"}, waves[1].policyDefinitions)
+	assert.Equal(t, []string{"test/BlobServicesDiagnosticsLogsToWorkspace"}, waves[2].policyAssignments)
+	assert.Len(t, waves[3].policyRoleAssignments, len(mg.GetPolicyRoleAssignments()))
+	assert.Empty(t, waves[3].policyAssignments)
+}
+
+// TestDeploymentWavesDependencies tests that a policy assignment is in a wave after the policy assignments it depends on,
+// and that a policy role assignment is in a wave after the custom role definition it assigns.
+func TestDeploymentWavesDependencies(t *testing.T) {
+	ctx := context.Background()
+	roleId := fmt.Sprintf("/providers/Microsoft.Management/managementGroups/waves/providers/Microsoft.Authorization/roleDefinitions/%s",
+		uuid.NewSHA1(uuid.NameSpaceURL, []byte("waves"+"Test-Role")))
+	assignment := func(name string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`{
+  "name": "` + name + `",
+  "location": "${default_location}",
+  "identity": { "type": "SystemAssigned" },
+  "properties": {
+    "policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/Deploy-Test",
+    "scope": "${current_scope_resource_id}"
+  }
+}`)}
+	}
+	lib := fstest.MapFS{
+		"archetype_definition_waves.json": &fstest.MapFile{Data: []byte(`{
+  "name": "waves",
+  "policy_assignments": [ "First", "Second" ],
+  "policy_definitions": [ "Deploy-Test" ],
+  "policy_set_definitions": [],
+  "role_definitions": [ "Test-Role" ]
+}`)},
+		"role_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Test-Role",
+  "properties": {
+    "roleName": "Test-Role",
+    "permissions": [ { "actions": [ "*/read" ] } ],
+    "assignableScopes": [ "${current_scope_resource_id}" ]
+  }
+}`)},
+		"policy_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Deploy-Test",
+  "properties": {
+    "mode": "All",
+    "policyType": "Custom",
+    "policyRule": {
+      "if": { "field": "type", "equals": "Microsoft.Storage/storageAccounts" },
+      "then": {
+        "effect": "DeployIfNotExists",
+        "details": {
+          "type": "Microsoft.Storage/storageAccounts",
+          "roleDefinitionIds": [ "` + roleId + `" ]
+        }
+      }
+    }
+  }
+}`)},
+		"policy_assignment_first.json":  assignment("First"),
+		"policy_assignment_second.json": assignment("Second"),
+	}
+	alz := alzlib.NewAlzLib()
+	require.NoError(t, alz.Init(ctx, lib))
+	arch, err := alz.CopyArchetype("waves", &alzlib.WellKnownPolicyValues{DefaultLocation: to.Ptr("westeurope")})
+	require.NoError(t, err)
+	require.NoError(t, alz.AddManagementGroupToDeployment(ctx, alzlib.AlzManagementGroupAddRequest{
+		Id:               "waves",
+		DisplayName:      "waves",
+		ParentId:         "00000000-0000-0000-0000-000000000000",
+		ParentIsExternal: true,
+		Archetype:        arch,
+	}))
+	mg := alz.Deployment.GetManagementGroup("waves")
+	require.NoError(t, mg.GeneratePolicyAssignmentAdditionalRoleAssignments(alz))
+	require.Len(t, mg.GetPolicyRoleAssignments(), 2)
+
+	waves := deploymentWaves([]*alzlib.AlzManagementGroup{mg}, map[string]map[string][]string{
+		"waves": {"Second": {"First"}},
+	})
+	require.Len(t, waves, 5)
+	assert.Equal(t, []string{"waves/Test-Role"}, waves[1].roleDefinitions)
+	assert.Equal(t, []string{"waves/First"}, waves[2].policyAssignments)
+	assert.Equal(t, []string{"waves/Second"}, waves[3].policyAssignments)
+	assert.Len(t, waves[3].policyRoleAssignments, 1, "the role assignment of First")
+	assert.Len(t, waves[4].policyRoleAssignments, 1, "the role assignment of Second")
+
+	// Without the dependency, both policy assignments are in the same wave.
+	waves = deploymentWaves([]*alzlib.AlzManagementGroup{mg}, nil)
+	require.Len(t, waves, 4)
+	assert.Equal(t, []string{"waves/First", "waves/Second"}, waves[2].policyAssignments)
+	assert.Len(t, waves[3].policyRoleAssignments, 2)
+}
+
+func TestManagementGroupObjectKey(t *testing.T) {
+	key, ok := managementGroupObjectKey("/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/Deny-Public-Endpoints")
+	assert.True(t, ok)
+	assert.Equal(t, "alz/Deny-Public-Endpoints", key)
+
+	_, ok = managementGroupObjectKey("/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000000")
+	assert.False(t, ok)
+}
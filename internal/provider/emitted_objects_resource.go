@@ -0,0 +1,231 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// emittedObjectsPrivateKey is the private state key of the object names emitted by the last apply.
+const emittedObjectsPrivateKey = "emitted_objects"
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &EmittedObjectsResource{}
+var _ resource.ResourceWithImportState = &EmittedObjectsResource{}
+var _ resource.ResourceWithModifyPlan = &EmittedObjectsResource{}
+
+func NewEmittedObjectsResource() resource.Resource {
+	return &EmittedObjectsResource{}
+}
+
+// EmittedObjectsResource defines the resource implementation.
+// The resource only records the object names in private state, it does not delete the objects in Azure.
+type EmittedObjectsResource struct{}
+
+// EmittedObjectsResourceModel describes the resource data model.
+type EmittedObjectsResourceModel struct {
+	Id             types.String `tfsdk:"id"`
+	Objects        types.Map    `tfsdk:"objects"`         // map of set of string
+	RemovedObjects types.Map    `tfsdk:"removed_objects"` // map of set of string
+}
+
+// privateState is implemented by the private state of the resource requests and responses.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func (r EmittedObjectsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_emitted_objects"
+}
+
+func (r *EmittedObjectsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Emitted objects resource. Records the names of the objects emitted by the `alz_archetype` data sources in private state, " +
+			"so that the objects dropped by a library or customization change are known at plan time. " +
+			"Use `removed_objects` to delete the objects explicitly, rather than leaving orphans in Azure. " +
+			"The resource does not delete the objects in Azure.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The id of the record, e.g. the name of the management group.",
+			},
+			"objects": schema.MapAttribute{
+				Required: true,
+				MarkdownDescription: "A map of object types to the names of the emitted objects, " +
+					"e.g. `{ policy_assignments = keys(data.alz_archetype.example.alz_policy_assignments) }`.",
+				ElementType: types.SetType{ElemType: types.StringType},
+			},
+			"removed_objects": schema.MapAttribute{
+				Computed: true,
+				MarkdownDescription: "The names of the objects that were emitted by the last apply and are not in `objects`, keyed by object type. " +
+					"Only the object types with removed objects are present. " +
+					"The removed objects are empty when the resource is created or imported, and are kept until `objects` changes again.",
+				ElementType: types.SetType{ElemType: types.StringType},
+			},
+		},
+	}
+}
+
+// ModifyPlan computes the removed objects from the private state, so that they are shown in the plan.
+func (r *EmittedObjectsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var planned EmittedObjectsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &planned)...)
+	var prior *EmittedObjectsResourceModel
+	if !req.State.Raw.IsNull() {
+		prior = new(EmittedObjectsResourceModel)
+		resp.Diagnostics.Append(req.State.Get(ctx, prior)...)
+	}
+	emitted, diags := emittedObjects(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(planned.setRemovedObjects(ctx, emitted, prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("removed_objects"), planned.RemovedObjects)...)
+}
+
+func (r *EmittedObjectsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data EmittedObjectsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(data.setRemovedObjects(ctx, nil, nil)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(data.setEmittedObjects(ctx, resp.Private)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *EmittedObjectsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data EmittedObjectsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.Objects.IsNull() {
+		// Imported resources have only an id.
+		data.Objects = types.MapValueMust(types.SetType{ElemType: types.StringType}, map[string]attr.Value{})
+	}
+	if data.RemovedObjects.IsNull() {
+		data.RemovedObjects = types.MapValueMust(types.SetType{ElemType: types.StringType}, map[string]attr.Value{})
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *EmittedObjectsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var planned, current EmittedObjectsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &planned)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &current)...)
+	emitted, diags := emittedObjects(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(planned.setRemovedObjects(ctx, emitted, &current)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(planned.setEmittedObjects(ctx, resp.Private)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &planned)...)
+}
+
+func (r *EmittedObjectsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// There is nothing to delete in Azure, the state is removed by the framework.
+}
+
+func (r *EmittedObjectsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// setRemovedObjects sets the removed objects of the planned model from the objects emitted by the last apply,
+// and the prior model, which are nil if the resource is being created.
+// The removed objects are unknown if the planned objects are not yet known,
+// and the prior removed objects are kept if the objects have not changed.
+func (m *EmittedObjectsResourceModel) setRemovedObjects(ctx context.Context, emitted map[string][]string, prior *EmittedObjectsResourceModel) diag.Diagnostics {
+	setType := types.SetType{ElemType: types.StringType}
+	planned, known, diags := stringSetMap(ctx, m.Objects)
+	if diags.HasError() {
+		return diags
+	}
+	if !known {
+		m.RemovedObjects = types.MapUnknown(setType)
+		return diags
+	}
+	if prior != nil && prior.Objects.Equal(m.Objects) && !prior.RemovedObjects.IsNull() && !prior.RemovedObjects.IsUnknown() {
+		m.RemovedObjects = prior.RemovedObjects
+		return diags
+	}
+	var d diag.Diagnostics
+	m.RemovedObjects, d = types.MapValueFrom(ctx, setType, removedObjects(emitted, planned))
+	diags.Append(d...)
+	return diags
+}
+
+// setEmittedObjects records the objects of the model in the private state.
+func (m *EmittedObjectsResourceModel) setEmittedObjects(ctx context.Context, private privateState) diag.Diagnostics {
+	objects, _, diags := stringSetMap(ctx, m.Objects)
+	if diags.HasError() {
+		return diags
+	}
+	b, err := json.Marshal(objects)
+	if err != nil {
+		diags.AddError("Unable to marshal emitted objects", err.Error())
+		return diags
+	}
+	diags.Append(private.SetKey(ctx, emittedObjectsPrivateKey, b)...)
+	return diags
+}
+
+// emittedObjects returns the objects emitted by the last apply from the private state, or nil if there are none.
+func emittedObjects(ctx context.Context, private privateState) (map[string][]string, diag.Diagnostics) {
+	b, diags := private.GetKey(ctx, emittedObjectsPrivateKey)
+	if diags.HasError() || len(b) == 0 {
+		return nil, diags
+	}
+	var res map[string][]string
+	if err := json.Unmarshal(b, &res); err != nil {
+		diags.AddError("Unable to unmarshal emitted objects", fmt.Sprintf("The private state of the resource is invalid: %s", err.Error()))
+		return nil, diags
+	}
+	return res, diags
+}
+
+// removedObjects returns the sorted names of the emitted objects that are not planned, keyed by object type.
+// Object types without removed objects are omitted.
+func removedObjects(emitted, planned map[string][]string) map[string][]string {
+	res := make(map[string][]string)
+	for typ, names := range emitted {
+		keep := make(map[string]bool, len(planned[typ]))
+		for _, name := range planned[typ] {
+			keep[name] = true
+		}
+		for _, name := range names {
+			if !keep[name] {
+				res[typ] = append(res[typ], name)
+			}
+		}
+		sort.Strings(res[typ])
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPrivateState is an in-memory private state.
+type testPrivateState map[string][]byte
+
+func (p testPrivateState) GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics) {
+	return p[key], nil
+}
+
+func (p testPrivateState) SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics {
+	p[key] = value
+	return nil
+}
+
+func TestRemovedObjects(t *testing.T) {
+	emitted := map[string][]string{
+		"policy_assignments": {"Deny-Public-IP", "Audit-UnusedResources", "Deploy-MDFC-Config"},
+		"role_definitions":   {"Network-Subnet-Contributor"},
+	}
+	planned := map[string][]string{
+		"policy_assignments": {"Deploy-MDFC-Config"},
+		"role_definitions":   {"Network-Subnet-Contributor"},
+	}
+	assert.Equal(t, map[string][]string{
+		"policy_assignments": {"Audit-UnusedResources", "Deny-Public-IP"},
+	}, removedObjects(emitted, planned))
+	assert.Empty(t, removedObjects(emitted, emitted))
+	assert.Empty(t, removedObjects(nil, planned))
+}
+
+func TestEmittedObjectsSetRemovedObjects(t *testing.T) {
+	ctx := context.Background()
+	model := func(objects map[string][]string) EmittedObjectsResourceModel {
+		m, diags := types.MapValueFrom(ctx, types.SetType{ElemType: types.StringType}, objects)
+		require.False(t, diags.HasError())
+		return EmittedObjectsResourceModel{Id: types.StringValue("alz"), Objects: m}
+	}
+	removed := func(m EmittedObjectsResourceModel) map[string][]string {
+		var res map[string][]string
+		require.False(t, m.RemovedObjects.ElementsAs(ctx, &res, false).HasError())
+		return res
+	}
+	private := make(testPrivateState)
+
+	// Create
+	created := model(map[string][]string{"policy_assignments": {"Deny-Public-IP", "Deploy-MDFC-Config"}})
+	require.False(t, created.setRemovedObjects(ctx, nil, nil).HasError())
+	assert.Empty(t, removed(created))
+	require.False(t, created.setEmittedObjects(ctx, private).HasError())
+
+	// Remove
+	emitted, diags := emittedObjects(ctx, private)
+	require.False(t, diags.HasError())
+	updated := model(map[string][]string{"policy_assignments": {"Deploy-MDFC-Config"}})
+	require.False(t, updated.setRemovedObjects(ctx, emitted, &created).HasError())
+	assert.Equal(t, map[string][]string{"policy_assignments": {"Deny-Public-IP"}}, removed(updated))
+	require.False(t, updated.setEmittedObjects(ctx, private).HasError())
+
+	// No change keeps the removed objects of the last change
+	emitted, diags = emittedObjects(ctx, private)
+	require.False(t, diags.HasError())
+	unchanged := model(map[string][]string{"policy_assignments": {"Deploy-MDFC-Config"}})
+	require.False(t, unchanged.setRemovedObjects(ctx, emitted, &updated).HasError())
+	assert.True(t, unchanged.RemovedObjects.Equal(updated.RemovedObjects))
+
+	// Unknown
+	unknown := model(nil)
+	unknown.Objects = types.MapValueMust(types.SetType{ElemType: types.StringType}, map[string]attr.Value{
+		"policy_assignments": types.SetUnknown(types.StringType),
+	})
+	require.False(t, unknown.setRemovedObjects(ctx, emitted, &updated).HasError())
+	assert.True(t, unknown.RemovedObjects.IsUnknown())
+
+	// Invalid private state
+	_, diags = emittedObjects(ctx, testPrivateState{emittedObjectsPrivateKey: []byte(`[]`)})
+	assert.True(t, diags.HasError())
+}
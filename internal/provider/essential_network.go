@@ -0,0 +1,45 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// essentialNetworkOnly disables the outbound calls that the configuration does not explicitly require, if `essential_network_only` is set,
+// and returns a warning that lists them. The config is the model as read from the configuration,
+// and data is the model after the environment is read, before the defaults are set.
+func essentialNetworkOnly(config AlzProviderModel, data *AlzProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !config.EssentialNetworkOnly.ValueBool() {
+		return diags
+	}
+	skipped := make([]string, 0, 3)
+	// data is checked rather than config, so that a value that is already set is not overwritten.
+	if data.UseAlzLib.IsNull() {
+		data.UseAlzLib = types.BoolValue(false)
+		skipped = append(skipped, "the download of the ALZ library, as `use_alz_lib` is not set")
+	}
+	if config.MetadataHost.IsNull() && data.MetadataHost.ValueString() != "" {
+		skipped = append(skipped, fmt.Sprintf("the metadata endpoint of %s from the `ARM_METADATA_HOSTNAME` environment variable, as `metadata_host` is not set", data.MetadataHost.ValueString()))
+		data.MetadataHost = types.StringNull()
+	}
+	if !data.SkipProviderRegistration.ValueBool() {
+		data.SkipProviderRegistration = types.BoolValue(true)
+		skipped = append(skipped, "the registration of resource providers")
+	}
+	if len(skipped) != 0 {
+		diags.AddAttributeWarning(
+			path.Root("essential_network_only"),
+			"Non-essential network calls skipped",
+			"The provider is configured with `essential_network_only` and does not make these calls:\n  - "+strings.Join(skipped, "\n  - "),
+		)
+	}
+	return diags
+}
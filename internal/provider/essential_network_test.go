@@ -0,0 +1,53 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEssentialNetworkOnly tests that the calls the configuration does not explicitly require are disabled and listed.
+func TestEssentialNetworkOnly(t *testing.T) {
+	config := AlzProviderModel{
+		EssentialNetworkOnly:     types.BoolValue(false),
+		UseAlzLib:                types.BoolNull(),
+		MetadataHost:             types.StringNull(),
+		SkipProviderRegistration: types.BoolNull(),
+	}
+	data := config
+	data.MetadataHost = types.StringValue("management.local.azurestack.external")
+	assert.Empty(t, essentialNetworkOnly(config, &data), "not set")
+	assert.True(t, data.UseAlzLib.IsNull())
+
+	config.EssentialNetworkOnly = types.BoolValue(true)
+	diags := essentialNetworkOnly(config, &data)
+	require.Equal(t, 1, diags.WarningsCount())
+	assert.Contains(t, diags.Warnings()[0].Detail(), "the download of the ALZ library")
+	assert.Contains(t, diags.Warnings()[0].Detail(), "management.local.azurestack.external")
+	assert.Contains(t, diags.Warnings()[0].Detail(), "the registration of resource providers")
+	assert.False(t, data.UseAlzLib.ValueBool())
+	assert.True(t, data.MetadataHost.IsNull())
+	assert.True(t, data.SkipProviderRegistration.ValueBool())
+
+	// Explicitly configured calls are made.
+	config.UseAlzLib = types.BoolValue(true)
+	config.MetadataHost = types.StringValue("management.local.azurestack.external")
+	config.SkipProviderRegistration = types.BoolValue(true)
+	data = config
+	assert.Empty(t, essentialNetworkOnly(config, &data))
+	assert.True(t, data.UseAlzLib.ValueBool())
+	assert.Equal(t, "management.local.azurestack.external", data.MetadataHost.ValueString())
+
+	// A value that is already set is not overwritten.
+	config.UseAlzLib = types.BoolNull()
+	data = config
+	data.UseAlzLib = types.BoolValue(true)
+	diags = essentialNetworkOnly(config, &data)
+	assert.Empty(t, diags)
+	assert.True(t, data.UseAlzLib.ValueBool())
+}
@@ -0,0 +1,253 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+const (
+	hierarchyImportFormatHcl  = "hcl"
+	hierarchyImportFormatJson = "json"
+)
+
+// importedManagementGroup is a live management group with the nearest library archetype.
+type importedManagementGroup struct {
+	managementGroupInfo
+	BaseArchetype string
+	MatchScore    float64
+}
+
+var hierarchyImportLabelInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// nearestArchetype returns the archetype whose policy assignments are most similar to the supplied assignments,
+// using the Jaccard index as the score. Ties are broken by archetype name.
+func nearestArchetype(assignments mapset.Set[string], archetypes map[string]mapset.Set[string]) (string, float64) {
+	names := make([]string, 0, len(archetypes))
+	for k := range archetypes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var best string
+	bestScore := -1.0
+	for _, name := range names {
+		score := jaccardIndex(assignments, archetypes[name])
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return best, bestScore
+}
+
+// jaccardIndex returns the size of the intersection divided by the size of the union.
+// Two empty sets are identical.
+func jaccardIndex(a, b mapset.Set[string]) float64 {
+	union := a.Union(b).Cardinality()
+	if union == 0 {
+		return 1
+	}
+	return float64(a.Intersect(b).Cardinality()) / float64(union)
+}
+
+// sortHierarchy returns the management groups so that parents come before their children,
+// starting at the root. Siblings are sorted by name.
+// Management groups that are not connected to the root are appended, sorted by name.
+func sortHierarchy(root string, mgs []managementGroupInfo) []managementGroupInfo {
+	children := make(map[string][]managementGroupInfo)
+	byName := make(map[string]managementGroupInfo, len(mgs))
+	for _, mg := range mgs {
+		children[mg.ParentName] = append(children[mg.ParentName], mg)
+		byName[mg.Name] = mg
+	}
+	for _, c := range children {
+		sort.Slice(c, func(i, j int) bool { return c[i].Name < c[j].Name })
+	}
+	res := make([]managementGroupInfo, 0, len(mgs))
+	seen := mapset.NewThreadUnsafeSet[string]()
+	queue := make([]managementGroupInfo, 0, len(mgs))
+	if mg, ok := byName[root]; ok {
+		queue = append(queue, mg)
+	} else {
+		queue = append(queue, children[root]...)
+	}
+	for len(queue) > 0 {
+		mg := queue[0]
+		queue = queue[1:]
+		if !seen.Add(mg.Name) {
+			continue
+		}
+		res = append(res, mg)
+		queue = append(queue, children[mg.Name]...)
+	}
+	rest := make([]managementGroupInfo, 0)
+	for _, mg := range mgs {
+		if !seen.Contains(mg.Name) {
+			rest = append(rest, mg)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool { return rest[i].Name < rest[j].Name })
+	return append(res, rest...)
+}
+
+// hierarchyMaps returns the children, parent and descendants of each management group, for lookups in Terraform.
+// Every management group is a key of children and descendants, with an empty list if it has none; the lists are sorted by name.
+// Parents are only returned for management groups that have a management group parent, which may be outside the hierarchy.
+func hierarchyMaps(mgs []managementGroupInfo) (children map[string][]string, parents map[string]string, descendants map[string][]string) {
+	children = make(map[string][]string, len(mgs))
+	parents = make(map[string]string, len(mgs))
+	for _, mg := range mgs {
+		if _, ok := children[mg.Name]; !ok {
+			children[mg.Name] = make([]string, 0)
+		}
+	}
+	for _, mg := range mgs {
+		if mg.ParentName == "" {
+			continue
+		}
+		parents[mg.Name] = mg.ParentName
+		if c, ok := children[mg.ParentName]; ok {
+			children[mg.ParentName] = append(c, mg.Name)
+		}
+	}
+	for _, c := range children {
+		sort.Strings(c)
+	}
+
+	descendants = make(map[string][]string, len(mgs))
+	var walk func(name string, seen mapset.Set[string]) []string
+	walk = func(name string, seen mapset.Set[string]) []string {
+		if d, ok := descendants[name]; ok {
+			return d
+		}
+		d := make([]string, 0)
+		for _, c := range children[name] {
+			if !seen.Add(c) {
+				continue // a cycle, which Azure does not allow
+			}
+			d = append(d, c)
+			d = append(d, walk(c, seen)...)
+		}
+		sort.Strings(d)
+		descendants[name] = d
+		return d
+	}
+	for name := range children {
+		walk(name, mapset.NewThreadUnsafeSet(name))
+	}
+	return children, parents, descendants
+}
+
+// hierarchyImportLabels returns a unique Terraform block label for each management group name.
+func hierarchyImportLabels(mgs []importedManagementGroup) map[string]string {
+	res := make(map[string]string, len(mgs))
+	used := mapset.NewThreadUnsafeSet[string]()
+	for _, mg := range mgs {
+		label := hierarchyImportLabelInvalidChars.ReplaceAllString(mg.Name, "_")
+		if label == "" || (label[0] >= '0' && label[0] <= '9') || label[0] == '-' {
+			label = "mg_" + label
+		}
+		unique := label
+		for i := 2; !used.Add(unique); i++ {
+			unique = fmt.Sprintf("%s_%d", label, i)
+		}
+		res[mg.Name] = unique
+	}
+	return res
+}
+
+// hierarchyImportParentRef returns the Terraform expression for the parent of the management group.
+// Parents that are in the import refer to the data source, so that Terraform orders the hierarchy.
+func hierarchyImportParentRef(mg importedManagementGroup, labels map[string]string) (string, bool) {
+	if l, ok := labels[mg.ParentName]; ok {
+		return fmt.Sprintf("data.alz_archetype.%s.id", l), true
+	}
+	return mg.ParentName, false
+}
+
+// hierarchyImportHcl returns `alz_archetype` data source blocks for the management groups.
+// If location is empty, the blocks refer to `var.location`.
+func hierarchyImportHcl(mgs []importedManagementGroup, location string) string {
+	labels := hierarchyImportLabels(mgs)
+	loc := "var.location"
+	if location != "" {
+		loc = hclQuote(location)
+	}
+	var sb strings.Builder
+	for i, mg := range mgs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		parent, isRef := hierarchyImportParentRef(mg, labels)
+		if !isRef {
+			parent = hclQuote(parent)
+		}
+		sb.WriteString(fmt.Sprintf("data \"alz_archetype\" %s {\n", hclQuote(labels[mg.Name])))
+		sb.WriteString(fmt.Sprintf("  id             = %s\n", hclQuote(mg.Name)))
+		sb.WriteString(fmt.Sprintf("  parent_id      = %s\n", parent))
+		sb.WriteString(fmt.Sprintf("  display_name   = %s\n", hclQuote(mg.DisplayName)))
+		sb.WriteString(fmt.Sprintf("  base_archetype = %s # %.0f%% policy assignment match\n", hclQuote(mg.BaseArchetype), mg.MatchScore*100))
+		sb.WriteString("  defaults = {\n")
+		sb.WriteString(fmt.Sprintf("    location = %s\n", loc))
+		sb.WriteString("  }\n")
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+// hierarchyImportJson returns the `alz_archetype` data sources for the management groups in Terraform JSON syntax.
+// If location is empty, the data sources refer to `var.location`.
+func hierarchyImportJson(mgs []importedManagementGroup, location string) (string, error) {
+	labels := hierarchyImportLabels(mgs)
+	if location == "" {
+		location = "${var.location}"
+	}
+	blocks := make(map[string]any, len(mgs))
+	for _, mg := range mgs {
+		parent, isRef := hierarchyImportParentRef(mg, labels)
+		if isRef {
+			parent = "${" + parent + "}"
+		} else {
+			parent = hclEscapeTemplate(parent)
+		}
+		blocks[labels[mg.Name]] = map[string]any{
+			"id":             hclEscapeTemplate(mg.Name),
+			"parent_id":      parent,
+			"display_name":   hclEscapeTemplate(mg.DisplayName),
+			"base_archetype": hclEscapeTemplate(mg.BaseArchetype),
+			"defaults": map[string]any{
+				"location": location,
+			},
+		}
+	}
+	b, err := json.MarshalIndent(map[string]any{
+		"data": map[string]any{
+			"alz_archetype": blocks,
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling hierarchy import: %w", err)
+	}
+	return string(b), nil
+}
+
+// hclQuote returns the string as an HCL quoted template, escaping template sequences.
+func hclQuote(s string) string {
+	return strconv.Quote(hclEscapeTemplate(s))
+}
+
+// hclEscapeTemplate escapes the template sequences in the string, which are also interpreted in Terraform JSON syntax.
+func hclEscapeTemplate(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
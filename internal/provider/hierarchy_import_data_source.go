@@ -0,0 +1,285 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &HierarchyImportDataSource{}
+
+func NewHierarchyImportDataSource() datasource.DataSource {
+	return &HierarchyImportDataSource{}
+}
+
+// HierarchyImportDataSource defines the data source implementation.
+type HierarchyImportDataSource struct {
+	alz *alzProviderData
+}
+
+// HierarchyImportDataSourceModel describes the data source data model.
+type HierarchyImportDataSourceModel struct {
+	Children              types.Map                            `tfsdk:"children"` // map of list of string
+	Content               types.String                         `tfsdk:"content"`
+	DefaultLocation       types.String                         `tfsdk:"default_location"`
+	Descendants           types.Map                            `tfsdk:"descendants"` // map of list of string
+	Format                types.String                         `tfsdk:"format"`
+	Id                    types.String                         `tfsdk:"id"`
+	ManagementGroups      []HierarchyImportManagementGroupType `tfsdk:"management_groups"`
+	Parents               types.Map                            `tfsdk:"parents"` // map of string
+	RootManagementGroupId types.String                         `tfsdk:"root_management_group_id"`
+}
+
+// HierarchyImportManagementGroupType is a live management group and the nearest library archetype.
+type HierarchyImportManagementGroupType struct {
+	BaseArchetype types.String  `tfsdk:"base_archetype"`
+	DisplayName   types.String  `tfsdk:"display_name"`
+	Id            types.String  `tfsdk:"id"`
+	MatchScore    types.Float64 `tfsdk:"match_score"`
+	ParentId      types.String  `tfsdk:"parent_id"`
+}
+
+func (d *HierarchyImportDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_hierarchy_import"
+}
+
+func (d *HierarchyImportDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Hierarchy import data source. Reads an existing management group hierarchy from Azure and generates equivalent `alz_archetype` data sources, to help bring the hierarchy under management. " +
+			"The base archetype of each management group is the library archetype whose policy assignments best match the policy assignments at the management group scope. " +
+			"The mapping is best-effort and the generated configuration should be reviewed before use.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"root_management_group_id": schema.StringAttribute{
+				MarkdownDescription: "The name of the management group at the top of the hierarchy to import. The management group and all of its descendants are imported.",
+				Required:            true,
+			},
+
+			"format": schema.StringAttribute{
+				MarkdownDescription: "The format of `content`. Must be one of `hcl` or `json`. Default is `hcl`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(hierarchyImportFormatHcl, hierarchyImportFormatJson),
+				},
+			},
+
+			"default_location": schema.StringAttribute{
+				MarkdownDescription: "The location to use in the `defaults` of the generated data sources. If not set, the generated data sources refer to `var.location`.",
+				Optional:            true,
+			},
+
+			"content": schema.StringAttribute{
+				MarkdownDescription: "The generated `alz_archetype` data sources, in the requested format.",
+				Computed:            true,
+			},
+
+			"children": schema.MapAttribute{
+				MarkdownDescription: "The names of the child management groups of each imported management group, sorted by name. " +
+					"Every imported management group is a key, with an empty list if it has no children.",
+				Computed:    true,
+				ElementType: types.ListType{ElemType: types.StringType},
+			},
+
+			"parents": schema.MapAttribute{
+				MarkdownDescription: "The name of the parent management group of each imported management group. " +
+					"The root management group is included if its parent is a management group, e.g. the tenant root group.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+
+			"descendants": schema.MapAttribute{
+				MarkdownDescription: "The names of all of the management groups below each imported management group, sorted by name. " +
+					"Use this to apply an operation to a subtree, e.g. everything under `landingzones`, with a lookup.",
+				Computed:    true,
+				ElementType: types.ListType{ElemType: types.StringType},
+			},
+
+			"management_groups": schema.ListNestedAttribute{
+				MarkdownDescription: "The imported management groups, with parents before their children.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "The management group name.",
+							Computed:            true,
+						},
+						"display_name": schema.StringAttribute{
+							MarkdownDescription: "The management group display name.",
+							Computed:            true,
+						},
+						"parent_id": schema.StringAttribute{
+							MarkdownDescription: "The parent management group name.",
+							Computed:            true,
+						},
+						"base_archetype": schema.StringAttribute{
+							MarkdownDescription: "The library archetype that best matches the policy assignments at the management group scope.",
+							Computed:            true,
+						},
+						"match_score": schema.Float64Attribute{
+							MarkdownDescription: "The similarity of the policy assignments to those of the base archetype, from `0` to `1`.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *HierarchyImportDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *HierarchyImportDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data HierarchyImportDataSourceModel
+
+	if d.alz == nil || d.alz.clients == nil || d.alz.clients.ManagementGroupsClient == nil || d.alz.clients.PolicyAssignmentsClient == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	if d.alz.offline {
+		resp.Diagnostics.AddError(offlineErrorSummary, "The hierarchy is read from Azure, which the provider cannot access with `offline`.")
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rootId := data.RootManagementGroupId.ValueString()
+	mgClient := d.alz.clients.ManagementGroupsClient
+	root, err := mgClient.Get(ctx, rootId)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("root_management_group_id"), "Failed to read management group", err.Error())
+		return
+	}
+	descendants, err := mgClient.ListDescendants(ctx, rootId)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("root_management_group_id"), "Failed to list management group descendants", err.Error())
+		return
+	}
+
+	d.alz.mu.Lock()
+	archetypes := make(map[string]mapset.Set[string])
+	for _, name := range d.alz.ListArchetypes() {
+		arch, err := d.alz.CopyArchetype(name, nil)
+		if err != nil {
+			d.alz.mu.Unlock()
+			resp.Diagnostics.AddError("Failed to copy archetype", err.Error())
+			return
+		}
+		archetypes[name] = arch.PolicyAssignments
+	}
+	d.alz.mu.Unlock()
+
+	mgs := sortHierarchy(root.Name, append([]managementGroupInfo{root}, descendants...))
+	imported := make([]importedManagementGroup, len(mgs))
+	data.ManagementGroups = make([]HierarchyImportManagementGroupType, len(mgs))
+	for i, mg := range mgs {
+		assignments, err := listManagementGroupPolicyAssignmentNames(ctx, d.alz.clients.PolicyAssignmentsClient, mg.Name)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to list policy assignments", fmt.Sprintf("management group %s: %s", mg.Name, err.Error()))
+			return
+		}
+		imported[i] = importedManagementGroup{managementGroupInfo: mg}
+		imported[i].BaseArchetype, imported[i].MatchScore = nearestArchetype(assignments, archetypes)
+		data.ManagementGroups[i] = HierarchyImportManagementGroupType{
+			BaseArchetype: types.StringValue(imported[i].BaseArchetype),
+			DisplayName:   types.StringValue(mg.DisplayName),
+			Id:            types.StringValue(mg.Name),
+			MatchScore:    types.Float64Value(imported[i].MatchScore),
+			ParentId:      types.StringValue(mg.ParentName),
+		}
+	}
+
+	var diags diag.Diagnostics
+	childMap, parentMap, descendantMap := hierarchyMaps(mgs)
+	data.Children, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, childMap)
+	resp.Diagnostics.Append(diags...)
+	data.Parents, diags = types.MapValueFrom(ctx, types.StringType, parentMap)
+	resp.Diagnostics.Append(diags...)
+	data.Descendants, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, descendantMap)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	switch data.Format.ValueString() {
+	case hierarchyImportFormatJson:
+		content, err := hierarchyImportJson(imported, data.DefaultLocation.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to generate hierarchy import", err.Error())
+			return
+		}
+		data.Content = types.StringValue(content)
+	default:
+		data.Content = types.StringValue(hierarchyImportHcl(imported, data.DefaultLocation.ValueString()))
+	}
+	data.Id = types.StringValue(rootId)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// listManagementGroupPolicyAssignmentNames returns the names of the policy assignments at the management group scope.
+// Assignments inherited from parent management groups are not included.
+func listManagementGroupPolicyAssignmentNames(ctx context.Context, client *armpolicy.AssignmentsClient, mgName string) (mapset.Set[string], error) {
+	res := mapset.NewThreadUnsafeSet[string]()
+	pager := client.NewListForManagementGroupPager(mgName, &armpolicy.AssignmentsClientListForManagementGroupOptions{
+		Filter: to.Ptr("atExactScope()"),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, pa := range page.Value {
+			if pa == nil || pa.Name == nil {
+				continue
+			}
+			res.Add(*pa.Name)
+		}
+	}
+	return res, nil
+}
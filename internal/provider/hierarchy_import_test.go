@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearestArchetype(t *testing.T) {
+	archetypes := map[string]mapset.Set[string]{
+		"corp":   mapset.NewThreadUnsafeSet("Deny-Public-Endpoints", "Deploy-Private-DNS-Zones"),
+		"empty":  mapset.NewThreadUnsafeSet[string](),
+		"online": mapset.NewThreadUnsafeSet[string](),
+		"root":   mapset.NewThreadUnsafeSet("Deploy-ASC-Monitoring", "Deploy-MDFC-Config", "Deny-Classic-Resources"),
+	}
+
+	name, score := nearestArchetype(mapset.NewThreadUnsafeSet("Deploy-ASC-Monitoring", "Deploy-MDFC-Config"), archetypes)
+	assert.Equal(t, "root", name)
+	assert.InDelta(t, 2.0/3.0, score, 0.0001)
+
+	name, score = nearestArchetype(mapset.NewThreadUnsafeSet("Deny-Public-Endpoints", "Deploy-Private-DNS-Zones"), archetypes)
+	assert.Equal(t, "corp", name)
+	assert.Equal(t, 1.0, score)
+
+	// Ties are broken by name.
+	name, score = nearestArchetype(mapset.NewThreadUnsafeSet[string](), archetypes)
+	assert.Equal(t, "empty", name)
+	assert.Equal(t, 1.0, score)
+
+	name, score = nearestArchetype(mapset.NewThreadUnsafeSet("a"), nil)
+	assert.Equal(t, "", name)
+	assert.Equal(t, 0.0, score)
+}
+
+func TestSortHierarchy(t *testing.T) {
+	mgs := []managementGroupInfo{
+		{Name: "corp", ParentName: "landingzones"},
+		{Name: "platform", ParentName: "alz"},
+		{Name: "landingzones", ParentName: "alz"},
+		{Name: "alz", ParentName: "tenant"},
+		{Name: "online", ParentName: "landingzones"},
+		{Name: "orphan", ParentName: "other"},
+	}
+	res := sortHierarchy("alz", mgs)
+	names := make([]string, len(res))
+	for i, mg := range res {
+		names[i] = mg.Name
+	}
+	assert.Equal(t, []string{"alz", "landingzones", "platform", "corp", "online", "orphan"}, names)
+}
+
+func TestHierarchyImportLabels(t *testing.T) {
+	labels := hierarchyImportLabels([]importedManagementGroup{
+		{managementGroupInfo: managementGroupInfo{Name: "alz"}},
+		{managementGroupInfo: managementGroupInfo{Name: "1-landing.zones"}},
+		{managementGroupInfo: managementGroupInfo{Name: "1-landing(zones"}},
+	})
+	assert.Equal(t, "alz", labels["alz"])
+	assert.Equal(t, "mg_1-landing_zones", labels["1-landing.zones"])
+	assert.Equal(t, "mg_1-landing_zones_2", labels["1-landing(zones"])
+}
+
+func testImportedManagementGroups() []importedManagementGroup {
+	return []importedManagementGroup{
+		{
+			managementGroupInfo: managementGroupInfo{Name: "alz", DisplayName: "Azure Landing Zones", ParentName: "00000000-0000-0000-0000-000000000000"},
+			BaseArchetype:       "root",
+			MatchScore:          1,
+		},
+		{
+			managementGroupInfo: managementGroupInfo{Name: "corp", DisplayName: "Corp ${env}", ParentName: "alz"},
+			BaseArchetype:       "corp",
+			MatchScore:          0.5,
+		},
+	}
+}
+
+func TestHierarchyImportHcl(t *testing.T) {
+	res := hierarchyImportHcl(testImportedManagementGroups(), "")
+	assert.Equal(t, `data "alz_archetype" "alz" {
+  id             = "alz"
+  parent_id      = "00000000-0000-0000-0000-000000000000"
+  display_name   = "Azure Landing Zones"
+  base_archetype = "root" # 100% policy assignment match
+  defaults = {
+    location = var.location
+  }
+}
+
+data "alz_archetype" "corp" {
+  id             = "corp"
+  parent_id      = data.alz_archetype.alz.id
+  display_name   = "Corp $${env}"
+  base_archetype = "corp" # 50% policy assignment match
+  defaults = {
+    location = var.location
+  }
+}
+`, res)
+}
+
+func TestHierarchyImportJson(t *testing.T) {
+	res, err := hierarchyImportJson(testImportedManagementGroups(), "westeurope")
+	require.NoError(t, err)
+	var got struct {
+		Data struct {
+			AlzArchetype map[string]map[string]any `json:"alz_archetype"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res), &got))
+	require.Len(t, got.Data.AlzArchetype, 2)
+	assert.Equal(t, "${data.alz_archetype.alz.id}", got.Data.AlzArchetype["corp"]["parent_id"])
+	assert.Equal(t, "Corp $${env}", got.Data.AlzArchetype["corp"]["display_name"])
+	assert.Equal(t, map[string]any{"location": "westeurope"}, got.Data.AlzArchetype["alz"]["defaults"])
+}
+
+func TestHierarchyMaps(t *testing.T) {
+	children, parents, descendants := hierarchyMaps([]managementGroupInfo{
+		{Name: "corp", ParentName: "landingzones"},
+		{Name: "alz", ParentName: "tenant"},
+		{Name: "online", ParentName: "landingzones"},
+		{Name: "landingzones", ParentName: "alz"},
+		{Name: "platform", ParentName: "alz"},
+		{Name: "orphan"},
+	})
+	assert.Equal(t, map[string][]string{
+		"alz":          {"landingzones", "platform"},
+		"corp":         {},
+		"landingzones": {"corp", "online"},
+		"online":       {},
+		"orphan":       {},
+		"platform":     {},
+	}, children)
+	assert.Equal(t, map[string]string{
+		"alz":          "tenant",
+		"corp":         "landingzones",
+		"landingzones": "alz",
+		"online":       "landingzones",
+		"platform":     "alz",
+	}, parents)
+	assert.Equal(t, map[string][]string{
+		"alz":          {"corp", "landingzones", "online", "platform"},
+		"corp":         {},
+		"landingzones": {"corp", "online"},
+		"online":       {},
+		"orphan":       {},
+		"platform":     {},
+	}, descendants)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// imdsHost is the host of the Azure instance metadata service, used by managed identity credentials.
+// It is link-local, so it is never reached through a proxy.
+const imdsHost = "169.254.169.254"
+
+// newHttpClient returns an *http.Client that honours the proxy and custom CA settings in the provider data.
+// The client is shared by the Azure SDK clients, the credentials and the library downloads.
+func newHttpClient(data AlzProviderModel) (*http.Client, error) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unable to clone default http transport")
+	}
+	transport = transport.Clone()
+
+	if isKnown(data.ProxyUrl) && data.ProxyUrl.ValueString() != "" {
+		if _, err := url.Parse(data.ProxyUrl.ValueString()); err != nil {
+			return nil, fmt.Errorf("unable to parse proxy url: %w", err)
+		}
+		transport.Proxy = newProxyFunc(data.ProxyUrl.ValueString())
+	}
+
+	if isKnown(data.CustomCaCerts) && data.CustomCaCerts.ValueString() != "" {
+		pool, err := newCertPoolFromFile(data.CustomCaCerts.ValueString())
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// newProxyFunc returns a proxy function that sends requests through the proxy url,
+// except to the hosts in the `NO_PROXY` environment variable, localhost and the instance metadata service.
+func newProxyFunc(proxyUrl string) func(*http.Request) (*url.URL, error) {
+	noProxy := []string{imdsHost}
+	if val := getFirstSetEnvVar("NO_PROXY", "no_proxy"); val != "" {
+		noProxy = append(noProxy, val)
+	}
+	cfg := &httpproxy.Config{
+		HTTPProxy:  proxyUrl,
+		HTTPSProxy: proxyUrl,
+		NoProxy:    strings.Join(noProxy, ","),
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// newCertPoolFromFile returns the system cert pool with the PEM encoded certificates in the supplied file appended.
+func newCertPoolFromFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read custom CA certificates file %s: %w", path, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid PEM encoded certificates found in custom CA certificates file %s", path)
+	}
+	return pool, nil
+}
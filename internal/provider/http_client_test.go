@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHttpClient(t *testing.T) {
+	// Test with no proxy or custom CA.
+	client, err := newHttpClient(AlzProviderModel{})
+	require.NoError(t, err)
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	if transport.TLSClientConfig != nil {
+		assert.Nil(t, transport.TLSClientConfig.RootCAs)
+	}
+
+	// Test with proxy url.
+	client, err = newHttpClient(AlzProviderModel{
+		ProxyUrl: types.StringValue("http://proxy.example.com:8080"),
+	})
+	require.NoError(t, err)
+	transport, ok = client.Transport.(*http.Transport)
+	require.True(t, ok)
+	req, _ := http.NewRequest(http.MethodGet, "https://management.azure.com", http.NoBody)
+	proxyUrl, err := transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:8080", proxyUrl.String())
+
+	// Test that managed identity and the hosts in NO_PROXY bypass the proxy.
+	t.Setenv("NO_PROXY", "internal.example.com")
+	client, err = newHttpClient(AlzProviderModel{
+		ProxyUrl: types.StringValue("http://proxy.example.com:8080"),
+	})
+	require.NoError(t, err)
+	transport, ok = client.Transport.(*http.Transport)
+	require.True(t, ok)
+	for _, u := range []string{"http://169.254.169.254/metadata/identity/oauth2/token", "https://internal.example.com/lib.zip", "http://localhost:8080"} {
+		req, _ = http.NewRequest(http.MethodGet, u, http.NoBody)
+		proxyUrl, err = transport.Proxy(req)
+		assert.NoError(t, err)
+		assert.Nil(t, proxyUrl, u)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://github.com/Azure/Azure-Landing-Zones-Library", http.NoBody)
+	proxyUrl, err = transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:8080", proxyUrl.String())
+
+	// Test with a missing custom CA file.
+	_, err = newHttpClient(AlzProviderModel{
+		CustomCaCerts: types.StringValue(filepath.Join(t.TempDir(), "missing.pem")),
+	})
+	assert.ErrorContains(t, err, "unable to read custom CA certificates file")
+
+	// Test with a custom CA file that does not contain certificates.
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), 0600))
+	_, err = newHttpClient(AlzProviderModel{
+		CustomCaCerts: types.StringValue(invalid),
+	})
+	assert.ErrorContains(t, err, "no valid PEM encoded certificates found")
+
+	// Test with a valid custom CA file.
+	valid := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(valid, testSelfSignedCertPem(t), 0600))
+	client, err = newHttpClient(AlzProviderModel{
+		CustomCaCerts: types.StringValue(valid),
+	})
+	require.NoError(t, err)
+	transport, ok = client.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+}
+
+// testSelfSignedCertPem generates a self-signed PEM encoded CA certificate for testing.
+func testSelfSignedCertPem(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
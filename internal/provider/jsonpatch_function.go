@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/Azure/terraform-provider-alz/internal/jsonpatch"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &JsonPatchFunction{}
+
+func NewJsonPatchFunction() function.Function {
+	return &JsonPatchFunction{}
+}
+
+// JsonPatchFunction applies a JSON patch to a JSON document.
+type JsonPatchFunction struct{}
+
+func (f *JsonPatchFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "jsonpatch"
+}
+
+func (f *JsonPatchFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Apply a JSON patch (RFC 6902) to a JSON document",
+		MarkdownDescription: "Applies a JSON patch ([RFC 6902](https://datatracker.ietf.org/doc/html/rfc6902)) to a JSON document and returns the patched document. " +
+			"Use this to make surgical modifications to the JSON outputs of the provider, e.g. a value in `alz_policy_assignments`, without external tools. " +
+			"The `add`, `remove`, `replace`, `move`, `copy` and `test` operations are supported. " +
+			"If any operation fails, including a `test`, the function returns an error. " +
+			"Numbers are preserved as written, and object keys in the result are sorted.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "document",
+				MarkdownDescription: "The JSON document to patch.",
+			},
+			function.StringParameter{
+				Name:                "patch",
+				MarkdownDescription: "The JSON patch, an array of operations. Use `jsonencode` to build it from a Terraform value.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *JsonPatchFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var document, patch string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &document, &patch))
+	if resp.Error != nil {
+		return
+	}
+
+	res, err := jsonpatch.Apply([]byte(document), []byte(patch))
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, string(res)))
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runJsonPatchFunction(document, patch string) *function.RunResponse {
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{
+			types.StringValue(document),
+			types.StringValue(patch),
+		}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	NewJsonPatchFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestJsonPatchFunction(t *testing.T) {
+	resp := runJsonPatchFunction(
+		`{"properties":{"enforcementMode":"Default","parameters":{"effect":{"value":"DeployIfNotExists"}}}}`,
+		`[{"op":"replace","path":"/properties/enforcementMode","value":"DoNotEnforce"},{"op":"remove","path":"/properties/parameters/effect"}]`,
+	)
+	require.Nil(t, resp.Error)
+	s, ok := resp.Result.Value().(types.String)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"properties":{"enforcementMode":"DoNotEnforce","parameters":{}}}`, s.ValueString())
+
+	resp = runJsonPatchFunction(`{}`, `[{"op":"test","path":"/a","value":1}]`)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), `member "a" not found`)
+}
@@ -0,0 +1,341 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &LibChangelogDataSource{}
+
+func NewLibChangelogDataSource() datasource.DataSource {
+	return &LibChangelogDataSource{}
+}
+
+// LibChangelogDataSource defines the data source implementation.
+type LibChangelogDataSource struct {
+	alz *alzProviderData
+}
+
+// LibChangelogDataSourceModel describes the data source data model.
+type LibChangelogDataSourceModel struct {
+	ArchetypeMembership  map[string]LibChangelogArchetypeMembershipType `tfsdk:"archetype_membership"`
+	Archetypes           *LibChangelogChangeType                        `tfsdk:"archetypes"`
+	FromRef              types.String                                   `tfsdk:"from_ref"`
+	Id                   types.String                                   `tfsdk:"id"`
+	PolicyAssignments    *LibChangelogChangeType                        `tfsdk:"policy_assignments"`
+	PolicyDefinitions    *LibChangelogChangeType                        `tfsdk:"policy_definitions"`
+	PolicySetDefinitions *LibChangelogChangeType                        `tfsdk:"policy_set_definitions"`
+	RoleDefinitions      *LibChangelogChangeType                        `tfsdk:"role_definitions"`
+	Summary              types.String                                   `tfsdk:"summary"`
+	ToRef                types.String                                   `tfsdk:"to_ref"`
+}
+
+// LibChangelogChangeType is the names of the objects of one kind that changed between the two library versions.
+type LibChangelogChangeType struct {
+	Added    types.Set `tfsdk:"added"`    // set of string
+	Modified types.Set `tfsdk:"modified"` // set of string
+	Removed  types.Set `tfsdk:"removed"`  // set of string
+}
+
+// LibChangelogArchetypeMembershipType is the change in membership of an archetype.
+type LibChangelogArchetypeMembershipType struct {
+	PolicyAssignmentsAdded      types.Set `tfsdk:"policy_assignments_added"`       // set of string
+	PolicyAssignmentsRemoved    types.Set `tfsdk:"policy_assignments_removed"`     // set of string
+	PolicyDefinitionsAdded      types.Set `tfsdk:"policy_definitions_added"`       // set of string
+	PolicyDefinitionsRemoved    types.Set `tfsdk:"policy_definitions_removed"`     // set of string
+	PolicySetDefinitionsAdded   types.Set `tfsdk:"policy_set_definitions_added"`   // set of string
+	PolicySetDefinitionsRemoved types.Set `tfsdk:"policy_set_definitions_removed"` // set of string
+	RoleDefinitionsAdded        types.Set `tfsdk:"role_definitions_added"`         // set of string
+	RoleDefinitionsRemoved      types.Set `tfsdk:"role_definitions_removed"`       // set of string
+}
+
+func (d *LibChangelogDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_lib_changelog"
+}
+
+func libChangelogChangeSchema(kind string) schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		MarkdownDescription: fmt.Sprintf("The names of the %s that changed.", kind),
+		Computed:            true,
+		Attributes: map[string]schema.Attribute{
+			"added": schema.SetAttribute{
+				MarkdownDescription: fmt.Sprintf("The %s that are only in `to_ref`.", kind),
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"modified": schema.SetAttribute{
+				MarkdownDescription: fmt.Sprintf("The %s that are in both versions but have changed.", kind),
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"removed": schema.SetAttribute{
+				MarkdownDescription: fmt.Sprintf("The %s that are only in `from_ref`.", kind),
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *LibChangelogDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	membershipAttributes := make(map[string]schema.Attribute)
+	for _, kind := range []string{"policy_assignments", "policy_definitions", "policy_set_definitions", "role_definitions"} {
+		desc := strings.ReplaceAll(kind, "_", " ")
+		membershipAttributes[kind+"_added"] = schema.SetAttribute{
+			MarkdownDescription: fmt.Sprintf("The %s added to the archetype.", desc),
+			Computed:            true,
+			ElementType:         types.StringType,
+		}
+		membershipAttributes[kind+"_removed"] = schema.SetAttribute{
+			MarkdownDescription: fmt.Sprintf("The %s removed from the archetype.", desc),
+			Computed:            true,
+			ElementType:         types.StringType,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "ALZ library changelog data source. Downloads two versions of the ALZ library and returns the objects that were added, removed or modified between them, " +
+			"and the changes to archetype membership. Use this to review the impact of a library upgrade before changing `alz_lib_ref`.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"from_ref": schema.StringAttribute{
+				MarkdownDescription: "The ALZ library tag to compare from, e.g. `platform/alz/2024.03.00`. A version constraint may also be used, see the provider `alz_lib_ref` attribute.",
+				Required:            true,
+			},
+
+			"to_ref": schema.StringAttribute{
+				MarkdownDescription: "The ALZ library tag to compare to, e.g. `platform/alz/2024.07.01`. A version constraint may also be used, see the provider `alz_lib_ref` attribute.",
+				Required:            true,
+			},
+
+			"archetypes":             libChangelogChangeSchema("archetypes"),
+			"policy_assignments":     libChangelogChangeSchema("policy assignments"),
+			"policy_definitions":     libChangelogChangeSchema("policy definitions"),
+			"policy_set_definitions": libChangelogChangeSchema("policy set definitions"),
+			"role_definitions":       libChangelogChangeSchema("role definitions"),
+
+			"archetype_membership": schema.MapNestedAttribute{
+				MarkdownDescription: "The membership changes of the archetypes that are in both versions, keyed by archetype name. Only archetypes with changes are included.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: membershipAttributes,
+				},
+			},
+
+			"summary": schema.StringAttribute{
+				MarkdownDescription: "A markdown summary of the changes, suitable for a pull request description.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *LibChangelogDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *LibChangelogDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data LibChangelogDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	if d.alz.offline {
+		resp.Diagnostics.AddError(offlineErrorSummary, "The ALZ library releases are downloaded, which the provider cannot do with `offline`.")
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.alz.readTimeout)
+	defer cancel()
+
+	indexes := make([]*libraryIndex, 2)
+	for i, ref := range []struct {
+		path  path.Path
+		value string
+	}{
+		{path.Root("from_ref"), data.FromRef.ValueString()},
+		{path.Root("to_ref"), data.ToRef.ValueString()},
+	} {
+		lib, err := fetchAlzLibRef(ctx, d.alz.alzLibProfile, ref.value, d.alz.libraryDownloadOptions)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(ref.path, "Failed to download ALZ library", err.Error())
+			return
+		}
+		if indexes[i], err = newLibraryIndex([]fs.FS{lib}); err != nil {
+			resp.Diagnostics.AddAttributeError(ref.path, "Failed to index ALZ library", err.Error())
+			return
+		}
+	}
+
+	changelog := newLibraryChangelog(indexes[0], indexes[1])
+	var diags diag.Diagnostics
+	data.Archetypes, diags = convertLibraryChange(ctx, changelog.archetypes)
+	resp.Diagnostics.Append(diags...)
+	data.PolicyAssignments, diags = convertLibraryChange(ctx, changelog.policyAssignments)
+	resp.Diagnostics.Append(diags...)
+	data.PolicyDefinitions, diags = convertLibraryChange(ctx, changelog.policyDefinitions)
+	resp.Diagnostics.Append(diags...)
+	data.PolicySetDefinitions, diags = convertLibraryChange(ctx, changelog.policySetDefinitions)
+	resp.Diagnostics.Append(diags...)
+	data.RoleDefinitions, diags = convertLibraryChange(ctx, changelog.roleDefinitions)
+	resp.Diagnostics.Append(diags...)
+	data.ArchetypeMembership, diags = convertLibraryArchetypeChanges(ctx, changelog.archetypeMembership)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Summary = types.StringValue(changelog.Markdown(data.FromRef.ValueString(), data.ToRef.ValueString()))
+	data.Id = types.StringValue(fmt.Sprintf("%s..%s", data.FromRef.ValueString(), data.ToRef.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// fetchAlzLibRef downloads the ALZ library profile at the supplied ref, which may be a version constraint.
+// Each ref is downloaded to its own directory so that it is only fetched once.
+func fetchAlzLibRef(ctx context.Context, profile alzLibProfile, ref string, opts libfetcher.Options) (fs.FS, error) {
+	if isAlzLibRefConstraint(ref) {
+		tags, err := listAlzLibTags(ctx, &opts)
+		if err != nil {
+			return nil, err
+		}
+		if ref, err = resolveAlzLibRef(ref, profile.tagPrefix(), tags); err != nil {
+			return nil, err
+		}
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	src := profile.url(ref)
+	dst := filepath.Join(alzLibDirBase, "changelog", libChangelogDirName(ref))
+	// The libraries are downloaded to the same directories by every instance of this data source.
+	defer lockLibChangelogDir(dst)()
+	opts.Pwd = pwd
+	lib, err := libfetcher.Fetch(ctx, src, dst, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch library %s: %w", src, err)
+	}
+	return lib, nil
+}
+
+// libChangelogDirLocks serializes the downloads to each changelog directory.
+// The mutex only guards the map, so that different refs are downloaded concurrently.
+var libChangelogDirLocks = struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // keyed by directory
+}{locks: make(map[string]*sync.Mutex)}
+
+// lockLibChangelogDir locks the changelog directory and returns the function that unlocks it.
+func lockLibChangelogDir(dir string) func() {
+	libChangelogDirLocks.mu.Lock()
+	l, ok := libChangelogDirLocks.locks[dir]
+	if !ok {
+		l = &sync.Mutex{}
+		libChangelogDirLocks.locks[dir] = l
+	}
+	libChangelogDirLocks.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// libChangelogDirName returns a directory name for the ref, replacing characters that are not safe in paths.
+func libChangelogDirName(ref string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, ref)
+}
+
+// convertLibraryChange converts a library change to the framework type.
+func convertLibraryChange(ctx context.Context, c libraryChange) (*LibChangelogChangeType, diag.Diagnostics) {
+	var diags, d diag.Diagnostics
+	res := new(LibChangelogChangeType)
+	res.Added, d = types.SetValueFrom(ctx, types.StringType, c.added.ToSlice())
+	diags.Append(d...)
+	res.Modified, d = types.SetValueFrom(ctx, types.StringType, c.modified.ToSlice())
+	diags.Append(d...)
+	res.Removed, d = types.SetValueFrom(ctx, types.StringType, c.removed.ToSlice())
+	diags.Append(d...)
+	return res, diags
+}
+
+// convertLibraryArchetypeChanges converts the archetype membership changes to the framework type.
+func convertLibraryArchetypeChanges(ctx context.Context, src map[string]libraryArchetypeChange) (map[string]LibChangelogArchetypeMembershipType, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	res := make(map[string]LibChangelogArchetypeMembershipType, len(src))
+	for name, c := range src {
+		var v LibChangelogArchetypeMembershipType
+		for _, s := range []struct {
+			dst *types.Set
+			src mapset.Set[string]
+		}{
+			{&v.PolicyAssignmentsAdded, c.added.policyAssignments},
+			{&v.PolicyAssignmentsRemoved, c.removed.policyAssignments},
+			{&v.PolicyDefinitionsAdded, c.added.policyDefinitions},
+			{&v.PolicyDefinitionsRemoved, c.removed.policyDefinitions},
+			{&v.PolicySetDefinitionsAdded, c.added.policySetDefinitions},
+			{&v.PolicySetDefinitionsRemoved, c.removed.policySetDefinitions},
+			{&v.RoleDefinitionsAdded, c.added.roleDefinitions},
+			{&v.RoleDefinitionsRemoved, c.removed.roleDefinitions},
+		} {
+			var d diag.Diagnostics
+			*s.dst, d = types.SetValueFrom(ctx, types.StringType, s.src.ToSlice())
+			diags.Append(d...)
+		}
+		res[name] = v
+	}
+	return res, diags
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// azureDevOpsScope is the Microsoft Entra ID scope of Azure DevOps.
+const azureDevOpsScope = "499b84ac-1321-427f-aa17-267ca6975798/.default"
+
+// defaultLibGitUsername is the user name sent with a token, which is accepted by GitHub and ignored by Azure DevOps.
+const defaultLibGitUsername = "x-access-token"
+
+// LibGitCredentialType describes the credential of a host in the lib_git_credentials provider attribute.
+type LibGitCredentialType struct {
+	Token                  types.String `tfsdk:"token"`
+	UseProviderCredentials types.Bool   `tfsdk:"use_provider_credentials"`
+	Username               types.String `tfsdk:"username"`
+}
+
+// libGitHeaders returns the authorization header for each host in the lib_git_credentials attribute, keyed by the URL prefix of the host.
+// A token is sent with basic authentication. If use_provider_credentials is set, a Microsoft Entra ID token for Azure DevOps
+// is requested with the provider credentials, e.g. OpenID Connect, and sent as a bearer token.
+func libGitHeaders(ctx context.Context, src map[string]LibGitCredentialType, cred azcore.TokenCredential) (map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if len(src) == 0 {
+		return nil, diags
+	}
+	hosts := make([]string, 0, len(src))
+	for h := range src {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	res := make(map[string]string, len(src))
+	for _, h := range hosts {
+		v := src[h]
+		p := path.Root("lib_git_credentials").AtMapKey(h)
+		prefix := fmt.Sprintf("https://%s/", h)
+		switch {
+		case v.UseProviderCredentials.ValueBool() && !v.Token.IsNull():
+			diags.AddAttributeError(p, "Invalid library git credential", fmt.Sprintf("Only one of `token` and `use_provider_credentials` can be set for %s.", h))
+		case v.UseProviderCredentials.ValueBool():
+			if cred == nil {
+				diags.AddAttributeError(p.AtName("use_provider_credentials"), "Invalid library git credential", fmt.Sprintf("The provider has no credentials to authenticate to %s.", h))
+				continue
+			}
+			tok, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureDevOpsScope}})
+			if err != nil {
+				diags.AddAttributeError(p.AtName("use_provider_credentials"), "Failed to get token for library git repository", fmt.Sprintf("Unable to get a Microsoft Entra ID token for %s: %s", h, err))
+				continue
+			}
+			res[prefix] = "Authorization: Bearer " + tok.Token
+		case !v.Token.IsNull():
+			username := defaultLibGitUsername
+			if !v.Username.IsNull() {
+				username = v.Username.ValueString()
+			}
+			res[prefix] = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+v.Token.ValueString()))
+		default:
+			diags.AddAttributeError(p, "Invalid library git credential", fmt.Sprintf("One of `token` and `use_provider_credentials` must be set for %s.", h))
+		}
+	}
+	return res, diags
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// The values of `lib_overwrite`.
+const (
+	libOverwriteError = "error"
+	libOverwriteWarn  = "warn"
+	libOverwriteAllow = "allow"
+)
+
+// libOverwriteModes are the values of `lib_overwrite`.
+var libOverwriteModes = []string{libOverwriteError, libOverwriteWarn, libOverwriteAllow}
+
+// libraryOverwriteDiagnostics returns an error or a warning that lists the objects defined in more than one library,
+// using the names of the libraries, depending on the `lib_overwrite` mode. Nothing is returned for `allow`.
+func libraryOverwriteDiagnostics(mode string, idx *libraryIndex, names []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	overrides := idx.Overrides()
+	if mode == libOverwriteAllow || len(overrides) == 0 {
+		return diags
+	}
+	summary := libraryOverridesSummary(overrides, names)
+	if mode == libOverwriteWarn {
+		diags.AddAttributeWarning(
+			path.Root("lib_overwrite"),
+			"Library objects overridden",
+			"The following objects are defined in more than one library, and the definition in the last library is used:\n"+summary,
+		)
+		return diags
+	}
+	diags.AddAttributeError(
+		path.Root("lib_overwrite"),
+		"Library objects defined in more than one library",
+		"The following objects are defined in more than one library. Rename them, or set `lib_overwrite` to `warn` or `allow` to use the definition in the last library:\n"+summary,
+	)
+	return diags
+}
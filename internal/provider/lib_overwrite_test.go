@@ -0,0 +1,45 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryOverwriteDiagnostics(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"policy_definition_a.json":    &fstest.MapFile{Data: []byte(`{"name": "a"}`)},
+		"policy_definition_b.json":    &fstest.MapFile{Data: []byte(`{"name": "b"}`)},
+		"role_definition_r.json":      &fstest.MapFile{Data: []byte(`{"name": "r", "properties": {"roleName": "Reader-Custom"}}`)},
+		"policy_assignment_pa.json":   &fstest.MapFile{Data: []byte(`{"name": "pa"}`)},
+		"archetype_definition_x.json": &fstest.MapFile{Data: []byte(`{"name": "x"}`)},
+	}
+	lib2 := fstest.MapFS{
+		"policy_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a", "properties": {}}`)},
+		"role_definition_r.json":   &fstest.MapFile{Data: []byte(`{"name": "r2", "properties": {"roleName": "Reader-Custom"}}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib1, lib2})
+	require.NoError(t, err)
+	names := []string{"./lib1", "./lib2"}
+
+	assert.Empty(t, libraryOverwriteDiagnostics(libOverwriteAllow, idx, names))
+
+	diags := libraryOverwriteDiagnostics(libOverwriteWarn, idx, names)
+	require.Equal(t, 1, diags.WarningsCount())
+	assert.False(t, diags.HasError())
+	assert.Contains(t, diags.Warnings()[0].Detail(), "policy definition a: defined in ./lib1, ./lib2, the definition in ./lib2 is used")
+
+	diags = libraryOverwriteDiagnostics(libOverwriteError, idx, names)
+	require.Equal(t, 1, diags.ErrorsCount())
+	assert.Contains(t, diags.Errors()[0].Detail(), "role definition Reader-Custom")
+
+	idx, err = newLibraryIndex([]fs.FS{lib1})
+	require.NoError(t, err)
+	assert.Empty(t, libraryOverwriteDiagnostics(libOverwriteError, idx, names[:1]))
+}
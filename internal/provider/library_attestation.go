@@ -0,0 +1,198 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// libraryAttestationFile is the name of the DSSE envelope in the root of a library that attests to its content.
+// The file is not part of the library digest.
+const libraryAttestationFile = ".alz-attestation.json"
+
+// inTotoPayloadType is the DSSE payload type of an in-toto statement.
+const inTotoPayloadType = "application/vnd.in-toto+json"
+
+// LibAttestationType describes the lib_attestation provider attribute.
+type LibAttestationType struct {
+	PublicKeys      types.List   `tfsdk:"public_keys"` // list of string
+	SourcesMatching types.String `tfsdk:"sources_matching"`
+}
+
+// libraryAttestationVerifier verifies that the libraries from matching sources are signed by one of the public keys.
+type libraryAttestationVerifier struct {
+	keys    []crypto.PublicKey
+	sources *regexp.Regexp // nil to verify every library
+}
+
+// dsseEnvelope is a Dead Simple Signing Envelope, stored as a detached file in the library.
+type dsseEnvelope struct {
+	Payload     string          `json:"payload"`
+	PayloadType string          `json:"payloadType"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyId string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// inTotoStatement is the part of an in-toto statement that identifies the attested content.
+type inTotoStatement struct {
+	Type    string `json:"_type"`
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// newLibraryAttestationVerifier parses the public keys and source expression of the lib_attestation attribute.
+func newLibraryAttestationVerifier(ctx context.Context, src *LibAttestationType) (*libraryAttestationVerifier, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	p := path.Root("lib_attestation")
+	var pems []string
+	diags.Append(src.PublicKeys.ElementsAs(ctx, &pems, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	res := &libraryAttestationVerifier{keys: make([]crypto.PublicKey, 0, len(pems))}
+	for i, s := range pems {
+		key, err := parsePublicKey(s)
+		if err != nil {
+			diags.AddAttributeError(p.AtName("public_keys").AtListIndex(i), "Invalid public key", err.Error())
+			continue
+		}
+		res.keys = append(res.keys, key)
+	}
+	if expr := src.SourcesMatching.ValueString(); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			diags.AddAttributeError(p.AtName("sources_matching"), "Invalid regular expression", err.Error())
+		}
+		res.sources = re
+	}
+	return res, diags
+}
+
+// parsePublicKey parses a PEM encoded PKIX public key. ECDSA, Ed25519 and RSA keys are supported.
+func parsePublicKey(s string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("the public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// verify checks the attestation of each library from a matching source, which are in the same order as the sources.
+// A library is verified if its attestation has a signature from one of the keys and a subject with the SHA-256 digest of the library.
+func (v *libraryAttestationVerifier) verify(sources []string, libs []fs.FS) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for i, src := range sources {
+		if v.sources != nil && !v.sources.MatchString(src) {
+			continue
+		}
+		if err := v.verifyLibrary(libs[i]); err != nil {
+			diags.AddAttributeError(path.Root("lib_attestation"), "Library attestation verification failed", fmt.Sprintf("The library %s could not be verified: %s", libfetcher.RedactSource(src), err))
+		}
+	}
+	return diags
+}
+
+// verifyLibrary checks the attestation of the library.
+func (v *libraryAttestationVerifier) verifyLibrary(lib fs.FS) error {
+	b, err := fs.ReadFile(lib, libraryAttestationFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("the library has no attestation, %s is missing", libraryAttestationFile)
+	}
+	if err != nil {
+		return err
+	}
+	var env dsseEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return fmt.Errorf("unable to unmarshal attestation: %w", err)
+	}
+	if env.PayloadType != inTotoPayloadType {
+		return fmt.Errorf("the attestation payload type is %q, expected %q", env.PayloadType, inTotoPayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return fmt.Errorf("unable to decode attestation payload: %w", err)
+	}
+	if !v.signed(dssePae(env.PayloadType, payload), env.Signatures) {
+		return errors.New("the attestation has no valid signature from the configured public keys")
+	}
+	var stmt inTotoStatement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return fmt.Errorf("unable to unmarshal in-toto statement: %w", err)
+	}
+	digest, err := libraryDigest(lib)
+	if err != nil {
+		return fmt.Errorf("unable to compute library digest: %w", err)
+	}
+	for _, s := range stmt.Subject {
+		if s.Digest["sha256"] == digest {
+			return nil
+		}
+	}
+	return fmt.Errorf("the attestation does not have a subject with the library digest sha256:%s", digest)
+}
+
+// signed returns true if one of the signatures of the message is from one of the keys.
+func (v *libraryAttestationVerifier) signed(msg []byte, sigs []dsseSignature) bool {
+	hash := sha256.Sum256(msg)
+	for _, s := range sigs {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		for _, key := range v.keys {
+			switch k := key.(type) {
+			case *ecdsa.PublicKey:
+				if ecdsa.VerifyASN1(k, hash[:], sig) {
+					return true
+				}
+			case ed25519.PublicKey:
+				if ed25519.Verify(k, msg, sig) {
+					return true
+				}
+			case *rsa.PublicKey:
+				if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil || rsa.VerifyPSS(k, crypto.SHA256, hash[:], sig, nil) == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// dssePae returns the DSSE pre-authentication encoding of the payload, which is the message that is signed.
+func dssePae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
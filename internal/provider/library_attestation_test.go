@@ -0,0 +1,114 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/fs"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAttestation returns a DSSE envelope of an in-toto statement for the library digest, signed by the signer.
+func testAttestation(t *testing.T, lib fs.FS, sign func(msg []byte) []byte) []byte {
+	digest, err := libraryDigest(lib)
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []any{map[string]any{"name": "alz", "digest": map[string]string{"sha256": digest}}},
+		"predicateType": "https://slsa.dev/provenance/v1",
+	})
+	require.NoError(t, err)
+	b, err := json.Marshal(dsseEnvelope{
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		PayloadType: inTotoPayloadType,
+		Signatures:  []dsseSignature{{Sig: base64.StdEncoding.EncodeToString(sign(dssePae(inTotoPayloadType, payload)))}},
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func testPublicKeyPem(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestLibraryAttestationVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifier, diags := newLibraryAttestationVerifier(context.Background(), &LibAttestationType{
+		PublicKeys: types.ListValueMust(types.StringType, []attr.Value{
+			types.StringValue(testPublicKeyPem(t, ecKey.Public())),
+			types.StringValue(testPublicKeyPem(t, edPub)),
+		}),
+		SourcesMatching: types.StringNull(),
+	})
+	require.False(t, diags.HasError(), diags)
+	require.Len(t, verifier.keys, 2)
+
+	newLib := func() fstest.MapFS {
+		return fstest.MapFS{"archetype_definition_root.json": {Data: []byte(`{"name":"root"}`)}}
+	}
+	ecLib := newLib()
+	ecLib[libraryAttestationFile] = &fstest.MapFile{Data: testAttestation(t, ecLib, func(msg []byte) []byte {
+		hash := sha256.Sum256(msg)
+		sig, err := ecdsa.SignASN1(rand.Reader, ecKey, hash[:])
+		require.NoError(t, err)
+		return sig
+	})}
+	edLib := newLib()
+	edLib[libraryAttestationFile] = &fstest.MapFile{Data: testAttestation(t, edLib, func(msg []byte) []byte {
+		return ed25519.Sign(edKey, msg)
+	})}
+	untrustedLib := newLib()
+	untrustedLib[libraryAttestationFile] = &fstest.MapFile{Data: testAttestation(t, untrustedLib, func(msg []byte) []byte {
+		return ed25519.Sign(otherKey, msg)
+	})}
+	tamperedLib := newLib()
+	tamperedLib[libraryAttestationFile] = edLib[libraryAttestationFile]
+	tamperedLib["policy_definitions/evil.json"] = &fstest.MapFile{Data: []byte(`{}`)}
+
+	diags = verifier.verify([]string{"ec", "ed"}, []fs.FS{ecLib, edLib})
+	assert.False(t, diags.HasError(), diags)
+
+	diags = verifier.verify([]string{"untrusted", "tampered", "unsigned"}, []fs.FS{untrustedLib, tamperedLib, newLib()})
+	require.Equal(t, 3, diags.ErrorsCount())
+	assert.Contains(t, diags.Errors()[0].Detail(), "no valid signature")
+	assert.Contains(t, diags.Errors()[1].Detail(), "does not have a subject with the library digest")
+	assert.Contains(t, diags.Errors()[2].Detail(), "has no attestation")
+
+	// Only libraries from matching sources are verified.
+	verifier.sources = regexp.MustCompile(`^https://mirror\.example\.com/`)
+	diags = verifier.verify([]string{"./lib", "https://mirror.example.com/alz.tar.gz"}, []fs.FS{newLib(), edLib})
+	assert.False(t, diags.HasError(), diags)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, err := parsePublicKey("not a key")
+	assert.ErrorContains(t, err, "not PEM encoded")
+	_, err = parsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")})))
+	assert.ErrorContains(t, err, "unable to parse public key")
+}
@@ -0,0 +1,154 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// libraryChange is the names of the objects of one kind that were added, removed or modified between two libraries.
+type libraryChange struct {
+	added    mapset.Set[string]
+	removed  mapset.Set[string]
+	modified mapset.Set[string]
+}
+
+// libraryArchetypeChange is the change in the membership of an archetype that exists in both libraries.
+type libraryArchetypeChange struct {
+	added   libraryContent
+	removed libraryContent
+}
+
+// libraryChangelog is the difference between two library indexes.
+type libraryChangelog struct {
+	archetypes           libraryChange
+	archetypeMembership  map[string]libraryArchetypeChange
+	policyAssignments    libraryChange
+	policyDefinitions    libraryChange
+	policySetDefinitions libraryChange
+	roleDefinitions      libraryChange
+}
+
+// newLibraryChangelog compares two library indexes.
+// Objects are modified if the compacted JSON of the library file has changed.
+// Archetypes are modified if their membership has changed.
+func newLibraryChangelog(from, to *libraryIndex) libraryChangelog {
+	res := libraryChangelog{
+		archetypeMembership:  make(map[string]libraryArchetypeChange),
+		policyAssignments:    diffLibraryObjects(from, to, from.policyAssignments, to.policyAssignments, policyAssignmentFilePrefix),
+		policyDefinitions:    diffLibraryObjects(from, to, from.policyDefinitions, to.policyDefinitions, policyDefinitionFilePrefix),
+		policySetDefinitions: diffLibraryObjects(from, to, from.policySetDefinitions, to.policySetDefinitions, policySetDefinitionFilePrefix),
+		roleDefinitions:      diffLibraryObjects(from, to, from.roleDefinitions, to.roleDefinitions, roleDefinitionFilePrefix),
+	}
+
+	fromArchetypes := mapset.NewThreadUnsafeSet[string]()
+	for k := range from.archetypes {
+		fromArchetypes.Add(k)
+	}
+	toArchetypes := mapset.NewThreadUnsafeSet[string]()
+	for k := range to.archetypes {
+		toArchetypes.Add(k)
+	}
+	res.archetypes = libraryChange{
+		added:    toArchetypes.Difference(fromArchetypes),
+		removed:  fromArchetypes.Difference(toArchetypes),
+		modified: mapset.NewThreadUnsafeSet[string](),
+	}
+	for name := range fromArchetypes.Intersect(toArchetypes).Iter() {
+		change := libraryArchetypeChange{
+			added:   to.archetypes[name].Difference(from.archetypes[name]),
+			removed: from.archetypes[name].Difference(to.archetypes[name]),
+		}
+		if change.added.Cardinality() == 0 && change.removed.Cardinality() == 0 {
+			continue
+		}
+		res.archetypes.modified.Add(name)
+		res.archetypeMembership[name] = change
+	}
+	return res
+}
+
+// diffLibraryObjects compares the objects of one kind in two library indexes.
+func diffLibraryObjects(from, to *libraryIndex, fromNames, toNames mapset.Set[string], prefix string) libraryChange {
+	res := libraryChange{
+		added:    toNames.Difference(fromNames),
+		removed:  fromNames.Difference(toNames),
+		modified: mapset.NewThreadUnsafeSet[string](),
+	}
+	for name := range fromNames.Intersect(toNames).Iter() {
+		key := libraryObjectKey(prefix, name)
+		if from.hashes[key] != to.hashes[key] {
+			res.modified.Add(name)
+		}
+	}
+	return res
+}
+
+// Markdown returns a markdown summary of the changelog, suitable for a pull request description.
+func (c libraryChangelog) Markdown(fromRef, toRef string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## ALZ library changes from `%s` to `%s`\n", fromRef, toRef))
+	sections := []struct {
+		name   string
+		change libraryChange
+	}{
+		{"Archetypes", c.archetypes},
+		{"Policy assignments", c.policyAssignments},
+		{"Policy definitions", c.policyDefinitions},
+		{"Policy set definitions", c.policySetDefinitions},
+		{"Role definitions", c.roleDefinitions},
+	}
+	changed := false
+	for _, s := range sections {
+		if s.change.added.Cardinality()+s.change.removed.Cardinality()+s.change.modified.Cardinality() == 0 {
+			continue
+		}
+		changed = true
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", s.name))
+		writeMarkdownList(&sb, "Added", s.change.added)
+		writeMarkdownList(&sb, "Removed", s.change.removed)
+		writeMarkdownList(&sb, "Modified", s.change.modified)
+	}
+
+	names := make([]string, 0, len(c.archetypeMembership))
+	for k := range c.archetypeMembership {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := c.archetypeMembership[name]
+		sb.WriteString(fmt.Sprintf("\n### Archetype `%s` membership\n\n", name))
+		writeMarkdownList(&sb, "Policy assignments added", m.added.policyAssignments)
+		writeMarkdownList(&sb, "Policy assignments removed", m.removed.policyAssignments)
+		writeMarkdownList(&sb, "Policy definitions added", m.added.policyDefinitions)
+		writeMarkdownList(&sb, "Policy definitions removed", m.removed.policyDefinitions)
+		writeMarkdownList(&sb, "Policy set definitions added", m.added.policySetDefinitions)
+		writeMarkdownList(&sb, "Policy set definitions removed", m.removed.policySetDefinitions)
+		writeMarkdownList(&sb, "Role definitions added", m.added.roleDefinitions)
+		writeMarkdownList(&sb, "Role definitions removed", m.removed.roleDefinitions)
+	}
+
+	if !changed {
+		sb.WriteString("\nNo changes.\n")
+	}
+	return sb.String()
+}
+
+// writeMarkdownList writes a sorted markdown list of the set, if it is not empty.
+func writeMarkdownList(sb *strings.Builder, title string, set mapset.Set[string]) {
+	if set.Cardinality() == 0 {
+		return
+	}
+	names := set.ToSlice()
+	sort.Strings(names)
+	sb.WriteString(fmt.Sprintf("%s:\n\n", title))
+	for _, n := range names {
+		sb.WriteString(fmt.Sprintf("- `%s`\n", n))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewLibraryChangelog tests that added, removed and modified objects are detected,
+// that formatting changes are ignored, and that archetype membership changes are reported.
+func TestNewLibraryChangelog(t *testing.T) {
+	from := fstest.MapFS{
+		"policy_definition_a.json":       &fstest.MapFile{Data: []byte(`{"name": "a", "properties": {"mode": "All"}}`)},
+		"policy_definition_b.json":       &fstest.MapFile{Data: []byte(`{"name": "b", "properties": {"mode": "All"}}`)},
+		"policy_definition_c.json":       &fstest.MapFile{Data: []byte(`{"name": "c", "properties": {"mode": "All"}}`)},
+		"policy_assignment_pa.json":      &fstest.MapFile{Data: []byte(`{"name": "pa"}`)},
+		"archetype_definition_root.json": &fstest.MapFile{Data: []byte(`{"name": "root", "policy_definitions": ["a", "b"], "policy_assignments": ["pa"]}`)},
+		"archetype_definition_old.json":  &fstest.MapFile{Data: []byte(`{"name": "old"}`)},
+		"archetype_definition_same.json": &fstest.MapFile{Data: []byte(`{"name": "same", "policy_definitions": ["c"]}`)},
+	}
+	to := fstest.MapFS{
+		"policy_definition_a.json":       &fstest.MapFile{Data: []byte("{\n  \"name\": \"a\",\n  \"properties\": {\n    \"mode\": \"All\"\n  }\n}")},
+		"policy_definition_c.json":       &fstest.MapFile{Data: []byte(`{"name": "c", "properties": {"mode": "Indexed"}}`)},
+		"policy_definition_d.json":       &fstest.MapFile{Data: []byte(`{"name": "d", "properties": {"mode": "All"}}`)},
+		"policy_assignment_pa.json":      &fstest.MapFile{Data: []byte(`{"name": "pa"}`)},
+		"archetype_definition_root.json": &fstest.MapFile{Data: []byte(`{"name": "root", "policy_definitions": ["a", "d"], "policy_assignments": ["pa"]}`)},
+		"archetype_definition_new.json":  &fstest.MapFile{Data: []byte(`{"name": "new"}`)},
+		"archetype_definition_same.json": &fstest.MapFile{Data: []byte(`{"name": "same", "policy_definitions": ["c"]}`)},
+	}
+	fromIdx, err := newLibraryIndex([]fs.FS{from})
+	require.NoError(t, err)
+	toIdx, err := newLibraryIndex([]fs.FS{to})
+	require.NoError(t, err)
+
+	c := newLibraryChangelog(fromIdx, toIdx)
+	assert.ElementsMatch(t, []string{"d"}, c.policyDefinitions.added.ToSlice())
+	assert.ElementsMatch(t, []string{"b"}, c.policyDefinitions.removed.ToSlice())
+	assert.ElementsMatch(t, []string{"c"}, c.policyDefinitions.modified.ToSlice())
+	assert.Equal(t, 0, c.policyAssignments.modified.Cardinality())
+	assert.ElementsMatch(t, []string{"new"}, c.archetypes.added.ToSlice())
+	assert.ElementsMatch(t, []string{"old"}, c.archetypes.removed.ToSlice())
+	assert.ElementsMatch(t, []string{"root"}, c.archetypes.modified.ToSlice())
+	require.Contains(t, c.archetypeMembership, "root")
+	assert.NotContains(t, c.archetypeMembership, "same")
+	assert.ElementsMatch(t, []string{"d"}, c.archetypeMembership["root"].added.policyDefinitions.ToSlice())
+	assert.ElementsMatch(t, []string{"b"}, c.archetypeMembership["root"].removed.policyDefinitions.ToSlice())
+	assert.Equal(t, 0, c.archetypeMembership["root"].added.policyAssignments.Cardinality())
+
+	md := c.Markdown("v1", "v2")
+	assert.Contains(t, md, "## ALZ library changes from `v1` to `v2`")
+	assert.Contains(t, md, "### Policy definitions\n\nAdded:\n\n- `d`\n\nRemoved:\n\n- `b`\n\nModified:\n\n- `c`\n")
+	assert.Contains(t, md, "### Archetype `root` membership")
+	assert.NotContains(t, md, "No changes.")
+
+	assert.Contains(t, newLibraryChangelog(fromIdx, fromIdx).Markdown("v1", "v1"), "No changes.")
+}
+
+func TestLibChangelogDirName(t *testing.T) {
+	assert.Equal(t, "platform_alz_2024.03.00", libChangelogDirName("platform/alz/2024.03.00"))
+	assert.Equal(t, "___2024.03", libChangelogDirName("~> 2024.03"))
+}
+
+// TestLockLibChangelogDir tests that a changelog directory is locked while other directories can be locked.
+func TestLockLibChangelogDir(t *testing.T) {
+	unlock := lockLibChangelogDir("a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lockLibChangelogDir("b")()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("another directory is locked")
+	}
+	assert.False(t, libChangelogDirLocks.locks["a"].TryLock(), "the directory is locked")
+	unlock()
+	assert.True(t, libChangelogDirLocks.locks["a"].TryLock(), "the directory is unlocked")
+	libChangelogDirLocks.locks["a"].Unlock()
+}
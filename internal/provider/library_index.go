@@ -0,0 +1,638 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+const (
+	archetypeDefinitionFilePrefix    = "archetype_definition_"
+	architectureDefinitionFilePrefix = "architecture_definition_"
+	policyAssignmentFilePrefix       = "policy_assignment_"
+	policyDefinitionFilePrefix       = "policy_definition_"
+	policySetDefinitionFilePrefix    = "policy_set_definition_"
+	roleDefinitionFilePrefix         = "role_definition_"
+	policyDefaultValuesFileSuffix    = "policy_default_values.json"
+)
+
+// policyEffectParameterRegex matches a policy rule effect that is a parameter, e.g. `[parameters('effect')]`.
+var policyEffectParameterRegex = regexp.MustCompile(`^\[\s*parameters\(\s*'([^']+)'\s*\)\s*\]$`)
+
+// libraryIndex holds data read directly from the library files that is not retained by alzlib.
+// For example, the Azure SDK role definition type does not have data actions, so alzlib drops them.
+type libraryIndex struct {
+	libraryContent
+	archetypes                  map[string]libraryContent                    // archetype definitions, keyed by name
+	architectures               map[string]libraryArchitectureDefinition     // architecture definitions, keyed by name
+	hashes                      map[string]string                            // sha256 of the compacted JSON, keyed by libraryObjectKey
+	origins                     map[string][]int                             // positions of the libraries that define the object, in order, keyed by libraryObjectKey
+	policyAssignmentDefinitions map[string]string                            // policy definition or set definition resource ids, keyed by policy assignment name
+	policyAssignmentParameters  map[string]map[string]string                 // normalized JSON parameter values, keyed by policy assignment name
+	policyDefaultValues         map[string]map[string][]string               // parameter names keyed by policy assignment name, keyed by default name
+	policyDefinitionMetadata    map[string]libraryPolicyDefinitionMetadata   // keyed by policy definition name
+	policySetDefinitionMembers  map[string][]string                          // policy definition resource ids, keyed by policy set definition name
+	roleDefinitionPermissions   map[string][]libraryRoleDefinitionPermission // keyed by role name
+}
+
+// libraryContent is the names of the objects in the libraries, using the same keys as alzlib.
+type libraryContent struct {
+	policyAssignments    mapset.Set[string]
+	policyDefinitions    mapset.Set[string]
+	policySetDefinitions mapset.Set[string]
+	roleDefinitions      mapset.Set[string] // role names, as used by alzlib
+}
+
+// libraryRoleDefinitionPermission is a permission block from a library role definition.
+type libraryRoleDefinitionPermission struct {
+	Actions        []string `json:"actions"`
+	NotActions     []string `json:"notActions"`
+	DataActions    []string `json:"dataActions"`
+	NotDataActions []string `json:"notDataActions"`
+}
+
+// libraryRoleDefinition is the subset of a library role definition file used by the index.
+type libraryRoleDefinition struct {
+	Properties struct {
+		RoleName    string                            `json:"roleName"`
+		Permissions []libraryRoleDefinitionPermission `json:"permissions"`
+	} `json:"properties"`
+}
+
+// libraryPolicyDefinition is the subset of a library policy definition file used by the index.
+type libraryPolicyDefinition struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Description string `json:"description"`
+		DisplayName string `json:"displayName"`
+		Metadata    struct {
+			Category string `json:"category"`
+		} `json:"metadata"`
+		Parameters map[string]struct {
+			AllowedValues []any `json:"allowedValues"`
+			DefaultValue  any   `json:"defaultValue"`
+		} `json:"parameters"`
+		PolicyRule struct {
+			Then struct {
+				Effect string `json:"effect"`
+			} `json:"then"`
+		} `json:"policyRule"`
+	} `json:"properties"`
+}
+
+// libraryPolicyAssignment is the subset of a library policy assignment file used by the index.
+type libraryPolicyAssignment struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Parameters map[string]struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"parameters"`
+		PolicyDefinitionId string `json:"policyDefinitionId"`
+	} `json:"properties"`
+}
+
+// libraryPolicySetDefinition is the subset of a library policy set definition file used by the index.
+type libraryPolicySetDefinition struct {
+	Name       string `json:"name"`
+	Properties struct {
+		PolicyDefinitions []struct {
+			PolicyDefinitionId string `json:"policyDefinitionId"`
+		} `json:"policyDefinitions"`
+	} `json:"properties"`
+}
+
+// libraryPolicyDefaultValues is a library policy default values file, e.g. `alz_policy_default_values.json`.
+type libraryPolicyDefaultValues struct {
+	Defaults []struct {
+		DefaultName       string `json:"default_name"`
+		PolicyAssignments []struct {
+			PolicyAssignmentName string   `json:"policy_assignment_name"`
+			ParameterNames       []string `json:"parameter_names"`
+		} `json:"policy_assignments"`
+	} `json:"defaults"`
+}
+
+// libraryPolicyDefinitionMetadata is the searchable metadata of a library policy definition.
+type libraryPolicyDefinitionMetadata struct {
+	Category        string
+	DefaultEffect   string // the effect if the assignment does not set the effect parameter, empty if unknown
+	Description     string
+	DisplayName     string
+	EffectParameter string   // the name of the parameter that sets the effect, empty if the effect is a literal
+	Effects         []string // the possible effects, sorted
+}
+
+// libraryArchetypeDefinition is a library archetype definition file.
+type libraryArchetypeDefinition struct {
+	Name                 string   `json:"name"`
+	PolicyAssignments    []string `json:"policy_assignments"`
+	PolicyDefinitions    []string `json:"policy_definitions"`
+	PolicySetDefinitions []string `json:"policy_set_definitions"`
+	RoleDefinitions      []string `json:"role_definitions"`
+}
+
+// libraryArchitectureDefinition is a library architecture definition file, which describes a management group hierarchy.
+type libraryArchitectureDefinition struct {
+	Name             string                               `json:"name"`
+	ManagementGroups []libraryArchitectureManagementGroup `json:"management_groups"`
+}
+
+// libraryArchitectureManagementGroup is a management group of a library architecture definition.
+type libraryArchitectureManagementGroup struct {
+	Archetypes                 []string                  `json:"archetypes"`
+	Defaults                   map[string]string         `json:"defaults"` // archetype defaults of the management group and its descendants
+	DisplayName                string                    `json:"display_name"`
+	Exists                     bool                      `json:"exists"` // the management group already exists and is not created
+	Id                         string                    `json:"id"`
+	ParentId                   *string                   `json:"parent_id"`                    // null for the management groups at the top of the architecture
+	PolicyAssignmentParameters map[string]map[string]any `json:"policy_assignment_parameters"` // parameter values, keyed by policy assignment name
+}
+
+// newLibraryIndex walks the supplied libraries in order and indexes the files.
+// Objects in later libraries replace those of the same name in earlier libraries.
+func newLibraryIndex(libs []fs.FS) (*libraryIndex, error) {
+	idx := &libraryIndex{
+		libraryContent:              newLibraryContent(),
+		archetypes:                  make(map[string]libraryContent),
+		architectures:               make(map[string]libraryArchitectureDefinition),
+		hashes:                      make(map[string]string),
+		origins:                     make(map[string][]int),
+		policyAssignmentDefinitions: make(map[string]string),
+		policyAssignmentParameters:  make(map[string]map[string]string),
+		policyDefaultValues:         make(map[string]map[string][]string),
+		policyDefinitionMetadata:    make(map[string]libraryPolicyDefinitionMetadata),
+		policySetDefinitionMembers:  make(map[string][]string),
+		roleDefinitionPermissions:   make(map[string][]libraryRoleDefinitionPermission),
+	}
+	for i, lib := range libs {
+		if err := fs.WalkDir(lib, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("error walking directory %s: %w", path, err)
+			}
+			if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".json" {
+				return nil
+			}
+			switch n := strings.ToLower(d.Name()); {
+			case strings.HasPrefix(n, archetypeDefinitionFilePrefix):
+				return idx.addArchetypeDefinition(i, lib, path)
+			case strings.HasPrefix(n, architectureDefinitionFilePrefix):
+				return idx.addArchitectureDefinition(i, lib, path)
+			case strings.HasPrefix(n, policyAssignmentFilePrefix):
+				return idx.addPolicyAssignment(i, lib, path)
+			case strings.HasPrefix(n, policyDefinitionFilePrefix):
+				return idx.addPolicyDefinition(i, lib, path)
+			case strings.HasPrefix(n, policySetDefinitionFilePrefix):
+				return idx.addPolicySetDefinition(i, lib, path)
+			case strings.HasPrefix(n, roleDefinitionFilePrefix):
+				return idx.addRoleDefinition(i, lib, path)
+			case strings.HasSuffix(n, policyDefaultValuesFileSuffix):
+				return idx.addPolicyDefaultValues(lib, path)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// newLibraryContent returns a libraryContent with empty sets.
+func newLibraryContent() libraryContent {
+	return libraryContent{
+		policyAssignments:    mapset.NewThreadUnsafeSet[string](),
+		policyDefinitions:    mapset.NewThreadUnsafeSet[string](),
+		policySetDefinitions: mapset.NewThreadUnsafeSet[string](),
+		roleDefinitions:      mapset.NewThreadUnsafeSet[string](),
+	}
+}
+
+// Difference returns the content that is not in other.
+func (c libraryContent) Difference(other libraryContent) libraryContent {
+	return libraryContent{
+		policyAssignments:    c.policyAssignments.Difference(other.policyAssignments),
+		policyDefinitions:    c.policyDefinitions.Difference(other.policyDefinitions),
+		policySetDefinitions: c.policySetDefinitions.Difference(other.policySetDefinitions),
+		roleDefinitions:      c.roleDefinitions.Difference(other.roleDefinitions),
+	}
+}
+
+// Cardinality returns the total number of objects.
+func (c libraryContent) Cardinality() int {
+	return c.policyAssignments.Cardinality() + c.policyDefinitions.Cardinality() + c.policySetDefinitions.Cardinality() + c.roleDefinitions.Cardinality()
+}
+
+// libraryObjectKey returns the key used for an object in the index hashes, using the file prefix as the kind.
+func libraryObjectKey(prefix, name string) string {
+	return prefix + name
+}
+
+// setHash sets the hash of the object and records the position of the library that defines it.
+func (idx *libraryIndex) setHash(n int, key, hash string) {
+	idx.hashes[key] = hash
+	idx.origins[key] = append(idx.origins[key], n)
+}
+
+// readLibraryFile reads the file and returns its content with the sha256 of the compacted JSON.
+func readLibraryFile(lib fs.FS, path string) ([]byte, string, error) {
+	b, err := fs.ReadFile(lib, path)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, b); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling %s: %w", path, err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return b, hex.EncodeToString(sum[:]), nil
+}
+
+// addArchetypeDefinition reads the archetype definition file and adds its members to the index.
+func (idx *libraryIndex) addArchetypeDefinition(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	ad := new(libraryArchetypeDefinition)
+	if err := json.Unmarshal(b, ad); err != nil {
+		return fmt.Errorf("error unmarshalling archetype definition %s: %w", path, err)
+	}
+	if ad.Name == "" {
+		return nil
+	}
+	idx.setHash(n, libraryObjectKey(archetypeDefinitionFilePrefix, ad.Name), hash)
+	idx.archetypes[ad.Name] = libraryContent{
+		policyAssignments:    mapset.NewThreadUnsafeSet(ad.PolicyAssignments...),
+		policyDefinitions:    mapset.NewThreadUnsafeSet(ad.PolicyDefinitions...),
+		policySetDefinitions: mapset.NewThreadUnsafeSet(ad.PolicySetDefinitions...),
+		roleDefinitions:      mapset.NewThreadUnsafeSet(ad.RoleDefinitions...),
+	}
+	return nil
+}
+
+// addArchitectureDefinition reads the architecture definition file and adds it to the index.
+func (idx *libraryIndex) addArchitectureDefinition(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	ad := new(libraryArchitectureDefinition)
+	if err := json.Unmarshal(b, ad); err != nil {
+		return fmt.Errorf("error unmarshalling architecture definition %s: %w", path, err)
+	}
+	if ad.Name == "" {
+		return nil
+	}
+	idx.setHash(n, libraryObjectKey(architectureDefinitionFilePrefix, ad.Name), hash)
+	idx.architectures[ad.Name] = *ad
+	return nil
+}
+
+// addRoleDefinition reads the role definition file and adds its permissions to the index.
+func (idx *libraryIndex) addRoleDefinition(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	rd := new(libraryRoleDefinition)
+	if err := json.Unmarshal(b, rd); err != nil {
+		return fmt.Errorf("error unmarshalling role definition %s: %w", path, err)
+	}
+	if rd.Properties.RoleName == "" {
+		return nil
+	}
+	idx.roleDefinitions.Add(rd.Properties.RoleName)
+	idx.setHash(n, libraryObjectKey(roleDefinitionFilePrefix, rd.Properties.RoleName), hash)
+	idx.roleDefinitionPermissions[rd.Properties.RoleName] = rd.Properties.Permissions
+	return nil
+}
+
+// addPolicyAssignment reads the policy assignment file and adds its parameter values to the index.
+func (idx *libraryIndex) addPolicyAssignment(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	pa := new(libraryPolicyAssignment)
+	if err := json.Unmarshal(b, pa); err != nil {
+		return fmt.Errorf("error unmarshalling policy assignment %s: %w", path, err)
+	}
+	if pa.Name == "" {
+		return nil
+	}
+	idx.policyAssignments.Add(pa.Name)
+	idx.setHash(n, libraryObjectKey(policyAssignmentFilePrefix, pa.Name), hash)
+	params := make(map[string]string, len(pa.Properties.Parameters))
+	for name, v := range pa.Properties.Parameters {
+		if len(v.Value) == 0 {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(v.Value, &value); err != nil {
+			return fmt.Errorf("error unmarshalling parameter %s of policy assignment %s: %w", name, path, err)
+		}
+		if params[name], err = normalizedJson(value); err != nil {
+			return fmt.Errorf("error marshalling parameter %s of policy assignment %s: %w", name, path, err)
+		}
+	}
+	idx.policyAssignmentParameters[pa.Name] = params
+	idx.policyAssignmentDefinitions[pa.Name] = pa.Properties.PolicyDefinitionId
+	return nil
+}
+
+// addPolicyDefaultValues reads the policy default values file and adds the parameters of each default to the index.
+// Defaults of the same name in several files, or libraries, are merged.
+func (idx *libraryIndex) addPolicyDefaultValues(lib fs.FS, path string) error {
+	b, _, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	dvs := new(libraryPolicyDefaultValues)
+	if err := json.Unmarshal(b, dvs); err != nil {
+		return fmt.Errorf("error unmarshalling policy default values %s: %w", path, err)
+	}
+	for _, dv := range dvs.Defaults {
+		if dv.DefaultName == "" {
+			continue
+		}
+		if _, ok := idx.policyDefaultValues[dv.DefaultName]; !ok {
+			idx.policyDefaultValues[dv.DefaultName] = make(map[string][]string)
+		}
+		for _, pa := range dv.PolicyAssignments {
+			params := idx.policyDefaultValues[dv.DefaultName][pa.PolicyAssignmentName]
+			idx.policyDefaultValues[dv.DefaultName][pa.PolicyAssignmentName] = append(params, pa.ParameterNames...)
+		}
+	}
+	return nil
+}
+
+// addPolicySetDefinition reads the policy set definition file and adds its member policy definitions to the index.
+func (idx *libraryIndex) addPolicySetDefinition(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	psd := new(libraryPolicySetDefinition)
+	if err := json.Unmarshal(b, psd); err != nil {
+		return fmt.Errorf("error unmarshalling policy set definition %s: %w", path, err)
+	}
+	if psd.Name == "" {
+		return nil
+	}
+	idx.policySetDefinitions.Add(psd.Name)
+	idx.setHash(n, libraryObjectKey(policySetDefinitionFilePrefix, psd.Name), hash)
+	members := make([]string, 0, len(psd.Properties.PolicyDefinitions))
+	for _, pd := range psd.Properties.PolicyDefinitions {
+		members = append(members, pd.PolicyDefinitionId)
+	}
+	idx.policySetDefinitionMembers[psd.Name] = members
+	return nil
+}
+
+// addPolicyDefinition reads the policy definition file and adds its metadata to the index.
+func (idx *libraryIndex) addPolicyDefinition(n int, lib fs.FS, path string) error {
+	b, hash, err := readLibraryFile(lib, path)
+	if err != nil {
+		return err
+	}
+	pd := new(libraryPolicyDefinition)
+	if err := json.Unmarshal(b, pd); err != nil {
+		return fmt.Errorf("error unmarshalling policy definition %s: %w", path, err)
+	}
+	if pd.Name == "" {
+		return nil
+	}
+	idx.policyDefinitions.Add(pd.Name)
+	idx.setHash(n, libraryObjectKey(policyDefinitionFilePrefix, pd.Name), hash)
+	effectParameter, defaultEffect := pd.defaultEffect()
+	idx.policyDefinitionMetadata[pd.Name] = libraryPolicyDefinitionMetadata{
+		Category:        pd.Properties.Metadata.Category,
+		DefaultEffect:   defaultEffect,
+		Description:     pd.Properties.Description,
+		DisplayName:     pd.Properties.DisplayName,
+		EffectParameter: effectParameter,
+		Effects:         pd.effects(),
+	}
+	return nil
+}
+
+// defaultEffect returns the name of the parameter that sets the effect of the policy definition, if any,
+// and the effect when the parameter is not set, which is the default value of the parameter.
+func (pd *libraryPolicyDefinition) defaultEffect() (string, string) {
+	effect := pd.Properties.PolicyRule.Then.Effect
+	m := policyEffectParameterRegex.FindStringSubmatch(effect)
+	if m == nil {
+		return "", effect
+	}
+	def, _ := pd.Properties.Parameters[m[1]].DefaultValue.(string)
+	return m[1], def
+}
+
+// effects returns the possible effects of the policy definition.
+// If the effect is a parameter, these are the allowed values and default value of the parameter.
+func (pd *libraryPolicyDefinition) effects() []string {
+	effect := pd.Properties.PolicyRule.Then.Effect
+	if effect == "" {
+		return nil
+	}
+	m := policyEffectParameterRegex.FindStringSubmatch(effect)
+	if m == nil {
+		return []string{effect}
+	}
+	param, ok := pd.Properties.Parameters[m[1]]
+	if !ok {
+		return nil
+	}
+	set := mapset.NewThreadUnsafeSet[string]()
+	for _, v := range append(param.AllowedValues, param.DefaultValue) {
+		if s, ok := v.(string); ok && s != "" {
+			set.Add(s)
+		}
+	}
+	res := set.ToSlice()
+	sort.Strings(res)
+	return res
+}
+
+// PolicyAssignmentParameters returns the normalized JSON parameter values of the named library policy assignment,
+// as written in the library, keyed by parameter name.
+func (idx *libraryIndex) PolicyAssignmentParameters(name string) (map[string]string, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	p, ok := idx.policyAssignmentParameters[name]
+	return p, ok
+}
+
+// Origins returns the positions of the libraries that define the object, in the order the libraries were processed.
+// The last library is the one whose definition is used, and it replaces the definitions of the earlier libraries.
+func (idx *libraryIndex) Origins(prefix, name string) []int {
+	if idx == nil {
+		return nil
+	}
+	return idx.origins[libraryObjectKey(prefix, name)]
+}
+
+// ContentFrom returns the objects that are defined by the library at position n or a later library,
+// e.g. the libraries from `lib_urls` rather than the ALZ library.
+func (idx *libraryIndex) ContentFrom(n int) libraryContent {
+	res := newLibraryContent()
+	if idx == nil {
+		return res
+	}
+	for _, c := range []struct {
+		prefix string
+		src    mapset.Set[string]
+		dst    mapset.Set[string]
+	}{
+		{policyAssignmentFilePrefix, idx.policyAssignments, res.policyAssignments},
+		{policyDefinitionFilePrefix, idx.policyDefinitions, res.policyDefinitions},
+		{policySetDefinitionFilePrefix, idx.policySetDefinitions, res.policySetDefinitions},
+		{roleDefinitionFilePrefix, idx.roleDefinitions, res.roleDefinitions},
+	} {
+		for name := range c.src.Iter() {
+			if slices.ContainsFunc(idx.Origins(c.prefix, name), func(o int) bool { return o >= n }) {
+				c.dst.Add(name)
+			}
+		}
+	}
+	return res
+}
+
+// PolicyDefinitionMetadata returns the searchable metadata of the library policy definitions, keyed by name.
+func (idx *libraryIndex) PolicyDefinitionMetadata() map[string]libraryPolicyDefinitionMetadata {
+	if idx == nil {
+		return nil
+	}
+	return idx.policyDefinitionMetadata
+}
+
+// RoleDefinitionPermissions returns the permission blocks of the named role definition, as written in the library.
+func (idx *libraryIndex) RoleDefinitionPermissions(name string) ([]libraryRoleDefinitionPermission, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	p, ok := idx.roleDefinitionPermissions[name]
+	return p, ok
+}
+
+// ObjectCounts returns the number of objects of each kind defined by the library at position n, keyed by kind, e.g. `policy_definition`.
+func (idx *libraryIndex) ObjectCounts(n int) map[string]int {
+	res := make(map[string]int, len(libraryObjectKinds))
+	if idx == nil {
+		return res
+	}
+	for _, k := range libraryObjectKinds {
+		res[strings.TrimSuffix(k.prefix, "_")] = 0
+	}
+	for key, origins := range idx.origins {
+		if !slices.Contains(origins, n) {
+			continue
+		}
+		for _, k := range libraryObjectKinds {
+			if strings.HasPrefix(key, k.prefix) {
+				res[strings.TrimSuffix(k.prefix, "_")]++
+				break
+			}
+		}
+	}
+	return res
+}
+
+// ArchitectureNames returns the names of the architecture definitions in the libraries, sorted.
+func (idx *libraryIndex) ArchitectureNames() []string {
+	if idx == nil {
+		return nil
+	}
+	return sortedKeys(idx.architectures)
+}
+
+// Architecture returns the named architecture definition, as written in the library.
+func (idx *libraryIndex) Architecture(name string) (libraryArchitectureDefinition, bool) {
+	if idx == nil {
+		return libraryArchitectureDefinition{}, false
+	}
+	ad, ok := idx.architectures[name]
+	return ad, ok
+}
+
+// PolicyDefaultValueNames returns the names of the policy default values in the libraries, sorted.
+func (idx *libraryIndex) PolicyDefaultValueNames() []string {
+	if idx == nil {
+		return nil
+	}
+	return sortedKeys(idx.policyDefaultValues)
+}
+
+// PolicyDefaultValueParameters returns the names of the parameters that accept the policy default value, keyed by policy assignment name.
+func (idx *libraryIndex) PolicyDefaultValueParameters(name string) map[string][]string {
+	if idx == nil {
+		return nil
+	}
+	return idx.policyDefaultValues[name]
+}
+
+// PolicyAssignmentDefinitionIds returns the resource ids of the policy definitions and policy set definitions
+// that are assigned by the library policy assignments, sorted and without duplicates.
+func (idx *libraryIndex) PolicyAssignmentDefinitionIds() []string {
+	if idx == nil {
+		return nil
+	}
+	ids := mapset.NewThreadUnsafeSet[string]()
+	for _, id := range idx.policyAssignmentDefinitions {
+		if id != "" {
+			ids.Add(id)
+		}
+	}
+	res := ids.ToSlice()
+	sort.Strings(res)
+	return res
+}
+
+// MissingDefinitions returns the resource ids of the policy definitions and policy set definitions that are referenced by the
+// named policy assignments, or by the policy set definitions they assign, but are not in the libraries, sorted.
+// These are typically built-in definitions that alzlib would otherwise read from Azure.
+func (idx *libraryIndex) MissingDefinitions(assignments []string) []string {
+	if idx == nil {
+		return nil
+	}
+	missing := mapset.NewThreadUnsafeSet[string]()
+	addIfMissing := func(id string) {
+		if name := resourceIdName(id); !idx.policyDefinitions.Contains(name) {
+			missing.Add(id)
+		}
+	}
+	for _, pa := range assignments {
+		id := idx.policyAssignmentDefinitions[pa]
+		if id == "" {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(id), "/policysetdefinitions/") {
+			addIfMissing(id)
+			continue
+		}
+		name := resourceIdName(id)
+		if !idx.policySetDefinitions.Contains(name) {
+			missing.Add(id)
+			continue
+		}
+		for _, member := range idx.policySetDefinitionMembers[name] {
+			addIfMissing(member)
+		}
+	}
+	res := missing.ToSlice()
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,218 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewLibraryIndex tests that role definition permissions are indexed by role name,
+// and that later libraries replace earlier ones.
+func TestNewLibraryIndex(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"role_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "00000000-0000-0000-0000-000000000000",
+  "properties": {
+    "roleName": "Test-Role",
+    "permissions": [
+      {
+        "actions": ["Microsoft.Storage/*/read"],
+        "notActions": [],
+        "dataActions": ["Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read"],
+        "notDataActions": []
+      }
+    ]
+  }
+}`)},
+		"policy_definition_test.json": &fstest.MapFile{Data: []byte(`{}`)},
+	}
+	lib2 := fstest.MapFS{
+		"sub/role_definition_other.json": &fstest.MapFile{Data: []byte(`{
+  "properties": {
+    "roleName": "Test-Role",
+    "permissions": [
+      {
+        "actions": ["*/read"],
+        "notDataActions": ["Microsoft.KeyVault/vaults/secrets/getSecret/action"]
+      }
+    ]
+  }
+}`)},
+	}
+
+	idx, err := newLibraryIndex([]fs.FS{lib1})
+	require.NoError(t, err)
+	perms, ok := idx.RoleDefinitionPermissions("Test-Role")
+	require.True(t, ok)
+	require.Len(t, perms, 1)
+	assert.Equal(t, []string{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read"}, perms[0].DataActions)
+
+	idx, err = newLibraryIndex([]fs.FS{lib1, lib2})
+	require.NoError(t, err)
+	perms, ok = idx.RoleDefinitionPermissions("Test-Role")
+	require.True(t, ok)
+	require.Len(t, perms, 1)
+	assert.Empty(t, perms[0].DataActions)
+	assert.Equal(t, []string{"Microsoft.KeyVault/vaults/secrets/getSecret/action"}, perms[0].NotDataActions)
+
+	_, ok = idx.RoleDefinitionPermissions("missing")
+	assert.False(t, ok)
+
+	var nilIdx *libraryIndex
+	_, ok = nilIdx.RoleDefinitionPermissions("Test-Role")
+	assert.False(t, ok)
+
+	_, err = newLibraryIndex([]fs.FS{fstest.MapFS{"role_definition_bad.json": &fstest.MapFile{Data: []byte(`{`)}}})
+	assert.ErrorContains(t, err, "error unmarshalling role_definition_bad.json")
+}
+
+func TestLibraryIndexPolicyDefinitionMetadata(t *testing.T) {
+	lib := fstest.MapFS{
+		"policy_definition_param.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Deploy-Test",
+  "properties": {
+    "displayName": "Deploy test",
+    "metadata": {"category": "Monitoring"},
+    "parameters": {
+      "effect": {"type": "String", "allowedValues": ["DeployIfNotExists", "Disabled"], "defaultValue": "DeployIfNotExists"}
+    },
+    "policyRule": {"if": {}, "then": {"effect": "[parameters('effect')]"}}
+  }
+}`)},
+		"policy_definition_literal.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Deny-Test",
+  "properties": {
+    "description": "Denies test resources.",
+    "displayName": "Deny test",
+    "policyRule": {"if": {}, "then": {"effect": "deny"}}
+  }
+}`)},
+	}
+
+	idx, err := newLibraryIndex([]fs.FS{lib})
+	require.NoError(t, err)
+	md := idx.PolicyDefinitionMetadata()
+	assert.Equal(t, libraryPolicyDefinitionMetadata{
+		Category:        "Monitoring",
+		DefaultEffect:   "DeployIfNotExists",
+		DisplayName:     "Deploy test",
+		EffectParameter: "effect",
+		Effects:         []string{"DeployIfNotExists", "Disabled"},
+	}, md["Deploy-Test"])
+	assert.Equal(t, []string{"deny"}, md["Deny-Test"].Effects)
+	assert.Equal(t, "deny", md["Deny-Test"].DefaultEffect)
+	assert.Equal(t, "Denies test resources.", md["Deny-Test"].Description)
+	assert.Empty(t, md["Deny-Test"].EffectParameter)
+	assert.True(t, idx.policyDefinitions.Contains("Deny-Test"))
+}
+
+func TestLibraryIndexPolicyAssignmentParameters(t *testing.T) {
+	lib := fstest.MapFS{
+		"policy_assignment_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Deploy-Test",
+  "properties": {
+    "parameters": {
+      "effect": { "value": "DeployIfNotExists" },
+      "tags": { "value": { "b": 1, "a": [true] } },
+      "empty": {}
+    }
+  }
+}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib})
+	require.NoError(t, err)
+	assert.True(t, idx.policyAssignments.Contains("Deploy-Test"))
+	params, ok := idx.PolicyAssignmentParameters("Deploy-Test")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"effect": `"DeployIfNotExists"`,
+		"tags":   `{"a":[true],"b":1}`,
+	}, params)
+
+	_, ok = idx.PolicyAssignmentParameters("missing")
+	assert.False(t, ok)
+
+	var nilIdx *libraryIndex
+	_, ok = nilIdx.PolicyAssignmentParameters("Deploy-Test")
+	assert.False(t, ok)
+}
+
+func TestLibraryIndexOrigins(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"policy_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a"}`)},
+		"policy_definition_b.json": &fstest.MapFile{Data: []byte(`{"name": "b"}`)},
+	}
+	lib2 := fstest.MapFS{
+		"policy_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a", "properties": {}}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib1, lib2})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, idx.Origins(policyDefinitionFilePrefix, "a"))
+	assert.Equal(t, []int{0}, idx.Origins(policyDefinitionFilePrefix, "b"))
+	assert.Nil(t, idx.Origins(policyAssignmentFilePrefix, "a"))
+	assert.Equal(t, 2, idx.ObjectCounts(0)["policy_definition"])
+	assert.Equal(t, 1, idx.ObjectCounts(1)["policy_definition"])
+	assert.Equal(t, 0, idx.ObjectCounts(1)["policy_assignment"])
+
+	var nilIdx *libraryIndex
+	assert.Nil(t, nilIdx.Origins(policyDefinitionFilePrefix, "a"))
+	assert.Empty(t, nilIdx.ObjectCounts(0))
+}
+
+// TestLibraryIndexContentFrom tests that only the objects defined by the later libraries are returned,
+// including those that replace an object of an earlier library.
+func TestLibraryIndexContentFrom(t *testing.T) {
+	alz := fstest.MapFS{
+		"policy_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a"}`)},
+		"policy_definition_b.json": &fstest.MapFile{Data: []byte(`{"name": "b"}`)},
+		"role_definition_r.json":   &fstest.MapFile{Data: []byte(`{"name": "r", "properties": {"roleName": "Reader"}}`)},
+	}
+	custom := fstest.MapFS{
+		"policy_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a", "properties": {}}`)},
+		"policy_assignment_c.json": &fstest.MapFile{Data: []byte(`{"name": "c"}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{alz, custom})
+	require.NoError(t, err)
+
+	assert.Equal(t, idx.Cardinality(), idx.ContentFrom(0).Cardinality())
+	res := idx.ContentFrom(1)
+	assert.ElementsMatch(t, []string{"a"}, res.policyDefinitions.ToSlice())
+	assert.ElementsMatch(t, []string{"c"}, res.policyAssignments.ToSlice())
+	assert.Equal(t, 0, res.roleDefinitions.Cardinality())
+	assert.Equal(t, 0, idx.ContentFrom(2).Cardinality())
+
+	var nilIdx *libraryIndex
+	assert.Equal(t, 0, nilIdx.ContentFrom(0).Cardinality())
+}
+
+func TestLibraryIndexPolicyAssignmentDefinitionIds(t *testing.T) {
+	lib := fstest.MapFS{
+		"policy_assignment_a.json": &fstest.MapFile{Data: []byte(`{
+  "name": "A",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Authorization/policySetDefinitions/00000000-0000-0000-0000-000000000002"}
+}`)},
+		"policy_assignment_b.json": &fstest.MapFile{Data: []byte(`{
+  "name": "B",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001"}
+}`)},
+		"policy_assignment_c.json": &fstest.MapFile{Data: []byte(`{
+  "name": "C",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001"}
+}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001",
+		"/providers/Microsoft.Authorization/policySetDefinitions/00000000-0000-0000-0000-000000000002",
+	}, idx.PolicyAssignmentDefinitionIds())
+
+	var nilIdx *libraryIndex
+	assert.Nil(t, nilIdx.PolicyAssignmentDefinitionIds())
+}
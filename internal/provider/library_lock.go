@@ -0,0 +1,244 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// libraryLockVersion is the version of the library lock file format.
+const libraryLockVersion = 1
+
+// libraryLock records the resolved library sources and a digest of their content, so that repeated plans use identical libraries.
+type libraryLock struct {
+	Version   int                   `json:"version"`
+	AlzLibRef *libraryLockAlzLibRef `json:"alz_lib_ref,omitempty"`
+	Libraries []libraryLockEntry    `json:"libraries"`
+}
+
+// libraryLockAlzLibRef is the ALZ library release that an `alz_lib_ref` version constraint resolved to.
+type libraryLockAlzLibRef struct {
+	Constraint string `json:"constraint"`
+	Resolved   string `json:"resolved"`
+}
+
+// libraryLockEntry is a library source, with its credentials redacted, and the SHA-256 digest of its content.
+// The digest pins the library, as the source may not change when its content does, e.g. a branch or a blob.
+type libraryLockEntry struct {
+	Source string `json:"source"`
+	Sha256 string `json:"sha256"`
+}
+
+// newLibraryLock returns the lock of the libraries, which are in the same order as the sources.
+// The credentials in the sources, e.g. tokens and SAS signatures, are not recorded, so that the lock file can be committed.
+// The constraint and resolved ref are recorded if the ALZ library ref is a version constraint.
+func newLibraryLock(sources []string, libs []fs.FS, constraint, resolved string) (*libraryLock, error) {
+	res := &libraryLock{
+		Version:   libraryLockVersion,
+		Libraries: make([]libraryLockEntry, len(sources)),
+	}
+	if constraint != "" && constraint != resolved {
+		res.AlzLibRef = &libraryLockAlzLibRef{Constraint: constraint, Resolved: resolved}
+	}
+	for i, src := range sources {
+		src = libfetcher.RedactSource(src)
+		digest, err := libraryDigest(libs[i])
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute the digest of library %s: %w", src, err)
+		}
+		res.Libraries[i] = libraryLockEntry{Source: src, Sha256: digest}
+	}
+	return res, nil
+}
+
+// lockedAlzLibRef returns the locked release of the `alz_lib_ref` version constraint, if the lock has one.
+func (l *libraryLock) lockedAlzLibRef(constraint string) (string, bool) {
+	if l == nil || l.AlzLibRef == nil || l.AlzLibRef.Constraint != constraint {
+		return "", false
+	}
+	return l.AlzLibRef.Resolved, true
+}
+
+// differences returns a description of each difference between the lock and the current lock, in the order of the libraries.
+func (l *libraryLock) differences(current *libraryLock) []string {
+	var res []string
+	if l.Version != current.Version {
+		res = append(res, fmt.Sprintf("the lock file version is %d, expected %d", l.Version, current.Version))
+	}
+	locked := make(map[string]string, len(l.Libraries))
+	for _, e := range l.Libraries {
+		locked[e.Source] = e.Sha256
+	}
+	sources := make(map[string]bool, len(current.Libraries))
+	for _, e := range current.Libraries {
+		sources[e.Source] = true
+		digest, ok := locked[e.Source]
+		switch {
+		case !ok:
+			res = append(res, fmt.Sprintf("the library %s is not in the lock file", e.Source))
+		case digest != e.Sha256:
+			res = append(res, fmt.Sprintf("the content of library %s has changed, the digest is %s, expected %s", e.Source, e.Sha256, digest))
+		}
+	}
+	for _, e := range l.Libraries {
+		if !sources[e.Source] {
+			res = append(res, fmt.Sprintf("the library %s is in the lock file but is no longer used", e.Source))
+		}
+	}
+	return res
+}
+
+// checkLibraryLock compares the libraries with the lock read from the lock file, which is nil if the file does not exist.
+// The file is created or updated with a warning, unless strict is set, in which case a difference is an error.
+func checkLibraryLock(name string, strict bool, lock *libraryLock, sources []string, libs []fs.FS, constraint, resolved string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	current, err := newLibraryLock(sources, libs, constraint, resolved)
+	if err != nil {
+		diags.AddError("Failed to lock libraries", err.Error())
+		return diags
+	}
+	if lock != nil {
+		differences := lock.differences(current)
+		if len(differences) == 0 {
+			return diags
+		}
+		summary := "Library lock file is stale"
+		detail := fmt.Sprintf("The libraries differ from the library lock file %s:\n  - %s", name, strings.Join(differences, "\n  - "))
+		if strict {
+			diags.AddAttributeError(path.Root("lock_file"), summary, detail+"\n\nRun a plan without `lock_file_strict` to update it.")
+			return diags
+		}
+		diags.AddAttributeWarning(path.Root("lock_file"), summary, detail+"\n\nThe lock file has been updated.")
+	}
+	if err := writeLibraryLock(name, current); err != nil {
+		diags.AddAttributeError(path.Root("lock_file"), "Failed to write library lock file", err.Error())
+	}
+	return diags
+}
+
+// checkLibraryDigests compares the content of the libraries with the expected digests, keyed by source.
+// Every source with a digest must be a library, and the digests are compared ignoring case.
+func checkLibraryDigests(expected map[string]string, sources []string, libs []fs.FS) error {
+	var errs []error
+	used := make(map[string]bool, len(expected))
+	for i, src := range sources {
+		want, ok := expected[src]
+		if !ok {
+			continue
+		}
+		used[src] = true
+		digest, err := libraryDigest(libs[i])
+		if err != nil {
+			return fmt.Errorf("unable to compute the digest of library %s: %w", libfetcher.RedactSource(src), err)
+		}
+		if !strings.EqualFold(digest, want) {
+			errs = append(errs, fmt.Errorf("the content of library %s has digest %s, expected %s", libfetcher.RedactSource(src), digest, strings.ToLower(want)))
+		}
+	}
+	unused := make([]string, 0)
+	for src := range expected {
+		if !used[src] {
+			unused = append(unused, src)
+		}
+	}
+	sort.Strings(unused)
+	for _, src := range unused {
+		errs = append(errs, fmt.Errorf("the library %s has a digest but is not in the libraries", libfetcher.RedactSource(src)))
+	}
+	return errors.Join(errs...)
+}
+
+// readLibraryLock reads the lock file, returning nil if the file does not exist.
+func readLibraryLock(name string) (*libraryLock, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read library lock file %s: %w", name, err)
+	}
+	res := new(libraryLock)
+	if err := json.Unmarshal(b, res); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal library lock file %s: %w", name, err)
+	}
+	return res, nil
+}
+
+// writeLibraryLock writes the lock file.
+func writeLibraryLock(name string, lock *libraryLock) error {
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write library lock file %s: %w", name, err)
+	}
+	return nil
+}
+
+// libraryDigest returns the hex encoded SHA-256 digest of the files in the library, independent of the order they are read in.
+// The top level `.git` directory of a cloned library is not part of the content and is ignored,
+// and neither is the attestation of the library, which signs the digest.
+// Every other file is included, as alzlib reads every JSON file. Symbolic links are followed, as alzlib reads the file they link to,
+// and links to directories are ignored, as alzlib does not walk them.
+func libraryDigest(lib fs.FS) (string, error) {
+	files := make(map[string]string)
+	if err := fs.WalkDir(lib, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if name == libraryAttestationFile {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			fi, err := fs.Stat(lib, name)
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				return nil
+			}
+			if !fi.Mode().IsRegular() {
+				return fmt.Errorf("%s is not a regular file", name)
+			}
+		}
+		b, err := fs.ReadFile(lib, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		files[name] = hex.EncodeToString(sum[:])
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s\x00%s\n", p, files[p])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
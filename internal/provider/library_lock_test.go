@@ -0,0 +1,190 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryDigest(t *testing.T) {
+	lib := fstest.MapFS{
+		"archetype_definition_root.json":  {Data: []byte(`{"name":"root"}`)},
+		"policy_definitions/deny.json":    {Data: []byte(`{"name":"deny"}`)},
+		".git/HEAD":                       {Data: []byte("ref: refs/heads/main")},
+		".gitignore":                      {Data: []byte("*.tmp")},
+		"policy_definitions/.gitkeep":     {Data: []byte("")},
+		"policy_definitions/sub/tag.json": {Data: []byte(`{"name":"tag"}`)},
+	}
+	digest, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.Len(t, digest, 64)
+
+	// Git metadata does not change the digest.
+	lib[".git/HEAD"] = &fstest.MapFile{Data: []byte("0123456789abcdef")}
+	same, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.Equal(t, digest, same)
+
+	// Content does.
+	lib["policy_definitions/deny.json"] = &fstest.MapFile{Data: []byte(`{"name":"deny2"}`)}
+	changed, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+
+	// Only the top level .git directory is ignored, as alzlib reads every JSON file.
+	for _, name := range []string{".github/x.alz_policy_definition.json", ".gitx.json", "policy_definitions/.git/x.json"} {
+		added := maps.Clone(lib)
+		added[name] = &fstest.MapFile{Data: []byte(`{"name":"added"}`)}
+		got, err := libraryDigest(added)
+		require.NoError(t, err)
+		assert.NotEqual(t, changed, got, name)
+	}
+}
+
+// TestLibraryDigestSymlinks tests that the files that symbolic links point to are part of the digest.
+func TestLibraryDigestSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need elevated permissions on Windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "deny.json")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "archetype_definition_root.json"), []byte(`{"name":"root"}`), 0o644))
+	require.NoError(t, os.WriteFile(target, []byte(`{"name":"deny"}`), 0o644))
+	lib := os.DirFS(dir)
+	digest, err := libraryDigest(lib)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Symlink(target, filepath.Join(dir, "policy_definition_deny.alz_policy_definition.json")))
+	linked, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, linked, "a linked file is included")
+
+	require.NoError(t, os.WriteFile(target, []byte(`{"name":"deny2"}`), 0o644))
+	changed, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.NotEqual(t, linked, changed, "the content of a linked file is included")
+
+	require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(dir, "linked_dir")))
+	withDir, err := libraryDigest(lib)
+	require.NoError(t, err)
+	assert.Equal(t, changed, withDir, "links to directories are ignored")
+
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.json"), filepath.Join(dir, "broken.json")))
+	_, err = libraryDigest(lib)
+	assert.Error(t, err, "broken links are an error")
+}
+
+func TestLibraryLockDifferences(t *testing.T) {
+	locked := &libraryLock{
+		Version: libraryLockVersion,
+		Libraries: []libraryLockEntry{
+			{Source: "alz", Sha256: "aaaa"},
+			{Source: "./lib", Sha256: "bbbb"},
+			{Source: "./old", Sha256: "cccc"},
+		},
+	}
+	assert.Empty(t, locked.differences(locked))
+
+	current := &libraryLock{
+		Version: libraryLockVersion,
+		Libraries: []libraryLockEntry{
+			{Source: "alz", Sha256: "aaaa"},
+			{Source: "./lib", Sha256: "dddd"},
+			{Source: "./new", Sha256: "eeee"},
+		},
+	}
+	assert.Equal(t, []string{
+		"the content of library ./lib has changed, the digest is dddd, expected bbbb",
+		"the library ./new is not in the lock file",
+		"the library ./old is in the lock file but is no longer used",
+	}, locked.differences(current))
+}
+
+func TestLockedAlzLibRef(t *testing.T) {
+	var lock *libraryLock
+	_, ok := lock.lockedAlzLibRef("~> 2024.03.0")
+	assert.False(t, ok)
+
+	lock = &libraryLock{AlzLibRef: &libraryLockAlzLibRef{Constraint: "~> 2024.03.0", Resolved: "platform/alz/2024.03.01"}}
+	ref, ok := lock.lockedAlzLibRef("~> 2024.03.0")
+	assert.True(t, ok)
+	assert.Equal(t, "platform/alz/2024.03.01", ref)
+	_, ok = lock.lockedAlzLibRef("~> 2024.07.0")
+	assert.False(t, ok)
+}
+
+func TestCheckLibraryLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".alz.lock.json")
+	sources := []string{"./lib"}
+	libs := []fs.FS{fstest.MapFS{"archetype_definition_root.json": {Data: []byte(`{"name":"root"}`)}}}
+
+	// A missing lock file is created.
+	lock, err := readLibraryLock(name)
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+	diags := checkLibraryLock(name, false, lock, sources, libs, "~> 2024.03.0", "platform/alz/2024.03.01")
+	require.False(t, diags.HasError(), diags)
+	assert.Zero(t, diags.WarningsCount())
+	lock, err = readLibraryLock(name)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	assert.Equal(t, "platform/alz/2024.03.01", lock.AlzLibRef.Resolved)
+
+	// An up to date lock file is unchanged.
+	diags = checkLibraryLock(name, true, lock, sources, libs, "~> 2024.03.0", "platform/alz/2024.03.01")
+	assert.False(t, diags.HasError(), diags)
+
+	// A stale lock file is an error if strict, otherwise it is updated with a warning.
+	changed := []fs.FS{fstest.MapFS{"archetype_definition_root.json": {Data: []byte(`{"name":"changed"}`)}}}
+	diags = checkLibraryLock(name, true, lock, sources, changed, "~> 2024.03.0", "platform/alz/2024.03.01")
+	assert.True(t, diags.HasError())
+	diags = checkLibraryLock(name, false, lock, sources, changed, "~> 2024.03.0", "platform/alz/2024.03.01")
+	assert.False(t, diags.HasError(), diags)
+	assert.Equal(t, 1, diags.WarningsCount())
+	updated, err := readLibraryLock(name)
+	require.NoError(t, err)
+	assert.NotEqual(t, lock.Libraries[0].Sha256, updated.Libraries[0].Sha256)
+}
+
+func TestCheckLibraryDigests(t *testing.T) {
+	sources := []string{"./lib", "./other"}
+	libs := []fs.FS{
+		fstest.MapFS{"archetype_definition_root.json": {Data: []byte(`{"name":"root"}`)}},
+		fstest.MapFS{"archetype_definition_other.json": {Data: []byte(`{"name":"other"}`)}},
+	}
+	digest, err := libraryDigest(libs[0])
+	require.NoError(t, err)
+
+	// Only the libraries with a digest are checked, ignoring case.
+	assert.NoError(t, checkLibraryDigests(map[string]string{"./lib": strings.ToUpper(digest)}, sources, libs))
+
+	wrong := strings.Repeat("0", 64)
+	err = checkLibraryDigests(map[string]string{"./lib": wrong, "./missing": digest}, sources, libs)
+	assert.ErrorContains(t, err, "the content of library ./lib has digest "+digest+", expected "+wrong)
+	assert.ErrorContains(t, err, "the library ./missing has a digest but is not in the libraries")
+}
+
+// TestNewLibraryLockRedactsSources tests that the credentials in the sources are not written to the lock,
+// so that a rotated token does not make the lock stale.
+func TestNewLibraryLockRedactsSources(t *testing.T) {
+	lib := fstest.MapFS{"archetype_definition_root.json": {Data: []byte(`{"name":"root"}`)}}
+	lock, err := newLibraryLock([]string{"azblob::https://account.blob.core.windows.net/c/lib.tar.gz?sig=first"}, []fs.FS{lib}, "", "")
+	require.NoError(t, err)
+	require.Len(t, lock.Libraries, 1)
+	assert.Equal(t, "azblob::https://account.blob.core.windows.net/c/lib.tar.gz?sig=REDACTED", lock.Libraries[0].Source)
+
+	rotated, err := newLibraryLock([]string{"azblob::https://account.blob.core.windows.net/c/lib.tar.gz?sig=second"}, []fs.FS{lib}, "", "")
+	require.NoError(t, err)
+	assert.Empty(t, lock.differences(rotated))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// libraryObjectKinds are the file prefixes of the library objects, with the name of the kind used in diagnostics.
+var libraryObjectKinds = []struct {
+	prefix, name string
+}{
+	{archetypeDefinitionFilePrefix, "archetype definition"},
+	{architectureDefinitionFilePrefix, "architecture definition"},
+	{policyAssignmentFilePrefix, "policy assignment"},
+	{policyDefinitionFilePrefix, "policy definition"},
+	{policySetDefinitionFilePrefix, "policy set definition"},
+	{roleDefinitionFilePrefix, "role definition"},
+}
+
+// libraryOverride is an object that is defined in more than one library.
+type libraryOverride struct {
+	kind      string // the kind of object, e.g. `policy definition`
+	name      string
+	libraries []int // the positions of the libraries that define the object, in order; the definition of the last is used
+}
+
+// Overrides returns the objects that are defined in more than one library, sorted by kind and name.
+// Objects defined more than once in the same library are not included, as alzlib rejects them.
+func (idx *libraryIndex) Overrides() []libraryOverride {
+	if idx == nil {
+		return nil
+	}
+	res := make([]libraryOverride, 0)
+	for _, k := range libraryObjectKinds {
+		var found []libraryOverride
+		for key, origins := range idx.origins {
+			name, ok := strings.CutPrefix(key, k.prefix)
+			if !ok {
+				continue
+			}
+			libs := make([]int, 0, len(origins))
+			for _, o := range origins {
+				if len(libs) == 0 || libs[len(libs)-1] != o {
+					libs = append(libs, o)
+				}
+			}
+			if len(libs) > 1 {
+				found = append(found, libraryOverride{kind: k.name, name: name, libraries: libs})
+			}
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+		res = append(res, found...)
+	}
+	return res
+}
+
+// libraryOverridesSummary lists the overridden objects and the libraries that define them, using the names of the libraries.
+func libraryOverridesSummary(overrides []libraryOverride, names []string) string {
+	var sb strings.Builder
+	for _, o := range overrides {
+		libs := make([]string, len(o.libraries))
+		for i, n := range o.libraries {
+			libs[i] = names[n]
+		}
+		sb.WriteString(fmt.Sprintf("  - %s %s: defined in %s, the definition in %s is used\n", o.kind, o.name, strings.Join(libs, ", "), libs[len(libs)-1]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLibraryOverrides tests that objects defined in more than one library are listed by kind and name,
+// and that objects in one library are not.
+func TestLibraryOverrides(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"policy_definition_b.json":     &fstest.MapFile{Data: []byte(`{"name": "b"}`)},
+		"policy_definition_a.json":     &fstest.MapFile{Data: []byte(`{"name": "a"}`)},
+		"policy_set_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a"}`)},
+		"policy_definition_c.json":     &fstest.MapFile{Data: []byte(`{"name": "c"}`)},
+	}
+	lib2 := fstest.MapFS{
+		"policy_set_definition_a.json": &fstest.MapFile{Data: []byte(`{"name": "a", "properties": {}}`)},
+		"policy_definition_b.json":     &fstest.MapFile{Data: []byte(`{"name": "b", "properties": {}}`)},
+	}
+	lib3 := fstest.MapFS{
+		"policy_definition_b.json":         &fstest.MapFile{Data: []byte(`{"name": "b", "properties": {"mode": "All"}}`)},
+		"archetype_definition_x.json":      &fstest.MapFile{Data: []byte(`{"name": "x"}`)},
+		"more/archetype_definition_x.json": &fstest.MapFile{Data: []byte(`{"name": "x"}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib1, lib2, lib3})
+	require.NoError(t, err)
+
+	overrides := idx.Overrides()
+	assert.Equal(t, []libraryOverride{
+		{kind: "policy definition", name: "b", libraries: []int{0, 1, 2}},
+		{kind: "policy set definition", name: "a", libraries: []int{0, 1}},
+	}, overrides)
+	assert.Equal(t,
+		"  - policy definition b: defined in alz, ./lib, ./more, the definition in ./more is used\n"+
+			"  - policy set definition a: defined in alz, ./lib, the definition in ./lib is used\n",
+		libraryOverridesSummary(overrides, []string{"alz", "./lib", "./more"}))
+
+	var nilIdx *libraryIndex
+	assert.Nil(t, nilIdx.Overrides())
+}
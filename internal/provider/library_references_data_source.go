@@ -0,0 +1,121 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &LibraryReferencesDataSource{}
+
+func NewLibraryReferencesDataSource() datasource.DataSource {
+	return &LibraryReferencesDataSource{}
+}
+
+// LibraryReferencesDataSource defines the data source implementation.
+type LibraryReferencesDataSource struct {
+	alz *alzProviderData
+}
+
+// LibraryReferencesDataSourceModel describes the data source data model.
+type LibraryReferencesDataSourceModel struct {
+	AlzLibRef         types.String `tfsdk:"alz_lib_ref"`
+	AlzLibRefResolved types.String `tfsdk:"alz_lib_ref_resolved"`
+	Id                types.String `tfsdk:"id"`
+	LibUrls           types.List   `tfsdk:"lib_urls"` // list of string
+}
+
+func (d *LibraryReferencesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_library_references"
+}
+
+func (d *LibraryReferencesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Library references data source. Returns the library references used by the provider, including the exact ALZ library release when `alz_lib_ref` is a version constraint. " +
+			"Record the resolved reference to pin it in the provider configuration.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"alz_lib_ref": schema.StringAttribute{
+				MarkdownDescription: "The `alz_lib_ref` from the provider configuration, which may be a version constraint.",
+				Computed:            true,
+			},
+
+			"alz_lib_ref_resolved": schema.StringAttribute{
+				MarkdownDescription: "The ALZ library tag that was used. Null if the ALZ library is not used.",
+				Computed:            true,
+			},
+
+			"lib_urls": schema.ListAttribute{
+				MarkdownDescription: "The library sources, in the order they were processed.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *LibraryReferencesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *LibraryReferencesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data LibraryReferencesDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue("library_references")
+	data.AlzLibRef = types.StringValue(d.alz.alzLibRef)
+	data.AlzLibRefResolved = types.StringNull()
+	if d.alz.alzLibRefResolved != "" {
+		data.AlzLibRefResolved = types.StringValue(d.alz.alzLibRefResolved)
+	}
+	urls, diags := types.ListValueFrom(ctx, types.StringType, d.alz.libUrls)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.LibUrls = urls
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ManagementGroupRoleAssignmentType describes a role assignment in the management_group_role_assignments provider attribute.
+type ManagementGroupRoleAssignmentType struct {
+	Archetypes                 types.Set    `tfsdk:"archetypes"` // set of string
+	ManagementGroupIdsMatching types.String `tfsdk:"management_group_ids_matching"`
+	PrincipalId                types.String `tfsdk:"principal_id"`
+	RoleDefinitionId           types.String `tfsdk:"role_definition_id"`
+}
+
+// managementGroupRoleAssignment is a compiled entry of the management_group_role_assignments attribute.
+type managementGroupRoleAssignment struct {
+	key              string
+	archetypes       mapset.Set[string] // nil if not set
+	re               *regexp.Regexp     // nil if not set
+	principalId      types.String
+	roleDefinitionId types.String
+}
+
+// compileManagementGroupRoleAssignments compiles the selectors of the management_group_role_assignments attribute.
+// The result is sorted by key. Each role assignment must have at least one selector.
+func compileManagementGroupRoleAssignments(ctx context.Context, src map[string]ManagementGroupRoleAssignmentType) ([]managementGroupRoleAssignment, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([]managementGroupRoleAssignment, 0, len(src))
+	for _, k := range keys {
+		v := src[k]
+		p := path.Root("management_group_role_assignments").AtMapKey(k)
+		ra := managementGroupRoleAssignment{
+			key:              k,
+			principalId:      v.PrincipalId,
+			roleDefinitionId: v.RoleDefinitionId,
+		}
+		if !v.Archetypes.IsNull() && !v.Archetypes.IsUnknown() {
+			var archetypes []string
+			diags.Append(v.Archetypes.ElementsAs(ctx, &archetypes, false)...)
+			ra.archetypes = mapset.NewThreadUnsafeSet(archetypes...)
+		}
+		if expr := v.ManagementGroupIdsMatching.ValueString(); expr != "" {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				diags.AddAttributeError(p.AtName("management_group_ids_matching"), "Invalid regular expression", err.Error())
+				continue
+			}
+			ra.re = re
+		}
+		if ra.archetypes == nil && ra.re == nil {
+			diags.AddAttributeError(p, "Missing selector", "At least one of `archetypes` or `management_group_ids_matching` must be set.")
+			continue
+		}
+		res = append(res, ra)
+	}
+	return res, diags
+}
+
+// matches returns true if the management group is selected by all of the selectors of the role assignment.
+func (ra managementGroupRoleAssignment) matches(mgName, archetype string) bool {
+	if ra.archetypes != nil && !ra.archetypes.Contains(archetype) {
+		return false
+	}
+	if ra.re != nil && !ra.re.MatchString(mgName) {
+		return false
+	}
+	return true
+}
+
+// selectedRoleAssignments returns the role assignments that select the management group, keyed by the attribute key.
+// The role assignments are at the management group scope.
+func selectedRoleAssignments(src []managementGroupRoleAssignment, mgName, archetype, mgResourceId string) map[string]AlzRoleAssignmentType {
+	res := make(map[string]AlzRoleAssignmentType)
+	for _, ra := range src {
+		if !ra.matches(mgName, archetype) {
+			continue
+		}
+		res[ra.key] = AlzRoleAssignmentType{
+			PrincipalId:      ra.principalId,
+			RoleDefinitionId: ra.roleDefinitionId,
+			Scope:            types.StringValue(mgResourceId),
+		}
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSelectedRoleAssignments tests that role assignments are expanded for the management groups that all selectors match.
+func TestSelectedRoleAssignments(t *testing.T) {
+	src := map[string]ManagementGroupRoleAssignmentType{
+		"lz-reader": {
+			Archetypes:                 types.SetValueMust(types.StringType, []attr.Value{types.StringValue("landing_zones")}),
+			ManagementGroupIdsMatching: types.StringNull(),
+			PrincipalId:                types.StringValue("00000000-0000-0000-0000-000000000001"),
+			RoleDefinitionId:           types.StringValue("reader"),
+		},
+		"corp-contributor": {
+			Archetypes:                 types.SetValueMust(types.StringType, []attr.Value{types.StringValue("corp"), types.StringValue("online")}),
+			ManagementGroupIdsMatching: types.StringValue("^corp"),
+			PrincipalId:                types.StringValue("00000000-0000-0000-0000-000000000002"),
+			RoleDefinitionId:           types.StringValue("contributor"),
+		},
+	}
+	ras, diags := compileManagementGroupRoleAssignments(context.Background(), src)
+	require.False(t, diags.HasError())
+	require.Len(t, ras, 2)
+	assert.Equal(t, "corp-contributor", ras[0].key)
+
+	res := selectedRoleAssignments(ras, "landingzones", "landing_zones", "/providers/Microsoft.Management/managementGroups/landingzones")
+	assert.Equal(t, map[string]AlzRoleAssignmentType{
+		"lz-reader": {
+			PrincipalId:      types.StringValue("00000000-0000-0000-0000-000000000001"),
+			RoleDefinitionId: types.StringValue("reader"),
+			Scope:            types.StringValue("/providers/Microsoft.Management/managementGroups/landingzones"),
+		},
+	}, res)
+
+	res = selectedRoleAssignments(ras, "corp-canary", "corp", "/providers/Microsoft.Management/managementGroups/corp-canary")
+	assert.Len(t, res, 1)
+	assert.Contains(t, res, "corp-contributor")
+
+	// Both selectors must match.
+	assert.Empty(t, selectedRoleAssignments(ras, "online", "online", "/providers/Microsoft.Management/managementGroups/online"))
+}
+
+func TestCompileManagementGroupRoleAssignmentsErrors(t *testing.T) {
+	_, diags := compileManagementGroupRoleAssignments(context.Background(), map[string]ManagementGroupRoleAssignmentType{
+		"none": {
+			Archetypes:                 types.SetNull(types.StringType),
+			ManagementGroupIdsMatching: types.StringNull(),
+		},
+	})
+	require.True(t, diags.HasError())
+	assert.Equal(t, "Missing selector", diags[0].Summary())
+
+	_, diags = compileManagementGroupRoleAssignments(context.Background(), map[string]ManagementGroupRoleAssignmentType{
+		"bad": {
+			Archetypes:                 types.SetNull(types.StringType),
+			ManagementGroupIdsMatching: types.StringValue("("),
+		},
+	})
+	require.True(t, diags.HasError())
+	assert.Equal(t, "Invalid regular expression", diags[0].Summary())
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	managementGroupsApiVersion   = "2021-04-01"
+	permissionsApiVersion        = "2022-04-01"
+	managementGroupsResourceType = "Microsoft.Management/managementGroups"
+)
+
+// ManagementGroupsClient reads the management group hierarchy and the caller's permissions on management groups.
+// The Azure SDK management groups module is not a dependency of the provider,
+// so the client uses the ARM pipeline directly.
+type ManagementGroupsClient struct {
+	endpoint string
+	pl       runtime.Pipeline
+}
+
+// managementGroupInfo is a management group in the live hierarchy.
+type managementGroupInfo struct {
+	Name        string
+	DisplayName string
+	ParentName  string // empty if the parent is not a management group
+}
+
+// managementGroupResponse is the subset of the management group and descendant responses used by the client.
+type managementGroupResponse struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Properties struct {
+		DisplayName string `json:"displayName"`
+		Parent      *struct {
+			Id string `json:"id"`
+		} `json:"parent"` // descendants
+		Details *struct {
+			Parent *struct {
+				Id string `json:"id"`
+			} `json:"parent"`
+		} `json:"details"` // management group
+	} `json:"properties"`
+}
+
+// managementGroupDescendantsResponse is a page of the management group descendants response.
+type managementGroupDescendantsResponse struct {
+	Value    []managementGroupResponse `json:"value"`
+	NextLink string                    `json:"nextLink"`
+}
+
+// permissionsResponse is a page of the permissions response.
+type permissionsResponse struct {
+	Value    []libraryRoleDefinitionPermission `json:"value"`
+	NextLink string                            `json:"nextLink"`
+}
+
+func newManagementGroupsClient(cred azcore.TokenCredential, options *arm.ClientOptions) (*ManagementGroupsClient, error) {
+	cl, err := arm.NewClient("provider-alz.ManagementGroupsClient", "v0.0.0", cred, options)
+	if err != nil {
+		return nil, err
+	}
+	return &ManagementGroupsClient{
+		endpoint: cl.Endpoint(),
+		pl:       cl.Pipeline(),
+	}, nil
+}
+
+// Get returns the management group with the supplied name.
+func (c *ManagementGroupsClient) Get(ctx context.Context, name string) (managementGroupInfo, error) {
+	resp := new(managementGroupResponse)
+	u := runtime.JoinPaths(c.endpoint, "/providers/Microsoft.Management/managementGroups", url.PathEscape(name))
+	if err := c.get(ctx, u, resp); err != nil {
+		return managementGroupInfo{}, err
+	}
+	return resp.info(), nil
+}
+
+// ListDescendants returns the management groups below the supplied management group.
+// Subscriptions are not returned.
+func (c *ManagementGroupsClient) ListDescendants(ctx context.Context, name string) ([]managementGroupInfo, error) {
+	var res []managementGroupInfo
+	u := runtime.JoinPaths(c.endpoint, "/providers/Microsoft.Management/managementGroups", url.PathEscape(name), "descendants")
+	for u != "" {
+		page := new(managementGroupDescendantsResponse)
+		if err := c.get(ctx, u, page); err != nil {
+			return nil, err
+		}
+		res = append(res, page.managementGroups()...)
+		u = page.NextLink
+	}
+	return res, nil
+}
+
+// ListPermissions returns the permissions of the caller at the management group scope.
+func (c *ManagementGroupsClient) ListPermissions(ctx context.Context, name string) ([]libraryRoleDefinitionPermission, error) {
+	var res []libraryRoleDefinitionPermission
+	u := runtime.JoinPaths(c.endpoint, "/providers/Microsoft.Management/managementGroups", url.PathEscape(name), "providers/Microsoft.Authorization/permissions")
+	u += "?api-version=" + permissionsApiVersion
+	for u != "" {
+		page := new(permissionsResponse)
+		if err := c.get(ctx, u, page); err != nil {
+			return nil, err
+		}
+		res = append(res, page.Value...)
+		u = page.NextLink
+	}
+	return res, nil
+}
+
+// get sends a GET request to the url and unmarshals the response into v.
+func (c *ManagementGroupsClient) get(ctx context.Context, u string, v any) error {
+	return armGet(ctx, c.pl, u, managementGroupsApiVersion, v)
+}
+
+// armGet sends a GET request to the url using the pipeline and unmarshals the response into v.
+// The api-version is only added if the url does not have one, as next links include it.
+func armGet(ctx context.Context, pl runtime.Pipeline, u, apiVersion string, v any) error {
+	req, err := runtime.NewRequest(ctx, http.MethodGet, u)
+	if err != nil {
+		return err
+	}
+	q := req.Raw().URL.Query()
+	if !q.Has("api-version") {
+		q.Set("api-version", apiVersion)
+		req.Raw().URL.RawQuery = q.Encode()
+	}
+	req.Raw().Header["Accept"] = []string{"application/json"}
+	resp, err := pl.Do(req)
+	if err != nil {
+		return err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return runtime.NewResponseError(resp)
+	}
+	return runtime.UnmarshalAsJSON(resp, v)
+}
+
+// isNotFoundError returns true if the error is an Azure response with status 404.
+func isNotFoundError(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// managementGroups returns the management groups in the page.
+func (p managementGroupDescendantsResponse) managementGroups() []managementGroupInfo {
+	res := make([]managementGroupInfo, 0, len(p.Value))
+	for _, v := range p.Value {
+		if !strings.EqualFold(v.Type, managementGroupsResourceType) {
+			continue
+		}
+		res = append(res, v.info())
+	}
+	return res
+}
+
+// info returns the management group info from the response.
+func (r managementGroupResponse) info() managementGroupInfo {
+	var parentId string
+	if r.Properties.Parent != nil {
+		parentId = r.Properties.Parent.Id
+	}
+	if r.Properties.Details != nil && r.Properties.Details.Parent != nil {
+		parentId = r.Properties.Details.Parent.Id
+	}
+	res := managementGroupInfo{
+		Name:        r.Name,
+		DisplayName: r.Properties.DisplayName,
+	}
+	if strings.Contains(strings.ToLower(parentId), "/providers/microsoft.management/managementgroups/") {
+		res.ParentName = resourceIdName(parentId)
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testManagementGroupsClient(t *testing.T, h http.HandlerFunc) *ManagementGroupsClient {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &ManagementGroupsClient{
+		endpoint: srv.URL,
+		pl: runtime.NewPipeline("test", "v0.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{
+			Transport: srv.Client(),
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		}),
+	}
+}
+
+func TestManagementGroupsClientGet(t *testing.T) {
+	c := testManagementGroupsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/providers/Microsoft.Management/managementGroups/alz", r.URL.Path)
+		assert.Equal(t, managementGroupsApiVersion, r.URL.Query().Get("api-version"))
+		_, _ = w.Write([]byte(`{"name":"alz","type":"Microsoft.Management/managementGroups","properties":{"displayName":"ALZ","details":{"parent":{"id":"/providers/Microsoft.Management/managementGroups/tenant"}}}}`))
+	})
+	mg, err := c.Get(context.Background(), "alz")
+	require.NoError(t, err)
+	assert.Equal(t, managementGroupInfo{Name: "alz", DisplayName: "ALZ", ParentName: "tenant"}, mg)
+}
+
+func TestManagementGroupsClientListDescendants(t *testing.T) {
+	var c *ManagementGroupsClient
+	c = testManagementGroupsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"value":[{"name":"corp","type":"Microsoft.Management/managementGroups","properties":{"displayName":"Corp","parent":{"id":"/providers/Microsoft.Management/managementGroups/lz"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":[` +
+			`{"name":"lz","type":"Microsoft.Management/managementGroups","properties":{"displayName":"Landing zones","parent":{"id":"/providers/Microsoft.Management/managementGroups/alz"}}},` +
+			`{"name":"sub","type":"/subscriptions","properties":{"displayName":"Sub","parent":{"id":"/providers/Microsoft.Management/managementGroups/lz"}}}` +
+			`],"nextLink":"` + c.endpoint + `/providers/Microsoft.Management/managementGroups/alz/descendants?api-version=` + managementGroupsApiVersion + `&page=2"}`))
+	})
+	mgs, err := c.ListDescendants(context.Background(), "alz")
+	require.NoError(t, err)
+	assert.Equal(t, []managementGroupInfo{
+		{Name: "lz", DisplayName: "Landing zones", ParentName: "alz"},
+		{Name: "corp", DisplayName: "Corp", ParentName: "lz"},
+	}, mgs)
+}
+
+func TestManagementGroupsClientError(t *testing.T) {
+	c := testManagementGroupsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"code":"AuthorizationFailed","message":"denied"}}`))
+	})
+	_, err := c.Get(context.Background(), "alz")
+	assert.ErrorContains(t, err, "AuthorizationFailed")
+}
+
+func TestManagementGroupsClientListPermissions(t *testing.T) {
+	c := testManagementGroupsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/permissions", r.URL.Path)
+		assert.Equal(t, permissionsApiVersion, r.URL.Query().Get("api-version"))
+		_, _ = w.Write([]byte(`{"value":[{"actions":["*"],"notActions":["Microsoft.Authorization/*/Delete"],"dataActions":[],"notDataActions":[]}]}`))
+	})
+	perms, err := c.ListPermissions(context.Background(), "alz")
+	require.NoError(t, err)
+	require.Len(t, perms, 1)
+	assert.Equal(t, []string{"*"}, perms[0].Actions)
+	assert.Equal(t, []string{"Microsoft.Authorization/*/Delete"}, perms[0].NotActions)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+// defaultNonComplianceMessages sets the non-compliance message of the policy assignments with the Deny effect that have none
+// to the description of the library policy definition they assign, and returns the names of the changed assignments, sorted.
+// Assignments of policy set definitions and built-in definitions are not changed, as their descriptions are not in the library.
+// The assignments are replaced in the map, so that the properties shared with alzlib are not modified.
+func defaultNonComplianceMessages(pas map[string]armpolicy.Assignment, metadata map[string]libraryPolicyDefinitionMetadata) []string {
+	res := make([]string, 0)
+	for k, pa := range pas {
+		if pa.Properties == nil || len(pa.Properties.NonComplianceMessages) != 0 || pa.Properties.PolicyDefinitionID == nil {
+			continue
+		}
+		id := *pa.Properties.PolicyDefinitionID
+		if !strings.Contains(strings.ToLower(id), "/policydefinitions/") {
+			continue
+		}
+		md, ok := metadata[resourceIdName(id)]
+		if !ok || md.Description == "" {
+			continue
+		}
+		if complianceEffectCategory(policyAssignmentEffect(pa, metadata), pa) != complianceEffectDeny {
+			continue
+		}
+		props := *pa.Properties
+		props.NonComplianceMessages = []*armpolicy.NonComplianceMessage{{Message: to.Ptr(md.Description)}}
+		pa.Properties = &props
+		pas[k] = pa
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
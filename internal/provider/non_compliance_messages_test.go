@@ -0,0 +1,61 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultNonComplianceMessages(t *testing.T) {
+	const pdPrefix = "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/"
+	metadata := map[string]libraryPolicyDefinitionMetadata{
+		"Deny-Public-IP":  {DefaultEffect: "Deny", Description: "Public IP addresses are not allowed."},
+		"Deny-Param":      {DefaultEffect: "Deny", Description: "Denied by parameter.", EffectParameter: "effect"},
+		"Deny-NoDesc":     {DefaultEffect: "Deny"},
+		"Audit-Something": {DefaultEffect: "Audit", Description: "Audited."},
+	}
+	pa := func(def string, params map[string]any, msgs ...string) armpolicy.Assignment {
+		res := armpolicy.Assignment{Properties: &armpolicy.AssignmentProperties{
+			PolicyDefinitionID: to.Ptr(pdPrefix + def),
+			Parameters:         make(map[string]*armpolicy.ParameterValuesValue),
+		}}
+		for k, v := range params {
+			res.Properties.Parameters[k] = &armpolicy.ParameterValuesValue{Value: v}
+		}
+		for _, msg := range msgs {
+			res.Properties.NonComplianceMessages = append(res.Properties.NonComplianceMessages, &armpolicy.NonComplianceMessage{Message: to.Ptr(msg)})
+		}
+		return res
+	}
+	deny := pa("Deny-Public-IP", nil)
+	shared := deny.Properties
+	pas := map[string]armpolicy.Assignment{
+		"deny":     deny,
+		"param":    pa("Deny-Param", map[string]any{"effect": "Deny"}),
+		"disabled": pa("Deny-Param", map[string]any{"effect": "Disabled"}),
+		"nodesc":   pa("Deny-NoDesc", nil),
+		"audit":    pa("Audit-Something", nil),
+		"declared": pa("Deny-Public-IP", nil, "Declared message."),
+		"set": {Properties: &armpolicy.AssignmentProperties{
+			PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policySetDefinitions/Deny-Public-IP"),
+		}},
+		"none": {},
+	}
+
+	assert.Equal(t, []string{"deny", "param"}, defaultNonComplianceMessages(pas, metadata))
+	assert.Equal(t, "Public IP addresses are not allowed.", *pas["deny"].Properties.NonComplianceMessages[0].Message)
+	assert.Equal(t, "Denied by parameter.", *pas["param"].Properties.NonComplianceMessages[0].Message)
+	assert.Empty(t, pas["disabled"].Properties.NonComplianceMessages)
+	assert.Empty(t, pas["nodesc"].Properties.NonComplianceMessages)
+	assert.Empty(t, pas["audit"].Properties.NonComplianceMessages)
+	assert.Equal(t, "Declared message.", *pas["declared"].Properties.NonComplianceMessages[0].Message)
+	assert.Empty(t, pas["set"].Properties.NonComplianceMessages)
+
+	// The properties shared with alzlib are not modified.
+	assert.Empty(t, shared.NonComplianceMessages)
+}
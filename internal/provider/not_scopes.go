@@ -0,0 +1,95 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// notScopesSubscriptionsRegex matches a not scope that is replaced by the subscriptions of a management group,
+// e.g. `${subscriptions:decommissioned}`.
+var notScopesSubscriptionsRegex = regexp.MustCompile(`^\$\{subscriptions:([^}]+)\}$`)
+
+// applyPolicyAssignmentNotScopes replaces the not scopes of the policy assignments that have `not_scopes` in the modifications.
+// The not scopes are not supported by alzlib's ModifyPolicyAssignment, so they are applied to the copy of the policy assignments used for the output.
+func applyPolicyAssignmentNotScopes(pas map[string]armpolicy.Assignment, mods map[string]PolicyAssignmentType) {
+	for k, v := range mods {
+		pa, ok := pas[k]
+		if !ok || pa.Properties == nil || !isKnown(v.NotScopes) {
+			continue
+		}
+		notScopes := make([]*string, 0, len(v.NotScopes.Elements()))
+		for _, e := range v.NotScopes.Elements() {
+			if s, ok := e.(types.String); ok {
+				notScopes = append(notScopes, s.ValueStringPointer())
+			}
+		}
+		sort.Slice(notScopes, func(i, j int) bool { return *notScopes[i] < *notScopes[j] })
+		pas[k] = withNotScopes(pa, notScopes)
+	}
+}
+
+// expandNotScopesSubscriptions replaces each `${subscriptions:<management group id>}` not scope of the policy assignments
+// with the resource ids of the subscriptions of that management group, from the `subscription_ids` of its archetype data source.
+// It returns true if any not scope was replaced. The management group must have been read already.
+func expandNotScopesSubscriptions(pas map[string]armpolicy.Assignment, subscriptions map[string][]string) (bool, error) {
+	expanded := false
+	for _, k := range sortedKeys(pas) {
+		pa := pas[k]
+		if pa.Properties == nil {
+			continue
+		}
+		var res []*string
+		changed := false
+		seen := make(map[string]bool, len(pa.Properties.NotScopes))
+		add := func(s string) {
+			if !seen[s] {
+				seen[s] = true
+				res = append(res, &s)
+			}
+		}
+		for _, ns := range pa.Properties.NotScopes {
+			if ns == nil {
+				continue
+			}
+			m := notScopesSubscriptionsRegex.FindStringSubmatch(*ns)
+			if m == nil {
+				add(*ns)
+				continue
+			}
+			subs, ok := subscriptions[m[1]]
+			if !ok {
+				return false, fmt.Errorf(
+					"the not scope %s of policy assignment %s references the management group %s, which is not an `alz_archetype` data source that has been read. "+
+						"Add the data source of the management group to `depends_on`, so that it is read first",
+					*ns, k, m[1],
+				)
+			}
+			for _, sub := range subs {
+				add("/subscriptions/" + trimSubscriptionsPrefix(sub))
+			}
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		pas[k] = withNotScopes(pa, res)
+		expanded = true
+	}
+	return expanded, nil
+}
+
+// withNotScopes returns the policy assignment with the not scopes. The properties are copied,
+// as they are shared with the policy assignment held by alzlib.
+func withNotScopes(pa armpolicy.Assignment, notScopes []*string) armpolicy.Assignment {
+	props := *pa.Properties
+	props.NotScopes = notScopes
+	pa.Properties = &props
+	return pa
+}
@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPolicyAssignmentNotScopes tests that the not scopes are replaced by the modifications,
+// and that the subscriptions tokens are expanded without changing the policy assignments held by alzlib.
+func TestPolicyAssignmentNotScopes(t *testing.T) {
+	library := &armpolicy.AssignmentProperties{NotScopes: []*string{to.Ptr("${subscriptions:decommissioned}")}}
+	pas := map[string]armpolicy.Assignment{
+		"Deny-Public-IP": {Properties: &armpolicy.AssignmentProperties{NotScopes: []*string{to.Ptr("/subscriptions/old")}}},
+		"Enable-DDoS":    {Properties: library},
+		"Audit-Tags":     {Properties: &armpolicy.AssignmentProperties{}},
+	}
+	applyPolicyAssignmentNotScopes(pas, map[string]PolicyAssignmentType{
+		"Deny-Public-IP": {NotScopes: types.SetValueMust(types.StringType, []attr.Value{
+			types.StringValue("${subscriptions:sandboxes}"),
+			types.StringValue("${subscriptions:decommissioned}"),
+		})},
+		"Audit-Tags": {NotScopes: types.SetNull(types.StringType)},
+	})
+
+	subs := map[string][]string{
+		"decommissioned": {"00000000-0000-0000-0000-000000000001", "/subscriptions/00000000-0000-0000-0000-000000000002"},
+		"sandboxes":      {"00000000-0000-0000-0000-000000000001"},
+	}
+	expanded, err := expandNotScopesSubscriptions(pas, subs)
+	require.NoError(t, err)
+	assert.True(t, expanded)
+	assert.Equal(t, []*string{
+		to.Ptr("/subscriptions/00000000-0000-0000-0000-000000000001"),
+		to.Ptr("/subscriptions/00000000-0000-0000-0000-000000000002"),
+	}, pas["Deny-Public-IP"].Properties.NotScopes)
+	assert.Equal(t, []*string{
+		to.Ptr("/subscriptions/00000000-0000-0000-0000-000000000001"),
+		to.Ptr("/subscriptions/00000000-0000-0000-0000-000000000002"),
+	}, pas["Enable-DDoS"].Properties.NotScopes)
+	assert.Empty(t, pas["Audit-Tags"].Properties.NotScopes)
+	assert.Equal(t, []*string{to.Ptr("${subscriptions:decommissioned}")}, library.NotScopes, "the library assignment is not changed")
+
+	expanded, err = expandNotScopesSubscriptions(map[string]armpolicy.Assignment{
+		"Audit-Tags": {Properties: &armpolicy.AssignmentProperties{NotScopes: []*string{to.Ptr("/subscriptions/a")}}},
+	}, subs)
+	require.NoError(t, err)
+	assert.False(t, expanded)
+
+	_, err = expandNotScopesSubscriptions(map[string]armpolicy.Assignment{
+		"Audit-Tags": {Properties: &armpolicy.AssignmentProperties{NotScopes: []*string{to.Ptr("${subscriptions:corp}")}}},
+	}, subs)
+	assert.ErrorContains(t, err, "references the management group corp")
+}
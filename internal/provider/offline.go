@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// offlineErrorSummary is the summary of the errors for features that need network access when the provider is offline.
+const offlineErrorSummary = "Not available in offline mode"
+
+// offlineConfigDiagnostics returns errors for the provider attributes that need network access, if the provider is offline.
+// Remote libraries can only be used from the library cache, so either the source must be a local directory or `lib_cache_dir` must be set.
+func offlineConfigDiagnostics(data AlzProviderModel, urls []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !data.Offline.ValueBool() {
+		return diags
+	}
+	if data.PreflightAuthorizationScope.ValueString() != "" {
+		diags.AddAttributeError(path.Root("preflight_authorization_scope"), offlineErrorSummary, "The pre-flight authorization check reads from Azure and cannot be used with `offline`.")
+	}
+	if data.ValidatePolicyAliases.ValueBool() {
+		diags.AddAttributeError(path.Root("validate_policy_aliases"), offlineErrorSummary, "The policy aliases are read from Azure and cannot be validated with `offline`.")
+	}
+	if data.CheckExistingManagementGroups.ValueBool() {
+		diags.AddAttributeError(path.Root("check_existing_management_groups"), offlineErrorSummary, "The existing management groups are read from Azure and cannot be checked with `offline`.")
+	}
+	if data.MetadataHost.ValueString() != "" {
+		diags.AddAttributeError(path.Root("metadata_host"), offlineErrorSummary, "The endpoints are read from the metadata host, which cannot be used with `offline`.")
+	}
+	if !data.LibCacheDir.IsNull() {
+		return diags
+	}
+	var remote []string
+	for _, src := range urls {
+		if libfetcher.Scheme(src) != "file" {
+			remote = append(remote, src)
+		}
+	}
+	if len(remote) != 0 {
+		diags.AddAttributeError(
+			path.Root("offline"),
+			offlineErrorSummary,
+			fmt.Sprintf("The libraries %s must be downloaded. Use local copies in `lib_urls`, or set `lib_cache_dir` to a cache that contains them.", strings.Join(remote, ", ")),
+		)
+	}
+	return diags
+}
+
+// offlineMissingDefinitionsError returns the detail of the error for policy assignments that reference definitions
+// that are not in the libraries, which alzlib would otherwise read from Azure.
+func offlineMissingDefinitionsError(missing []string) string {
+	return fmt.Sprintf(
+		"The provider is offline, but the policy assignments reference definitions that are not in the libraries and would be read from Azure: %s. "+
+			"Add these definitions to a library in `lib_urls`, or remove the assignments.",
+		strings.Join(missing, ", "),
+	)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOfflineConfigDiagnostics tests that attributes that read from Azure and remote libraries without a cache are errors offline.
+func TestOfflineConfigDiagnostics(t *testing.T) {
+	urls := []string{"github.com/Azure/Azure-Landing-Zones-Library//platform/alz?ref=2024.07.01", "./lib"}
+	data := AlzProviderModel{
+		Offline:                       types.BoolValue(false),
+		PreflightAuthorizationScope:   types.StringValue("alz"),
+		ValidatePolicyAliases:         types.BoolValue(true),
+		CheckExistingManagementGroups: types.BoolValue(true),
+		LibCacheDir:                   types.StringNull(),
+	}
+	assert.False(t, offlineConfigDiagnostics(data, urls).HasError(), "online")
+
+	data.Offline = types.BoolValue(true)
+	diags := offlineConfigDiagnostics(data, urls)
+	require.Equal(t, 4, diags.ErrorsCount())
+	assert.Contains(t, diags.Errors()[3].Detail(), "github.com/Azure/Azure-Landing-Zones-Library")
+	assert.NotContains(t, diags.Errors()[3].Detail(), "./lib")
+
+	data.PreflightAuthorizationScope = types.StringNull()
+	data.ValidatePolicyAliases = types.BoolValue(false)
+	data.CheckExistingManagementGroups = types.BoolValue(false)
+	data.LibCacheDir = types.StringValue(t.TempDir())
+	assert.False(t, offlineConfigDiagnostics(data, urls).HasError(), "remote libraries are served from the cache")
+}
+
+// TestLibraryIndexMissingDefinitions tests that definitions referenced by policy assignments, and the members of assigned
+// policy set definitions, that are not in the libraries are reported.
+func TestLibraryIndexMissingDefinitions(t *testing.T) {
+	lib := fstest.MapFS{
+		"policy_assignment_local.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Local",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/Local-Def"}
+}`)},
+		"policy_assignment_builtin.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Builtin",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001"}
+}`)},
+		"policy_assignment_set.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Set",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policySetDefinitions/Local-Set"}
+}`)},
+		"policy_assignment_builtin_set.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Builtin-Set",
+  "properties": {"policyDefinitionId": "/providers/Microsoft.Authorization/policySetDefinitions/00000000-0000-0000-0000-000000000002"}
+}`)},
+		"policy_definition_local.json": &fstest.MapFile{Data: []byte(`{"name": "Local-Def"}`)},
+		"policy_set_definition_local.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Local-Set",
+  "properties": {
+    "policyDefinitions": [
+      {"policyDefinitionId": "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/Local-Def"},
+      {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000003"}
+    ]
+  }
+}`)},
+	}
+	idx, err := newLibraryIndex([]fs.FS{lib})
+	require.NoError(t, err)
+
+	assert.Empty(t, idx.MissingDefinitions([]string{"Local"}))
+	assert.Equal(t, []string{
+		"/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000001",
+		"/providers/Microsoft.Authorization/policyDefinitions/00000000-0000-0000-0000-000000000003",
+		"/providers/Microsoft.Authorization/policySetDefinitions/00000000-0000-0000-0000-000000000002",
+	}, idx.MissingDefinitions([]string{"Local", "Builtin", "Set", "Builtin-Set"}))
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/terraform-provider-alz/internal/outputschema"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &OutputSchemaFunction{}
+
+func NewOutputSchemaFunction() function.Function {
+	return &OutputSchemaFunction{}
+}
+
+// OutputSchemaFunction returns the JSON schema of a computed output.
+type OutputSchemaFunction struct{}
+
+func (f *OutputSchemaFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "output_schema"
+}
+
+func (f *OutputSchemaFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Return the JSON schema of a computed output",
+		MarkdownDescription: "Returns the JSON schema (draft 2020-12) of a computed output of the provider, so that external test suites can validate the outputs structurally. " +
+			"The `alz_archetype` schema describes the computed attributes of the `alz_archetype` data source. " +
+			"The schemas named after the maps of ARM JSON, e.g. `alz_policy_assignments`, describe each decoded value of the map. " +
+			"The version of the schemas is in the `output_schema_version` attribute of the `alz_archetype` data source and in the `$id` of each schema.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "name",
+				MarkdownDescription: "The name of the schema. Must be one of " + strings.Join(backtickAll(outputschema.Names()), ", ") + ".",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *OutputSchemaFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &name))
+	if resp.Error != nil {
+		return
+	}
+
+	b, ok := outputschema.Get(name)
+	if !ok {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("schema %s not found, must be one of: %s", name, strings.Join(outputschema.Names(), ", ")))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, string(b)))
+}
+
+// backtickAll returns the strings formatted as markdown code.
+func backtickAll(s []string) []string {
+	res := make([]string, len(s))
+	for i, v := range s {
+		res[i] = "`" + v + "`"
+	}
+	return res
+}
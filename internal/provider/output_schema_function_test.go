@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/Azure/terraform-provider-alz/internal/outputschema"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runOutputSchemaFunction(name string) *function.RunResponse {
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(name)}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	NewOutputSchemaFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestOutputSchemaFunction(t *testing.T) {
+	resp := runOutputSchemaFunction("alz_policy_assignments")
+	require.Nil(t, resp.Error)
+	s, ok := resp.Result.Value().(types.String)
+	require.True(t, ok)
+	assert.True(t, json.Valid([]byte(s.ValueString())))
+
+	resp = runOutputSchemaFunction("missing")
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "schema missing not found")
+}
+
+// TestOutputSchemaArchetypeAttributes tests that the alz_archetype schema describes exactly the computed attributes of the data source,
+// and that there is a schema for each map of ARM JSON.
+func TestOutputSchemaArchetypeAttributes(t *testing.T) {
+	resp := new(datasource.SchemaResponse)
+	NewArchetypeDataSource().Schema(context.Background(), datasource.SchemaRequest{}, resp)
+	require.False(t, resp.Diagnostics.HasError())
+	computed := make([]string, 0)
+	for name, a := range resp.Schema.Attributes {
+		if a.IsComputed() && !a.IsOptional() && name != "id" {
+			computed = append(computed, name)
+		}
+	}
+	sort.Strings(computed)
+
+	b, ok := outputschema.Get("alz_archetype")
+	require.True(t, ok)
+	var s struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(b, &s))
+	sort.Strings(s.Required)
+	assert.Equal(t, computed, s.Required)
+	assert.Len(t, s.Properties, len(computed))
+
+	jsonMaps := 0
+	for name, raw := range s.Properties {
+		if string(raw) != `{ "$ref": "#/$defs/jsonMap" }` {
+			continue
+		}
+		jsonMaps++
+		_, ok := outputschema.Get(name)
+		assert.True(t, ok, name)
+	}
+	// The other schemas describe the alz_archetype attributes and the alz_compliance_report report.
+	assert.Equal(t, len(outputschema.Names())-2, jsonMaps)
+}
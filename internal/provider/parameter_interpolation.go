@@ -0,0 +1,98 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+// The tokens in policy assignment parameter string values that are replaced with the metadata of the management group.
+const (
+	managementGroupNameToken        = "${management_group_name}"
+	managementGroupDisplayNameToken = "${management_group_display_name}"
+)
+
+// interpolateManagementGroupParameters replaces the management group tokens in the parameter string values of the policy assignments,
+// including strings nested in arrays and objects, e.g. `Contact the platform team for ${management_group_name}`.
+// The assignments with replaced values are replaced in the map, so that the properties shared with alzlib are not modified.
+func interpolateManagementGroupParameters(pas map[string]armpolicy.Assignment, name, displayName string) {
+	r := strings.NewReplacer(managementGroupNameToken, name, managementGroupDisplayNameToken, displayName)
+	for k, pa := range pas {
+		if pa.Properties == nil || len(pa.Properties.Parameters) == 0 {
+			continue
+		}
+		var params map[string]*armpolicy.ParameterValuesValue
+		for param, v := range pa.Properties.Parameters {
+			if v == nil {
+				continue
+			}
+			res, changed := interpolateValue(v.Value, r)
+			if !changed {
+				continue
+			}
+			if params == nil {
+				params = make(map[string]*armpolicy.ParameterValuesValue, len(pa.Properties.Parameters))
+				for p, v := range pa.Properties.Parameters {
+					params[p] = v
+				}
+			}
+			params[param] = &armpolicy.ParameterValuesValue{Value: res}
+		}
+		if params == nil {
+			continue
+		}
+		props := *pa.Properties
+		props.Parameters = params
+		pa.Properties = &props
+		pas[k] = pa
+	}
+}
+
+// interpolateValue returns the value with the tokens replaced in its strings, and true if any string was changed.
+// Arrays and objects are copied if they are changed.
+func interpolateValue(v any, r *strings.Replacer) (any, bool) {
+	switch val := v.(type) {
+	case string:
+		res := r.Replace(val)
+		return res, res != val
+	case []any:
+		var res []any
+		for i, e := range val {
+			e, changed := interpolateValue(e, r)
+			if !changed {
+				continue
+			}
+			if res == nil {
+				res = append([]any(nil), val...)
+			}
+			res[i] = e
+		}
+		if res == nil {
+			return v, false
+		}
+		return res, true
+	case map[string]any:
+		var res map[string]any
+		for k, e := range val {
+			e, changed := interpolateValue(e, r)
+			if !changed {
+				continue
+			}
+			if res == nil {
+				res = make(map[string]any, len(val))
+				for k, e := range val {
+					res[k] = e
+				}
+			}
+			res[k] = e
+		}
+		if res == nil {
+			return v, false
+		}
+		return res, true
+	}
+	return v, false
+}
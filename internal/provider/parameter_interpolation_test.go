@@ -0,0 +1,43 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolateManagementGroupParameters(t *testing.T) {
+	message := &armpolicy.ParameterValuesValue{Value: "Contact the platform team for ${management_group_name} (${management_group_display_name})"}
+	nested := &armpolicy.ParameterValuesValue{Value: map[string]any{"tags": []any{"mg:${management_group_name}", "static"}, "count": float64(1)}}
+	unchanged := &armpolicy.ParameterValuesValue{Value: "westeurope"}
+	shared := &armpolicy.AssignmentProperties{
+		Parameters: map[string]*armpolicy.ParameterValuesValue{"message": message, "nested": nested, "location": unchanged},
+	}
+	static := &armpolicy.AssignmentProperties{
+		Parameters: map[string]*armpolicy.ParameterValuesValue{"location": unchanged},
+	}
+	pas := map[string]armpolicy.Assignment{
+		"tokens": {Name: to.Ptr("tokens"), Properties: shared},
+		"static": {Name: to.Ptr("static"), Properties: static},
+		"none":   {Name: to.Ptr("none")},
+	}
+
+	interpolateManagementGroupParameters(pas, "corp", "Corp")
+
+	params := pas["tokens"].Properties.Parameters
+	assert.Equal(t, "Contact the platform team for corp (Corp)", params["message"].Value)
+	assert.Equal(t, map[string]any{"tags": []any{"mg:corp", "static"}, "count": float64(1)}, params["nested"].Value)
+	assert.Same(t, unchanged, params["location"])
+	assert.Same(t, static, pas["static"].Properties, "assignments without tokens are not copied")
+	assert.Nil(t, pas["none"].Properties)
+
+	// The values shared with alzlib are not modified.
+	assert.Equal(t, "Contact the platform team for ${management_group_name} (${management_group_display_name})", message.Value)
+	assert.Equal(t, []any{"mg:${management_group_name}", "static"}, nested.Value.(map[string]any)["tags"])
+	assert.Same(t, message, shared.Parameters["message"])
+}
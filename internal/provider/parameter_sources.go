@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+// The sources of a policy assignment parameter value, from lowest to highest precedence.
+const (
+	parameterSourceDefinitionDefault      = "definition_default"
+	parameterSourceLibrary                = "library"
+	parameterSourceDefaults               = "defaults"
+	parameterSourceParameterSubstitutions = "parameter_substitutions"
+	parameterSourcePolicyDefaultValues    = "policy_default_values"
+	parameterSourcePolicyAssignmentModify = "policy_assignments_to_modify"
+	parameterSourceFanOut                 = "policy_assignments_to_fan_out"
+)
+
+// policyAssignmentParameterSources returns the source of each parameter value of the policy assignments,
+// keyed by policy assignment name and then parameter name.
+// The values set by the provider `parameter_substitutions` and `policy_default_values`, and by `policy_assignments_to_modify`,
+// are supplied in substituted, defaulted and modified.
+// Other values that differ from the library policy assignment were set from the `defaults` by alzlib.
+// Parameters that are declared by the assigned definition with a default value, but not set by the assignment,
+// are reported as `definition_default`. Definitions that are not in defs, e.g. built-in definitions, are not checked.
+// The values must be compared before they are converted to the declared types, as the library values are not converted.
+func policyAssignmentParameterSources(
+	pas map[string]armpolicy.Assignment,
+	lib *libraryIndex,
+	defs map[string]map[string]*armpolicy.ParameterDefinitionsValue,
+	substituted, defaulted, modified map[string]map[string]*armpolicy.ParameterValuesValue,
+) map[string]map[string]string {
+	res := make(map[string]map[string]string, len(pas))
+	for name, pa := range pas {
+		sources := make(map[string]string)
+		res[name] = sources
+		if pa.Properties == nil {
+			continue
+		}
+		libParams, inLib := lib.PolicyAssignmentParameters(name)
+		for param, v := range pa.Properties.Parameters {
+			switch {
+			case hasParameter(modified[name], param):
+				sources[param] = parameterSourcePolicyAssignmentModify
+			case hasParameter(defaulted[name], param):
+				sources[param] = parameterSourcePolicyDefaultValues
+			case hasParameter(substituted[name], param):
+				sources[param] = parameterSourceParameterSubstitutions
+			case !inLib:
+				sources[param] = parameterSourceLibrary
+			default:
+				sources[param] = parameterSourceDefaults
+				if v == nil {
+					continue
+				}
+				if value, err := normalizedJson(v.Value); err == nil && value == libParams[param] {
+					sources[param] = parameterSourceLibrary
+				}
+			}
+		}
+		if pa.Properties.PolicyDefinitionID == nil {
+			continue
+		}
+		for param, def := range defs[strings.ToLower(*pa.Properties.PolicyDefinitionID)] {
+			if _, ok := sources[param]; ok || def == nil || def.DefaultValue == nil {
+				continue
+			}
+			sources[param] = parameterSourceDefinitionDefault
+		}
+	}
+	return res
+}
+
+// fanOutParameterSources replaces the parameter sources of each fanned out policy assignment with those of its instances.
+// The fan out parameter of each instance is reported as `policy_assignments_to_fan_out`.
+func fanOutParameterSources(sources map[string]map[string]string, fanOuts map[string]policyAssignmentFanOut) {
+	for name, fo := range fanOuts {
+		original, ok := sources[name]
+		if !ok {
+			continue
+		}
+		delete(sources, name)
+		for suffix := range fo.destinations {
+			instance, err := policyAssignmentInstanceName(name, suffix)
+			if err != nil {
+				continue
+			}
+			s := make(map[string]string, len(original)+1)
+			for k, v := range original {
+				s[k] = v
+			}
+			s[fo.parameter] = parameterSourceFanOut
+			sources[instance] = s
+		}
+	}
+}
+
+// hasParameter returns true if the parameter is in the map of parameter values.
+func hasParameter(params map[string]*armpolicy.ParameterValuesValue, name string) bool {
+	_, ok := params[name]
+	return ok
+}
+
+// normalizedJson returns the JSON of the value after a round trip through the JSON decoder,
+// so that values with the same content have the same JSON regardless of their Go types.
+func normalizedJson(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var value any
+	if err := json.Unmarshal(b, &value); err != nil {
+		return "", err
+	}
+	b, err = json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
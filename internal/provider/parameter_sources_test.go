@@ -0,0 +1,134 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAssignmentParameterSources(t *testing.T) {
+	lib, err := newLibraryIndex([]fs.FS{fstest.MapFS{
+		"policy_assignment_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Deploy-Test",
+  "properties": {
+    "parameters": {
+      "effect": { "value": "DeployIfNotExists" },
+      "logAnalytics": { "value": "placeholder" },
+      "retention": { "value": 30 },
+      "region": { "value": "placeholder" },
+      "tags": { "value": { "a": "b" } }
+    }
+  }
+}`)},
+	}})
+	require.NoError(t, err)
+
+	defId := "/providers/Microsoft.Authorization/policyDefinitions/Deploy-Test"
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-Test": {
+			Properties: &armpolicy.AssignmentProperties{
+				PolicyDefinitionID: to.Ptr(defId),
+				Parameters: map[string]*armpolicy.ParameterValuesValue{
+					"effect":       {Value: "DeployIfNotExists"},
+					"logAnalytics": {Value: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/la"},
+					"retention":    {Value: 60},
+					"region":       {Value: "westeurope"},
+					"tags":         {Value: map[string]any{"a": "b"}},
+				},
+			},
+		},
+		"Not-In-Library": {
+			Properties: &armpolicy.AssignmentProperties{
+				Parameters: map[string]*armpolicy.ParameterValuesValue{
+					"effect": {Value: "Audit"},
+				},
+			},
+		},
+		"No-Properties": {},
+	}
+	defs := map[string]map[string]*armpolicy.ParameterDefinitionsValue{
+		"/providers/microsoft.authorization/policydefinitions/deploy-test": {
+			"effect":   {DefaultValue: "Disabled"},
+			"location": {DefaultValue: "uksouth"},
+			"required": {},
+		},
+	}
+	substituted := map[string]map[string]*armpolicy.ParameterValuesValue{
+		"Deploy-Test": {"region": {Value: "westeurope"}, "retention": {Value: 60}},
+	}
+	defaulted := map[string]map[string]*armpolicy.ParameterValuesValue{
+		"Deploy-Test": {"tags": {Value: map[string]any{"a": "b"}}},
+	}
+	modified := map[string]map[string]*armpolicy.ParameterValuesValue{
+		"Deploy-Test": {"retention": {Value: 60}},
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"Deploy-Test": {
+			"effect":       parameterSourceLibrary,
+			"logAnalytics": parameterSourceDefaults,
+			"retention":    parameterSourcePolicyAssignmentModify,
+			"region":       parameterSourceParameterSubstitutions,
+			"tags":         parameterSourcePolicyDefaultValues,
+			"location":     parameterSourceDefinitionDefault,
+		},
+		"Not-In-Library": {
+			"effect": parameterSourceLibrary,
+		},
+		"No-Properties": {},
+	}, policyAssignmentParameterSources(pas, lib, defs, substituted, defaulted, modified))
+}
+
+func TestFanOutParameterSources(t *testing.T) {
+	sources := map[string]map[string]string{
+		"Deploy-AzActivity-Log": {
+			"logAnalytics": parameterSourceDefaults,
+			"effect":       parameterSourceLibrary,
+		},
+		"Other": {
+			"effect": parameterSourceLibrary,
+		},
+	}
+	fanOutParameterSources(sources, map[string]policyAssignmentFanOut{
+		"Deploy-AzActivity-Log": {
+			parameter:    "logAnalytics",
+			destinations: map[string]string{"weu": "la-weu", "neu": "la-neu"},
+		},
+		"Missing": {
+			parameter:    "logAnalytics",
+			destinations: map[string]string{"weu": "la-weu"},
+		},
+	})
+	assert.Equal(t, map[string]map[string]string{
+		"Deploy-AzActivity-Lo-weu": {
+			"logAnalytics": parameterSourceFanOut,
+			"effect":       parameterSourceLibrary,
+		},
+		"Deploy-AzActivity-Lo-neu": {
+			"logAnalytics": parameterSourceFanOut,
+			"effect":       parameterSourceLibrary,
+		},
+		"Other": {
+			"effect": parameterSourceLibrary,
+		},
+	}, sources)
+}
+
+func TestNormalizedJson(t *testing.T) {
+	a, err := normalizedJson(map[string]any{"b": 1, "a": []string{"x"}})
+	require.NoError(t, err)
+	b, err := normalizedJson(map[string]any{"a": []any{"x"}, "b": float64(1)})
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	_, err = normalizedJson(func() {})
+	assert.Error(t, err)
+}
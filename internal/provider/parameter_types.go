@@ -0,0 +1,262 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+)
+
+// policyParameterDefinitions returns the parameter definitions of the policy definitions and policy set definitions
+// in the management groups, keyed by the lower case resource id of the definition.
+func policyParameterDefinitions(mgs []*alzlib.AlzManagementGroup) map[string]map[string]*armpolicy.ParameterDefinitionsValue {
+	res := make(map[string]map[string]*armpolicy.ParameterDefinitionsValue)
+	for _, mg := range mgs {
+		if mg == nil {
+			continue
+		}
+		for _, pd := range mg.GetPolicyDefinitionsMap() {
+			if pd.ID != nil && pd.Properties != nil {
+				res[strings.ToLower(*pd.ID)] = pd.Properties.Parameters
+			}
+		}
+		for _, psd := range mg.GetPolicySetDefinitionsMap() {
+			if psd.ID != nil && psd.Properties != nil {
+				res[strings.ToLower(*psd.ID)] = psd.Properties.Parameters
+			}
+		}
+	}
+	return res
+}
+
+// coercePolicyAssignmentParameters converts the parameter values of the policy assignments to the types declared
+// in the parameters of the assigned definition. Assignments of definitions that are not in defs, e.g. built-in
+// definitions, are not changed.
+// The assignments are replaced in the map, so that the properties shared with alzlib are not modified.
+func coercePolicyAssignmentParameters(pas map[string]armpolicy.Assignment, defs map[string]map[string]*armpolicy.ParameterDefinitionsValue) error {
+	for name, pa := range pas {
+		if pa.Properties == nil || pa.Properties.PolicyDefinitionID == nil || len(pa.Properties.Parameters) == 0 {
+			continue
+		}
+		paramDefs, ok := defs[strings.ToLower(*pa.Properties.PolicyDefinitionID)]
+		if !ok {
+			continue
+		}
+		params := make(map[string]*armpolicy.ParameterValuesValue, len(pa.Properties.Parameters))
+		for param, v := range pa.Properties.Parameters {
+			params[param] = v
+			def, ok := paramDefs[param]
+			if v == nil || !ok || def == nil || def.Type == nil {
+				continue
+			}
+			coerced, err := coerceParameterValue(v.Value, *def.Type)
+			if err != nil {
+				return fmt.Errorf("policy assignment %s: parameter %s: %w", name, param, err)
+			}
+			params[param] = &armpolicy.ParameterValuesValue{Value: coerced}
+		}
+		props := *pa.Properties
+		props.Parameters = params
+		pa.Properties = &props
+		pas[name] = pa
+	}
+	return nil
+}
+
+// coerceParameterValue converts the value to the parameter type.
+// Strings that are ARM template expressions, e.g. `[parameters('effect')]`, are not converted.
+func coerceParameterValue(v any, t armpolicy.ParameterType) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok && isArmExpression(s) {
+		if t != armpolicy.ParameterTypeArray || !json.Valid([]byte(s)) {
+			return v, nil
+		}
+	}
+	switch t {
+	case armpolicy.ParameterTypeString, armpolicy.ParameterTypeDateTime:
+		switch val := v.(type) {
+		case string:
+			return val, nil
+		case bool:
+			return strconv.FormatBool(val), nil
+		case float64:
+			return strconv.FormatFloat(val, 'f', -1, 64), nil
+		}
+	case armpolicy.ParameterTypeInteger:
+		switch val := v.(type) {
+		case float64:
+			if val == math.Trunc(val) {
+				return int64(val), nil
+			}
+		case int, int64:
+			return val, nil
+		case string:
+			if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case armpolicy.ParameterTypeFloat:
+		switch val := v.(type) {
+		case float64, int, int64:
+			return val, nil
+		case string:
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				return f, nil
+			}
+		}
+	case armpolicy.ParameterTypeBoolean:
+		switch val := v.(type) {
+		case bool:
+			return val, nil
+		case string:
+			if b, err := strconv.ParseBool(val); err == nil {
+				return b, nil
+			}
+		}
+	case armpolicy.ParameterTypeArray:
+		switch val := v.(type) {
+		case []any:
+			return val, nil
+		case string:
+			var a []any
+			if err := json.Unmarshal([]byte(val), &a); err == nil {
+				return a, nil
+			}
+		}
+	case armpolicy.ParameterTypeObject:
+		switch val := v.(type) {
+		case map[string]any:
+			return val, nil
+		case string:
+			var o map[string]any
+			if err := json.Unmarshal([]byte(val), &o); err == nil {
+				return o, nil
+			}
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unable to convert value %v of type %T to %s", v, v, t)
+}
+
+// isArmExpression returns true if the string is an ARM template expression.
+// Strings starting with `[[` are escaped literals.
+func isArmExpression(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") && !strings.HasPrefix(s, "[[")
+}
+
+// unwrapParameterValue returns the value of a parameter supplied in the ARM format, `{ "value": ... }`,
+// so that parameters can be supplied either as bare values or in the ARM format.
+// Any other value is returned unchanged. An object parameter whose only property is `value` must therefore be wrapped.
+func unwrapParameterValue(v any) any {
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		if inner, ok := m["value"]; ok {
+			return inner
+		}
+	}
+	return v
+}
+
+// decodeRawParameterValues decodes the raw parameters of a policy assignment.
+// Numbers are decoded as json.Number, so that they are encoded again exactly as supplied.
+// Values are not unwrapped from the ARM format, `{ "value": ... }`, so an object value whose only key is `value` is kept.
+func decodeRawParameterValues(src alztypes.PolicyParameterValue) (map[string]*armpolicy.ParameterValuesValue, error) {
+	if !isKnown(src) {
+		return nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(src.ValueString()))
+	dec.UseNumber()
+	params := make(map[string]any)
+	if err := dec.Decode(&params); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal policy parameters: %w", err)
+	}
+	res := make(map[string]*armpolicy.ParameterValuesValue, len(params))
+	for k, v := range params {
+		res[k] = &armpolicy.ParameterValuesValue{Value: v}
+	}
+	return res, nil
+}
+
+// mergeRawPolicyAssignmentParameters adds the raw parameters to the parameters of a policy assignment modification.
+// A parameter cannot be in both.
+func mergeRawPolicyAssignmentParameters(params map[string]*armpolicy.ParameterValuesValue, raw alztypes.PolicyParameterValue) (map[string]*armpolicy.ParameterValuesValue, error) {
+	rawParams, err := decodeRawParameterValues(raw)
+	if err != nil || len(rawParams) == 0 {
+		return params, err
+	}
+	if params == nil {
+		params = make(map[string]*armpolicy.ParameterValuesValue, len(rawParams))
+	}
+	for k, v := range rawParams {
+		if _, ok := params[k]; ok {
+			return nil, fmt.Errorf("parameter %s is in both parameters and raw_parameters", k)
+		}
+		params[k] = v
+	}
+	return params, nil
+}
+
+// applyRawPolicyAssignmentParameters sets the raw parameters of the modifications on the policy assignments again,
+// after coercePolicyAssignmentParameters, so that they are output exactly as supplied.
+// The assignments are replaced in the map, so that the properties shared with alzlib are not modified.
+func applyRawPolicyAssignmentParameters(pas map[string]armpolicy.Assignment, mods map[string]PolicyAssignmentType) error {
+	for k, v := range mods {
+		pa, ok := pas[k]
+		if !ok || pa.Properties == nil {
+			continue
+		}
+		raw, err := decodeRawParameterValues(v.RawParameters)
+		if err != nil {
+			return fmt.Errorf("policy assignment %s: %w", k, err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		params := make(map[string]*armpolicy.ParameterValuesValue, len(pa.Properties.Parameters))
+		for param, pv := range pa.Properties.Parameters {
+			params[param] = pv
+		}
+		for param, pv := range raw {
+			params[param] = pv
+		}
+		props := *pa.Properties
+		props.Parameters = params
+		pa.Properties = &props
+		pas[k] = pa
+	}
+	return nil
+}
+
+// marshalArmJSON marshals the ARM object without escaping the HTML characters `<`, `>` and `&`,
+// so that ARM template expressions and other strings are output verbatim.
+// The SDK types marshal with the default encoder, so the JSON is decoded and encoded again.
+func marshalArmJSON(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(decoded); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoerceParameterValue(t *testing.T) {
+	cases := []struct {
+		name  string
+		value any
+		typ   armpolicy.ParameterType
+		want  any
+		err   bool
+	}{
+		{"string", "a", armpolicy.ParameterTypeString, "a", false},
+		{"number to string", float64(1.5), armpolicy.ParameterTypeString, "1.5", false},
+		{"bool to string", true, armpolicy.ParameterTypeString, "true", false},
+		{"array to string", []any{"a"}, armpolicy.ParameterTypeString, nil, true},
+		{"integer", float64(3), armpolicy.ParameterTypeInteger, int64(3), false},
+		{"string to integer", "3", armpolicy.ParameterTypeInteger, int64(3), false},
+		{"fraction to integer", float64(3.5), armpolicy.ParameterTypeInteger, nil, true},
+		{"string to float", "3.5", armpolicy.ParameterTypeFloat, float64(3.5), false},
+		{"string to boolean", "true", armpolicy.ParameterTypeBoolean, true, false},
+		{"invalid boolean", "yes", armpolicy.ParameterTypeBoolean, nil, true},
+		{"string to array", `["a", "b"]`, armpolicy.ParameterTypeArray, []any{"a", "b"}, false},
+		{"string to object", `{"a": 1}`, armpolicy.ParameterTypeObject, map[string]any{"a": float64(1)}, false},
+		{"invalid object", "a", armpolicy.ParameterTypeObject, nil, true},
+		{"expression", "[parameters('effect')]", armpolicy.ParameterTypeArray, "[parameters('effect')]", false},
+		{"expression integer", "[parameters('count')]", armpolicy.ParameterTypeInteger, "[parameters('count')]", false},
+		{"escaped expression", "[[literal]", armpolicy.ParameterTypeInteger, nil, true},
+		{"nil", nil, armpolicy.ParameterTypeInteger, nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := coerceParameterValue(tc.value, tc.typ)
+			if tc.err {
+				assert.ErrorContains(t, err, "unable to convert value")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestCoercePolicyAssignmentParameters tests that parameters are converted using the definition parameter types,
+// and that the original assignment properties are not modified.
+func TestCoercePolicyAssignmentParameters(t *testing.T) {
+	defId := "/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policyDefinitions/def"
+	props := &armpolicy.AssignmentProperties{
+		PolicyDefinitionID: to.Ptr(defId),
+		Parameters: map[string]*armpolicy.ParameterValuesValue{
+			"count":   {Value: "5"},
+			"untyped": {Value: "5"},
+		},
+	}
+	pas := map[string]armpolicy.Assignment{
+		"pa":      {Properties: props},
+		"builtin": {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("/providers/Microsoft.Authorization/policyDefinitions/builtin"), Parameters: map[string]*armpolicy.ParameterValuesValue{"count": {Value: "5"}}}},
+	}
+	defs := map[string]map[string]*armpolicy.ParameterDefinitionsValue{
+		"/providers/microsoft.management/managementgroups/test/providers/microsoft.authorization/policydefinitions/def": {
+			"count": {Type: to.Ptr(armpolicy.ParameterTypeInteger)},
+		},
+	}
+	require.NoError(t, coercePolicyAssignmentParameters(pas, defs))
+	assert.Equal(t, int64(5), pas["pa"].Properties.Parameters["count"].Value)
+	assert.Equal(t, "5", pas["pa"].Properties.Parameters["untyped"].Value)
+	assert.Equal(t, "5", pas["builtin"].Properties.Parameters["count"].Value)
+	assert.Equal(t, "5", props.Parameters["count"].Value)
+
+	pas["pa"].Properties.Parameters["count"] = &armpolicy.ParameterValuesValue{Value: "five"}
+	assert.ErrorContains(t, coercePolicyAssignmentParameters(pas, defs), "policy assignment pa: parameter count")
+}
+
+func TestPolicyParameterDefinitions(t *testing.T) {
+	alz := newTestAlzProviderData(t)
+	defs := policyParameterDefinitions([]*alzlib.AlzManagementGroup{alz.Deployment.GetManagementGroup("test"), nil})
+	assert.NotEmpty(t, defs)
+	for id := range defs {
+		assert.Contains(t, id, "/providers/microsoft.management/managementgroups/test/providers/microsoft.authorization/policy")
+	}
+}
+
+// TestArmExpressionParametersVerbatim tests that ARM template expressions in the parameters of a policy assignment
+// are not converted or escaped between the supplied JSON and the output ARM JSON.
+func TestArmExpressionParametersVerbatim(t *testing.T) {
+	defId := "/providers/Microsoft.Management/managementGroups/test/providers/Microsoft.Authorization/policyDefinitions/def"
+	v, _ := alztypes.PolicyParameterType{}.ValueFromString(context.Background(), types.StringValue(`{
+		"effect": "[parameters('effect')]",
+		"name": "[concat(parameters('prefix'), '<&>')]",
+		"count": "[parameters('count')]"
+	}`))
+	params, err := convertPolicyAssignmentParametersToSdkType(v.(alztypes.PolicyParameterValue)) //nolint:forcetypeassert
+	require.NoError(t, err)
+	pas := map[string]armpolicy.Assignment{
+		"pa": {Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr(defId), Parameters: params}},
+	}
+	defs := map[string]map[string]*armpolicy.ParameterDefinitionsValue{
+		"/providers/microsoft.management/managementgroups/test/providers/microsoft.authorization/policydefinitions/def": {
+			"count":  {Type: to.Ptr(armpolicy.ParameterTypeInteger)},
+			"effect": {Type: to.Ptr(armpolicy.ParameterTypeString)},
+			"name":   {Type: to.Ptr(armpolicy.ParameterTypeString)},
+		},
+	}
+	require.NoError(t, coercePolicyAssignmentParameters(pas, defs))
+	b, err := marshalArmJSON(pas["pa"])
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"count":{"value":"[parameters('count')]"}`)
+	assert.Contains(t, string(b), `"effect":{"value":"[parameters('effect')]"}`)
+	assert.Contains(t, string(b), `"name":{"value":"[concat(parameters('prefix'), '<&>')]"}`)
+}
+
+// TestRawPolicyAssignmentParameters tests that raw parameters are merged with the parameters,
+// and are set again after the parameters are converted to the declared types.
+func TestRawPolicyAssignmentParameters(t *testing.T) {
+	raw, _ := alztypes.PolicyParameterType{}.ValueFromString(context.Background(), types.StringValue(`{"count": "[[literal]", "big": 12345678901234567890}`))
+	rawValue := raw.(alztypes.PolicyParameterValue) //nolint:forcetypeassert
+
+	params, err := mergeRawPolicyAssignmentParameters(map[string]*armpolicy.ParameterValuesValue{"effect": {Value: "Audit"}}, rawValue)
+	require.NoError(t, err)
+	assert.Len(t, params, 3)
+	assert.Equal(t, json.Number("12345678901234567890"), params["big"].Value)
+
+	_, err = mergeRawPolicyAssignmentParameters(map[string]*armpolicy.ParameterValuesValue{"count": {Value: 1}}, rawValue)
+	assert.ErrorContains(t, err, "parameter count is in both parameters and raw_parameters")
+
+	params, err = mergeRawPolicyAssignmentParameters(nil, alztypes.PolicyParameterValue{})
+	require.NoError(t, err)
+	assert.Nil(t, params)
+
+	props := &armpolicy.AssignmentProperties{Parameters: map[string]*armpolicy.ParameterValuesValue{"count": {Value: int64(1)}, "effect": {Value: "Audit"}}}
+	pas := map[string]armpolicy.Assignment{"pa": {Properties: props}}
+	require.NoError(t, applyRawPolicyAssignmentParameters(pas, map[string]PolicyAssignmentType{
+		"pa":      {RawParameters: rawValue},
+		"missing": {RawParameters: rawValue},
+	}))
+	assert.Equal(t, "[[literal]", pas["pa"].Properties.Parameters["count"].Value)
+	assert.Equal(t, "Audit", pas["pa"].Properties.Parameters["effect"].Value)
+	assert.Equal(t, int64(1), props.Parameters["count"].Value)
+	b, err := marshalArmJSON(pas["pa"])
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"big":{"value":12345678901234567890}`)
+}
+
+// TestParametersInArmFormat tests that parameter values in the ARM format are unwrapped, and bare values are unchanged.
+// Raw parameters are never unwrapped.
+func TestParametersInArmFormat(t *testing.T) {
+	v, _ := alztypes.PolicyParameterType{}.ValueFromString(context.Background(), types.StringValue(`{
+		"effect": {"value": "Audit"},
+		"count": 2,
+		"tags": {"value": {"value": "wrapped"}},
+		"object": {"value": "x", "other": "y"}
+	}`))
+	src := v.(alztypes.PolicyParameterValue) //nolint:forcetypeassert
+
+	params, err := convertPolicyAssignmentParametersToSdkType(src)
+	require.NoError(t, err)
+	assert.Equal(t, "Audit", params["effect"].Value)
+	assert.Equal(t, float64(2), params["count"].Value)
+	assert.Equal(t, map[string]any{"value": "wrapped"}, params["tags"].Value)
+	assert.Equal(t, map[string]any{"value": "x", "other": "y"}, params["object"].Value)
+
+	raw, err := decodeRawParameterValues(src)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"value": "Audit"}, raw["effect"].Value)
+	assert.Equal(t, json.Number("2"), raw["count"].Value)
+	assert.Equal(t, map[string]any{"value": map[string]any{"value": "wrapped"}}, raw["tags"].Value)
+}
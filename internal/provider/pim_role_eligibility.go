@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	pimRequestTypeAdminAssign    = "AdminAssign"
+	pimExpirationNoExpiration    = "NoExpiration"
+	pimExpirationAfterDuration   = "AfterDuration"
+	pimJustificationPlaceholder  = "Eligible assignment of role %s declared for management group %s"
+	pimRoleEligibilityRequestTag = "pim-eligibility"
+)
+
+// pimDurationRegex matches the ISO 8601 durations accepted by Privileged Identity Management, e.g. `P365D` or `PT8H`.
+var pimDurationRegex = regexp.MustCompile(`^P(?:\d+Y)?(?:\d+M)?(?:\d+W)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+S)?)?$`)
+
+// AlzPimRoleEligibilityRequestType describes a generated Privileged Identity Management role eligibility schedule request
+// from a `role_assignments_to_add` entry with `pim_eligible` set.
+type AlzPimRoleEligibilityRequestType struct {
+	Name             types.String `tfsdk:"name"`
+	PrincipalId      types.String `tfsdk:"principal_id"`
+	RequestBody      types.String `tfsdk:"request_body"`
+	RoleDefinitionId types.String `tfsdk:"role_definition_id"`
+	Scope            types.String `tfsdk:"scope"`
+}
+
+// pimRoleEligibilityRequestBody is the body of a `Microsoft.Authorization/roleEligibilityScheduleRequests` resource.
+type pimRoleEligibilityRequestBody struct {
+	Properties pimRoleEligibilityRequestProperties `json:"properties"`
+}
+
+type pimRoleEligibilityRequestProperties struct {
+	Justification    string                     `json:"justification"`
+	PrincipalId      string                     `json:"principalId"`
+	RequestType      string                     `json:"requestType"`
+	RoleDefinitionId string                     `json:"roleDefinitionId"`
+	ScheduleInfo     pimRoleEligibilitySchedule `json:"scheduleInfo"`
+}
+
+type pimRoleEligibilitySchedule struct {
+	Expiration pimRoleEligibilityExpiration `json:"expiration"`
+}
+
+type pimRoleEligibilityExpiration struct {
+	Duration string `json:"duration,omitempty"`
+	Type     string `json:"type"`
+}
+
+// newPimRoleEligibilityRequest generates the role eligibility schedule request for a role assignment.
+// The request has no start time, so that Azure starts the eligibility when the request is created,
+// and does not expire unless a duration is supplied.
+// If the justification is empty, a placeholder that names the role and management group is used.
+// The request name is a deterministic UUID of the scope, principal and role, so that it is stable between plans.
+func newPimRoleEligibilityRequest(ra AlzRoleAssignmentType, mgName, duration, justification string) (AlzPimRoleEligibilityRequestType, error) {
+	if duration != "" && !validPimDuration(duration) {
+		return AlzPimRoleEligibilityRequestType{}, fmt.Errorf("duration %s is not an ISO 8601 duration, e.g. `P365D`", duration)
+	}
+	if justification == "" {
+		justification = fmt.Sprintf(pimJustificationPlaceholder, resourceIdName(ra.RoleDefinitionId.ValueString()), mgName)
+	}
+	expiration := pimRoleEligibilityExpiration{Type: pimExpirationNoExpiration}
+	if duration != "" {
+		expiration = pimRoleEligibilityExpiration{Type: pimExpirationAfterDuration, Duration: duration}
+	}
+	body, err := json.Marshal(pimRoleEligibilityRequestBody{
+		Properties: pimRoleEligibilityRequestProperties{
+			Justification:    justification,
+			PrincipalId:      ra.PrincipalId.ValueString(),
+			RequestType:      pimRequestTypeAdminAssign,
+			RoleDefinitionId: ra.RoleDefinitionId.ValueString(),
+			ScheduleInfo:     pimRoleEligibilitySchedule{Expiration: expiration},
+		},
+	})
+	if err != nil {
+		return AlzPimRoleEligibilityRequestType{}, fmt.Errorf("unable to marshal role eligibility schedule request: %w", err)
+	}
+	name := uuid.NewSHA1(uuid.NameSpaceURL, []byte(pimRoleEligibilityRequestTag+ra.Scope.ValueString()+ra.PrincipalId.ValueString()+ra.RoleDefinitionId.ValueString()))
+	return AlzPimRoleEligibilityRequestType{
+		Name:             types.StringValue(name.String()),
+		PrincipalId:      ra.PrincipalId,
+		RequestBody:      types.StringValue(string(body)),
+		RoleDefinitionId: ra.RoleDefinitionId,
+		Scope:            ra.Scope,
+	}, nil
+}
+
+// validPimDuration returns true if the duration is an ISO 8601 duration with at least one component.
+func validPimDuration(duration string) bool {
+	return pimDurationRegex.MatchString(duration) && duration != "P" && !strings.HasSuffix(duration, "T")
+}
@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPimRoleEligibilityRequest(t *testing.T) {
+	ra := AlzRoleAssignmentType{
+		PrincipalId:      types.StringValue("11111111-1111-1111-1111-111111111111"),
+		RoleDefinitionId: types.StringValue("/providers/Microsoft.Authorization/roleDefinitions/b24988ac-6180-42a0-ab88-20f7382dd24c"),
+		Scope:            types.StringValue("/providers/Microsoft.Management/managementGroups/corp"),
+	}
+
+	t.Run("NoExpiration", func(t *testing.T) {
+		res, err := newPimRoleEligibilityRequest(ra, "corp", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, ra.Scope, res.Scope)
+		assert.Equal(t, ra.PrincipalId, res.PrincipalId)
+		assert.Equal(t, ra.RoleDefinitionId, res.RoleDefinitionId)
+		assert.JSONEq(t, `{
+			"properties": {
+				"justification": "Eligible assignment of role b24988ac-6180-42a0-ab88-20f7382dd24c declared for management group corp",
+				"principalId": "11111111-1111-1111-1111-111111111111",
+				"requestType": "AdminAssign",
+				"roleDefinitionId": "/providers/Microsoft.Authorization/roleDefinitions/b24988ac-6180-42a0-ab88-20f7382dd24c",
+				"scheduleInfo": {"expiration": {"type": "NoExpiration"}}
+			}
+		}`, res.RequestBody.ValueString())
+
+		again, err := newPimRoleEligibilityRequest(ra, "corp", "P30D", "")
+		require.NoError(t, err)
+		assert.Equal(t, res.Name, again.Name, "the name must not depend on the schedule")
+	})
+
+	t.Run("AfterDuration", func(t *testing.T) {
+		res, err := newPimRoleEligibilityRequest(ra, "corp", "P365D", "Break glass")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"properties": {
+				"justification": "Break glass",
+				"principalId": "11111111-1111-1111-1111-111111111111",
+				"requestType": "AdminAssign",
+				"roleDefinitionId": "/providers/Microsoft.Authorization/roleDefinitions/b24988ac-6180-42a0-ab88-20f7382dd24c",
+				"scheduleInfo": {"expiration": {"type": "AfterDuration", "duration": "P365D"}}
+			}
+		}`, res.RequestBody.ValueString())
+	})
+
+	t.Run("InvalidDuration", func(t *testing.T) {
+		for _, d := range []string{"P", "PT", "365D", "P1DT", "1h"} {
+			_, err := newPimRoleEligibilityRequest(ra, "corp", d, "")
+			assert.ErrorContains(t, err, "is not an ISO 8601 duration", d)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// policyAssignmentDependencies returns the names of the policy assignments that each policy assignment depends on,
+// declared with `depends_on_assignments` in policy_assignments_to_modify. Assignments without dependencies are omitted.
+// A fanned out assignment is replaced by its instances, both as a dependant and as a dependency.
+// Each dependency must be a policy assignment in pas, and the dependencies must not form a cycle.
+func policyAssignmentDependencies(
+	ctx context.Context,
+	src map[string]PolicyAssignmentType,
+	pas map[string]armpolicy.Assignment,
+	fanOuts map[string]policyAssignmentFanOut,
+) (map[string][]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	res := make(map[string][]string)
+	instances := fanOutInstanceNames(fanOuts)
+	expand := func(name string) []string {
+		if names, ok := instances[name]; ok {
+			return names
+		}
+		return []string{name}
+	}
+	for _, name := range sortedKeys(src) {
+		v := src[name]
+		if !isKnown(v.DependsOnAssignments) {
+			continue
+		}
+		p := path.Root("policy_assignments_to_modify").AtMapKey(name).AtName("depends_on_assignments")
+		var dependsOn []string
+		diags.Append(v.DependsOnAssignments.ElementsAs(ctx, &dependsOn, false)...)
+		deps := make([]string, 0, len(dependsOn))
+		for _, dep := range dependsOn {
+			if dep == name {
+				diags.AddAttributeError(p, "Invalid policy assignment dependency", fmt.Sprintf("The policy assignment %s cannot depend on itself.", name))
+				continue
+			}
+			for _, d := range expand(dep) {
+				if _, ok := pas[d]; !ok {
+					diags.AddAttributeError(p, "Policy assignment not found", fmt.Sprintf("The policy assignment %s depends on %s, which is not in the archetype.", name, d))
+					continue
+				}
+				deps = append(deps, d)
+			}
+		}
+		if len(deps) == 0 {
+			continue
+		}
+		sort.Strings(deps)
+		for _, n := range expand(name) {
+			res[n] = deps
+		}
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+	if cycle := dependencyCycle(res); cycle != nil {
+		diags.AddAttributeError(
+			path.Root("policy_assignments_to_modify"),
+			"Policy assignment dependency cycle",
+			fmt.Sprintf("The policy assignment dependencies form a cycle: %s.", strings.Join(cycle, " -> ")),
+		)
+		return nil, diags
+	}
+	return res, diags
+}
+
+// fanOutInstanceNames returns the names of the instances of each fanned out policy assignment.
+func fanOutInstanceNames(fanOuts map[string]policyAssignmentFanOut) map[string][]string {
+	res := make(map[string][]string, len(fanOuts))
+	for name, fo := range fanOuts {
+		for _, suffix := range sortedKeys(fo.destinations) {
+			instance, err := policyAssignmentInstanceName(name, suffix)
+			if err != nil {
+				continue
+			}
+			res[name] = append(res[name], instance)
+		}
+	}
+	return res
+}
+
+// dependencyCycle returns the names in a cycle of the dependency graph, starting and ending with the same name,
+// or nil if there is none. The graph is searched in name order, so that the reported cycle is stable.
+func dependencyCycle(deps map[string][]string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(deps))
+	var stack []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range stack {
+				if n == name {
+					return append(append([]string{}, stack[i:]...), name)
+				}
+			}
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, d := range deps[name] {
+			if cycle := visit(d); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range sortedKeys(deps) {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
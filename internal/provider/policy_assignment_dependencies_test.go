@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dependsOn(names ...string) PolicyAssignmentType {
+	elems := make([]attr.Value, len(names))
+	for i, n := range names {
+		elems[i] = types.StringValue(n)
+	}
+	return PolicyAssignmentType{DependsOnAssignments: types.SetValueMust(types.StringType, elems)}
+}
+
+func TestPolicyAssignmentDependencies(t *testing.T) {
+	ctx := context.Background()
+	pas := map[string]armpolicy.Assignment{
+		"Add-Tag":          {},
+		"Inherit-Tag":      {},
+		"Deploy-Diag-law1": {},
+		"Deploy-Diag-law2": {},
+		"Audit":            {},
+	}
+	fanOuts := map[string]policyAssignmentFanOut{
+		"Deploy-Diag": {parameter: "logAnalytics", destinations: map[string]string{"law1": "a", "law2": "b"}},
+	}
+
+	res, diags := policyAssignmentDependencies(ctx, map[string]PolicyAssignmentType{
+		"Inherit-Tag": dependsOn("Add-Tag"),
+		"Deploy-Diag": dependsOn("Inherit-Tag"),
+		"Audit":       dependsOn("Deploy-Diag"),
+		"Add-Tag":     {},
+	}, pas, fanOuts)
+	require.False(t, diags.HasError(), diags)
+	assert.Equal(t, map[string][]string{
+		"Inherit-Tag":      {"Add-Tag"},
+		"Deploy-Diag-law1": {"Inherit-Tag"},
+		"Deploy-Diag-law2": {"Inherit-Tag"},
+		"Audit":            {"Deploy-Diag-law1", "Deploy-Diag-law2"},
+	}, res)
+
+	_, diags = policyAssignmentDependencies(ctx, map[string]PolicyAssignmentType{
+		"Add-Tag":     dependsOn("Add-Tag"),
+		"Inherit-Tag": dependsOn("Missing"),
+	}, pas, nil)
+	assert.Equal(t, 2, diags.ErrorsCount())
+
+	_, diags = policyAssignmentDependencies(ctx, map[string]PolicyAssignmentType{
+		"Add-Tag":     dependsOn("Audit"),
+		"Audit":       dependsOn("Inherit-Tag"),
+		"Inherit-Tag": dependsOn("Add-Tag"),
+	}, pas, nil)
+	require.Equal(t, 1, diags.ErrorsCount())
+	assert.Contains(t, diags.Errors()[0].Detail(), "Add-Tag -> Audit -> Inherit-Tag -> Add-Tag")
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// policyAssignmentNameMaxLength is the maximum length of the name of a policy assignment at management group scope.
+const policyAssignmentNameMaxLength = 24
+
+// policyAssignmentFanOut is a policy assignment that is split into one instance per destination.
+type policyAssignmentFanOut struct {
+	parameter    string
+	destinations map[string]string // parameter values, keyed by instance name suffix
+}
+
+// policyAssignmentInstanceName returns the name of a fan out instance.
+// The assignment name is truncated so that the instance name fits the management group policy assignment name limit.
+func policyAssignmentInstanceName(name, suffix string) (string, error) {
+	keep := policyAssignmentNameMaxLength - len(suffix) - 1
+	if keep < 1 {
+		return "", fmt.Errorf("suffix %s is too long, policy assignment names are limited to %d characters", suffix, policyAssignmentNameMaxLength)
+	}
+	if len(name) > keep {
+		name = strings.TrimRight(name[:keep], "-")
+	}
+	return name + "-" + suffix, nil
+}
+
+// fanOutPolicyAssignments replaces each fanned out policy assignment in pas with one instance per destination,
+// with the parameter set to the destination. The policy role assignments of the assignment are copied to each instance,
+// and role assignment scopes equal to the original parameter value are replaced by the destination.
+// The parameter must be declared by the assigned definition, if the definition is in defs.
+// It returns the policy role assignments, with those of the fanned out assignments replaced.
+// The assignments are replaced in the map, so that the properties shared with alzlib are not modified.
+func fanOutPolicyAssignments(
+	pas map[string]armpolicy.Assignment,
+	pras []alzlib.PolicyRoleAssignment,
+	defs map[string]map[string]*armpolicy.ParameterDefinitionsValue,
+	fanOuts map[string]policyAssignmentFanOut,
+) ([]alzlib.PolicyRoleAssignment, error) {
+	names := make([]string, 0, len(fanOuts))
+	for name := range fanOuts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	instances := make(map[string]armpolicy.Assignment)
+	instanceRoleAssignments := make([]alzlib.PolicyRoleAssignment, 0)
+	for _, name := range names {
+		fo := fanOuts[name]
+		pa, ok := pas[name]
+		if !ok || pa.Properties == nil {
+			return nil, fmt.Errorf("policy assignment %s not found in the archetype", name)
+		}
+		if pa.Properties.PolicyDefinitionID != nil {
+			if paramDefs, ok := defs[strings.ToLower(*pa.Properties.PolicyDefinitionID)]; ok {
+				if _, ok := paramDefs[fo.parameter]; !ok {
+					return nil, fmt.Errorf("policy assignment %s: parameter %s is not declared by the assigned definition", name, fo.parameter)
+				}
+			}
+		}
+		var original string
+		if v, ok := pa.Properties.Parameters[fo.parameter]; ok && v != nil {
+			original, _ = v.Value.(string)
+		}
+
+		suffixes := make([]string, 0, len(fo.destinations))
+		for suffix := range fo.destinations {
+			suffixes = append(suffixes, suffix)
+		}
+		sort.Strings(suffixes)
+		for _, suffix := range suffixes {
+			dest := fo.destinations[suffix]
+			instanceName, err := policyAssignmentInstanceName(name, suffix)
+			if err != nil {
+				return nil, fmt.Errorf("policy assignment %s: %w", name, err)
+			}
+			if _, exists := instances[instanceName]; exists {
+				return nil, fmt.Errorf("policy assignment %s: instance name %s is used more than once", name, instanceName)
+			}
+			if _, exists := pas[instanceName]; exists {
+				return nil, fmt.Errorf("policy assignment %s: instance name %s is already used by a policy assignment in the archetype", name, instanceName)
+			}
+
+			params := make(map[string]*armpolicy.ParameterValuesValue, len(pa.Properties.Parameters)+1)
+			for k, v := range pa.Properties.Parameters {
+				params[k] = v
+			}
+			params[fo.parameter] = &armpolicy.ParameterValuesValue{Value: dest}
+			props := *pa.Properties
+			props.Parameters = params
+			if props.DisplayName != nil {
+				props.DisplayName = to.Ptr(fmt.Sprintf("%s (%s)", *props.DisplayName, suffix))
+			}
+			instance := pa
+			instance.Properties = &props
+			instance.Name = to.Ptr(instanceName)
+			if pa.ID != nil && pa.Name != nil {
+				instance.ID = to.Ptr(strings.TrimSuffix(*pa.ID, *pa.Name) + instanceName)
+			}
+			instances[instanceName] = instance
+
+			for _, pra := range pras {
+				if pra.AssignmentName != name {
+					continue
+				}
+				pra.AssignmentName = instanceName
+				if original != "" && strings.EqualFold(pra.Scope, original) {
+					pra.Scope = dest
+				}
+				instanceRoleAssignments = append(instanceRoleAssignments, pra)
+			}
+		}
+	}
+
+	res := make([]alzlib.PolicyRoleAssignment, 0, len(pras)+len(instanceRoleAssignments))
+	for _, pra := range pras {
+		if _, ok := fanOuts[pra.AssignmentName]; ok {
+			continue
+		}
+		res = append(res, pra)
+	}
+	for _, name := range names {
+		delete(pas, name)
+	}
+	for k, v := range instances {
+		pas[k] = v
+	}
+	return append(res, instanceRoleAssignments...), nil
+}
+
+// policyAssignmentFanOuts converts the `policy_assignments_to_fan_out` attribute.
+// Policy assignments removed for unavailable resource providers are not fanned out.
+func policyAssignmentFanOuts(ctx context.Context, src map[string]PolicyAssignmentFanOutType, removed libraryContent) (map[string]policyAssignmentFanOut, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	res := make(map[string]policyAssignmentFanOut, len(src))
+	for name, v := range src {
+		if removed.policyAssignments != nil && removed.policyAssignments.Contains(name) {
+			continue
+		}
+		dests := make(map[string]string, len(v.Destinations.Elements()))
+		diags.Append(v.Destinations.ElementsAs(ctx, &dests, false)...)
+		res[name] = policyAssignmentFanOut{
+			parameter:    v.Parameter.ValueString(),
+			destinations: dests,
+		}
+	}
+	return res, diags
+}
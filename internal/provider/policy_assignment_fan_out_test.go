@@ -0,0 +1,142 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAssignmentInstanceName(t *testing.T) {
+	name, err := policyAssignmentInstanceName("Deploy-VM-Monitoring", "weu")
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy-VM-Monitoring-weu", name)
+
+	name, err = policyAssignmentInstanceName("Deploy-AzActivity-Log", "weu")
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy-AzActivity-Lo-weu", name)
+
+	// Trailing hyphens are removed when the name is truncated.
+	name, err = policyAssignmentInstanceName("Deploy-AzActivity-Log", "neu01")
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy-AzActivity-neu01", name)
+
+	_, err = policyAssignmentInstanceName("Deploy-AzActivity-Log", "abcdefghijklmnopqrstuvw")
+	assert.ErrorContains(t, err, "is too long")
+}
+
+// TestFanOutPolicyAssignments tests that the assignment is replaced by instances,
+// and that the role assignments are copied with the destination scopes.
+func TestFanOutPolicyAssignments(t *testing.T) {
+	wsOrig := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/orig"
+	wsWeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/weu"
+	wsNeu := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/neu"
+	props := &armpolicy.AssignmentProperties{
+		DisplayName:        to.Ptr("Deploy VM monitoring"),
+		PolicyDefinitionID: to.Ptr("/providers/Microsoft.Management/managementGroups/root/providers/Microsoft.Authorization/policySetDefinitions/Deploy-VM-Monitoring"),
+		Parameters: map[string]*armpolicy.ParameterValuesValue{
+			"logAnalytics": {Value: wsOrig},
+			"other":        {Value: "x"},
+		},
+	}
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-VM-Monitoring": {
+			Name:       to.Ptr("Deploy-VM-Monitoring"),
+			ID:         to.Ptr("/providers/Microsoft.Management/managementGroups/root/providers/Microsoft.Authorization/policyAssignments/Deploy-VM-Monitoring"),
+			Properties: props,
+		},
+		"Other": {Name: to.Ptr("Other"), Properties: &armpolicy.AssignmentProperties{}},
+	}
+	pras := []alzlib.PolicyRoleAssignment{
+		{AssignmentName: "Deploy-VM-Monitoring", RoleDefinitionId: "contributor", Scope: "/providers/Microsoft.Management/managementGroups/root"},
+		{AssignmentName: "Deploy-VM-Monitoring", RoleDefinitionId: "la-contributor", Scope: wsOrig},
+		{AssignmentName: "Other", RoleDefinitionId: "reader", Scope: "/providers/Microsoft.Management/managementGroups/root"},
+	}
+	defs := map[string]map[string]*armpolicy.ParameterDefinitionsValue{
+		"/providers/microsoft.management/managementgroups/root/providers/microsoft.authorization/policysetdefinitions/deploy-vm-monitoring": {
+			"logAnalytics": {},
+			"other":        {},
+		},
+	}
+	fanOuts := map[string]policyAssignmentFanOut{
+		"Deploy-VM-Monitoring": {parameter: "logAnalytics", destinations: map[string]string{"weu": wsWeu, "neu": wsNeu}},
+	}
+
+	res, err := fanOutPolicyAssignments(pas, pras, defs, fanOuts)
+	require.NoError(t, err)
+	assert.Len(t, pas, 3)
+	assert.NotContains(t, pas, "Deploy-VM-Monitoring")
+	weu := pas["Deploy-VM-Monitoring-weu"]
+	assert.Equal(t, "Deploy-VM-Monitoring-weu", *weu.Name)
+	assert.Equal(t, "/providers/Microsoft.Management/managementGroups/root/providers/Microsoft.Authorization/policyAssignments/Deploy-VM-Monitoring-weu", *weu.ID)
+	assert.Equal(t, "Deploy VM monitoring (weu)", *weu.Properties.DisplayName)
+	assert.Equal(t, wsWeu, weu.Properties.Parameters["logAnalytics"].Value)
+	assert.Equal(t, "x", weu.Properties.Parameters["other"].Value)
+	assert.Equal(t, wsNeu, pas["Deploy-VM-Monitoring-neu"].Properties.Parameters["logAnalytics"].Value)
+	// The properties shared with alzlib are not modified.
+	assert.Equal(t, wsOrig, props.Parameters["logAnalytics"].Value)
+	assert.Equal(t, "Deploy VM monitoring", *props.DisplayName)
+
+	assert.ElementsMatch(t, []alzlib.PolicyRoleAssignment{
+		{AssignmentName: "Other", RoleDefinitionId: "reader", Scope: "/providers/Microsoft.Management/managementGroups/root"},
+		{AssignmentName: "Deploy-VM-Monitoring-neu", RoleDefinitionId: "contributor", Scope: "/providers/Microsoft.Management/managementGroups/root"},
+		{AssignmentName: "Deploy-VM-Monitoring-neu", RoleDefinitionId: "la-contributor", Scope: wsNeu},
+		{AssignmentName: "Deploy-VM-Monitoring-weu", RoleDefinitionId: "contributor", Scope: "/providers/Microsoft.Management/managementGroups/root"},
+		{AssignmentName: "Deploy-VM-Monitoring-weu", RoleDefinitionId: "la-contributor", Scope: wsWeu},
+	}, res)
+}
+
+func TestFanOutPolicyAssignmentsErrors(t *testing.T) {
+	newPas := func() map[string]armpolicy.Assignment {
+		return map[string]armpolicy.Assignment{
+			"a":   {Name: to.Ptr("a"), Properties: &armpolicy.AssignmentProperties{PolicyDefinitionID: to.Ptr("pd")}},
+			"a-x": {Name: to.Ptr("a-x"), Properties: &armpolicy.AssignmentProperties{}},
+		}
+	}
+	defs := map[string]map[string]*armpolicy.ParameterDefinitionsValue{"pd": {"p": {}}}
+
+	_, err := fanOutPolicyAssignments(newPas(), nil, defs, map[string]policyAssignmentFanOut{
+		"missing": {parameter: "p", destinations: map[string]string{"y": "v"}},
+	})
+	assert.ErrorContains(t, err, "policy assignment missing not found")
+
+	_, err = fanOutPolicyAssignments(newPas(), nil, defs, map[string]policyAssignmentFanOut{
+		"a": {parameter: "q", destinations: map[string]string{"y": "v"}},
+	})
+	assert.ErrorContains(t, err, "parameter q is not declared")
+
+	_, err = fanOutPolicyAssignments(newPas(), nil, defs, map[string]policyAssignmentFanOut{
+		"a": {parameter: "p", destinations: map[string]string{"x": "v"}},
+	})
+	assert.ErrorContains(t, err, "already used by a policy assignment")
+}
+
+func TestPolicyAssignmentFanOuts(t *testing.T) {
+	src := map[string]PolicyAssignmentFanOutType{
+		"a": {
+			Parameter:    types.StringValue("p"),
+			Destinations: types.MapValueMust(types.StringType, map[string]attr.Value{"x": types.StringValue("v")}),
+		},
+		"removed": {
+			Parameter:    types.StringValue("p"),
+			Destinations: types.MapValueMust(types.StringType, map[string]attr.Value{"x": types.StringValue("v")}),
+		},
+	}
+	removed := newLibraryContent()
+	removed.policyAssignments = mapset.NewThreadUnsafeSet("removed")
+	res, diags := policyAssignmentFanOuts(context.Background(), src, removed)
+	require.False(t, diags.HasError())
+	assert.Equal(t, map[string]policyAssignmentFanOut{
+		"a": {parameter: "p", destinations: map[string]string{"x": "v"}},
+	}, res)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// policyAssignmentIdentities returns the managed identity of each policy assignment that has one, keyed by policy assignment name.
+// The user assigned identity ids are sorted, so that the order of the identities in the library does not cause a diff.
+func policyAssignmentIdentities(pas map[string]armpolicy.Assignment) map[string]AlzPolicyAssignmentIdentityType {
+	res := make(map[string]AlzPolicyAssignmentIdentityType)
+	for name, pa := range pas {
+		if pa.Identity == nil || pa.Identity.Type == nil || *pa.Identity.Type == armpolicy.ResourceIdentityTypeNone {
+			continue
+		}
+		ids := make([]string, 0, len(pa.Identity.UserAssignedIdentities))
+		for id := range pa.Identity.UserAssignedIdentities {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		elems := make([]attr.Value, len(ids))
+		for i, id := range ids {
+			elems[i] = types.StringValue(id)
+		}
+		res[name] = AlzPolicyAssignmentIdentityType{
+			IdentityIds: types.ListValueMust(types.StringType, elems),
+			Type:        types.StringValue(string(*pa.Identity.Type)),
+		}
+	}
+	return res
+}
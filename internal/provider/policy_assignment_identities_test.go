@@ -0,0 +1,38 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyAssignmentIdentities(t *testing.T) {
+	const id1 = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/a"
+	const id2 = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/b"
+	pas := map[string]armpolicy.Assignment{
+		"system": {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeSystemAssigned)}},
+		"user": {Identity: &armpolicy.Identity{
+			Type:                   to.Ptr(armpolicy.ResourceIdentityTypeUserAssigned),
+			UserAssignedIdentities: map[string]*armpolicy.UserAssignedIdentitiesValue{id2: {}, id1: {}},
+		}},
+		"none":    {Identity: &armpolicy.Identity{Type: to.Ptr(armpolicy.ResourceIdentityTypeNone)}},
+		"missing": {},
+	}
+	assert.Equal(t, map[string]AlzPolicyAssignmentIdentityType{
+		"system": {
+			IdentityIds: types.ListValueMust(types.StringType, []attr.Value{}),
+			Type:        types.StringValue("SystemAssigned"),
+		},
+		"user": {
+			IdentityIds: types.ListValueMust(types.StringType, []attr.Value{types.StringValue(id1), types.StringValue(id2)}),
+			Type:        types.StringValue("UserAssigned"),
+		},
+	}, policyAssignmentIdentities(pas))
+}
@@ -0,0 +1,196 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// libraryPolicyAssignmentApiVersion is the api version of the policy assignment library files.
+const libraryPolicyAssignmentApiVersion = "2022-06-01"
+
+// managementGroupDefinitionIdRegex matches the management group part of a custom policy (set) definition id.
+var managementGroupDefinitionIdRegex = regexp.MustCompile(`(?i)^/providers/Microsoft\.Management/managementGroups/[^/]+(/providers/Microsoft\.Authorization/policy(Set)?Definitions/[^/]+)$`)
+
+// azurermPolicyAssignmentSystemMetadata are the metadata keys set by Azure, which are not part of the library file.
+var azurermPolicyAssignmentSystemMetadata = []string{"createdBy", "createdOn", "updatedBy", "updatedOn"}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &PolicyAssignmentToLibraryFunction{}
+
+func NewPolicyAssignmentToLibraryFunction() function.Function {
+	return &PolicyAssignmentToLibraryFunction{}
+}
+
+// PolicyAssignmentToLibraryFunction converts an azurerm management group policy assignment into a library policy assignment file.
+type PolicyAssignmentToLibraryFunction struct{}
+
+// azurermPolicyAssignment describes the attributes of the azurerm_management_group_policy_assignment resource that are converted.
+type azurermPolicyAssignment struct {
+	Description string `json:"description"`
+	DisplayName string `json:"display_name"`
+	Enforce     *bool  `json:"enforce"`
+	Identity    []struct {
+		IdentityIds []string `json:"identity_ids"`
+		Type        string   `json:"type"`
+	} `json:"identity"`
+	Metadata             string `json:"metadata"`
+	Name                 string `json:"name"`
+	NonComplianceMessage []struct {
+		Content                     string `json:"content"`
+		PolicyDefinitionReferenceId string `json:"policy_definition_reference_id"`
+	} `json:"non_compliance_message"`
+	NotScopes          []string `json:"not_scopes"`
+	Parameters         string   `json:"parameters"`
+	PolicyDefinitionId string   `json:"policy_definition_id"`
+}
+
+func (f *PolicyAssignmentToLibraryFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "policy_assignment_to_library"
+}
+
+func (f *PolicyAssignmentToLibraryFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Convert an azurerm policy assignment into a library policy assignment file",
+		MarkdownDescription: "Converts the JSON of an `azurerm_management_group_policy_assignment` resource into the content of a library policy assignment file, " +
+			"to move hand-written assignments into a library. " +
+			"The input is the resource from `terraform show -json`, the resource instance from the state file, or its attributes, e.g. `jsonencode(azurerm_management_group_policy_assignment.example)`. " +
+			"The location and scope are replaced by the `${default_location}` and `${current_scope_resource_id}` placeholders, " +
+			"and the management group of a custom policy (set) definition id is replaced by `placeholder`, as the library assignments are resolved at the scope of the archetype. " +
+			"The metadata set by Azure, e.g. `createdBy`, is removed. Overrides and resource selectors are not converted. " +
+			"Write the result to a file named `policy_assignment_<name>.json` in a library directory.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "policy_assignment",
+				MarkdownDescription: "The JSON of the `azurerm_management_group_policy_assignment` resource.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *PolicyAssignmentToLibraryFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var src string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &src))
+	if resp.Error != nil {
+		return
+	}
+
+	res, err := policyAssignmentToLibrary([]byte(src))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, string(res)))
+}
+
+// policyAssignmentToLibrary converts the JSON of an azurerm management group policy assignment into an indented library policy assignment file.
+// The attributes may be wrapped in the "values" of a `terraform show -json` resource, or the "attributes" of a state resource instance.
+func policyAssignmentToLibrary(src []byte) ([]byte, error) {
+	src, err := unwrapAzurermAttributes(src)
+	if err != nil {
+		return nil, err
+	}
+	var pa azurermPolicyAssignment
+	if err := json.Unmarshal(src, &pa); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal policy assignment: %w", err)
+	}
+	if pa.Name == "" {
+		return nil, errors.New("policy assignment has no name")
+	}
+	if pa.PolicyDefinitionId == "" {
+		return nil, fmt.Errorf("policy assignment %s has no policy_definition_id", pa.Name)
+	}
+
+	notScopes := make([]string, 0, len(pa.NotScopes))
+	notScopes = append(notScopes, pa.NotScopes...)
+	props := map[string]any{
+		"description":        pa.Description,
+		"displayName":        pa.DisplayName,
+		"enforcementMode":    "Default",
+		"notScopes":          notScopes,
+		"parameters":         map[string]any{},
+		"policyDefinitionId": managementGroupDefinitionIdRegex.ReplaceAllString(pa.PolicyDefinitionId, "/providers/Microsoft.Management/managementGroups/placeholder$1"),
+		"scope":              "${current_scope_resource_id}",
+	}
+	if pa.Enforce != nil && !*pa.Enforce {
+		props["enforcementMode"] = "DoNotEnforce"
+	}
+	if pa.Parameters != "" {
+		var params map[string]any
+		if err := json.Unmarshal([]byte(pa.Parameters), &params); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal parameters of policy assignment %s: %w", pa.Name, err)
+		}
+		props["parameters"] = params
+	}
+	if pa.Metadata != "" {
+		var metadata map[string]any
+		if err := json.Unmarshal([]byte(pa.Metadata), &metadata); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal metadata of policy assignment %s: %w", pa.Name, err)
+		}
+		for _, k := range azurermPolicyAssignmentSystemMetadata {
+			delete(metadata, k)
+		}
+		if len(metadata) != 0 {
+			props["metadata"] = metadata
+		}
+	}
+	if len(pa.NonComplianceMessage) != 0 {
+		msgs := make([]map[string]any, len(pa.NonComplianceMessage))
+		for i, m := range pa.NonComplianceMessage {
+			msgs[i] = map[string]any{"message": m.Content}
+			if m.PolicyDefinitionReferenceId != "" {
+				msgs[i]["policyDefinitionReferenceId"] = m.PolicyDefinitionReferenceId
+			}
+		}
+		props["nonComplianceMessages"] = msgs
+	}
+
+	res := map[string]any{
+		"type":       "Microsoft.Authorization/policyAssignments",
+		"apiVersion": libraryPolicyAssignmentApiVersion,
+		"name":       pa.Name,
+		"dependsOn":  []string{},
+		"location":   "${default_location}",
+		"properties": props,
+	}
+	if len(pa.Identity) != 0 && pa.Identity[0].Type != "" {
+		identity := map[string]any{"type": pa.Identity[0].Type}
+		if len(pa.Identity[0].IdentityIds) != 0 {
+			ids := make(map[string]any, len(pa.Identity[0].IdentityIds))
+			for _, id := range pa.Identity[0].IdentityIds {
+				ids[id] = map[string]any{}
+			}
+			identity["userAssignedIdentities"] = ids
+		}
+		res["identity"] = identity
+	}
+	return json.MarshalIndent(res, "", "  ")
+}
+
+// unwrapAzurermAttributes returns the attributes of a resource in the `terraform show -json` or state file format,
+// or the source if it is not wrapped.
+func unwrapAzurermAttributes(src []byte) ([]byte, error) {
+	var wrapped map[string]json.RawMessage
+	if err := json.Unmarshal(src, &wrapped); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal policy assignment: %w", err)
+	}
+	if _, ok := wrapped["policy_definition_id"]; ok {
+		return src, nil
+	}
+	for _, k := range []string{"values", "attributes"} {
+		if v, ok := wrapped[k]; ok {
+			return v, nil
+		}
+	}
+	return src, nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runPolicyAssignmentToLibraryFunction(src string) *function.RunResponse {
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(src)}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	NewPolicyAssignmentToLibraryFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestPolicyAssignmentToLibraryFunction(t *testing.T) {
+	const attributes = `{
+  "id": "/providers/Microsoft.Management/managementGroups/corp/providers/Microsoft.Authorization/policyAssignments/Deny-Public-IP",
+  "name": "Deny-Public-IP",
+  "management_group_id": "/providers/Microsoft.Management/managementGroups/corp",
+  "display_name": "Deny public IP addresses",
+  "description": "Denies the creation of public IP addresses.",
+  "enforce": false,
+  "location": "uksouth",
+  "metadata": "{\"category\":\"Network\",\"createdBy\":\"00000000-0000-0000-0000-000000000000\",\"createdOn\":\"2024-01-01T00:00:00Z\"}",
+  "not_scopes": ["/providers/Microsoft.Management/managementGroups/sandbox"],
+  "parameters": "{\"effect\":{\"value\":\"Deny\"}}",
+  "policy_definition_id": "/providers/Microsoft.Management/managementGroups/corp/providers/Microsoft.Authorization/policyDefinitions/Deny-Public-IP",
+  "non_compliance_message": [{"content": "Public IP addresses are not allowed.", "policy_definition_reference_id": ""}],
+  "identity": [{"type": "SystemAssigned", "identity_ids": [], "principal_id": "p", "tenant_id": "t"}]
+}`
+	const expected = `{
+  "apiVersion": "2022-06-01",
+  "dependsOn": [],
+  "identity": {
+    "type": "SystemAssigned"
+  },
+  "location": "${default_location}",
+  "name": "Deny-Public-IP",
+  "properties": {
+    "description": "Denies the creation of public IP addresses.",
+    "displayName": "Deny public IP addresses",
+    "enforcementMode": "DoNotEnforce",
+    "metadata": {
+      "category": "Network"
+    },
+    "nonComplianceMessages": [
+      {
+        "message": "Public IP addresses are not allowed."
+      }
+    ],
+    "notScopes": [
+      "/providers/Microsoft.Management/managementGroups/sandbox"
+    ],
+    "parameters": {
+      "effect": {
+        "value": "Deny"
+      }
+    },
+    "policyDefinitionId": "/providers/Microsoft.Management/managementGroups/placeholder/providers/Microsoft.Authorization/policyDefinitions/Deny-Public-IP",
+    "scope": "${current_scope_resource_id}"
+  },
+  "type": "Microsoft.Authorization/policyAssignments"
+}`
+
+	// Attributes, `terraform show -json` and state resource instance.
+	for _, src := range []string{
+		attributes,
+		`{"address": "azurerm_management_group_policy_assignment.example", "values": ` + attributes + `}`,
+		`{"schema_version": 0, "attributes": ` + attributes + `}`,
+	} {
+		resp := runPolicyAssignmentToLibraryFunction(src)
+		require.Nil(t, resp.Error)
+		assert.JSONEq(t, expected, resp.Result.Value().(types.String).ValueString())
+	}
+
+	// Built-in definition with a user assigned identity.
+	resp := runPolicyAssignmentToLibraryFunction(`{
+  "name": "Audit-Locations",
+  "policy_definition_id": "/providers/Microsoft.Authorization/policyDefinitions/e56962a6-4747-49cd-b67b-bf8b01975c4c",
+  "identity": [{"type": "UserAssigned", "identity_ids": ["/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id"]}]
+}`)
+	require.Nil(t, resp.Error)
+	assert.JSONEq(t, `{
+  "apiVersion": "2022-06-01",
+  "dependsOn": [],
+  "identity": {
+    "type": "UserAssigned",
+    "userAssignedIdentities": {
+      "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id": {}
+    }
+  },
+  "location": "${default_location}",
+  "name": "Audit-Locations",
+  "properties": {
+    "description": "",
+    "displayName": "",
+    "enforcementMode": "Default",
+    "notScopes": [],
+    "parameters": {},
+    "policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/e56962a6-4747-49cd-b67b-bf8b01975c4c",
+    "scope": "${current_scope_resource_id}"
+  },
+  "type": "Microsoft.Authorization/policyAssignments"
+}`, resp.Result.Value().(types.String).ValueString())
+
+	// Errors
+	resp = runPolicyAssignmentToLibraryFunction(`{"policy_definition_id": "/providers/Microsoft.Authorization/policyDefinitions/x"}`)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "policy assignment has no name")
+
+	resp = runPolicyAssignmentToLibraryFunction(`{"name": "a", "policy_definition_id": "x", "parameters": "{"}`)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "unable to unmarshal parameters of policy assignment a")
+
+	resp = runPolicyAssignmentToLibraryFunction(`[]`)
+	require.NotNil(t, resp.Error)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+// checkPolicyDefaultValues returns an error if a policy default value is not declared in the libraries.
+func checkPolicyDefaultValues(values map[string]*armpolicy.ParameterValuesValue, library *libraryIndex) error {
+	names := library.PolicyDefaultValueNames()
+	unknown := make([]string, 0)
+	for _, k := range sortedKeys(values) {
+		if library.PolicyDefaultValueParameters(k) == nil {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("the policy default values %s are not declared, the libraries have no policy default values", strings.Join(unknown, ", "))
+	}
+	return fmt.Errorf("the policy default values %s are not declared in the libraries, must be one of: %s", strings.Join(unknown, ", "), strings.Join(names, ", "))
+}
+
+// policyDefaultValuesForAssignments returns the parameter values to set for each policy assignment,
+// from the policy default values and the parameters that the libraries declare to accept each default.
+// Policy assignments that are not in the management group are ignored.
+func policyDefaultValuesForAssignments(pas map[string]armpolicy.Assignment, values map[string]*armpolicy.ParameterValuesValue, library *libraryIndex) map[string]map[string]*armpolicy.ParameterValuesValue {
+	if len(values) == 0 {
+		return nil
+	}
+	res := make(map[string]map[string]*armpolicy.ParameterValuesValue)
+	for _, name := range sortedKeys(values) {
+		for pa, params := range library.PolicyDefaultValueParameters(name) {
+			if _, ok := pas[pa]; !ok {
+				continue
+			}
+			if _, ok := res[pa]; !ok {
+				res[pa] = make(map[string]*armpolicy.ParameterValuesValue)
+			}
+			for _, param := range params {
+				res[pa][param] = values[name]
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyDefaultValues(t *testing.T) {
+	lib1 := fstest.MapFS{
+		"alz_policy_default_values.json": &fstest.MapFile{Data: []byte(`{
+  "defaults": [
+    {
+      "default_name": "ama_user_assigned_managed_identity_id",
+      "policy_assignments": [
+        { "policy_assignment_name": "Deploy-VM-Monitoring", "parameter_names": ["userAssignedIdentityResourceId"] },
+        { "policy_assignment_name": "Deploy-VMSS-Monitoring", "parameter_names": ["userAssignedIdentityResourceId"] }
+      ]
+    },
+    {
+      "default_name": "log_analytics_workspace_id",
+      "policy_assignments": [
+        { "policy_assignment_name": "Deploy-AzActivity-Log", "parameter_names": ["logAnalytics"] }
+      ]
+    }
+  ]
+}`)},
+	}
+	lib2 := fstest.MapFS{
+		"custom.policy_default_values.json": &fstest.MapFile{Data: []byte(`{
+  "defaults": [
+    {
+      "default_name": "log_analytics_workspace_id",
+      "policy_assignments": [
+        { "policy_assignment_name": "Deploy-Custom-Diag", "parameter_names": ["workspaceId", "logAnalytics"] }
+      ]
+    }
+  ]
+}`)},
+	}
+	library, err := newLibraryIndex([]fs.FS{lib1, lib2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ama_user_assigned_managed_identity_id", "log_analytics_workspace_id"}, library.PolicyDefaultValueNames())
+
+	law := &armpolicy.ParameterValuesValue{Value: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.OperationalInsights/workspaces/law"}
+	values := map[string]*armpolicy.ParameterValuesValue{"log_analytics_workspace_id": law}
+	require.NoError(t, checkPolicyDefaultValues(values, library))
+
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-AzActivity-Log": {},
+		"Deploy-Custom-Diag":    {},
+		"Deploy-VM-Monitoring":  {},
+	}
+	assert.Equal(t, map[string]map[string]*armpolicy.ParameterValuesValue{
+		"Deploy-AzActivity-Log": {"logAnalytics": law},
+		"Deploy-Custom-Diag":    {"workspaceId": law, "logAnalytics": law},
+	}, policyDefaultValuesForAssignments(pas, values, library))
+	assert.Nil(t, policyDefaultValuesForAssignments(pas, nil, library))
+
+	values["missing"] = law
+	err = checkPolicyDefaultValues(values, library)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the policy default values missing are not declared in the libraries")
+}
@@ -0,0 +1,209 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &PolicyDefinitionSearchDataSource{}
+
+func NewPolicyDefinitionSearchDataSource() datasource.DataSource {
+	return &PolicyDefinitionSearchDataSource{}
+}
+
+// PolicyDefinitionSearchDataSource defines the data source implementation.
+type PolicyDefinitionSearchDataSource struct {
+	alz *alzProviderData
+}
+
+// PolicyDefinitionSearchDataSourceModel describes the data source data model.
+type PolicyDefinitionSearchDataSourceModel struct {
+	Category            types.String                                    `tfsdk:"category"`
+	DisplayNameContains types.String                                    `tfsdk:"display_name_contains"`
+	Effect              types.String                                    `tfsdk:"effect"`
+	Id                  types.String                                    `tfsdk:"id"`
+	Names               types.Set                                       `tfsdk:"names"` // set of string
+	PolicyDefinitions   map[string]PolicyDefinitionSearchDefinitionType `tfsdk:"policy_definitions"`
+}
+
+// PolicyDefinitionSearchDefinitionType is the metadata of a matching policy definition.
+type PolicyDefinitionSearchDefinitionType struct {
+	Category    types.String `tfsdk:"category"`
+	DisplayName types.String `tfsdk:"display_name"`
+	Effects     types.Set    `tfsdk:"effects"` // set of string
+}
+
+func (d *PolicyDefinitionSearchDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_policy_definition_search"
+}
+
+func (d *PolicyDefinitionSearchDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Policy definition search data source. Searches the policy definitions in the provider libraries by category, display name or effect, to help build custom archetypes. " +
+			"All supplied criteria must match, and the matching is case-insensitive. If no criteria are supplied, all policy definitions are returned.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"category": schema.StringAttribute{
+				MarkdownDescription: "The `metadata.category` of the policy definition, e.g. `Monitoring`.",
+				Optional:            true,
+			},
+
+			"display_name_contains": schema.StringAttribute{
+				MarkdownDescription: "A substring of the display name of the policy definition.",
+				Optional:            true,
+			},
+
+			"effect": schema.StringAttribute{
+				MarkdownDescription: "An effect of the policy definition, e.g. `DeployIfNotExists`. " +
+					"If the effect of the policy definition is a parameter, the allowed values and default value of the parameter are its effects.",
+				Optional: true,
+			},
+
+			"names": schema.SetAttribute{
+				MarkdownDescription: "The names of the matching policy definitions, for use in custom archetype definitions.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+
+			"policy_definitions": schema.MapNestedAttribute{
+				MarkdownDescription: "The metadata of the matching policy definitions, keyed by name.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"category": schema.StringAttribute{
+							MarkdownDescription: "The `metadata.category` of the policy definition.",
+							Computed:            true,
+						},
+						"display_name": schema.StringAttribute{
+							MarkdownDescription: "The display name of the policy definition.",
+							Computed:            true,
+						},
+						"effects": schema.SetAttribute{
+							MarkdownDescription: "The possible effects of the policy definition.",
+							Computed:            true,
+							ElementType:         types.StringType,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *PolicyDefinitionSearchDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *PolicyDefinitionSearchDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data PolicyDefinitionSearchDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.alz.mu.Lock()
+	defer d.alz.mu.Unlock()
+
+	if d.alz.library == nil {
+		resp.Diagnostics.AddError("Library index not found", "The library index has not been created. Please report this issue to the provider developers.")
+		return
+	}
+
+	metadata := d.alz.library.PolicyDefinitionMetadata()
+	names := searchPolicyDefinitions(metadata, data.Category.ValueString(), data.DisplayNameContains.ValueString(), data.Effect.ValueString())
+
+	var diags diag.Diagnostics
+	data.Names, diags = types.SetValueFrom(ctx, types.StringType, names)
+	resp.Diagnostics.Append(diags...)
+	data.PolicyDefinitions = make(map[string]PolicyDefinitionSearchDefinitionType, len(names))
+	for _, name := range names {
+		md := metadata[name]
+		effects, diags := types.SetValueFrom(ctx, types.StringType, md.Effects)
+		resp.Diagnostics.Append(diags...)
+		data.PolicyDefinitions[name] = PolicyDefinitionSearchDefinitionType{
+			Category:    types.StringValue(md.Category),
+			DisplayName: types.StringValue(md.DisplayName),
+			Effects:     effects,
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", data.Category.ValueString(), data.DisplayNameContains.ValueString(), data.Effect.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// searchPolicyDefinitions returns the sorted names of the policy definitions that match all of the non-empty criteria.
+// The category and effect must be equal, and the display name must contain the supplied substring, ignoring case.
+func searchPolicyDefinitions(metadata map[string]libraryPolicyDefinitionMetadata, category, displayNameContains, effect string) []string {
+	res := make([]string, 0)
+	displayNameContains = strings.ToLower(displayNameContains)
+	for name, md := range metadata {
+		if category != "" && !strings.EqualFold(md.Category, category) {
+			continue
+		}
+		if displayNameContains != "" && !strings.Contains(strings.ToLower(md.DisplayName), displayNameContains) {
+			continue
+		}
+		if effect != "" && !containsFold(md.Effects, effect) {
+			continue
+		}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// containsFold returns true if the slice contains the string, ignoring case.
+func containsFold(s []string, v string) bool {
+	for _, e := range s {
+		if strings.EqualFold(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPolicyDefinitions(t *testing.T) {
+	metadata := map[string]libraryPolicyDefinitionMetadata{
+		"Deploy-VM-Monitoring": {Category: "Monitoring", DisplayName: "Deploy VM monitoring", Effects: []string{"DeployIfNotExists", "Disabled"}},
+		"Deny-Public-IP":       {Category: "Network", DisplayName: "Deny public IP addresses", Effects: []string{"Deny"}},
+		"Audit-Monitoring":     {Category: "Monitoring", DisplayName: "Audit diagnostic settings", Effects: []string{"AuditIfNotExists"}},
+	}
+
+	assert.Equal(t, []string{"Audit-Monitoring", "Deny-Public-IP", "Deploy-VM-Monitoring"}, searchPolicyDefinitions(metadata, "", "", ""))
+	assert.Equal(t, []string{"Audit-Monitoring", "Deploy-VM-Monitoring"}, searchPolicyDefinitions(metadata, "monitoring", "", ""))
+	assert.Equal(t, []string{"Deny-Public-IP"}, searchPolicyDefinitions(metadata, "", "PUBLIC ip", ""))
+	assert.Equal(t, []string{"Deploy-VM-Monitoring"}, searchPolicyDefinitions(metadata, "Monitoring", "", "deployifnotexists"))
+	assert.Empty(t, searchPolicyDefinitions(metadata, "Network", "", "DeployIfNotExists"))
+}
@@ -0,0 +1,351 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &PolicyExemptionsDataSource{}
+
+func NewPolicyExemptionsDataSource() datasource.DataSource {
+	return &PolicyExemptionsDataSource{}
+}
+
+// PolicyExemptionsDataSource defines the data source implementation.
+type PolicyExemptionsDataSource struct {
+	alz *alzProviderData
+}
+
+// PolicyExemptionsDataSourceModel describes the data source data model.
+type PolicyExemptionsDataSourceModel struct {
+	AlzPolicyExemptions map[string]AlzPolicyExemptionType `tfsdk:"alz_policy_exemptions"`
+	Id                  types.String                      `tfsdk:"id"`
+	Rules               []PolicyExemptionRuleType         `tfsdk:"rules"`
+	SubscriptionTags    types.Map                         `tfsdk:"subscription_tags"` // map of map of string
+}
+
+// PolicyExemptionRuleType describes a rule that exempts subscriptions with matching tags from policy assignments.
+type PolicyExemptionRuleType struct {
+	Description                  types.String `tfsdk:"description"`
+	ExemptionCategory            types.String `tfsdk:"exemption_category"`
+	ManagementGroupId            types.String `tfsdk:"management_group_id"`
+	Name                         types.String `tfsdk:"name"`
+	PolicyAssignmentNames        types.Set    `tfsdk:"policy_assignment_names"`         // set of string
+	PolicyDefinitionReferenceIds types.Set    `tfsdk:"policy_definition_reference_ids"` // set of string
+	Tags                         types.Map    `tfsdk:"tags"`                            // map of string
+}
+
+// AlzPolicyExemptionType is a generated policy exemption.
+type AlzPolicyExemptionType struct {
+	PolicyExemption types.String `tfsdk:"policy_exemption"`
+	Scope           types.String `tfsdk:"scope"`
+}
+
+// policyExemptionRule is the Go representation of PolicyExemptionRuleType.
+type policyExemptionRule struct {
+	description                  string
+	exemptionCategory            string
+	managementGroupId            string
+	name                         string
+	policyAssignmentNames        []string
+	policyDefinitionReferenceIds []string
+	tags                         map[string]string
+}
+
+// policyExemption is a generated policy exemption and the scope at which it should be created.
+type policyExemption struct {
+	exemption *armpolicy.Exemption
+	scope     string
+}
+
+func (d *PolicyExemptionsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_policy_exemptions"
+}
+
+func (d *PolicyExemptionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Policy exemptions data source. Generates policy exemptions for subscriptions whose tags match a rule, e.g. exempting `env = sandbox` subscriptions from a deny assignment. " +
+			"The management groups referenced by the rules must have been added to the deployment by an `alz_archetype` data source.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"subscription_tags": schema.MapAttribute{
+				MarkdownDescription: "A map of subscription ids to the tags of the subscription.",
+				Required:            true,
+				ElementType:         types.MapType{ElemType: types.StringType},
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(
+						stringvalidator.RegexMatches(regexp.MustCompile(`^[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}$`), "The subscription id must be a valid lowercase UUID."),
+					),
+				},
+			},
+
+			"rules": schema.ListNestedAttribute{
+				MarkdownDescription: "The rules used to generate the exemptions. A subscription matches a rule if it has all of the tags in the rule.",
+				Required:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "The name of the rule. Used to generate the exemption names, so should not be changed once deployed.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.LengthAtLeast(1),
+							},
+						},
+
+						"tags": schema.MapAttribute{
+							MarkdownDescription: "The tags that a subscription must have to match the rule. Tag names are compared case-insensitively and values are compared case-sensitively.",
+							Required:            true,
+							ElementType:         types.StringType,
+							Validators: []validator.Map{
+								mapvalidator.SizeAtLeast(1),
+							},
+						},
+
+						"management_group_id": schema.StringAttribute{
+							MarkdownDescription: "The name of the management group where the policy assignments are assigned.",
+							Required:            true,
+						},
+
+						"policy_assignment_names": schema.SetAttribute{
+							MarkdownDescription: "The names of the policy assignments to exempt the matching subscriptions from.",
+							Required:            true,
+							ElementType:         types.StringType,
+							Validators: []validator.Set{
+								setvalidator.SizeAtLeast(1),
+							},
+						},
+
+						"policy_definition_reference_ids": schema.SetAttribute{
+							MarkdownDescription: "The policy definition reference ids to exempt, when the policy assignment is of a policy set definition. If not specified, the whole assignment is exempted.",
+							Optional:            true,
+							ElementType:         types.StringType,
+						},
+
+						"exemption_category": schema.StringAttribute{
+							MarkdownDescription: "The exemption category. Must be one of `Waiver` or `Mitigated`. Default is `Waiver`.",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf("Waiver", "Mitigated"),
+							},
+						},
+
+						"description": schema.StringAttribute{
+							MarkdownDescription: "The description of the generated exemptions.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+
+			"alz_policy_exemptions": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of generated policy exemptions, keyed by the exemption name.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"policy_exemption": schema.StringAttribute{
+							MarkdownDescription: "The policy exemption as ARM JSON.",
+							Computed:            true,
+						},
+
+						"scope": schema.StringAttribute{
+							MarkdownDescription: "The resource id of the subscription where the exemption should be created.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *PolicyExemptionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *PolicyExemptionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data PolicyExemptionsDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	subTags := make(map[string]map[string]string)
+	resp.Diagnostics.Append(data.SubscriptionTags.ElementsAs(ctx, &subTags, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rules := make([]policyExemptionRule, len(data.Rules))
+	for i, r := range data.Rules {
+		rule := policyExemptionRule{
+			description:       r.Description.ValueString(),
+			exemptionCategory: r.ExemptionCategory.ValueString(),
+			managementGroupId: r.ManagementGroupId.ValueString(),
+			name:              r.Name.ValueString(),
+		}
+		resp.Diagnostics.Append(r.PolicyAssignmentNames.ElementsAs(ctx, &rule.policyAssignmentNames, false)...)
+		if isKnown(r.PolicyDefinitionReferenceIds) {
+			resp.Diagnostics.Append(r.PolicyDefinitionReferenceIds.ElementsAs(ctx, &rule.policyDefinitionReferenceIds, false)...)
+		}
+		resp.Diagnostics.Append(r.Tags.ElementsAs(ctx, &rule.tags, false)...)
+		rules[i] = rule
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.alz.mu.Lock()
+	defer d.alz.mu.Unlock()
+
+	exemptions, err := generatePolicyExemptions(subTags, rules, d.policyAssignmentId)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to generate policy exemptions", err.Error())
+		return
+	}
+
+	data.AlzPolicyExemptions = make(map[string]AlzPolicyExemptionType, len(exemptions))
+	names := make([]string, 0, len(exemptions))
+	for name, e := range exemptions {
+		b, err := json.Marshal(e.exemption)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to marshal policy exemption", err.Error())
+			return
+		}
+		data.AlzPolicyExemptions[name] = AlzPolicyExemptionType{
+			PolicyExemption: types.StringValue(string(b)),
+			Scope:           types.StringValue(e.scope),
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data.Id = types.StringValue(uuid.NewSHA1(uuid.NameSpaceURL, []byte(strings.Join(names, ","))).String())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// policyAssignmentId returns the resource id of the policy assignment in the deployment management group.
+func (d *PolicyExemptionsDataSource) policyAssignmentId(mgname, name string) (string, error) {
+	mg := d.alz.Deployment.GetManagementGroup(mgname)
+	if mg == nil {
+		return "", fmt.Errorf("management group %s not found, it must be added by an `alz_archetype` data source first", mgname)
+	}
+	pa, ok := mg.GetPolicyAssignmentMap()[name]
+	if !ok || pa.ID == nil {
+		return "", fmt.Errorf("policy assignment %s not found in management group %s", name, mgname)
+	}
+	return *pa.ID, nil
+}
+
+// generatePolicyExemptions generates a policy exemption for each subscription, rule and policy assignment where the subscription tags match the rule.
+// The assignmentId function returns the resource id of a policy assignment given the management group and assignment name.
+func generatePolicyExemptions(subscriptionTags map[string]map[string]string, rules []policyExemptionRule, assignmentId func(mg, name string) (string, error)) (map[string]policyExemption, error) {
+	res := make(map[string]policyExemption)
+	for _, rule := range rules {
+		category := armpolicy.ExemptionCategoryWaiver
+		if rule.exemptionCategory != "" {
+			category = armpolicy.ExemptionCategory(rule.exemptionCategory)
+		}
+		for sub, tags := range subscriptionTags {
+			if !tagsMatch(tags, rule.tags) {
+				continue
+			}
+			for _, paName := range rule.policyAssignmentNames {
+				paId, err := assignmentId(rule.managementGroupId, paName)
+				if err != nil {
+					return nil, fmt.Errorf("rule %s: %w", rule.name, err)
+				}
+				name := uuid.NewSHA1(uuid.NameSpaceURL, []byte(rule.name+sub+paId)).String()
+				e := &armpolicy.Exemption{
+					Name: to.Ptr(name),
+					Type: to.Ptr("Microsoft.Authorization/policyExemptions"),
+					Properties: &armpolicy.ExemptionProperties{
+						ExemptionCategory:  to.Ptr(category),
+						PolicyAssignmentID: to.Ptr(paId),
+						DisplayName:        to.Ptr(fmt.Sprintf("%s - %s", rule.name, paName)),
+					},
+				}
+				if rule.description != "" {
+					e.Properties.Description = to.Ptr(rule.description)
+				}
+				if len(rule.policyDefinitionReferenceIds) != 0 {
+					e.Properties.PolicyDefinitionReferenceIDs = to.SliceOfPtrs(rule.policyDefinitionReferenceIds...)
+				}
+				res[name] = policyExemption{
+					exemption: e,
+					scope:     "/subscriptions/" + sub,
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// tagsMatch returns true if all of the wanted tags are present in the tags.
+// Tag names are case-insensitive in Azure, tag values are case-sensitive.
+func tagsMatch(tags, want map[string]string) bool {
+	if len(want) == 0 {
+		return false
+	}
+	lower := make(map[string]string, len(tags))
+	for k, v := range tags {
+		lower[strings.ToLower(k)] = v
+	}
+	for k, v := range want {
+		got, ok := lower[strings.ToLower(k)]
+		if !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
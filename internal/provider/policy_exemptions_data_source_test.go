@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneratePolicyExemptions tests that exemptions are generated only for subscriptions with matching tags.
+func TestGeneratePolicyExemptions(t *testing.T) {
+	subTags := map[string]map[string]string{
+		"00000000-0000-0000-0000-000000000001": {"Env": "sandbox", "owner": "a"},
+		"00000000-0000-0000-0000-000000000002": {"env": "prod"},
+		"00000000-0000-0000-0000-000000000003": {},
+	}
+	rules := []policyExemptionRule{
+		{
+			name:                         "sandbox",
+			managementGroupId:            "landingzones",
+			policyAssignmentNames:        []string{"Deny-Public-IP", "Deny-Subnet-Without-Nsg"},
+			policyDefinitionReferenceIds: []string{"ref1"},
+			tags:                         map[string]string{"env": "sandbox"},
+		},
+	}
+	paId := func(mg, name string) (string, error) {
+		return fmt.Sprintf("/providers/Microsoft.Management/managementGroups/%s/providers/Microsoft.Authorization/policyAssignments/%s", mg, name), nil
+	}
+
+	res, err := generatePolicyExemptions(subTags, rules, paId)
+	require.NoError(t, err)
+	assert.Len(t, res, 2)
+	for name, e := range res {
+		assert.Equal(t, name, *e.exemption.Name)
+		assert.Equal(t, "/subscriptions/00000000-0000-0000-0000-000000000001", e.scope)
+		assert.Equal(t, armpolicy.ExemptionCategoryWaiver, *e.exemption.Properties.ExemptionCategory)
+		assert.Len(t, e.exemption.Properties.PolicyDefinitionReferenceIDs, 1)
+		assert.Nil(t, e.exemption.Properties.Description)
+	}
+
+	// Names are deterministic.
+	res2, err := generatePolicyExemptions(subTags, rules, paId)
+	require.NoError(t, err)
+	for name := range res {
+		assert.Contains(t, res2, name)
+	}
+
+	// Errors from the assignment lookup are returned.
+	_, err = generatePolicyExemptions(subTags, rules, func(mg, name string) (string, error) {
+		return "", fmt.Errorf("policy assignment %s not found in management group %s", name, mg)
+	})
+	assert.ErrorContains(t, err, "rule sandbox: policy assignment")
+}
+
+func TestTagsMatch(t *testing.T) {
+	assert.True(t, tagsMatch(map[string]string{"Env": "sandbox", "x": "y"}, map[string]string{"env": "sandbox"}))
+	assert.False(t, tagsMatch(map[string]string{"env": "Sandbox"}, map[string]string{"env": "sandbox"}))
+	assert.False(t, tagsMatch(map[string]string{"env": "sandbox"}, map[string]string{"env": "sandbox", "owner": "a"}))
+	assert.False(t, tagsMatch(map[string]string{"env": "sandbox"}, nil))
+}
@@ -0,0 +1,320 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// policyParameterReferenceRegex matches the parameter references in a template expression, e.g. `[parameters('effect')]`.
+var policyParameterReferenceRegex = regexp.MustCompile(`parameters\(\s*'([^']+)'\s*\)`)
+
+// policyLintResourceFields are the resource fields that are commonly compared with `value` instead of `field` by mistake.
+var policyLintResourceFields = map[string]bool{
+	"fullname": true,
+	"id":       true,
+	"kind":     true,
+	"location": true,
+	"name":     true,
+	"tags":     true,
+	"type":     true,
+}
+
+// policyLintFinding is a likely authoring mistake in a library file.
+type policyLintFinding struct {
+	file    string
+	line    int
+	message string
+}
+
+func (f policyLintFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.file, f.line, f.message)
+}
+
+// policyLintFile is a library file with an index of the line of each JSON pointer in the file.
+type policyLintFile struct {
+	name  string
+	obj   map[string]any
+	lines map[string]int
+}
+
+// lintPolicyLibraries runs static checks on the policy definitions and policy set definitions in the libraries,
+// and returns the findings sorted by file and line. Only the libraries from index from onwards are checked,
+// but deprecated policy definitions are found in all libraries. The names are used in the findings, with the file path appended.
+// The checks are heuristics that catch common mistakes before Azure rejects the definition, or accepts a definition that never matches:
+//   - a parameter that is referenced by the policy rule or policy definition references but not declared
+//   - an effect that is not a parameter, so it cannot be changed by assignments
+//   - a condition that compares `value` with a literal resource field name, instead of using `field`
+//   - a condition `field` that is an expression using `field()` or `current()`, instead of using `value`
+//   - a policy set definition that references a policy definition with `metadata.deprecated` set
+//
+// If aliases is not nil, each alias used as a `field` in a policy rule must be in the set, in lower case.
+func lintPolicyLibraries(libs []fs.FS, names []string, from int, aliases mapset.Set[string]) ([]policyLintFinding, error) {
+	deprecated := make(map[string]bool)
+	var definitions, setDefinitions []policyLintFile
+	for i, lib := range libs {
+		if err := fs.WalkDir(lib, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("error walking directory %s: %w", path, err)
+			}
+			n := strings.ToLower(d.Name())
+			if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".json" {
+				return nil
+			}
+			isDefinition := strings.HasPrefix(n, policyDefinitionFilePrefix)
+			isSetDefinition := strings.HasPrefix(n, policySetDefinitionFilePrefix)
+			if !isDefinition && !isSetDefinition {
+				return nil
+			}
+			b, err := fs.ReadFile(lib, path)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %w", path, err)
+			}
+			f := policyLintFile{name: names[i] + "/" + path}
+			if err := json.Unmarshal(b, &f.obj); err != nil {
+				return fmt.Errorf("error unmarshalling %s: %w", path, err)
+			}
+			if isDefinition {
+				if v, _ := jsonPointerValue(f.obj, "/properties/metadata/deprecated").(bool); v {
+					if name, _ := f.obj["name"].(string); name != "" {
+						deprecated[strings.ToLower(name)] = true
+					}
+				}
+			}
+			if i < from {
+				return nil
+			}
+			if f.lines, err = jsonLineIndex(b); err != nil {
+				return fmt.Errorf("error indexing %s: %w", path, err)
+			}
+			if isDefinition {
+				definitions = append(definitions, f)
+			} else {
+				setDefinitions = append(setDefinitions, f)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]policyLintFinding, 0)
+	for _, f := range definitions {
+		res = append(res, f.lintPolicyDefinition()...)
+		if aliases != nil {
+			res = append(res, f.lintPolicyAliases(aliases)...)
+		}
+	}
+	for _, f := range setDefinitions {
+		res = append(res, f.lintPolicySetDefinition(deprecated)...)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].file != res[j].file {
+			return res[i].file < res[j].file
+		}
+		return res[i].line < res[j].line
+	})
+	return res, nil
+}
+
+// finding returns a finding at the line of the JSON pointer.
+func (f policyLintFile) finding(ptr, format string, a ...any) policyLintFinding {
+	return policyLintFinding{file: f.name, line: f.lines[ptr], message: fmt.Sprintf(format, a...)}
+}
+
+// lintPolicyDefinition checks the parameters, effect and conditions of a policy definition.
+func (f policyLintFile) lintPolicyDefinition() []policyLintFinding {
+	res := f.lintParameterReferences("/properties/policyRule")
+
+	effectPtr := "/properties/policyRule/then/effect"
+	if effect, ok := jsonPointerValue(f.obj, effectPtr).(string); ok && !policyEffectParameterRegex.MatchString(effect) {
+		res = append(res, f.finding(effectPtr, "the effect %s is not a parameter, so it cannot be changed by policy assignments", effect))
+	}
+
+	for _, ptr := range []string{"/properties/policyRule/if", "/properties/policyRule/then/details/existenceCondition"} {
+		walkJsonPointers(jsonPointerValue(f.obj, ptr), ptr, func(ptr string, v any) {
+			cond, ok := v.(map[string]any)
+			if !ok {
+				return
+			}
+			if s, ok := cond["value"].(string); ok && isPolicyResourceField(s) {
+				res = append(res, f.finding(ptr+"/value", "the condition compares the literal string %q, use `field` to compare the resource property", s))
+			}
+			if s, ok := cond["field"].(string); ok && strings.HasPrefix(s, "[") && (strings.Contains(s, "field(") || strings.Contains(s, "current(")) {
+				res = append(res, f.finding(ptr+"/field", "the condition field is an expression, use `value` to compare the result of an expression"))
+			}
+		})
+	}
+	return res
+}
+
+// lintPolicyAliases returns a finding for each alias used as a `field` in the policy rule that is not in the set of aliases.
+// Aliases in nested deployment templates are ignored.
+func (f policyLintFile) lintPolicyAliases(aliases mapset.Set[string]) []policyLintFinding {
+	res := make([]policyLintFinding, 0)
+	ptr := "/properties/policyRule"
+	walkJsonPointers(jsonPointerValue(f.obj, ptr), ptr, func(ptr string, v any) {
+		m, ok := v.(map[string]any)
+		if !ok || strings.Contains(ptr, "/deployment/properties/template") {
+			return
+		}
+		s, ok := m["field"].(string)
+		if !ok || !isPolicyAlias(s) || aliases.Contains(strings.ToLower(s)) {
+			return
+		}
+		res = append(res, f.finding(ptr+"/field", "the alias %s is not in the Azure alias catalog, so the condition never matches", s))
+	})
+	return res
+}
+
+// lintPolicySetDefinition checks the parameters and policy definition references of a policy set definition.
+func (f policyLintFile) lintPolicySetDefinition(deprecated map[string]bool) []policyLintFinding {
+	res := f.lintParameterReferences("/properties/policyDefinitions")
+	refs, _ := jsonPointerValue(f.obj, "/properties/policyDefinitions").([]any)
+	for i, ref := range refs {
+		ptr := "/properties/policyDefinitions/" + strconv.Itoa(i) + "/policyDefinitionId"
+		id, _ := jsonPointerValue(ref, "/policyDefinitionId").(string)
+		if name := resourceIdName(id); deprecated[strings.ToLower(name)] {
+			res = append(res, f.finding(ptr, "references the deprecated policy definition %s", name))
+		}
+	}
+	return res
+}
+
+// lintParameterReferences returns a finding for each reference below the JSON pointer to a parameter that is not declared.
+// References in nested deployment templates are ignored.
+func (f policyLintFile) lintParameterReferences(ptr string) []policyLintFinding {
+	declared, _ := jsonPointerValue(f.obj, "/properties/parameters").(map[string]any)
+	res := make([]policyLintFinding, 0)
+	walkJsonPointers(jsonPointerValue(f.obj, ptr), ptr, func(ptr string, v any) {
+		s, ok := v.(string)
+		// Nested deployment templates have their own parameters.
+		if !ok || !strings.HasPrefix(s, "[") || strings.HasPrefix(s, "[[") || strings.Contains(ptr, "/deployment/properties/template/") {
+			return
+		}
+		for _, m := range policyParameterReferenceRegex.FindAllStringSubmatch(s, -1) {
+			if _, ok := declared[m[1]]; !ok {
+				res = append(res, f.finding(ptr, "the parameter %s is referenced but not declared", m[1]))
+			}
+		}
+	})
+	return res
+}
+
+// isPolicyResourceField returns true if the string is a resource field name or an alias, rather than a value to compare.
+func isPolicyResourceField(s string) bool {
+	if policyLintResourceFields[strings.ToLower(s)] {
+		return true
+	}
+	return strings.HasPrefix(s, "Microsoft.") && strings.Contains(s, "/") && !strings.ContainsAny(s, " [")
+}
+
+// isPolicyAlias returns true if the field is a resource provider alias, rather than a resource field name, tag or expression.
+func isPolicyAlias(s string) bool {
+	return !strings.HasPrefix(s, "[") && strings.Contains(s, "/")
+}
+
+// walkJsonPointers calls f for the value and each value nested in it, in a stable order, with the JSON pointer of the value.
+func walkJsonPointers(v any, ptr string, f func(ptr string, v any)) {
+	f(ptr, v)
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkJsonPointers(v[k], ptr+"/"+escapeJsonPointer(k), f)
+		}
+	case []any:
+		for i, e := range v {
+			walkJsonPointers(e, ptr+"/"+strconv.Itoa(i), f)
+		}
+	}
+}
+
+// jsonPointerValue returns the value at the JSON pointer, or nil if it does not exist.
+func jsonPointerValue(v any, ptr string) any {
+	if ptr == "" {
+		return v
+	}
+	for _, tok := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		switch c := v.(type) {
+		case map[string]any:
+			v = c[tok]
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil
+			}
+			v = c[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// escapeJsonPointer escapes a reference token of a JSON pointer.
+func escapeJsonPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// jsonLineIndex returns the line of each value in the JSON document, keyed by JSON pointer.
+func jsonLineIndex(b []byte) (map[string]int, error) {
+	res := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	lineAt := func(off int64) int {
+		for off < int64(len(b)) && strings.IndexByte(" \t\r\n:,", b[off]) >= 0 {
+			off++
+		}
+		return 1 + bytes.Count(b[:off], []byte("\n"))
+	}
+	var walk func(ptr string) error
+	walk = func(ptr string) error {
+		res[ptr] = lineAt(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k, _ := key.(string)
+				if err := walk(ptr + "/" + escapeJsonPointer(k)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(ptr + "/" + strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPolicyLibraries(t *testing.T) {
+	alz := fstest.MapFS{
+		"policy_definition_old.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Old-Policy",
+  "properties": {
+    "metadata": { "deprecated": true },
+    "policyRule": { "if": { "value": "type", "equals": "x" }, "then": { "effect": "Deny" } }
+  }
+}`)},
+	}
+	custom := fstest.MapFS{
+		"policies/policy_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Test-Policy",
+  "properties": {
+    "parameters": {
+      "effect": { "type": "String" },
+      "workspace": { "type": "String" }
+    },
+    "policyRule": {
+      "if": {
+        "allOf": [
+          { "value": "Microsoft.Storage/storageAccounts", "equals": "[parameters('resourceType')]" },
+          { "field": "[field('name')]", "equals": "x" },
+          { "field": "type", "equals": "[[parameters('literal')]" }
+        ]
+      },
+      "then": {
+        "effect": "DeployIfNotExists",
+        "details": {
+          "existenceCondition": { "value": "location", "equals": "[parameters('workspace')]" },
+          "deployment": {
+            "properties": {
+              "template": { "resources": [ { "name": "[parameters('templateParameter')]" } ] },
+              "parameters": { "workspace": { "value": "[parameters('workspace')]" } }
+            }
+          }
+        }
+      }
+    }
+  }
+}`)},
+		"policy_set_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Test-Set",
+  "properties": {
+    "parameters": { "effect": { "type": "String" } },
+    "policyDefinitions": [
+      {
+        "policyDefinitionId": "/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/policyDefinitions/Old-Policy",
+        "parameters": { "effect": { "value": "[parameters('effect')]" }, "other": { "value": "[parameters('missing')]" } }
+      }
+    ]
+  }
+}`)},
+	}
+
+	findings, err := lintPolicyLibraries([]fs.FS{alz, custom}, []string{"alz", "custom"}, 1, nil)
+	require.NoError(t, err)
+	got := make([]string, len(findings))
+	for i, f := range findings {
+		got[i] = f.String()
+	}
+	assert.Equal(t, []string{
+		"custom/policies/policy_definition_test.json:11: the parameter resourceType is referenced but not declared",
+		"custom/policies/policy_definition_test.json:11: the condition compares the literal string \"Microsoft.Storage/storageAccounts\", use `field` to compare the resource property",
+		"custom/policies/policy_definition_test.json:12: the condition field is an expression, use `value` to compare the result of an expression",
+		"custom/policies/policy_definition_test.json:17: the effect DeployIfNotExists is not a parameter, so it cannot be changed by policy assignments",
+		"custom/policies/policy_definition_test.json:19: the condition compares the literal string \"location\", use `field` to compare the resource property",
+		"custom/policy_set_definition_test.json:7: references the deprecated policy definition Old-Policy",
+		"custom/policy_set_definition_test.json:8: the parameter missing is referenced but not declared",
+	}, got)
+
+	findings, err = lintPolicyLibraries([]fs.FS{alz}, []string{"alz"}, 0, nil)
+	require.NoError(t, err)
+	assert.Len(t, findings, 2)
+
+	_, err = lintPolicyLibraries([]fs.FS{fstest.MapFS{"policy_definition_bad.json": &fstest.MapFile{Data: []byte(`{`)}}}, []string{"bad"}, 0, nil)
+	assert.ErrorContains(t, err, "error unmarshalling policy_definition_bad.json")
+}
+
+func TestJsonLineIndex(t *testing.T) {
+	lines, err := jsonLineIndex([]byte("{\n  \"a\": [\n    1,\n    { \"b/c\": true }\n  ]\n}"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"":          1,
+		"/a":        2,
+		"/a/0":      3,
+		"/a/1":      4,
+		"/a/1/b~1c": 4,
+	}, lines)
+
+	_, err = jsonLineIndex([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestJsonPointerValue(t *testing.T) {
+	v := map[string]any{"a": []any{"x", map[string]any{"b/c": 1.0}}}
+	assert.Equal(t, 1.0, jsonPointerValue(v, "/a/1/b~1c"))
+	assert.Equal(t, "x", jsonPointerValue(v, "/a/0"))
+	assert.Nil(t, jsonPointerValue(v, "/a/2"))
+	assert.Nil(t, jsonPointerValue(v, "/a/0/b"))
+	assert.Equal(t, v, jsonPointerValue(v, ""))
+}
+
+func TestLintPolicyLibrariesAliases(t *testing.T) {
+	lib := fstest.MapFS{
+		"policy_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "Test-Policy",
+  "properties": {
+    "parameters": { "effect": { "type": "String" } },
+    "policyRule": {
+      "if": {
+        "allOf": [
+          { "field": "type", "equals": "Microsoft.Storage/storageAccounts" },
+          { "field": "Microsoft.Storage/storageAccounts/supportsHttpsTrafficOnly", "equals": false },
+          { "field": "Microsoft.Storage/storageAccounts/missing", "exists": true },
+          { "field": "[concat('tags[', 'x', ']')]", "exists": true }
+        ]
+      },
+      "then": {
+        "effect": "[parameters('effect')]",
+        "details": {
+          "deployment": { "properties": { "template": { "resources": [ { "field": "Microsoft.Template/ignored" } ] } } }
+        }
+      }
+    }
+  }
+}`)},
+	}
+	aliases := mapset.NewThreadUnsafeSet("microsoft.storage/storageaccounts/supportshttpstrafficonly")
+
+	findings, err := lintPolicyLibraries([]fs.FS{lib}, []string{"custom"}, 0, aliases)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "custom/policy_definition_test.json:10: the alias Microsoft.Storage/storageAccounts/missing is not in the Azure alias catalog, so the condition never matches", findings[0].String())
+
+	findings, err = lintPolicyLibraries([]fs.FS{lib}, []string{"custom"}, 0, nil)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
@@ -1,9 +1,14 @@
 package provider
 
 import (
+	"context"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStandardizeRoleAssignmentRoleDefinititionId(t *testing.T) {
@@ -19,3 +24,26 @@ func TestStandardizeRoleAssignmentRoleDefinititionId(t *testing.T) {
 	output = standardizeRoleAssignmentRoleDefinititionId(input)
 	assert.Equal(t, expectedOutput, output)
 }
+
+// TestPolicyRoleAssignmentsDeleteOffline tests that a delete fails offline, and that the prior state is kept.
+func TestPolicyRoleAssignmentsDeleteOffline(t *testing.T) {
+	ctx := context.Background()
+	r := &PolicyRoleAssignmentsResource{alz: &alzProviderData{offline: true}}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	typ := schemaResp.Schema.Type().TerraformType(ctx)
+	obj, ok := typ.(tftypes.Object)
+	require.True(t, ok)
+	attrs := make(map[string]tftypes.Value)
+	for k, v := range obj.AttributeTypes {
+		attrs[k] = tftypes.NewValue(v, nil)
+	}
+	attrs["id"] = tftypes.NewValue(tftypes.String, "test")
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(typ, attrs)}
+
+	resp := &resource.DeleteResponse{State: state}
+	r.Delete(ctx, resource.DeleteRequest{State: state}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, offlineErrorSummary, resp.Diagnostics.Errors()[0].Summary())
+	assert.True(t, resp.State.Raw.Equal(state.Raw))
+}
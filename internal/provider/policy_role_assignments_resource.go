@@ -22,6 +22,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &PolicyRoleAssignmentsResource{}
 var _ resource.ResourceWithImportState = &PolicyRoleAssignmentsResource{}
+var _ resource.ResourceWithModifyPlan = &PolicyRoleAssignmentsResource{}
 
 var respErr *azcore.ResponseError
 
@@ -108,6 +109,25 @@ func (r *PolicyRoleAssignmentsResource) Configure(ctx context.Context, req resou
 	r.alz = data
 }
 
+// ModifyPlan fails any plan that changes the role assignments if the provider does not allow writes to Azure
+// or is offline, so that the change is reported before apply.
+func (r *PolicyRoleAssignmentsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.alz == nil || (!r.alz.assertNoAzureWrites && !r.alz.offline) {
+		return
+	}
+	if !req.State.Raw.IsNull() && req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	if r.alz.offline {
+		resp.Diagnostics.AddError(offlineErrorSummary, "The provider is configured with `offline`, so the role assignments cannot be created, updated or deleted.")
+		return
+	}
+	resp.Diagnostics.AddError(
+		"Azure writes are not allowed",
+		"The provider is configured with `assert_no_azure_writes`, so the role assignments cannot be created, updated or deleted.",
+	)
+}
+
 func (r *PolicyRoleAssignmentsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data PolicyRoleAssignmentsResourceModel
 
@@ -141,6 +161,12 @@ func (r *PolicyRoleAssignmentsResource) Read(ctx context.Context, req resource.R
 		return
 	}
 
+	// Offline, the role assignments cannot be refreshed from Azure, so the prior state is kept.
+	if r.alz != nil && r.alz.offline {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
 	for k, v := range data.Assignments {
 		tflog.Info(ctx, fmt.Sprintf("reading role assignment: %s", v.ResourceID.ValueString()))
 		if v.ResourceID.IsNull() || v.RoleDefinitionID.IsUnknown() {
@@ -228,6 +254,12 @@ func (r *PolicyRoleAssignmentsResource) Delete(ctx context.Context, req resource
 		return
 	}
 
+	// Offline, the role assignments cannot be deleted from Azure, so the delete fails and the prior state is kept.
+	if r.alz != nil && r.alz.offline {
+		resp.Diagnostics.AddError(offlineErrorSummary, "The provider is configured with `offline`, so the role assignments cannot be deleted. Delete them with the provider online.")
+		return
+	}
+
 	for k, v := range data.Assignments {
 		tflog.Info(ctx, fmt.Sprintf("deleting role assignment: %s", v.ResourceID.ValueString()))
 		if err := deletePolicyRoleAssignment(ctx, r.alz.clients.RoleAssignmentsClient, v.ResourceID.ValueString()); err != nil {
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// preflightRequiredAction is an action that the provider's principal needs at the root management group,
+// with the built-in role that grants it.
+type preflightRequiredAction struct {
+	action string
+	role   string
+}
+
+// preflightRequiredActions are the actions needed to deploy the hierarchy generated by the provider.
+var preflightRequiredActions = []preflightRequiredAction{
+	{"Microsoft.Management/managementGroups/write", "Management Group Contributor"},
+	{"Microsoft.Authorization/policyAssignments/write", "Resource Policy Contributor"},
+	{"Microsoft.Authorization/policyDefinitions/write", "Resource Policy Contributor"},
+	{"Microsoft.Authorization/policySetDefinitions/write", "Resource Policy Contributor"},
+}
+
+// permissionsLister lists the caller's permissions at a management group.
+type permissionsLister interface {
+	ListPermissions(ctx context.Context, name string) ([]libraryRoleDefinitionPermission, error)
+}
+
+// preflightAuthorizationCheck checks that the caller has the required actions at the management group.
+// The error lists the missing actions and the built-in roles that grant them.
+func preflightAuthorizationCheck(ctx context.Context, client permissionsLister, mgName string) error {
+	perms, err := client.ListPermissions(ctx, mgName)
+	if err != nil {
+		return fmt.Errorf("unable to read the permissions of the provider's principal at management group %s: %w", mgName, err)
+	}
+	var missing, roles []string
+	for _, r := range preflightRequiredActions {
+		if permissionsAllowAction(perms, r.action) {
+			continue
+		}
+		missing = append(missing, r.action)
+		if !containsFold(roles, r.role) {
+			roles = append(roles, r.role)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the provider's principal does not have the following permissions at management group %s: %s. "+
+		"Assign the %s role(s) at the management group, or a custom role with these actions",
+		mgName, strings.Join(missing, ", "), strings.Join(roles, " and "))
+}
+
+// permissionsAllowAction returns true if any permission block allows the action and does not exclude it in `notActions`.
+func permissionsAllowAction(perms []libraryRoleDefinitionPermission, action string) bool {
+	for _, p := range perms {
+		if actionsMatch(p.Actions, action) && !actionsMatch(p.NotActions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionsMatch returns true if the action matches any of the patterns, which may contain `*` wildcards.
+// Matching is case-insensitive, as in Azure RBAC.
+func actionsMatch(patterns []string, action string) bool {
+	for _, p := range patterns {
+		re := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*") + "$"
+		if ok, _ := regexp.MatchString(re, action); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePermissionsLister struct {
+	perms []libraryRoleDefinitionPermission
+	err   error
+}
+
+func (f fakePermissionsLister) ListPermissions(ctx context.Context, name string) ([]libraryRoleDefinitionPermission, error) {
+	return f.perms, f.err
+}
+
+func TestPermissionsAllowAction(t *testing.T) {
+	perms := []libraryRoleDefinitionPermission{
+		{Actions: []string{"Microsoft.Authorization/policy*"}, NotActions: []string{"Microsoft.Authorization/policyExemptions/*"}},
+	}
+	assert.True(t, permissionsAllowAction(perms, "Microsoft.Authorization/policyAssignments/write"))
+	assert.True(t, permissionsAllowAction(perms, "microsoft.authorization/policydefinitions/write"))
+	assert.False(t, permissionsAllowAction(perms, "Microsoft.Authorization/policyExemptions/write"))
+	assert.False(t, permissionsAllowAction(perms, "Microsoft.Management/managementGroups/write"))
+	assert.True(t, permissionsAllowAction([]libraryRoleDefinitionPermission{{Actions: []string{"*"}}}, "Microsoft.Management/managementGroups/write"))
+}
+
+func TestPreflightAuthorizationCheck(t *testing.T) {
+	ctx := context.Background()
+
+	err := preflightAuthorizationCheck(ctx, fakePermissionsLister{perms: []libraryRoleDefinitionPermission{{Actions: []string{"*"}}}}, "alz")
+	assert.NoError(t, err)
+
+	err = preflightAuthorizationCheck(ctx, fakePermissionsLister{perms: []libraryRoleDefinitionPermission{{Actions: []string{"Microsoft.Management/*"}}}}, "alz")
+	assert.ErrorContains(t, err, "Microsoft.Authorization/policyAssignments/write, Microsoft.Authorization/policyDefinitions/write")
+	assert.ErrorContains(t, err, "Assign the Resource Policy Contributor role(s)")
+
+	err = preflightAuthorizationCheck(ctx, fakePermissionsLister{}, "alz")
+	assert.ErrorContains(t, err, "Management Group Contributor and Resource Policy Contributor")
+
+	err = preflightAuthorizationCheck(ctx, fakePermissionsLister{err: errors.New("forbidden")}, "alz")
+	assert.ErrorContains(t, err, "unable to read the permissions")
+}
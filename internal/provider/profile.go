@@ -0,0 +1,66 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// profileNameRegexp matches the characters that are replaced in profile file names.
+var profileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// startProfile starts a CPU profile for the phase, writing it to the directory.
+// The returned function stops the CPU profile and writes a heap profile.
+// If the directory is empty, profiling is disabled and the returned function does nothing.
+// Failures are logged rather than returned, as profiling must not change the result of the operation.
+func startProfile(ctx context.Context, dir, phase string) func() {
+	if dir == "" {
+		return func() {}
+	}
+	prefix := filepath.Join(dir, fmt.Sprintf("%s-%d", profileNameRegexp.ReplaceAllString(phase, "_"), time.Now().UnixNano()))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		tflog.Warn(ctx, "Unable to create profile directory", map[string]interface{}{"error": err.Error()})
+		return func() {}
+	}
+
+	cpu, err := os.Create(prefix + ".cpu.pprof")
+	if err != nil {
+		tflog.Warn(ctx, "Unable to create CPU profile", map[string]interface{}{"error": err.Error()})
+		return func() {}
+	}
+	// Only one CPU profile can run at a time, so overlapping phases only have a heap profile.
+	cpuStarted := true
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		tflog.Debug(ctx, "Unable to start CPU profile", map[string]interface{}{"error": err.Error()})
+		cpuStarted = false
+		cpu.Close()
+		os.Remove(cpu.Name())
+	}
+
+	return func() {
+		if cpuStarted {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		heap, err := os.Create(prefix + ".heap.pprof")
+		if err != nil {
+			tflog.Warn(ctx, "Unable to create heap profile", map[string]interface{}{"error": err.Error()})
+			return
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			tflog.Warn(ctx, "Unable to write heap profile", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
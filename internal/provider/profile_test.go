@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProfile(t *testing.T) {
+	// Profiling is disabled without a directory.
+	startProfile(context.Background(), "", "configure")()
+
+	dir := filepath.Join(t.TempDir(), "profiles")
+	stop := startProfile(context.Background(), dir, "read archetype/test")
+	// Overlapping phases only write a heap profile.
+	startProfile(context.Background(), dir, "overlap")()
+	stop()
+
+	cpu, err := filepath.Glob(filepath.Join(dir, "read_archetype_test-*.cpu.pprof"))
+	require.NoError(t, err)
+	assert.Len(t, cpu, 1)
+	heap, err := filepath.Glob(filepath.Join(dir, "*.heap.pprof"))
+	require.NoError(t, err)
+	assert.Len(t, heap, 2)
+	overlap, err := filepath.Glob(filepath.Join(dir, "overlap-*.cpu.pprof"))
+	require.NoError(t, err)
+	assert.Empty(t, overlap)
+
+	fi, err := os.Stat(heap[0])
+	require.NoError(t, err)
+	assert.NotZero(t, fi.Size())
+}
@@ -7,10 +7,12 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
-	"net/url"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,17 +20,22 @@ import (
 
 	"github.com/Azure/alzlib"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm/policy"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
-	"github.com/hashicorp/go-getter/v2"
+	"github.com/Azure/terraform-provider-alz/internal/alztypes"
+	"github.com/Azure/terraform-provider-alz/internal/alzvalidators"
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -39,14 +46,21 @@ import (
 )
 
 const (
-	userAgentBase   = "AzureTerraformAlzProvider"
-	alzLibDirBase   = ".alzlib"
-	alzLibUrlFmtStr = "github.com/Azure/Azure-Landing-Zones-Library//platform/alz?"
-	alzLibRef       = "platform/alz/2024.03.00"
+	userAgentBase = "AzureTerraformAlzProvider"
+	alzLibDirBase = ".alzlib"
+	alzLibUrlBase = "github.com/Azure/Azure-Landing-Zones-Library//"
+	alzLibRef     = "platform/alz/2024.03.00"
+
+	// defaultParallelism is the default number of built-in definitions read from Azure concurrently, the same as alzlib.
+	defaultParallelism = 10
+
+	// maxAuxiliaryTenants is the maximum number of auxiliary tenants supported by Azure Resource Manager.
+	maxAuxiliaryTenants = 3
 )
 
 // Ensure ScaffoldingProvider satisfies various provider interfaces.
 var _ provider.Provider = &AlzProvider{}
+var _ provider.ProviderWithFunctions = &AlzProvider{}
 
 // AlzProvider defines the provider implementation.
 type AlzProvider struct {
@@ -55,39 +69,146 @@ type AlzProvider struct {
 	// testing.
 	version string
 	alz     *alzProviderData
+	// transport replaces the transport of the http client if set.
+	// It is only set by the acceptance tests, to replay recorded interactions.
+	transport http.RoundTripper
 }
 
 type AlzProviderClients struct {
-	RoleAssignmentsClient *armauthorization.RoleAssignmentsClient
+	ManagementGroupsClient  *ManagementGroupsClient
+	PolicyAssignmentsClient *armpolicy.AssignmentsClient
+	ResourceProvidersClient *ResourceProvidersClient
+	RoleAssignmentsClient   *armauthorization.RoleAssignmentsClient
 }
 
 type alzProviderData struct {
 	*alzlib.AlzLib
 	mu      *sync.Mutex
 	clients *AlzProviderClients
+	library *libraryIndex
+	// parameterSubstitutions are applied to the policy assignments in every management group.
+	parameterSubstitutions map[string]*armpolicy.ParameterValuesValue
+	// policyDefaultValues are applied to the parameters that the libraries declare to accept them, keyed by default name.
+	policyDefaultValues map[string]*armpolicy.ParameterValuesValue
+	// alzLibProfile is the selected flavor of the ALZ library.
+	alzLibProfile alzLibProfile
+	// alzLibRef is the configured ALZ library reference, which may be a version constraint.
+	alzLibRef string
+	// alzLibRefResolved is the ALZ library tag that was used, empty if the ALZ library is not used.
+	alzLibRefResolved string
+	// libUrls are the library sources, with their credentials redacted, in the order they were processed.
+	libUrls []string
+	// libraries are the provenance of the libraries, in the same order as libUrls.
+	libraries []libraryInfo
+	// libUrlsFrom is the position of the first library from `lib_urls`, which is after the ALZ library if it is used.
+	libUrlsFrom int
+	// providerVersion is the version of the provider.
+	providerVersion string
+	// libraryDownloadOptions are used by data sources that download additional libraries.
+	libraryDownloadOptions libfetcher.Options
+	// excludeDefaultAssignments match the names of policy assignments that are removed from every archetype.
+	excludeDefaultAssignments []*regexp.Regexp
+	// managementGroupRoleAssignments are added to every management group that their selectors match.
+	managementGroupRoleAssignments []managementGroupRoleAssignment
+	// archetypeDefaults are the default values of every archetype, nil if not set.
+	archetypeDefaults *ArchetypeDefaultsType
+	// defaultNonComplianceMessages sets the non-compliance message of Deny policy assignments from the library policy definition descriptions.
+	defaultNonComplianceMessages bool
+	// identityOverrides replace the system assigned identities of matching policy assignments in every management group.
+	identityOverrides []identityOverride
+	// assertNoAzureWrites prevents write requests to Azure.
+	assertNoAzureWrites bool
+	// checkExistingManagementGroups compares the declared management groups with those in Azure.
+	checkExistingManagementGroups bool
+	// readTimeout bounds the reads of the data sources that do not set a read timeout.
+	readTimeout time.Duration
+	// offline prevents all network access, only definitions in the libraries are used.
+	offline bool
+	// warnOnRemovedPolicyReferences ignores references in the configuration to policy assignments that are not in the archetype, with a warning.
+	warnOnRemovedPolicyReferences bool
+	// debugProfileDir is the directory for pprof profiles, empty if profiling is disabled.
+	debugProfileDir string
+	// unavailableResourceProviders are the resource provider namespaces that are not available in the cloud environment.
+	unavailableResourceProviders []string
+	// resolutions limits the number of archetype data sources that are resolved concurrently.
+	resolutions *resolutionLimiter
+	// managementGroupSubscriptions are the subscription ids of the archetype data sources that have been read, keyed by management group id.
+	// It is guarded by mu.
+	managementGroupSubscriptions map[string][]string
+	// policyAssignmentDependencies are the `depends_on_assignments` of the archetype data sources that have been read,
+	// keyed by management group name and then by policy assignment name. It is guarded by mu.
+	policyAssignmentDependencies map[string]map[string][]string
+	// subscriptions are the subscription ids declared by each archetype data source, to report subscriptions declared more than once.
+	subscriptions *subscriptionDeclarations
+	// builtInDefinitions are the built-in definitions read from Azure by alzlib.
+	builtInDefinitions *builtInDefinitionRecorder
+	// warningVerbosity is how the warnings of the data sources are shown, one of warningVerbosities.
+	warningVerbosity string
 }
 
 // AlzProviderModel describes the provider data model.
 type AlzProviderModel struct {
-	AlzLibRef                 types.String `tfsdk:"alz_lib_ref"`
-	AuxiliaryTenantIds        types.List   `tfsdk:"auxiliary_tenant_ids"`
-	ClientCertificatePassword types.String `tfsdk:"client_certificate_password"`
-	ClientCertificatePath     types.String `tfsdk:"client_certificate_path"`
-	ClientId                  types.String `tfsdk:"client_id"`
-	ClientSecret              types.String `tfsdk:"client_secret"`
-	Environment               types.String `tfsdk:"environment"`
-	LibOverwriteEnabled       types.Bool   `tfsdk:"lib_overwrite_enabled"`
-	LibUrls                   types.List   `tfsdk:"lib_urls"`
-	OidcRequestToken          types.String `tfsdk:"oidc_request_token"`
-	OidcRequestUrl            types.String `tfsdk:"oidc_request_url"`
-	OidcToken                 types.String `tfsdk:"oidc_token"`
-	OidcTokenFilePath         types.String `tfsdk:"oidc_token_file_path"`
-	SkipProviderRegistration  types.Bool   `tfsdk:"skip_provider_registration"`
-	TenantId                  types.String `tfsdk:"tenant_id"`
-	UseAlzLib                 types.Bool   `tfsdk:"use_alz_lib"`
-	UseCli                    types.Bool   `tfsdk:"use_cli"`
-	UseMsi                    types.Bool   `tfsdk:"use_msi"`
-	UseOidc                   types.Bool   `tfsdk:"use_oidc"`
+	AlzLibProfile                     types.String                                 `tfsdk:"alz_lib_profile"`
+	AlzLibRef                         types.String                                 `tfsdk:"alz_lib_ref"`
+	ArchetypeDefaults                 *ArchetypeDefaultsType                       `tfsdk:"archetype_defaults"`
+	AssertNoAzureWrites               types.Bool                                   `tfsdk:"assert_no_azure_writes"`
+	AuthMethod                        types.String                                 `tfsdk:"auth_method"`
+	AuxiliaryTenantIds                types.List                                   `tfsdk:"auxiliary_tenant_ids"`
+	BuiltInDefinitionCacheDir         types.String                                 `tfsdk:"built_in_definition_cache_dir"`
+	BuiltInDefinitionCacheTtl         types.String                                 `tfsdk:"built_in_definition_cache_ttl"`
+	BuiltInDefinitionVersions         types.Map                                    `tfsdk:"built_in_definition_versions"`
+	CheckExistingManagementGroups     types.Bool                                   `tfsdk:"check_existing_management_groups"`
+	ClientCertificatePassword         types.String                                 `tfsdk:"client_certificate_password"`
+	ClientCertificatePath             types.String                                 `tfsdk:"client_certificate_path"`
+	ClientId                          types.String                                 `tfsdk:"client_id"`
+	ClientSecret                      types.String                                 `tfsdk:"client_secret"`
+	CustomCaCerts                     types.String                                 `tfsdk:"custom_ca_certs"`
+	DebugProfileDir                   types.String                                 `tfsdk:"debug_profile_dir"`
+	DefaultNonComplianceMessages      types.Bool                                   `tfsdk:"default_non_compliance_messages"`
+	Environment                       types.String                                 `tfsdk:"environment"`
+	EssentialNetworkOnly              types.Bool                                   `tfsdk:"essential_network_only"`
+	ExcludeDefaultAssignmentsMatching types.List                                   `tfsdk:"exclude_default_assignments_matching"`
+	ForceRefresh                      types.Bool                                   `tfsdk:"force_refresh"`
+	IdentityOverrides                 types.Map                                    `tfsdk:"identity_overrides"`
+	LibAttestation                    *LibAttestationType                          `tfsdk:"lib_attestation"`
+	LibCacheDir                       types.String                                 `tfsdk:"lib_cache_dir"`
+	LibCacheTtl                       types.String                                 `tfsdk:"lib_cache_ttl"`
+	LibDigests                        types.Map                                    `tfsdk:"lib_digests"`
+	LibGitCredentials                 map[string]LibGitCredentialType              `tfsdk:"lib_git_credentials"`
+	LibGitSshKeyPath                  types.String                                 `tfsdk:"lib_git_ssh_key_path"`
+	LibOverwrite                      types.String                                 `tfsdk:"lib_overwrite"`
+	LibOverwriteEnabled               types.Bool                                   `tfsdk:"lib_overwrite_enabled"`
+	LibUrls                           types.List                                   `tfsdk:"lib_urls"`
+	LockFile                          types.String                                 `tfsdk:"lock_file"`
+	LockFileStrict                    types.Bool                                   `tfsdk:"lock_file_strict"`
+	ManagementGroupRoleAssignments    map[string]ManagementGroupRoleAssignmentType `tfsdk:"management_group_role_assignments"`
+	MaxParallelResolutions            types.Int64                                  `tfsdk:"max_parallel_resolutions"`
+	MetadataHost                      types.String                                 `tfsdk:"metadata_host"`
+	Offline                           types.Bool                                   `tfsdk:"offline"`
+	OidcRequestToken                  types.String                                 `tfsdk:"oidc_request_token"`
+	OidcRequestUrl                    types.String                                 `tfsdk:"oidc_request_url"`
+	OidcToken                         types.String                                 `tfsdk:"oidc_token"`
+	OidcTokenFilePath                 types.String                                 `tfsdk:"oidc_token_file_path"`
+	Parallelism                       types.Int64                                  `tfsdk:"parallelism"`
+	ParameterSubstitutions            alztypes.PolicyParameterValue                `tfsdk:"parameter_substitutions"`
+	PartnerId                         types.String                                 `tfsdk:"partner_id"`
+	PolicyDefaultValues               alztypes.PolicyParameterValue                `tfsdk:"policy_default_values"`
+	PrefetchBuiltInDefinitions        types.Bool                                   `tfsdk:"prefetch_built_in_definitions"`
+	PreflightAuthorizationScope       types.String                                 `tfsdk:"preflight_authorization_scope"`
+	ProxyUrl                          types.String                                 `tfsdk:"proxy_url"`
+	Retry                             *RetryType                                   `tfsdk:"retry"`
+	SkipProviderRegistration          types.Bool                                   `tfsdk:"skip_provider_registration"`
+	TenantId                          types.String                                 `tfsdk:"tenant_id"`
+	Timeouts                          *ProviderTimeoutsType                        `tfsdk:"timeouts"`
+	UnavailableResourceProviders      types.Set                                    `tfsdk:"unavailable_resource_providers"`
+	UseAlzLib                         types.Bool                                   `tfsdk:"use_alz_lib"`
+	UseCli                            types.Bool                                   `tfsdk:"use_cli"`
+	UseMsi                            types.Bool                                   `tfsdk:"use_msi"`
+	UseOidc                           types.Bool                                   `tfsdk:"use_oidc"`
+	UserAgentSuffix                   types.String                                 `tfsdk:"user_agent_suffix"`
+	ValidatePolicyAliases             types.Bool                                   `tfsdk:"validate_policy_aliases"`
+	WarnOnRemovedPolicyReferences     types.Bool                                   `tfsdk:"warn_on_removed_policy_references"`
+	WarningVerbosity                  types.String                                 `tfsdk:"warning_verbosity"`
 }
 
 func (p *AlzProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -100,18 +221,198 @@ func (p *AlzProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 		MarkdownDescription: "ALZ provider to generate archetype data for use with the ALZ Terraform module.",
 
 		Attributes: map[string]schema.Attribute{
-			"lib_overwrite_enabled": schema.BoolAttribute{
-				MarkdownDescription: "Whether to allow overwriting of the library by other lib directories. Default is `false`.",
+			"archetype_defaults": schema.SingleNestedAttribute{
+				MarkdownDescription: "The default values of every `alz_archetype` data source, so that they do not have to be repeated in the `defaults` of each data source. " +
+					"The values of the data source `defaults` and `regional_defaults` override these values.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"location": schema.StringAttribute{
+						MarkdownDescription: "Default location.",
+						Optional:            true,
+					},
+					"log_analytics_workspace_id": schema.StringAttribute{
+						MarkdownDescription: "Default Log Analytics workspace id.",
+						Optional:            true,
+						Validators: []validator.String{
+							alzvalidators.LogAnalyticsWorkspaceId(),
+						},
+					},
+				},
+			},
+
+			"exclude_default_assignments_matching": schema.ListAttribute{
+				MarkdownDescription: "A list of regular expressions matched against the names of the policy assignments in every archetype. " +
+					"Matching policy assignments are excluded from all `alz_archetype` data sources, e.g. `^Enable-DDoS-VNET$` to disable DDoS protection across the hierarchy. " +
+					"Use `^` and `$` to match the whole name.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+
+			"identity_overrides": schema.MapAttribute{
+				MarkdownDescription: "A map of regular expressions, matched against the names of the policy assignments in every archetype, to user assigned managed identity resource ids. " +
+					"Matching policy assignments that have a system assigned identity in the library use the user assigned identity instead, e.g. to use a shared identity for the Azure Monitor Agent policies. " +
+					"If more than one expression matches, the first in lexical order is used. " +
+					"Identities supplied in `policy_assignments_to_modify` of the `alz_archetype` data source take precedence.",
+				Optional:    true,
+				ElementType: types.StringType,
+				Validators: []validator.Map{
+					mapvalidator.ValueStringsAre(
+						alzvalidators.UserAssignedIdentityId(),
+					),
+				},
+			},
+
+			"lib_attestation": schema.SingleNestedAttribute{
+				MarkdownDescription: "Verify that the libraries are signed before they are used, so that only reviewed policy content is deployed. " +
+					"Each verified library must have a detached DSSE envelope named `" + libraryAttestationFile + "` in its root, with the payload type `" + inTotoPayloadType + "` and a base64 encoded in-toto statement as the payload. " +
+					"Each signature is over the DSSE pre-authentication encoding of the payload. " +
+					"Only this file is verified: attestations and signatures stored in an OCI registry, e.g. by `cosign attest`, and Sigstore bundles are not used. " +
+					"The envelope must have a signature from one of the `public_keys`, and the statement must have a subject with a `sha256` digest of the library content, which is the digest recorded in the `lock_file`. " +
+					"The provider fails to configure if a library has no attestation or the attestation is not valid.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"public_keys": schema.ListAttribute{
+						MarkdownDescription: "The PEM encoded public keys that are trusted to sign libraries. ECDSA, Ed25519 and RSA keys are supported.",
+						Required:            true,
+						ElementType:         types.StringType,
+						Validators: []validator.List{
+							listvalidator.SizeAtLeast(1),
+						},
+					},
+
+					"sources_matching": schema.StringAttribute{
+						MarkdownDescription: "A regular expression matched against the library sources, i.e. the ALZ library URL and the `lib_urls`. Only libraries from matching sources are verified, e.g. to verify the libraries from an internal mirror but not local directories. Every library is verified if not set.",
+						Optional:            true,
+					},
+				},
+			},
+
+			"lib_cache_dir": schema.StringAttribute{
+				MarkdownDescription: "A directory to cache the remote libraries in, so that each library is downloaded once per machine rather than on every plan, e.g. a directory that is kept between CI runs. " +
+					"Libraries are cached by source, so a source with a fixed ref, e.g. a release tag, can be cached for a long time. Local directories are not cached. The libraries are downloaded on every run if not set.",
+				Optional: true,
+			},
+
+			"lib_cache_ttl": schema.StringAttribute{
+				MarkdownDescription: "How long a cached library is used before it is downloaded again, as a duration, e.g. `24h`. A duration of `0` means cached libraries never expire. Only used with `lib_cache_dir`. Default is `24h`.",
 				Optional:            true,
 			},
 
-			"auxiliary_tenant_ids": schema.ListAttribute{
-				MarkdownDescription: "A list of auxiliary tenant ids which should be used. If not specified, value will be attempted to be read from the `ARM_AUXILIARY_TENANT_IDS` environment variable. When configuring from the environment, use a semicolon as a delimiter.",
-				ElementType:         types.StringType,
+			"lib_digests": schema.MapAttribute{
+				MarkdownDescription: "A map of library sources to the expected SHA-256 digest of their content, so that tampered or changed libraries are rejected, e.g. in CI. " +
+					"The keys are entries of `lib_urls`, or the ALZ library source when `use_alz_lib` is set, and the digests are those recorded in the `lock_file`. " +
+					"The provider fails if the content of a library does not match its digest. Libraries that are not in the map are not checked.",
+				Optional:    true,
+				ElementType: types.StringType,
+				Validators: []validator.Map{
+					mapvalidator.ValueStringsAre(
+						stringvalidator.RegexMatches(regexp.MustCompile(`^[0-9a-fA-F]{64}$`), "The digest must be a hex encoded SHA-256 digest."),
+					),
+				},
+			},
+
+			"force_refresh": schema.BoolAttribute{
+				MarkdownDescription: "Whether to download every library, replacing the cached copy in `lib_cache_dir`, and to read every built-in definition, replacing the cached copy in `built_in_definition_cache_dir`. Default is `false`.",
+				Optional:            true,
+			},
+
+			"built_in_definition_cache_dir": schema.StringAttribute{
+				MarkdownDescription: "A directory to cache the built-in policy definitions and policy set definitions read from Azure in, so that each definition is read once per machine rather than on every plan, e.g. a directory that is kept between CI runs. " +
+					"Definitions are cached by Azure endpoint, id and API version, so one directory can be shared between clouds. The definitions are read on every run if not set.",
+				Optional: true,
+			},
+
+			"built_in_definition_cache_ttl": schema.StringAttribute{
+				MarkdownDescription: "How long a cached built-in definition is used before it is read again, as a duration, e.g. `24h`. A duration of `0` means cached definitions never expire. Only used with `built_in_definition_cache_dir`. Default is `24h`.",
 				Optional:            true,
+			},
+
+			"built_in_definition_versions": schema.MapAttribute{
+				MarkdownDescription: "A map of the names of built-in policy definitions and policy set definitions to the version that is read from Azure, e.g. `1.2.0`, " +
+					"so that the plan does not change when Microsoft publishes a new version of a definition. " +
+					"Definitions that are not in the map are read at their latest version. The policy assignments are not changed, they assign the definition by id.",
+				Optional:    true,
+				ElementType: types.StringType,
+				Validators: []validator.Map{
+					mapvalidator.ValueStringsAre(
+						stringvalidator.RegexMatches(builtInDefinitionVersionRegex, "must be a definition version, e.g. `1.2.0`"),
+					),
+				},
+			},
+
+			"lib_git_credentials": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of host names, e.g. `github.com` or `dev.azure.com`, to the credentials used to clone libraries from private git repositories on the host over HTTPS, " +
+					"e.g. `git::https://dev.azure.com/org/project/_git/lib//platform/custom?ref=v1.0.0`. " +
+					"The credentials are only sent to the host and are not written to the library source or the cloned repository. One of `token` and `use_provider_credentials` must be set.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"token": schema.StringAttribute{
+							MarkdownDescription: "A personal access token, e.g. a GitHub fine-grained token or an Azure DevOps PAT with read access to the code.",
+							Optional:            true,
+							Sensitive:           true,
+						},
+
+						"use_provider_credentials": schema.BoolAttribute{
+							MarkdownDescription: "Whether to authenticate to Azure DevOps with a Microsoft Entra ID token from the provider credentials, e.g. OpenID Connect workload identity federation, instead of a personal access token. Default is `false`.",
+							Optional:            true,
+						},
+
+						"username": schema.StringAttribute{
+							MarkdownDescription: "The user name sent with the `token`. Default is `x-access-token`, which GitHub accepts for tokens and Azure DevOps ignores.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+
+			"lib_git_ssh_key_path": schema.StringAttribute{
+				MarkdownDescription: "The path to the private key used to clone libraries from git repositories over SSH, e.g. `git::ssh://git@github.com/org/lib.git`. The key must not have a passphrase. The SSH agent and default keys are used if not set.",
+				Optional:            true,
+			},
+
+			"lib_overwrite": schema.StringAttribute{
+				MarkdownDescription: "What to do when an object with the same name is defined in more than one library, e.g. a policy definition in the ALZ library and in a library of `lib_urls`. " +
+					"Must be one of `error`, `warn` or `allow`. With `error`, the provider fails and lists the objects. " +
+					"With `warn`, the definition of the last library is used and a warning lists the objects. " +
+					"With `allow`, the definition of the last library is used silently. Default is `error`, or `warn` if `lib_overwrite_enabled` is `true`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(libOverwriteModes...),
+					stringvalidator.ConflictsWith(path.MatchRoot("lib_overwrite_enabled")),
+				},
+			},
+
+			"lib_overwrite_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether to allow objects in later libraries to replace objects of the same kind and name in earlier libraries. " +
+					"The libraries are processed in order, the ALZ library first and then `lib_urls`, and the definition in the last library is used. " +
+					"A warning lists each replaced object and the libraries that define it. If `false`, an object defined in more than one library is an error. Default is `false`.",
+				Optional:           true,
+				DeprecationMessage: "Use `lib_overwrite` instead. `lib_overwrite_enabled = true` is the same as `lib_overwrite = \"warn\"`.",
+			},
+
+			"auth_method": schema.StringAttribute{
+				MarkdownDescription: "The method used to authenticate to Azure. Must be one of `client_secret`, `client_certificate`, `oidc`, `msi` or `cli`. " +
+					"If set, only this method is used, with the `tenant_id`, `client_id` and the attributes of the method, e.g. `client_secret` or `oidc_token_file_path`, and the provider fails if it cannot authenticate. " +
+					"For `msi`, the `client_id` selects a user assigned identity. " +
+					"If not set, the environment, OpenID Connect, managed identity and Azure CLI credentials are tried in order, as enabled by the `use_*` attributes.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(authMethods...),
+				},
+			},
+
+			"auxiliary_tenant_ids": schema.ListAttribute{
+				MarkdownDescription: "A list of auxiliary tenant ids which should be used. " +
+					"The credential is allowed to get tokens for these tenants, and a token for each is sent with every Azure API call, including the reads of built-in definitions, " +
+					"so that resources in other tenants can be read, e.g. with Azure Lighthouse or in multi-tenant management scenarios. At most 3 auxiliary tenants are supported. " +
+					"If not specified, value will be attempted to be read from the `ARM_AUXILIARY_TENANT_IDS` environment variable. When configuring from the environment, use a semicolon as a delimiter.",
+				ElementType: types.StringType,
+				Optional:    true,
 				Validators: []validator.List{
+					listvalidator.SizeAtMost(maxAuxiliaryTenants),
 					listvalidator.ValueStringsAre(
-						stringvalidator.RegexMatches(regexp.MustCompile(`^[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}$`), "The client id must be a valid lowercase UUID."),
+						stringvalidator.RegexMatches(regexp.MustCompile(`^[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}$`), "The auxiliary tenant id must be a valid lowercase UUID."),
 					),
 				},
 			},
@@ -141,23 +442,138 @@ func (p *AlzProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				Sensitive:           true,
 			},
 
+			"assert_no_azure_writes": schema.BoolAttribute{
+				MarkdownDescription: "Whether the provider is prevented from sending write requests to Azure. " +
+					"Every Azure request other than `GET` or `HEAD` fails without being sent, resource provider registration is disabled, " +
+					"and the `alz_policy_role_assignments` resource fails to plan any change. " +
+					"Use this where the provider is only allowed to generate data, e.g. for security reviews. Default is `false`.",
+				Optional: true,
+			},
+
+			"check_existing_management_groups": schema.BoolAttribute{
+				MarkdownDescription: "Whether each `alz_archetype` data source reads the management group of the same name from Azure, " +
+					"and warns if it exists with a different display name or parent than declared. " +
+					"This catches differences between the configuration and Azure before a management group is unexpectedly renamed or moved. " +
+					"The check is skipped if the management group cannot be read. Default is `false`.",
+				Optional: true,
+			},
+
+			"custom_ca_certs": schema.StringAttribute{
+				MarkdownDescription: "The path to a file containing one or more PEM encoded CA certificates to trust, in addition to the system certificate pool. " +
+					"Use this when outbound TLS connections are inspected by a proxy. Applies to Azure API calls and library downloads.",
+				Optional: true,
+			},
+
+			"lock_file": schema.StringAttribute{
+				MarkdownDescription: "The path to a library lock file, e.g. `.alz.lock.json`, relative to the working directory. " +
+					"The lock file records the ALZ library release that an `alz_lib_ref` version constraint resolved to, and a SHA-256 digest of the content of each library, which pins the library. The credentials in the library sources, e.g. tokens and SAS signatures, are not recorded. " +
+					"If the file does not exist it is created. If it exists, the version constraint resolves to the locked release, and a warning is shown and the file is updated if the libraries differ from the lock. " +
+					"Commit the file so that repeated plans, e.g. in CI, use identical library content.",
+				Optional: true,
+			},
+
+			"lock_file_strict": schema.BoolAttribute{
+				MarkdownDescription: "Whether the provider fails if the `lock_file` does not exist or the libraries differ from the lock, instead of creating or updating the file. " +
+					"Use this in CI to make sure that plans use the reviewed library content. Default is `false`.",
+				Optional: true,
+			},
+
+			"default_non_compliance_messages": schema.BoolAttribute{
+				MarkdownDescription: "Whether the policy assignments with the `Deny` effect that have no non-compliance message are given one, which is the description of the library policy definition they assign. " +
+					"This improves the messages shown to users in the portal when a request is denied, without declaring them in `policy_assignments_to_modify`. " +
+					"Assignments of policy set definitions and built-in definitions are not changed. Default is `false`.",
+				Optional: true,
+			},
+
+			"debug_profile_dir": schema.StringAttribute{
+				MarkdownDescription: "A directory to write pprof CPU and heap profiles of the provider configuration and data source reads, to investigate performance with large libraries. " +
+					"Each phase writes files named after the phase and a timestamp. Profiling is disabled if not set.",
+				Optional: true,
+			},
+
 			"environment": schema.StringAttribute{
-				MarkdownDescription: "The cloud environment which should be used. Possible values are `public`, `usgovernment` and `china`. Defaults to `public`. If not specified, value will be attempted to be read from the `ARM_ENVIRONMENT` environment variable.",
+				MarkdownDescription: "The cloud environment which should be used. Possible values are `public`, `usgovernment` and `china`. Defaults to `public`. The environment selects the Entra ID authority used to authenticate and the Azure Resource Manager endpoint used to read built-in policy definitions, management groups and resource providers. If not specified, value will be attempted to be read from the `ARM_ENVIRONMENT` environment variable.",
 				Optional:            true,
 				Validators: []validator.String{
 					stringvalidator.OneOf("public", "usgovernment", "china"),
 				},
 			},
 
+			"essential_network_only": schema.BoolAttribute{
+				MarkdownDescription: "Only make the outbound calls that the configuration explicitly requires. " +
+					"The ALZ library is not downloaded unless `use_alz_lib` is set, the metadata endpoint is not read unless `metadata_host` is set, rather than read from the environment, " +
+					"and resource providers are not registered. A warning lists the calls that the provider would otherwise have made. " +
+					"Unlike `offline`, the configured libraries are downloaded and the built-in definitions are read from Azure. Default is `false`.",
+				Optional: true,
+			},
+
 			"lib_urls": schema.ListAttribute{
-				MarkdownDescription: "A list of directories or URLs to use for ALZ libraries. The URLs will be processed in order. See <https://pkg.go.dev/github.com/hashicorp/go-getter#readme-url-format> for URL syntax. Note that if use_alz_lib is set to true then it will always be the first library used.",
-				ElementType:         types.StringType,
-				Optional:            true,
+				MarkdownDescription: "A list of directories or URLs to use for ALZ libraries. The URLs will be processed in order. See <https://pkg.go.dev/github.com/hashicorp/go-getter#readme-url-format> for URL syntax. OCI artifacts can be used with the `oci://registry/repository:tag` syntax, and are pulled from Azure Container Registry with the provider credentials, which need the `AcrPull` role, or anonymously from other registries. Archives in Azure blob storage can be used with the `azblob::https://account.blob.core.windows.net/container/lib.tar.gz` syntax, or a container or a prefix in a container ending with `/`, e.g. `azblob::https://account.blob.core.windows.net/container/alz/2024.07.0/`, to download every blob under the prefix as a file of the library, e.g. to mirror libraries without git access. Blob storage sources are authorized with a SAS token in the URL, which must allow listing for a container, or with the provider credentials. The `s3::` and `gcs::` syntax of go-getter is not supported. Note that if use_alz_lib is set to true then it will always be the first library used. " +
+					"The policy definitions and policy set definitions in these libraries are checked for common authoring mistakes, e.g. undeclared parameters, an effect that is not a parameter, or `value` used instead of `field`, and a warning with the file and line is shown for each. The data actions of the role definitions are checked for the `{Company}.{ProviderName}/{resourceType}/{action}` format, and a warning is shown for role definitions with data actions, as custom roles with data actions cannot be assigned at management group scope.",
+				ElementType: types.StringType,
+				Optional:    true,
 				Validators: []validator.List{
 					listvalidator.UniqueValues(),
 				},
 			},
 
+			"management_group_role_assignments": schema.MapNestedAttribute{
+				MarkdownDescription: "A map of role assignments that are declared once and added to every management group that the selectors match, e.g. a reader grant on all landing zone management groups. " +
+					"The role assignments are added to `alz_role_assignments` of the matching `alz_archetype` data sources, with the same keys, at the management group scope. " +
+					"At least one selector must be set, and all of the selectors that are set must match. " +
+					"Role assignments in `role_assignments_to_add` of the `alz_archetype` data source with the same key take precedence.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"role_definition_id": schema.StringAttribute{
+							MarkdownDescription: "The role definition resource id to assign.",
+							Required:            true,
+						},
+
+						"principal_id": schema.StringAttribute{
+							MarkdownDescription: "The principal id to assign the role to.",
+							Required:            true,
+						},
+
+						"archetypes": schema.SetAttribute{
+							MarkdownDescription: "Select the management groups whose `base_archetype` is one of these archetypes, e.g. `landing_zones`.",
+							Optional:            true,
+							ElementType:         types.StringType,
+						},
+
+						"management_group_ids_matching": schema.StringAttribute{
+							MarkdownDescription: "Select the management groups whose name matches this regular expression, e.g. `^(corp|online)$`.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+
+			"max_parallel_resolutions": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of `alz_archetype` data sources that are resolved concurrently. " +
+					"Lower the value to reduce memory use on constrained CI runners, or raise it on larger machines. " +
+					"Defaults to the number of CPUs.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+
+			"metadata_host": schema.StringAttribute{
+				MarkdownDescription: "The host name of the Azure Resource Manager to use instead of that of the `environment`, e.g. `management.local.azurestack.external` for Azure Stack Hub. " +
+					"The resource manager and login endpoints are read from its metadata endpoint when the provider is configured, and are used for all Azure API calls, including reading built-in policy definitions. " +
+					"If not specified, value will be attempted to be read from the `ARM_METADATA_HOSTNAME` environment variable.",
+				Optional: true,
+			},
+
+			"offline": schema.BoolAttribute{
+				MarkdownDescription: "Never access the network when the provider is configured or data sources are read. " +
+					"Libraries must be local directories, or be served from `lib_cache_dir` regardless of `lib_cache_ttl`. " +
+					"Only the definitions in the libraries are used, so the archetype data source fails if a policy assignment references a built-in definition that is not in a library. " +
+					"Attributes that read from Azure, such as `preflight_authorization_scope`, cannot be set. Default is `false`.",
+				Optional: true,
+			},
+
 			"oidc_request_token": schema.StringAttribute{
 				MarkdownDescription: "The bearer token for the request to the OIDC provider. For use when authenticating using OpenID Connect. If not specified, value will be attempted to be read from the first non-empty value of the `ARM_OIDC_REQUEST_TOKEN` and `ACTIONS_ID_TOKEN_REQUEST_TOKEN` environment variables.",
 				Optional:            true,
@@ -180,6 +596,100 @@ func (p *AlzProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				Optional:            true,
 			},
 
+			"parallelism": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of built-in policy definitions and policy set definitions that are read from Azure concurrently when `prefetch_built_in_definitions` is `true`. " +
+					"Definitions read when an `alz_archetype` data source is read are read one at a time. " +
+					"Raise the value to speed up large hierarchies, or lower it if the requests are throttled. Default is `10`.",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 100),
+				},
+			},
+
+			"parameter_substitutions": schema.StringAttribute{
+				MarkdownDescription: "Policy assignment parameter values to apply to every management group. " +
+					"Each value is set in every policy assignment that has a parameter of the same name, e.g. `emailSecurityContact`. " +
+					"Values supplied in `policy_assignments_to_modify` of the `alz_archetype` data source take precedence. " +
+					"String values may contain `${management_group_name}` and `${management_group_display_name}`, which are replaced with the name and display name of each management group. " +
+					"In HCL, escape the tokens as `$${management_group_name}`. " +
+					"**Note:** This is a JSON string, use `jsonencode()` to construct the map. " +
+					"Example: `jsonencode({\"emailSecurityContact\": \"security@example.com\"})`. " +
+					"Values can also be supplied in the ARM format, e.g. `jsonencode({ emailSecurityContact = { value = \"security@example.com\" } })`.",
+				CustomType: alztypes.PolicyParameterType{},
+				Optional:   true,
+			},
+
+			"policy_default_values": schema.StringAttribute{
+				MarkdownDescription: "Values for the policy default values declared in the libraries, e.g. `ama_user_assigned_managed_identity_id` or `log_analytics_workspace_id` in the ALZ library. " +
+					"Each value is set in every parameter that the library policy default values files, e.g. `alz_policy_default_values.json`, declare to accept it, in the policy assignments of every management group. " +
+					"Values supplied in `policy_assignments_to_modify` of the `alz_archetype` data source take precedence, and these values take precedence over `parameter_substitutions`. " +
+					"The provider fails if a default is not declared in the libraries. " +
+					"**Note:** This is a JSON string, use `jsonencode()` to construct the map. " +
+					"Example: `jsonencode({\"log_analytics_workspace_id\": azurerm_log_analytics_workspace.example.id})`",
+				CustomType: alztypes.PolicyParameterType{},
+				Optional:   true,
+			},
+
+			"preflight_authorization_scope": schema.StringAttribute{
+				MarkdownDescription: "The name of the management group at the top of the hierarchy. " +
+					"If set, the provider checks that its principal can create management groups, policy definitions, policy set definitions and policy assignments at this management group when it is configured, " +
+					"e.g. with the Management Group Contributor and Resource Policy Contributor roles. " +
+					"This fails early with a clear message instead of an authorization error part way through an apply.",
+				Optional: true,
+			},
+
+			"partner_id": schema.StringAttribute{
+				MarkdownDescription: "A GUID/UUID that is registered with Microsoft to attribute the usage of the Azure API calls to a partner, e.g. for a managed service provider. " +
+					"It is added to the user agent of the Azure API calls as `pid-<partner_id>`, as the `azurerm` provider does. The `pid-` prefix is optional. " +
+					"If not specified, value will be attempted to be read from the `ARM_PARTNER_ID` environment variable.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(partnerIdRegex, "The partner id must be a valid UUID, optionally prefixed with `pid-`."),
+				},
+			},
+
+			"prefetch_built_in_definitions": schema.BoolAttribute{
+				MarkdownDescription: "Whether the built-in policy definitions and policy set definitions assigned by the library policy assignments are read from Azure when the provider is configured, " +
+					"concurrently, at most `parallelism` at a time, rather than when each `alz_archetype` data source is read. " +
+					"This keeps the latency of each data source low with large hierarchies, at the cost of reading the definitions of archetypes that are not used. " +
+					"Ignored if `offline` is `true`. Default is `false`.",
+				Optional: true,
+			},
+
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "The URL of the HTTP proxy to use for outbound connections, e.g. `http://proxy.example.com:8080`. Applies to Azure API calls and library downloads. The hosts in the `NO_PROXY` environment variable, localhost and the instance metadata service used by managed identity are not proxied.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(regexp.MustCompile(`^(http|https|socks5)://`), "The proxy url must begin with `http://`, `https://` or `socks5://`."),
+				},
+			},
+
+			"retry": schema.SingleNestedAttribute{
+				MarkdownDescription: "The retry policy of the Azure API calls, e.g. to avoid intermittent plan failures due to throttling with large hierarchies. " +
+					"Failed requests are retried with an exponential backoff, and throttled (`429`) or unavailable (`503`) responses are retried after the delay in their `Retry-After` header instead. " +
+					"A request is not retried if its `Retry-After` is longer than `max_retry_delay`.",
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"max_retries": schema.Int64Attribute{
+						MarkdownDescription: "The maximum number of times a failed request is retried. Set to `0` to disable retries. Defaults to `3`.",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.Between(0, 100),
+						},
+					},
+
+					"max_retry_delay": schema.StringAttribute{
+						MarkdownDescription: "The maximum delay before a retry, as a duration, e.g. `2m`. Defaults to `60s`.",
+						Optional:            true,
+					},
+
+					"retry_delay": schema.StringAttribute{
+						MarkdownDescription: "The delay before the first retry, as a duration, e.g. `2s`. The delay doubles with each retry, up to `max_retry_delay`. Defaults to `800ms`.",
+						Optional:            true,
+					},
+				},
+			},
+
 			"skip_provider_registration": schema.BoolAttribute{
 				MarkdownDescription: "Should the provider skip registering all of the resource providers that it supports, if they're not already registered? Default is `false`. If not specified, value will be attempted to be read from the `ARM_SKIP_PROVIDER_REGISTRATION` environment variable.",
 				Optional:            true,
@@ -193,15 +703,40 @@ func (p *AlzProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				},
 			},
 
+			"unavailable_resource_providers": schema.SetAttribute{
+				MarkdownDescription: "The resource provider namespaces that are not available in the cloud environment, e.g. `Microsoft.Chaos`. " +
+					"Library policy definitions that reference these namespaces are removed from the `alz_archetype` data source outputs, " +
+					"together with the policy set definitions and policy assignments that use them, and a warning lists the removed objects. " +
+					"Defaults to a built-in list for the `usgovernment` and `china` environments, and an empty list for `public`. " +
+					"Setting this attribute replaces the built-in list.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+
 			"use_alz_lib": schema.BoolAttribute{
 				MarkdownDescription: "Use the default ALZ library to resolve archetypes. Default is `true`. " +
-					"The ALZ library is always used first, and then the directories or URLs specified in `lib_urls` are used in order.",
+					"The ALZ library is always used first, and then the directories or URLs specified in `lib_urls` are used in order. " +
+					"Set to `false` to use only the libraries in `lib_urls`, e.g. for a fully custom policy estate without the ALZ archetypes. " +
+					"If neither library is configured, the provider warns, and the data sources that read the libraries, e.g. `alz_archetype`, fail.",
 				Optional: true,
 			},
 
+			"alz_lib_profile": schema.StringAttribute{
+				MarkdownDescription: "The flavor of the ALZ library to use, each stored under its own path in the library repository. " +
+					"Must be one of `alz` (`platform/alz`), `amba` (`platform/amba`) or `slz` (`platform/slz`, the Sovereign Landing Zone). Default is `alz`. " +
+					fmt.Sprintf("The `alz_lib_ref` attribute must be a release of the profile, e.g. `platform/slz@<version>`. It defaults to the pinned release `%s` for `alz`, and must be set for the other profiles.", alzLibRef),
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(alzLibProfileNames()...),
+				},
+			},
+
 			"alz_lib_ref": schema.StringAttribute{
-				MarkdownDescription: fmt.Sprintf("The reference (tag) in the ALZ library to use. Default is `%s`.", alzLibRef),
-				Optional:            true,
+				MarkdownDescription: fmt.Sprintf("The reference (tag) in the ALZ library to use. Default is `%s` for the `alz` `alz_lib_profile`. The other profiles do not have a default. ", alzLibRef) +
+					"A release can also be given as the library path and version, e.g. `platform/alz@2024.07.5`, where the path must be that of the `alz_lib_profile`. " +
+					"A version constraint, e.g. `~> 2024.03.0` or `platform/alz@~> 2024.03.0`, can be used instead to select the latest matching release of the `alz_lib_profile`, e.g. `platform/alz/`. " +
+					"The resolved reference is available from the `alz_library_references` data source.",
+				Optional: true,
 			},
 
 			"use_cli": schema.BoolAttribute{
@@ -218,6 +753,55 @@ func (p *AlzProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				MarkdownDescription: "Allow OpenID Connect to be used for authentication. Default is `false`. If not specified, value will be attempted to be read from the `ARM_USE_OIDC` environment variable.",
 				Optional:            true,
 			},
+
+			"user_agent_suffix": schema.StringAttribute{
+				MarkdownDescription: "A value appended to the user agent of the Azure API calls, so that the traffic of a pipeline or team can be identified in the Azure activity logs. " +
+					"If not specified, value will be attempted to be read from the `TF_APPEND_USER_AGENT` environment variable.",
+				Optional: true,
+			},
+
+			"validate_policy_aliases": schema.BoolAttribute{
+				MarkdownDescription: "Whether the aliases used as the `field` of conditions in the policy definitions from `lib_urls` are validated against the live Azure alias catalog. " +
+					"An invalid alias does not cause an error in Azure, but the condition never matches, so the policy silently has no effect. " +
+					"A warning with the file and line is shown for each alias that is not in the catalog. " +
+					"The catalog is read once when the provider is configured. If it cannot be read, a warning is shown and the aliases are not validated. Default is `false`.",
+				Optional: true,
+			},
+
+			"warn_on_removed_policy_references": schema.BoolAttribute{
+				MarkdownDescription: "Whether references to policy assignments that are not in the archetype are warnings instead of errors. " +
+					"This applies to the keys of `policy_assignments_to_modify`, `policy_assignments_to_fan_out` and `assignment_principal_ids`, and to `depends_on_assignments`, of the `alz_archetype` data source. " +
+					"Use this when upgrading the libraries, which may remove policy assignments that the configuration still references. " +
+					"The references are ignored, and the warning lists the changes to the configuration that remove them. Default is `false`.",
+				Optional: true,
+			},
+
+			"warning_verbosity": schema.StringAttribute{
+				MarkdownDescription: "How the warnings of the provider configuration and of each `alz_archetype` data source are shown. " +
+					"The other data sources and resources return at most one warning, which is always shown. " +
+					"Must be one of `summary` or `all`. With `summary`, more than one warning is replaced by a single warning that counts them by summary, " +
+					"and the details of each warning are written to the provider logs at the `WARN` level. With `all`, each warning is shown. Default is `all`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(warningVerbosities...),
+				},
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"timeouts": schema.SingleNestedBlock{
+				MarkdownDescription: "The timeouts of the provider, e.g. to raise them for large libraries or slow connections, or to lower them so that a plan fails quickly rather than hanging.",
+				Attributes: map[string]schema.Attribute{
+					"configure": schema.StringAttribute{
+						MarkdownDescription: "The maximum duration of the provider configuration, including the library downloads and the Azure requests, e.g. `10m`. Defaults to `5m`.",
+						Optional:            true,
+					},
+					"read": schema.StringAttribute{
+						MarkdownDescription: "The maximum duration of the read of a data source that does not set its own read timeout, including the Azure requests, e.g. `10m`. Defaults to `5m`.",
+						Optional:            true,
+					},
+				},
+			},
 		},
 	}
 }
@@ -233,14 +817,32 @@ func (p *AlzProvider) Configure(ctx context.Context, req provider.ConfigureReque
 	}
 
 	tflog.Debug(ctx, "Provider AlzLib not present, beginning configuration")
+	started := time.Now()
 
 	var data AlzProviderModel
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	config := data
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	// The warnings are summarized when the configuration returns, as the default verbosity is set later.
+	defer func() {
+		resp.Diagnostics = summarizeWarnings(ctx, resp.Diagnostics, data.WarningVerbosity.ValueString())
+	}()
+
+	defer startProfile(ctx, data.DebugProfileDir.ValueString(), "configure")()
+
+	configureTimeout, readTimeout, diags := providerTimeouts(data.Timeouts)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, configureTimeout)
+	defer cancel()
+
 	// Read the environment variables and set in data
 	// if the data is not already set and the environment variable is set.
 	configureFromEnvironment(&data)
@@ -253,25 +855,73 @@ func (p *AlzProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		return
 	}
 
+	// Disable the outbound calls that are not explicitly configured.
+	resp.Diagnostics.Append(essentialNetworkOnly(config, &data)...)
+
 	// Set the default values if not already set in the config or by environment.
 	configureDefaults(&data)
 
+	// Create the http client used for all outbound connections.
+	httpClient, err := newHttpClient(data)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create http client", err.Error())
+		return
+	}
+	if p.transport != nil {
+		httpClient.Transport = p.transport
+	}
+	downloadOptions := libraryDownloadOptions(data, httpClient)
+
+	if resp.Diagnostics.Append(offlineConfigDiagnostics(data, nil)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Get the endpoints of the cloud, which may be read from the metadata host.
+	cloudConfig, err := cloudConfiguration(ctx, data, httpClient)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not determine cloud configuration", err.Error())
+		return
+	}
+
+	retry, diags := retryOptions(data.Retry)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The options of all of the Azure SDK clients and credentials.
+	clientOptions := azcore.ClientOptions{
+		Cloud:     cloudConfig,
+		Retry:     retry,
+		Transport: httpClient,
+	}
+
+	userAgent := providerUserAgent(p.version, data.PartnerId.ValueString(), data.UserAgentSuffix.ValueString())
+
 	// Get a token credential.
-	cred, diags := getTokenCredential(data)
+	cred, diags := getTokenCredential(data, clientOptions)
 	resp.Diagnostics = append(resp.Diagnostics, diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	// Create the clients
-	clients, diags := getClients(cred, data, fmt.Sprintf("%s/%s", userAgentBase, p.version))
+	clients, diags := getClients(cred, data, clientOptions, userAgent)
 	resp.Diagnostics = append(resp.Diagnostics, diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	if scope := data.PreflightAuthorizationScope.ValueString(); scope != "" {
+		if err := preflightAuthorizationCheck(ctx, clients.ManagementGroupsClient, scope); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("preflight_authorization_scope"), "Pre-flight authorization check failed", err.Error())
+			return
+		}
+	}
+
 	// Create the AlzLib.
-	alz, diags := configureAlzLib(cred, data, fmt.Sprintf("%s/%s", userAgentBase, p.version))
+	builtInDefinitions := newBuiltInDefinitionRecorder()
+	alz, policyClients, diags := configureAlzLib(cred, data, clientOptions, userAgent, builtInDefinitions)
 	resp.Diagnostics = append(resp.Diagnostics, diags...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -281,11 +931,69 @@ func (p *AlzProvider) Configure(ctx context.Context, req provider.ConfigureReque
 
 	// Create the fs.FS library file systems based on the configuration.
 	urls := make([]string, 0)
+	alzLibRefResolved := ""
+	alzLibRefConstraint := ""
+	profile := alzLibProfiles[data.AlzLibProfile.ValueString()]
+	var lock *libraryLock
+	if !data.LockFile.IsNull() {
+		if lock, err = readLibraryLock(data.LockFile.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("lock_file"), "Failed to read library lock file", err.Error())
+			return
+		}
+		if lock == nil && data.LockFileStrict.ValueBool() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("lock_file"),
+				"Library lock file not found",
+				fmt.Sprintf("The library lock file %s does not exist and `lock_file_strict` is set. Run a plan without `lock_file_strict` to create it.", data.LockFile.ValueString()),
+			)
+			return
+		}
+	}
 	if data.UseAlzLib.ValueBool() {
-		q := url.Values{}
-		q.Add("ref", data.AlzLibRef.ValueString())
-		q.Add("depth", "1")
-		urls = append(urls, alzLibUrlFmtStr+q.Encode())
+		if data.AlzLibRef.ValueString() == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("alz_lib_ref"),
+				"ALZ library reference not set",
+				fmt.Sprintf("The `alz_lib_profile` %s does not have a pinned release. Set `alz_lib_ref` to a release of %s, e.g. `%s@<version>`, or to a version constraint.", data.AlzLibProfile.ValueString(), profile.path, profile.path),
+			)
+			return
+		}
+		alzLibRefResolved, err = normalizeAlzLibRef(data.AlzLibRef.ValueString(), profile)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("alz_lib_ref"), "Invalid ALZ library reference", err.Error())
+			return
+		}
+		if locked, ok := lock.lockedAlzLibRef(alzLibRefResolved); ok {
+			tflog.Debug(ctx, "Using locked alz_lib_ref", map[string]interface{}{
+				"constraint": alzLibRefResolved,
+				"ref":        locked,
+			})
+			alzLibRefConstraint, alzLibRefResolved = alzLibRefResolved, locked
+		} else if isAlzLibRefConstraint(alzLibRefResolved) {
+			if data.Offline.ValueBool() {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("alz_lib_ref"),
+					offlineErrorSummary,
+					fmt.Sprintf("The version constraint %s is resolved by listing the ALZ library releases. Use a release tag, or a `lock_file` that locks the constraint.", alzLibRefResolved),
+				)
+				return
+			}
+			alzLibRefConstraint = alzLibRefResolved
+			tags, err := listAlzLibTags(ctx, &downloadOptions)
+			if err != nil {
+				resp.Diagnostics.AddError("Failed to list ALZ library releases", err.Error())
+				return
+			}
+			if alzLibRefResolved, err = resolveAlzLibRef(alzLibRefResolved, profile.tagPrefix(), tags); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("alz_lib_ref"), "Failed to resolve ALZ library version constraint", err.Error())
+				return
+			}
+			tflog.Debug(ctx, "Resolved alz_lib_ref version constraint", map[string]interface{}{
+				"constraint": data.AlzLibRef.ValueString(),
+				"ref":        alzLibRefResolved,
+			})
+		}
+		urls = append(urls, profile.url(alzLibRefResolved))
 	}
 	if len(data.LibUrls.Elements()) != 0 {
 		// We turn the list of elements into a list of strings,
@@ -298,18 +1006,211 @@ func (p *AlzProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		}
 		urls = append(urls, dirs...)
 	}
+	// Without libraries, the data sources that read them, e.g. alz_archetype, fail when they are read,
+	// but a configuration that only uses the other data sources and resources still works.
+	if len(urls) == 0 {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("lib_urls"),
+			"No libraries configured",
+			"The ALZ library is not used, as `use_alz_lib` is false or `essential_network_only` is set, and `lib_urls` is empty. "+
+				"The data sources that read the libraries, e.g. `alz_archetype`, will fail. Set `lib_urls` to the directories or URLs of your libraries.",
+		)
+	}
+	if resp.Diagnostics.Append(offlineConfigDiagnostics(data, urls)...); resp.Diagnostics.HasError() {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
-	defer cancel()
-	libdirfs, err := getLibs(ctx, urls)
+	var cache *libfetcher.Cache
+	if !data.LibCacheDir.IsNull() {
+		ttl, err := time.ParseDuration(data.LibCacheTtl.ValueString())
+		if err != nil || ttl < 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("lib_cache_ttl"), "Invalid library cache TTL", fmt.Sprintf("The library cache TTL %s must be a non-negative duration, e.g. `24h`.", data.LibCacheTtl.ValueString()))
+			return
+		}
+		cache = &libfetcher.Cache{
+			Dir:     data.LibCacheDir.ValueString(),
+			TTL:     ttl,
+			Refresh: data.ForceRefresh.ValueBool(),
+			Offline: data.Offline.ValueBool(),
+		}
+	}
+	// Credentials for git are not needed offline, and a token request would access the network.
+	var gitHeaders map[string]string
+	if !data.Offline.ValueBool() {
+		gitHeaders, diags = libGitHeaders(ctx, data.LibGitCredentials, cred)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	libOptions := downloadOptions
+	libOptions.Credential = cred
+	libOptions.GitHeaders = gitHeaders
+	libOptions.GitSshKeyPath = data.LibGitSshKeyPath.ValueString()
+	libdirfs, err := getLibs(ctx, urls, &libOptions, cache)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to download libraries", err.Error())
 		return
 	}
+	// The sources may contain credentials, so only the redacted sources are logged, reported or stored.
+	names := make([]string, len(urls))
+	for i, src := range urls {
+		names[i] = libfetcher.RedactSource(src)
+	}
+	if data.LibAttestation != nil {
+		verifier, diags := newLibraryAttestationVerifier(ctx, data.LibAttestation)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(verifier.verify(urls, libdirfs)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if !data.LockFile.IsNull() {
+		resp.Diagnostics.Append(checkLibraryLock(data.LockFile.ValueString(), data.LockFileStrict.ValueBool(), lock, urls, libdirfs, alzLibRefConstraint, alzLibRefResolved)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if !data.LibDigests.IsNull() {
+		digests := make(map[string]string, len(data.LibDigests.Elements()))
+		resp.Diagnostics.Append(data.LibDigests.ElementsAs(ctx, &digests, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if err := checkLibraryDigests(digests, urls, libdirfs); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("lib_digests"), "Library digests do not match", err.Error())
+			return
+		}
+	}
+	libraries, err := newLibraryInfos(urls, libdirfs)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read library provenance", err.Error())
+		return
+	}
+	library, err := newLibraryIndex(libdirfs)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to index libraries", err.Error())
+		return
+	}
+	// Report the objects defined in more than one library before alzlib, which only names the first.
+	if resp.Diagnostics.Append(libraryOverwriteDiagnostics(data.LibOverwrite.ValueString(), library, names)...); resp.Diagnostics.HasError() {
+		return
+	}
+	for i, src := range names {
+		fields := map[string]interface{}{"source": src}
+		for kind, n := range library.ObjectCounts(i) {
+			fields[kind+"s"] = n
+		}
+		tflog.Debug(ctx, "Indexed library", fields)
+	}
+	for _, o := range library.Overrides() {
+		libs := make([]string, len(o.libraries))
+		for i, n := range o.libraries {
+			libs[i] = names[n]
+		}
+		tflog.Debug(ctx, "Library object overwritten by a later library", map[string]interface{}{
+			"object":        o.kind + " " + o.name,
+			"libraries":     libs,
+			"lib_overwrite": data.LibOverwrite.ValueString(),
+		})
+	}
+	initStarted := time.Now()
 	if err := alz.Init(ctx, libdirfs...); err != nil {
 		resp.Diagnostics.AddError("Failed to initialize AlzLib", err.Error())
 		return
 	}
+	tflog.Debug(ctx, "Initialized AlzLib", map[string]interface{}{
+		"archetypes":  len(alz.ListArchetypes()),
+		"duration_ms": time.Since(initStarted).Milliseconds(),
+	})
+	if data.PrefetchBuiltInDefinitions.ValueBool() && !data.Offline.ValueBool() {
+		// Read the built-in definitions of every library policy assignment in one pass, rather than in each data source read.
+		// alzlib reads one definition at a time, so they are read concurrently first and alzlib reads them from the recorder.
+		ids := library.PolicyAssignmentDefinitionIds()
+		prefetchStarted := time.Now()
+		if err := builtInDefinitions.Prefetch(ctx, policyClients, alz, ids, int(data.Parallelism.ValueInt64())); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("prefetch_built_in_definitions"), "Failed to prefetch built-in definitions", err.Error())
+			return
+		}
+		if err := alz.GetDefinitionsFromAzure(ctx, ids); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("prefetch_built_in_definitions"), "Failed to prefetch built-in definitions", err.Error())
+			return
+		}
+		tflog.Debug(ctx, "Prefetched built-in definitions", map[string]interface{}{
+			"definitions": len(ids),
+			"duration_ms": time.Since(prefetchStarted).Milliseconds(),
+		})
+	}
+	// Only the libraries from `lib_urls` are checked, as the ALZ library is not authored by the user.
+	lintFrom := 0
+	if data.UseAlzLib.ValueBool() {
+		lintFrom = 1
+	}
+	var aliases mapset.Set[string]
+	if data.ValidatePolicyAliases.ValueBool() {
+		if aliases, err = clients.ResourceProvidersClient.ListAliases(ctx); err != nil {
+			resp.Diagnostics.AddAttributeWarning(path.Root("validate_policy_aliases"), "Unable to read the Azure alias catalog, policy aliases are not validated", err.Error())
+		}
+	}
+	lintFindings, err := lintPolicyLibraries(libdirfs, names, lintFrom, aliases)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to check library policy definitions", err.Error())
+		return
+	}
+	for _, f := range lintFindings {
+		resp.Diagnostics.AddWarning("Possible mistake in library policy definition", f.String())
+	}
+	roleFindings, err := lintRoleDefinitions(libdirfs, names, lintFrom)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to check library role definitions", err.Error())
+		return
+	}
+	for _, f := range roleFindings {
+		resp.Diagnostics.AddWarning("Possible mistake in library role definition", f.String())
+	}
+	parameterSubstitutions, err := convertPolicyAssignmentParametersToSdkType(data.ParameterSubstitutions)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("parameter_substitutions"), "Invalid parameter substitutions", err.Error())
+		return
+	}
+	policyDefaultValues, err := convertPolicyAssignmentParametersToSdkType(data.PolicyDefaultValues)
+	if err == nil {
+		err = checkPolicyDefaultValues(policyDefaultValues, library)
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("policy_default_values"), "Invalid policy default values", err.Error())
+		return
+	}
+
+	excludeDefaultAssignments, diags := compileExcludeDefaultAssignments(ctx, data.ExcludeDefaultAssignmentsMatching)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	identityOverrides, diags := compileIdentityOverrides(ctx, data.IdentityOverrides)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	managementGroupRoleAssignments, diags := compileManagementGroupRoleAssignments(ctx, data.ManagementGroupRoleAssignments)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var unavailableResourceProviders []string
+	if !data.UnavailableResourceProviders.IsNull() {
+		unavailableResourceProviders = make([]string, 0, len(data.UnavailableResourceProviders.Elements()))
+		resp.Diagnostics.Append(data.UnavailableResourceProviders.ElementsAs(ctx, &unavailableResourceProviders, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 
 	// Store the alz pointer in the provider struct so we don't have to do all this work every time `.Configure` is called.
 	// Due to fetch from Azure, it takes approx 30 seconds each time and is called 4-5 time during a single acceptance test.
@@ -317,15 +1218,51 @@ func (p *AlzProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		AlzLib:  alz,
 		mu:      &sync.Mutex{},
 		clients: clients,
+		library: library,
+
+		parameterSubstitutions: parameterSubstitutions,
+		policyDefaultValues:    policyDefaultValues,
+		alzLibProfile:          profile,
+		alzLibRef:              data.AlzLibRef.ValueString(),
+		alzLibRefResolved:      alzLibRefResolved,
+		libUrls:                names,
+		libraries:              libraries,
+		libUrlsFrom:            lintFrom,
+		providerVersion:        p.version,
+		libraryDownloadOptions: downloadOptions,
+
+		assertNoAzureWrites:            data.AssertNoAzureWrites.ValueBool(),
+		checkExistingManagementGroups:  data.CheckExistingManagementGroups.ValueBool(),
+		offline:                        data.Offline.ValueBool(),
+		warnOnRemovedPolicyReferences:  data.WarnOnRemovedPolicyReferences.ValueBool(),
+		readTimeout:                    readTimeout,
+		debugProfileDir:                data.DebugProfileDir.ValueString(),
+		defaultNonComplianceMessages:   data.DefaultNonComplianceMessages.ValueBool(),
+		archetypeDefaults:              data.ArchetypeDefaults,
+		excludeDefaultAssignments:      excludeDefaultAssignments,
+		identityOverrides:              identityOverrides,
+		managementGroupRoleAssignments: managementGroupRoleAssignments,
+		unavailableResourceProviders:   unavailableResourceProvidersForEnvironment(data.Environment.ValueString(), unavailableResourceProviders),
+		resolutions:                    newResolutionLimiter(int(data.MaxParallelResolutions.ValueInt64())),
+		managementGroupSubscriptions:   make(map[string][]string),
+		policyAssignmentDependencies:   make(map[string]map[string][]string),
+		subscriptions:                  newSubscriptionDeclarations(),
+		builtInDefinitions:             builtInDefinitions,
+		warningVerbosity:               data.WarningVerbosity.ValueString(),
 	}
 	resp.DataSourceData = p.alz
 	resp.ResourceData = p.alz
-	tflog.Debug(ctx, "Provider configuration finished")
+	tflog.Debug(ctx, "Provider configuration finished", map[string]interface{}{
+		"libraries":   len(urls),
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
 }
 
 func (p *AlzProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewPolicyRoleAssignmentResource,
+		NewSubscriptionPlacementResource,
+		NewEmittedObjectsResource,
 	}
 }
 
@@ -333,6 +1270,27 @@ func (p *AlzProvider) DataSources(ctx context.Context) []func() datasource.DataS
 	return []func() datasource.DataSource{
 		NewArchetypeDataSource,
 		NewArchetypeKeysDataSource,
+		NewArchitectureDataSource,
+		NewPolicyExemptionsDataSource,
+		NewLibraryReferencesDataSource,
+		NewUnusedLibraryContentDataSource,
+		NewLibChangelogDataSource,
+		NewHierarchyImportDataSource,
+		NewPolicyDefinitionSearchDataSource,
+		NewProviderInfoDataSource,
+		NewDeploymentWavesDataSource,
+		NewComplianceReportDataSource,
+	}
+}
+
+func (p *AlzProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewDaysToDurationFunction,
+		NewJsonPatchFunction,
+		NewOutputSchemaFunction,
+		NewPolicyAssignmentToLibraryFunction,
+		NewSemicolonDelimitedFunction,
+		NewSyntheticLibraryFunction,
 	}
 }
 
@@ -362,6 +1320,10 @@ func configureAuxTenants(ctx context.Context, data *AlzProviderModel) diag.Diagn
 			auxTenants = strings.Split(v, ";")
 		}
 		var diags diag.Diagnostics
+		if len(auxTenants) > maxAuxiliaryTenants {
+			diags.AddAttributeError(path.Root("auxiliary_tenant_ids"), "Too many auxiliary tenants", fmt.Sprintf("ARM_AUXILIARY_TENANT_IDS has %d tenant ids, at most %d are supported.", len(auxTenants), maxAuxiliaryTenants))
+			return diags
+		}
 		data.AuxiliaryTenantIds, diags = types.ListValueFrom(ctx, types.StringType, auxTenants)
 		return diags
 	}
@@ -390,6 +1352,18 @@ func configureFromEnvironment(data *AlzProviderModel) {
 		data.Environment = types.StringValue(val)
 	}
 
+	if val := getFirstSetEnvVar("ARM_METADATA_HOSTNAME"); val != "" && data.MetadataHost.IsNull() {
+		data.MetadataHost = types.StringValue(val)
+	}
+
+	if val := getFirstSetEnvVar("ARM_PARTNER_ID"); val != "" && data.PartnerId.IsNull() {
+		data.PartnerId = types.StringValue(val)
+	}
+
+	if val := getFirstSetEnvVar("TF_APPEND_USER_AGENT"); val != "" && data.UserAgentSuffix.IsNull() {
+		data.UserAgentSuffix = types.StringValue(val)
+	}
+
 	if val := getFirstSetEnvVar("ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN"); val != "" && data.OidcRequestToken.IsNull() {
 		data.OidcRequestToken = types.StringValue(val)
 	}
@@ -466,6 +1440,17 @@ func configureAzIdentityEnvironment(data *AlzProviderModel) {
 	}
 }
 
+// libraryDownloadOptions returns the options of the library downloads that do not depend on the library source.
+// The git CLI does not use the http client, so the proxy and CA certificates are passed to the git commands,
+// rather than set in the environment of the provider process.
+func libraryDownloadOptions(data AlzProviderModel, httpClient *http.Client) libfetcher.Options {
+	return libfetcher.Options{
+		HttpClient:     httpClient,
+		GitProxyUrl:    data.ProxyUrl.ValueString(),
+		GitCaCertsPath: data.CustomCaCerts.ValueString(),
+	}
+}
+
 // listElementsToStrings converts a list of attr.Value to a list of strings.
 func listElementsToStrings(list []attr.Value) []string {
 	if len(list) == 0 {
@@ -483,33 +1468,58 @@ func listElementsToStrings(list []attr.Value) []string {
 }
 
 // configureAlzLib configures the alzlib for use by the provider.
-func configureAlzLib(token *azidentity.ChainedTokenCredential, data AlzProviderModel, userAgent string) (*alzlib.AlzLib, diag.Diagnostics) {
+// It also returns the policy client factory used by alzlib, to read built-in definitions through the same pipeline.
+func configureAlzLib(token *azidentity.ChainedTokenCredential, data AlzProviderModel, clientOptions azcore.ClientOptions, userAgent string, builtInDefinitions *builtInDefinitionRecorder) (*alzlib.AlzLib, *armpolicy.ClientFactory, diag.Diagnostics) {
 	var diags diag.Diagnostics
-	popts := new(policy.ClientOptions)
-	popts.DisableRPRegistration = data.SkipProviderRegistration.ValueBool()
-	popts.PerRetryPolicies = append(popts.PerRetryPolicies, withUserAgent(userAgent))
+	popts := azureClientOptions(data, clientOptions, userAgent)
+
+	// The policies run in order: the recorder, then the version policy, then the cache policy.
+	// The recorder is first, so that it records the definitions returned by the version and cache policies.
+	if builtInDefinitions != nil {
+		popts.PerCallPolicies = append(popts.PerCallPolicies, builtInDefinitions)
+	}
+
+	// The version policy is before the cache policy, so that the pinned versions are cached by version.
+	if versions := builtInDefinitionVersions(data.BuiltInDefinitionVersions); len(versions) != 0 {
+		popts.PerCallPolicies = append(popts.PerCallPolicies, &BuiltInDefinitionVersionPolicy{Versions: versions})
+	}
+
+	if !data.BuiltInDefinitionCacheDir.IsNull() {
+		ttl, err := time.ParseDuration(data.BuiltInDefinitionCacheTtl.ValueString())
+		if err != nil || ttl < 0 {
+			diags.AddAttributeError(path.Root("built_in_definition_cache_ttl"), "Invalid built-in definition cache TTL", fmt.Sprintf("The built-in definition cache TTL %s must be a non-negative duration, e.g. `24h`.", data.BuiltInDefinitionCacheTtl.ValueString()))
+			return nil, nil, diags
+		}
+		popts.PerCallPolicies = append(popts.PerCallPolicies, &BuiltInDefinitionCachePolicy{
+			Dir:     data.BuiltInDefinitionCacheDir.ValueString(),
+			TTL:     ttl,
+			Refresh: data.ForceRefresh.ValueBool(),
+		})
+	}
 
 	alz := alzlib.NewAlzLib()
 	cf, err := armpolicy.NewClientFactory("", token, popts)
 	if err != nil {
 		diags.AddError("failed to create Azure Policy client factory: %v", err.Error())
-		return nil, diags
+		return nil, nil, diags
 	}
 
-	alz.AddPolicyClient(cf)
+	// Offline, alzlib must not read missing definitions from Azure.
+	if !data.Offline.ValueBool() {
+		alz.AddPolicyClient(cf)
+	}
 
-	alz.Options.AllowOverwrite = data.LibOverwriteEnabled.ValueBool()
+	alz.Options.AllowOverwrite = data.LibOverwrite.ValueString() != libOverwriteError
+	alz.Options.Parallelism = int(data.Parallelism.ValueInt64())
 
-	return alz, diags
+	return alz, cf, diags
 }
 
-func getClients(token *azidentity.ChainedTokenCredential, data AlzProviderModel, userAgent string) (*AlzProviderClients, diag.Diagnostics) {
+func getClients(token *azidentity.ChainedTokenCredential, data AlzProviderModel, clientOptions azcore.ClientOptions, userAgent string) (*AlzProviderClients, diag.Diagnostics) {
 	var diags diag.Diagnostics
 	clients := new(AlzProviderClients)
 
-	popts := new(policy.ClientOptions)
-	popts.DisableRPRegistration = data.SkipProviderRegistration.ValueBool()
-	popts.PerRetryPolicies = append(popts.PerRetryPolicies, withUserAgent(userAgent))
+	popts := azureClientOptions(data, clientOptions, userAgent)
 
 	client, err := armauthorization.NewRoleAssignmentsClient("", token, popts)
 
@@ -522,39 +1532,107 @@ func getClients(token *azidentity.ChainedTokenCredential, data AlzProviderModel,
 
 	clients.RoleAssignmentsClient = client
 
+	mgClient, err := newManagementGroupsClient(token, popts)
+	if err != nil {
+		diags.AddError("failed to create Azure Management Groups client: %v", err.Error())
+		return clients, diags
+	}
+
+	clients.ManagementGroupsClient = mgClient
+
+	rpClient, err := newResourceProvidersClient(token, popts)
+	if err != nil {
+		diags.AddError("failed to create Azure Resource Providers client: %v", err.Error())
+		return clients, diags
+	}
+
+	clients.ResourceProvidersClient = rpClient
+
+	paClient, err := armpolicy.NewAssignmentsClient("", token, popts)
+	if err != nil {
+		diags.AddError("failed to create Azure Policy Assignments client: %v", err.Error())
+		return clients, diags
+	}
+
+	clients.PolicyAssignmentsClient = paClient
+
 	return clients, diags
 }
 
 // getTokenCredential gets a token credential based on the provider data.
-func getTokenCredential(data AlzProviderModel) (*azidentity.ChainedTokenCredential, diag.Diagnostics) {
-	var diags diag.Diagnostics
-	var cloudConfig cloud.Configuration
-	env := data.Environment.ValueString()
-	switch strings.ToLower(env) {
-	case "public":
-		cloudConfig = cloud.AzurePublic
-	case "usgovernment":
-		cloudConfig = cloud.AzureGovernment
-	case "china":
-		cloudConfig = cloud.AzureChina
-	default:
-		diags.AddError("Could not determine cloud configuration", "Valid values are 'public', 'usgovernment', or 'china'")
-		return nil, diags
-	}
-
+func getTokenCredential(data AlzProviderModel, clientOptions azcore.ClientOptions) (*azidentity.ChainedTokenCredential, diag.Diagnostics) {
 	auxTenants := listElementsToStrings(data.AuxiliaryTenantIds.Elements())
 
 	option := &azidentity.DefaultAzureCredentialOptions{
 		AdditionallyAllowedTenants: auxTenants,
-		ClientOptions: azcore.ClientOptions{
-			Cloud: cloudConfig,
-		},
-		TenantID: data.TenantId.ValueString(),
+		ClientOptions:              clientOptions,
+		TenantID:                   data.TenantId.ValueString(),
 	}
 
+	if !data.AuthMethod.IsNull() {
+		return newAuthMethodCredential(data, option)
+	}
 	return newDefaultAzureCredential(data, option)
 }
 
+// compileExcludeDefaultAssignments compiles the regular expressions in the exclude_default_assignments_matching attribute.
+func compileExcludeDefaultAssignments(ctx context.Context, l types.List) ([]*regexp.Regexp, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if l.IsNull() || l.IsUnknown() {
+		return nil, diags
+	}
+	var exprs []string
+	diags.Append(l.ElementsAs(ctx, &exprs, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for i, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			diags.AddAttributeError(path.Root("exclude_default_assignments_matching").AtListIndex(i), "Invalid regular expression", err.Error())
+			continue
+		}
+		res = append(res, re)
+	}
+	return res, diags
+}
+
+// identityOverride is a compiled entry of the identity_overrides attribute.
+type identityOverride struct {
+	re         *regexp.Regexp
+	identityId string
+}
+
+// compileIdentityOverrides compiles the regular expressions in the identity_overrides attribute.
+// The result is sorted by expression so that the first match is deterministic.
+func compileIdentityOverrides(ctx context.Context, m types.Map) ([]identityOverride, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if m.IsNull() || m.IsUnknown() {
+		return nil, diags
+	}
+	overrides := make(map[string]string, len(m.Elements()))
+	diags.Append(m.ElementsAs(ctx, &overrides, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	exprs := make([]string, 0, len(overrides))
+	for k := range overrides {
+		exprs = append(exprs, k)
+	}
+	sort.Strings(exprs)
+	res := make([]identityOverride, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			diags.AddAttributeError(path.Root("identity_overrides").AtMapKey(expr), "Invalid regular expression", err.Error())
+			continue
+		}
+		res = append(res, identityOverride{re: re, identityId: overrides[expr]})
+	}
+	return res, diags
+}
+
 // configureDefaults sets default values if they aren't already set.
 func configureDefaults(data *AlzProviderModel) {
 	// Use azure public cloud by default.
@@ -587,14 +1665,72 @@ func configureDefaults(data *AlzProviderModel) {
 		data.UseAlzLib = types.BoolValue(true)
 	}
 
+	// Read built-in definitions with the same parallelism as alzlib by default.
+	if data.Parallelism.IsNull() {
+		data.Parallelism = types.Int64Value(defaultParallelism)
+	}
+
+	// Do not generate non-compliance messages by default.
+	if data.DefaultNonComplianceMessages.IsNull() {
+		data.DefaultNonComplianceMessages = types.BoolValue(false)
+	}
+
+	// Read built-in definitions when they are first used by default.
+	if data.PrefetchBuiltInDefinitions.IsNull() {
+		data.PrefetchBuiltInDefinitions = types.BoolValue(false)
+	}
+
+	// Make all outbound calls by default.
+	if data.EssentialNetworkOnly.IsNull() {
+		data.EssentialNetworkOnly = types.BoolValue(false)
+	}
+
+	// Access the network by default.
+	if data.Offline.IsNull() {
+		data.Offline = types.BoolValue(false)
+	}
+
 	// Do not allow library overwrite by default.
 	if data.LibOverwriteEnabled.IsNull() {
 		data.LibOverwriteEnabled = types.BoolValue(false)
 	}
 
-	// Set alzLibRef
-	if data.AlzLibRef.IsNull() {
-		data.AlzLibRef = types.StringValue(alzLibRef)
+	// Fail on objects defined in more than one library by default, unless the deprecated lib_overwrite_enabled is set.
+	if data.LibOverwrite.IsNull() {
+		data.LibOverwrite = types.StringValue(libOverwriteError)
+		if data.LibOverwriteEnabled.ValueBool() {
+			data.LibOverwrite = types.StringValue(libOverwriteWarn)
+		}
+	}
+
+	// Cache libraries for a day by default.
+	if data.LibCacheTtl.IsNull() {
+		data.LibCacheTtl = types.StringValue("24h")
+	}
+
+	// Cache built-in definitions for a day by default.
+	if data.BuiltInDefinitionCacheTtl.IsNull() {
+		data.BuiltInDefinitionCacheTtl = types.StringValue("24h")
+	}
+
+	// Use the ALZ library profile by default.
+	if data.AlzLibProfile.IsNull() {
+		data.AlzLibProfile = types.StringValue(alzLibProfileDefault)
+	}
+
+	// Set alzLibRef to the default of the profile, if it has one.
+	if ref := alzLibProfiles[data.AlzLibProfile.ValueString()].defaultRef; data.AlzLibRef.IsNull() && ref != "" {
+		data.AlzLibRef = types.StringValue(ref)
+	}
+
+	// Resolve one archetype per CPU by default.
+	if data.MaxParallelResolutions.IsNull() {
+		data.MaxParallelResolutions = types.Int64Value(int64(runtime.NumCPU()))
+	}
+
+	// Show each warning by default.
+	if data.WarningVerbosity.IsNull() {
+		data.WarningVerbosity = types.StringValue(warningVerbosityAll)
 	}
 }
 
@@ -609,7 +1745,9 @@ func newDefaultAzureCredential(data AlzProviderModel, options *azidentity.Defaul
 	if data.UseOidc.ValueBool() {
 		oidcCred, err := NewOidcCredential(&OidcCredentialOptions{
 			ClientOptions: azcore.ClientOptions{
-				Cloud: options.Cloud,
+				Cloud:     options.Cloud,
+				Retry:     options.Retry,
+				Transport: options.Transport,
 			},
 			AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
 			TenantID:                   data.TenantId.ValueString(),
@@ -677,30 +1815,36 @@ func newDefaultAzureCredential(data AlzProviderModel, options *azidentity.Defaul
 
 // getLibs downloads the libraries from the URLs and returns a slice of fs.FS
 // for use in the alzlib.
-func getLibs(ctx context.Context, urls []string) ([]fs.FS, error) {
+// The fetcher for each URL is selected by its scheme, see the libfetcher package.
+// The options are used for every fetch, with the working directory set to that of the provider.
+// If cache is not nil, remote libraries are fetched through the cache.
+func getLibs(ctx context.Context, urls []string, opts *libfetcher.Options, cache *libfetcher.Cache) ([]fs.FS, error) {
 	res := make([]fs.FS, len(urls))
 	pwd, err := os.Getwd()
-	client := &getter.Client{}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
+	opts.Pwd = pwd
 
 	for i, src := range urls {
-		dst := filepath.Join(alzLibDirBase, strconv.Itoa(i))
-		if _, err := os.Stat(dst); err == nil {
-			if err := os.RemoveAll(dst); err != nil {
-				return nil, fmt.Errorf("failed to remove existing directory %s: %w", dst, err)
-			}
-		}
-		req := &getter.Request{
-			Src: src,
-			Dst: dst,
-			Pwd: pwd,
+		var lib fs.FS
+		var err error
+		started := time.Now()
+		if cache != nil {
+			lib, err = cache.Fetch(ctx, src, opts)
+		} else {
+			lib, err = libfetcher.Fetch(ctx, src, filepath.Join(alzLibDirBase, strconv.Itoa(i)), opts)
 		}
-		if _, err := client.Get(ctx, req); err != nil {
-			return nil, err
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch library %s: %w", libfetcher.RedactSource(src), err)
 		}
-		res[i] = os.DirFS(dst)
+		tflog.Debug(ctx, "Fetched library", map[string]interface{}{
+			"source":      libfetcher.RedactSource(src),
+			"scheme":      libfetcher.Scheme(src),
+			"cache":       cache != nil,
+			"duration_ms": time.Since(started).Milliseconds(),
+		})
+		res[i] = lib
 	}
 	return res, nil
 }
@@ -0,0 +1,229 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// gitCommitRegexp matches a full git object id.
+var gitCommitRegexp = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &ProviderInfoDataSource{}
+
+func NewProviderInfoDataSource() datasource.DataSource {
+	return &ProviderInfoDataSource{}
+}
+
+// ProviderInfoDataSource defines the data source implementation.
+type ProviderInfoDataSource struct {
+	alz *alzProviderData
+}
+
+// ProviderInfoDataSourceModel describes the data source data model.
+type ProviderInfoDataSourceModel struct {
+	AlzLibCommit      types.String              `tfsdk:"alz_lib_commit"`
+	AlzLibRefResolved types.String              `tfsdk:"alz_lib_ref_resolved"`
+	Id                types.String              `tfsdk:"id"`
+	Libraries         []ProviderInfoLibraryType `tfsdk:"libraries"`
+	ProviderVersion   types.String              `tfsdk:"provider_version"`
+}
+
+// ProviderInfoLibraryType describes a loaded library.
+type ProviderInfoLibraryType struct {
+	GitCommit types.String `tfsdk:"git_commit"`
+	Sha256    types.String `tfsdk:"sha256"`
+	Source    types.String `tfsdk:"source"`
+}
+
+// libraryInfo is the provenance of a loaded library.
+type libraryInfo struct {
+	source    string
+	sha256    string
+	gitCommit string // empty if the library is not a git repository
+}
+
+// newLibraryInfos returns the provenance of the libraries, which are in the same order as the sources.
+// The credentials in the sources are redacted, as in the library lock, as the sources are stored in the state.
+func newLibraryInfos(sources []string, libs []fs.FS) ([]libraryInfo, error) {
+	res := make([]libraryInfo, len(sources))
+	for i, src := range sources {
+		src = libfetcher.RedactSource(src)
+		digest, err := libraryDigest(libs[i])
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute the digest of library %s: %w", src, err)
+		}
+		res[i] = libraryInfo{source: src, sha256: digest, gitCommit: gitHeadCommit(libs[i])}
+	}
+	return res, nil
+}
+
+// gitHeadCommit returns the commit checked out in the git repository of the library, e.g. a cloned library,
+// or an empty string if the library is not a git repository or the commit cannot be read.
+func gitHeadCommit(lib fs.FS) string {
+	head, err := fs.ReadFile(lib, ".git/HEAD")
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !ok {
+		return validGitCommit(string(head))
+	}
+	if b, err := fs.ReadFile(lib, path.Join(".git", ref)); err == nil {
+		return validGitCommit(string(b))
+	}
+	packed, err := fs.ReadFile(lib, ".git/packed-refs")
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(packed))
+	for scanner.Scan() {
+		if commit, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return validGitCommit(commit)
+		}
+	}
+	return ""
+}
+
+// validGitCommit returns the trimmed commit, or an empty string if it is not a git object id.
+func validGitCommit(s string) string {
+	s = strings.TrimSpace(s)
+	if !gitCommitRegexp.MatchString(s) {
+		return ""
+	}
+	return s
+}
+
+func (d *ProviderInfoDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_provider_info"
+}
+
+func (d *ProviderInfoDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Provider info data source. Returns the provider version and the provenance of the loaded libraries, " +
+			"so that deployments can record the governance content they were generated from, e.g. in outputs or tags.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"alz_lib_commit": schema.StringAttribute{
+				MarkdownDescription: "The git commit of the ALZ library release that was used. Null if the ALZ library is not used or was not cloned with git.",
+				Computed:            true,
+			},
+
+			"alz_lib_ref_resolved": schema.StringAttribute{
+				MarkdownDescription: "The ALZ library tag that was used. Null if the ALZ library is not used.",
+				Computed:            true,
+			},
+
+			"libraries": schema.ListNestedAttribute{
+				MarkdownDescription: "The loaded libraries, in the order they were processed.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"git_commit": schema.StringAttribute{
+							MarkdownDescription: "The git commit of the library. Null if the library is not a git repository.",
+							Computed:            true,
+						},
+
+						"sha256": schema.StringAttribute{
+							MarkdownDescription: "The SHA-256 digest of the library content, the same as in the `lock_file`.",
+							Computed:            true,
+						},
+
+						"source": schema.StringAttribute{
+							MarkdownDescription: "The library source. Credentials in the source, e.g. tokens and SAS signatures, are redacted.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+
+			"provider_version": schema.StringAttribute{
+				MarkdownDescription: "The version of the provider, `dev` for a local build.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *ProviderInfoDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *ProviderInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ProviderInfoDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue("provider_info")
+	data.ProviderVersion = types.StringValue(d.alz.providerVersion)
+	data.AlzLibRefResolved = types.StringNull()
+	data.AlzLibCommit = types.StringNull()
+	if d.alz.alzLibRefResolved != "" {
+		data.AlzLibRefResolved = types.StringValue(d.alz.alzLibRefResolved)
+		// The ALZ library is always the first library.
+		if len(d.alz.libraries) != 0 && d.alz.libraries[0].gitCommit != "" {
+			data.AlzLibCommit = types.StringValue(d.alz.libraries[0].gitCommit)
+		}
+	}
+	data.Libraries = make([]ProviderInfoLibraryType, len(d.alz.libraries))
+	for i, lib := range d.alz.libraries {
+		data.Libraries[i] = ProviderInfoLibraryType{
+			GitCommit: types.StringNull(),
+			Sha256:    types.StringValue(lib.sha256),
+			Source:    types.StringValue(lib.source),
+		}
+		if lib.gitCommit != "" {
+			data.Libraries[i].GitCommit = types.StringValue(lib.gitCommit)
+		}
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
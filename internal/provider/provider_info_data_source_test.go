@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHeadCommit(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	for name, tc := range map[string]struct {
+		lib  fstest.MapFS
+		want string
+	}{
+		"detached": {fstest.MapFS{".git/HEAD": {Data: []byte(commit + "\n")}}, commit},
+		"ref": {fstest.MapFS{
+			".git/HEAD":            {Data: []byte("ref: refs/heads/main\n")},
+			".git/refs/heads/main": {Data: []byte(commit + "\n")},
+		}, commit},
+		"packed ref": {fstest.MapFS{
+			".git/HEAD":        {Data: []byte("ref: refs/heads/main\n")},
+			".git/packed-refs": {Data: []byte("# pack-refs with: peeled fully-peeled sorted\n" + commit + " refs/heads/main\n")},
+		}, commit},
+		"not a repository": {fstest.MapFS{"lib.json": {Data: []byte("{}")}}, ""},
+		"invalid":          {fstest.MapFS{".git/HEAD": {Data: []byte("garbage")}}, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, gitHeadCommit(tc.lib))
+		})
+	}
+}
+
+func TestNewLibraryInfos(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	libs := []fs.FS{
+		fstest.MapFS{"lib.json": {Data: []byte("{}")}, ".git/HEAD": {Data: []byte(commit)}},
+		fstest.MapFS{"lib.json": {Data: []byte("{}")}},
+	}
+	res, err := newLibraryInfos([]string{"alz", "git::https://token@example.com/lib.git?ref=v1"}, libs)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, libraryInfo{source: "alz", sha256: res[1].sha256, gitCommit: commit}, res[0])
+	assert.Equal(t, "git::https://REDACTED@example.com/lib.git?ref=v1", res[1].source)
+	assert.Empty(t, res[1].gitCommit)
+	assert.Len(t, res[1].sha256, 64)
+}
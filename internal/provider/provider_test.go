@@ -5,16 +5,26 @@ package provider
 
 import (
 	"context"
+	"io/fs"
 	"math/big"
+	"net/http"
 	"os"
+	"sync"
 	"testing"
 
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/terraform-provider-alz/internal/libfetcher"
+	"github.com/Azure/terraform-provider-alz/internal/recorder"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -35,6 +45,57 @@ func testAccProtoV6ProviderFactoriesUnique() map[string]func() (tfprotov6.Provid
 	}
 }
 
+// testAccProtoV6ProviderFactoriesFixture returns provider factories whose provider replays the interactions
+// recorded in the fixture file, so the acceptance test runs without a tenant.
+// The service principal credentials are set to those the fixture was recorded with, which are all zeros.
+// Set ALZ_RECORD=true to send the requests to Azure using the ARM_* environment variables and replace the fixture instead.
+func testAccProtoV6ProviderFactoriesFixture(t *testing.T, fixture string) map[string]func() (tfprotov6.ProviderServer, error) {
+	t.Helper()
+	mode := recorder.ModeReplay
+	if str2Bool(os.Getenv("ALZ_RECORD")) {
+		mode = recorder.ModeRecord
+	} else {
+		t.Setenv("ARM_TENANT_ID", "00000000-0000-0000-0000-000000000000")
+		t.Setenv("ARM_CLIENT_ID", "00000000-0000-0000-0000-000000000000")
+		t.Setenv("ARM_CLIENT_SECRET", "00000000-0000-0000-0000-000000000000")
+	}
+	transport, err := recorder.New(mode, fixture, nil)
+	require.NoError(t, err)
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"alz": providerserver.NewProtocol6WithError(&AlzProvider{version: "test", transport: transport}),
+	}
+}
+
+// newTestAlzProviderData returns provider data initialised from the acceptance test library,
+// with a management group `test` using the `test` archetype.
+// The test library only uses custom definitions, so no connection to Azure is required.
+func newTestAlzProviderData(t *testing.T) *alzProviderData {
+	t.Helper()
+	libs := []fs.FS{os.DirFS("testdata/testacc_lib")}
+	alz := alzlib.NewAlzLib()
+	require.NoError(t, alz.Init(context.Background(), libs...))
+	library, err := newLibraryIndex(libs)
+	require.NoError(t, err)
+
+	arch, err := alz.CopyArchetype("test", &alzlib.WellKnownPolicyValues{DefaultLocation: to.Ptr("westeurope")})
+	require.NoError(t, err)
+	require.NoError(t, alz.AddManagementGroupToDeployment(context.Background(), alzlib.AlzManagementGroupAddRequest{
+		Id:               "test",
+		DisplayName:      "test",
+		ParentId:         "00000000-0000-0000-0000-000000000000",
+		ParentIsExternal: true,
+		Archetype:        arch,
+	}))
+	return &alzProviderData{
+		AlzLib:  alz,
+		mu:      &sync.Mutex{},
+		clients: new(AlzProviderClients),
+		library: library,
+
+		readTimeout: defaultReadTimeout,
+	}
+}
+
 // testAccPreCheck ensures that the environment is properly configured for acceptance testing.
 func testAccPreCheck(t *testing.T) {
 	// You can add code here to run prior to any test case execution, for example assertions
@@ -218,6 +279,13 @@ func TestConfigureAuxTenants(t *testing.T) {
 	assert.True(t, data.AuxiliaryTenantIds.Equal(lv))
 	assert.Empty(t, diags)
 	_ = os.Unsetenv("ARM_AUXILIARY_TENANT_IDS")
+
+	// Test when ARM_AUXILIARY_TENANT_IDS environment variable has more tenants than are supported
+	t.Setenv("ARM_AUXILIARY_TENANT_IDS", "tenant1;tenant2;tenant3;tenant4")
+	data = &AlzProviderModel{}
+	diags = configureAuxTenants(context.Background(), data)
+	assert.True(t, diags.HasError())
+	_ = os.Unsetenv("ARM_AUXILIARY_TENANT_IDS")
 }
 
 func TestConfigureAzIdentityEnvironment(t *testing.T) {
@@ -305,3 +373,101 @@ func TestListElementsToStrings(t *testing.T) {
 	result = listElementsToStrings(list)
 	assert.Nil(t, result)
 }
+
+// TestLibraryDownloadOptions tests that the proxy and CA certificates are passed to git in the download options,
+// and that the environment of the provider process is not changed.
+func TestLibraryDownloadOptions(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("GIT_SSL_CAINFO", "")
+
+	// Test when no data fields are set
+	httpClient := &http.Client{}
+	opts := libraryDownloadOptions(AlzProviderModel{}, httpClient)
+	assert.Same(t, httpClient, opts.HttpClient)
+	assert.Empty(t, opts.GitProxyUrl)
+	assert.Empty(t, opts.GitCaCertsPath)
+
+	// Test when all data fields are set
+	opts = libraryDownloadOptions(AlzProviderModel{
+		ProxyUrl:      types.StringValue("http://proxy.example.com:8080"),
+		CustomCaCerts: types.StringValue("/path/to/ca.pem"),
+	}, httpClient)
+	assert.Equal(t, "http://proxy.example.com:8080", opts.GitProxyUrl)
+	assert.Equal(t, "/path/to/ca.pem", opts.GitCaCertsPath)
+	assert.Contains(t, opts.GitEnviron(), "https_proxy=http://proxy.example.com:8080")
+	assert.Contains(t, opts.GitEnviron(), "GIT_SSL_CAINFO=/path/to/ca.pem")
+	assert.Empty(t, os.Getenv("HTTP_PROXY"))
+	assert.Empty(t, os.Getenv("HTTPS_PROXY"))
+	assert.Empty(t, os.Getenv("GIT_SSL_CAINFO"))
+}
+
+func TestCompileExcludeDefaultAssignments(t *testing.T) {
+	res, diags := compileExcludeDefaultAssignments(context.Background(), types.ListNull(types.StringType))
+	assert.False(t, diags.HasError())
+	assert.Empty(t, res)
+
+	l, _ := types.ListValueFrom(context.Background(), types.StringType, []string{"^Enable-DDoS", "Deny-.*"})
+	res, diags = compileExcludeDefaultAssignments(context.Background(), l)
+	require.False(t, diags.HasError())
+	require.Len(t, res, 2)
+	assert.True(t, res[0].MatchString("Enable-DDoS-VNET"))
+
+	l, _ = types.ListValueFrom(context.Background(), types.StringType, []string{"valid", "("})
+	_, diags = compileExcludeDefaultAssignments(context.Background(), l)
+	assert.True(t, diags.HasError())
+}
+
+func TestCompileIdentityOverrides(t *testing.T) {
+	res, diags := compileIdentityOverrides(context.Background(), types.MapNull(types.StringType))
+	assert.False(t, diags.HasError())
+	assert.Empty(t, res)
+
+	m, _ := types.MapValueFrom(context.Background(), types.StringType, map[string]string{
+		"^Deploy-VM-Monitoring$": "id2",
+		"-Monitoring$":           "id1",
+	})
+	res, diags = compileIdentityOverrides(context.Background(), m)
+	require.False(t, diags.HasError())
+	require.Len(t, res, 2)
+	assert.Equal(t, "-Monitoring$", res[0].re.String())
+	assert.Equal(t, "id1", res[0].identityId)
+
+	m, _ = types.MapValueFrom(context.Background(), types.StringType, map[string]string{"(": "id"})
+	_, diags = compileIdentityOverrides(context.Background(), m)
+	assert.True(t, diags.HasError())
+}
+
+// TestConfigureAlzLibParallelism tests that the parallelism defaults to that of alzlib and is passed to alzlib.
+func TestConfigureAlzLibParallelism(t *testing.T) {
+	cred, err := azidentity.NewChainedTokenCredential([]azcore.TokenCredential{staticTokenCredential{}}, nil)
+	require.NoError(t, err)
+	data := AlzProviderModel{}
+	configureDefaults(&data)
+	assert.Equal(t, int64(alzlib.NewAlzLib().Options.Parallelism), data.Parallelism.ValueInt64())
+
+	data.Parallelism = types.Int64Value(25)
+	alz, _, diags := configureAlzLib(cred, data, azcore.ClientOptions{}, "test", nil)
+	require.False(t, diags.HasError())
+	assert.Equal(t, 25, alz.Options.Parallelism)
+}
+
+// TestConfigureDefaultsAlzLibRef tests that alz_lib_ref defaults to the pinned release of the alz profile only.
+func TestConfigureDefaultsAlzLibRef(t *testing.T) {
+	data := AlzProviderModel{}
+	configureDefaults(&data)
+	assert.Equal(t, alzLibRef, data.AlzLibRef.ValueString())
+
+	data = AlzProviderModel{AlzLibProfile: types.StringValue("slz")}
+	configureDefaults(&data)
+	assert.True(t, data.AlzLibRef.IsNull())
+}
+
+// TestGetLibsRedactsSources tests that the credentials in a library source are not in the error of a failed fetch.
+func TestGetLibsRedactsSources(t *testing.T) {
+	_, err := getLibs(context.Background(), []string{"unknown::https://token@example.com/lib.git?sig=secret"}, &libfetcher.Options{}, nil)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "token")
+	assert.NotContains(t, err.Error(), "secret")
+	assert.Contains(t, err.Error(), "unknown::https://REDACTED@example.com/lib.git?sig=REDACTED")
+}
@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// defaultConfigureTimeout bounds the library downloads and Azure requests of the provider configuration.
+	defaultConfigureTimeout = 5 * time.Minute
+	// defaultReadTimeout bounds the reads of the data sources that do not set a read timeout.
+	defaultReadTimeout = 5 * time.Minute
+)
+
+// ProviderTimeoutsType describes the timeouts provider block.
+type ProviderTimeoutsType struct {
+	Configure types.String `tfsdk:"configure"`
+	Read      types.String `tfsdk:"read"`
+}
+
+// providerTimeouts returns the configure and read timeouts from the timeouts block, which may be nil.
+// Timeouts that are not set use the defaults.
+func providerTimeouts(src *ProviderTimeoutsType) (time.Duration, time.Duration, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if src == nil {
+		return defaultConfigureTimeout, defaultReadTimeout, diags
+	}
+	duration := func(name string, v types.String, def time.Duration) time.Duration {
+		if v.IsNull() || v.IsUnknown() {
+			return def
+		}
+		d, err := time.ParseDuration(v.ValueString())
+		if err != nil || d <= 0 {
+			diags.AddAttributeError(path.Root("timeouts").AtName(name), "Invalid duration", fmt.Sprintf("The value %s must be a positive duration, e.g. `10m`.", v.ValueString()))
+			return def
+		}
+		return d
+	}
+	configure := duration("configure", src.Configure, defaultConfigureTimeout)
+	read := duration("read", src.Read, defaultReadTimeout)
+	return configure, read, diags
+}
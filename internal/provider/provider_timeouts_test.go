@@ -0,0 +1,28 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderTimeouts(t *testing.T) {
+	configure, read, diags := providerTimeouts(nil)
+	require.False(t, diags.HasError())
+	assert.Equal(t, defaultConfigureTimeout, configure)
+	assert.Equal(t, defaultReadTimeout, read)
+
+	configure, read, diags = providerTimeouts(&ProviderTimeoutsType{Configure: types.StringValue("20m"), Read: types.StringNull()})
+	require.False(t, diags.HasError())
+	assert.Equal(t, 20*time.Minute, configure)
+	assert.Equal(t, defaultReadTimeout, read)
+
+	_, _, diags = providerTimeouts(&ProviderTimeoutsType{Configure: types.StringValue("0s"), Read: types.StringValue("later")})
+	assert.Equal(t, 2, diags.ErrorsCount())
+}
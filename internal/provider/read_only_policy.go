@@ -0,0 +1,58 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// errAzureWriteBlocked is returned for requests that are blocked by `assert_no_azure_writes`.
+var errAzureWriteBlocked = errors.New("the provider is configured with `assert_no_azure_writes` and does not send write requests to Azure")
+
+// ReadOnlyPolicy fails any request that is not a read, without sending it.
+type ReadOnlyPolicy struct{}
+
+func (c ReadOnlyPolicy) Do(req *policy.Request) (*http.Response, error) {
+	switch req.Raw().Method {
+	case http.MethodGet, http.MethodHead:
+		return req.Next()
+	}
+	return nil, nonRetriableError{fmt.Errorf("%s %s: %w", req.Raw().Method, req.Raw().URL.Path, errAzureWriteBlocked)}
+}
+
+// nonRetriableError is an error that the Azure SDK retry policy returns without retrying the request.
+type nonRetriableError struct {
+	error
+}
+
+// NonRetriable implements the errorinfo.NonRetriable interface of the Azure SDK.
+func (nonRetriableError) NonRetriable() {}
+
+func (e nonRetriableError) Unwrap() error {
+	return e.error
+}
+
+var _ policy.Policy = ReadOnlyPolicy{}
+
+// azureClientOptions returns the options for the Azure SDK clients.
+// If `assert_no_azure_writes` is set, resource provider registration is disabled,
+// as it sends a write request, and every other write request fails.
+// The auxiliary tenants are authenticated in each request, for cross-tenant access, e.g. with Azure Lighthouse.
+func azureClientOptions(data AlzProviderModel, clientOptions azcore.ClientOptions, userAgent string) *arm.ClientOptions {
+	popts := &arm.ClientOptions{ClientOptions: clientOptions}
+	popts.AuxiliaryTenants = listElementsToStrings(data.AuxiliaryTenantIds.Elements())
+	popts.DisableRPRegistration = data.SkipProviderRegistration.ValueBool()
+	popts.PerRetryPolicies = append(popts.PerRetryPolicies, withUserAgent(userAgent))
+	if data.AssertNoAzureWrites.ValueBool() {
+		popts.DisableRPRegistration = true
+		popts.PerRetryPolicies = append(popts.PerRetryPolicies, ReadOnlyPolicy{})
+	}
+	return popts
+}
@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransport records the methods of the requests it receives and responds with an empty JSON object.
+type recordingTransport struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.methods = append(t.methods, req.Method)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+// staticTokenCredential returns a fixed token.
+type staticTokenCredential struct{}
+
+func (staticTokenCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func testReadOnlyClients(t *testing.T, assertNoAzureWrites bool) (*AlzProviderClients, *recordingTransport) {
+	cred, err := azidentity.NewChainedTokenCredential([]azcore.TokenCredential{staticTokenCredential{}}, nil)
+	require.NoError(t, err)
+	transport := new(recordingTransport)
+	data := AlzProviderModel{
+		AssertNoAzureWrites:      types.BoolValue(assertNoAzureWrites),
+		SkipProviderRegistration: types.BoolValue(false),
+	}
+	clients, diags := getClients(cred, data, azcore.ClientOptions{Cloud: cloud.AzurePublic, Transport: &http.Client{Transport: transport}}, "test")
+	require.False(t, diags.HasError())
+	return clients, transport
+}
+
+// TestAssertNoAzureWrites tests that the clients do not send write requests when `assert_no_azure_writes` is set,
+// and that read requests are sent.
+func TestAssertNoAzureWrites(t *testing.T) {
+	ctx := context.Background()
+	clients, transport := testReadOnlyClients(t, true)
+	scope := "/providers/Microsoft.Management/managementGroups/alz"
+	name := "00000000-0000-0000-0000-000000000000"
+
+	_, err := clients.RoleAssignmentsClient.Create(ctx, scope, name, armauthorization.RoleAssignmentCreateParameters{
+		Properties: &armauthorization.RoleAssignmentProperties{
+			PrincipalID:      to.Ptr(name),
+			RoleDefinitionID: to.Ptr("/providers/Microsoft.Authorization/roleDefinitions/" + name),
+		},
+	}, nil)
+	assert.ErrorIs(t, err, errAzureWriteBlocked)
+	_, err = clients.RoleAssignmentsClient.Delete(ctx, scope, name, nil)
+	assert.ErrorIs(t, err, errAzureWriteBlocked)
+	assert.Empty(t, transport.methods)
+
+	_, err = clients.ManagementGroupsClient.Get(ctx, "alz")
+	require.NoError(t, err)
+	assert.Equal(t, []string{http.MethodGet}, transport.methods)
+}
+
+func TestAssertNoAzureWritesDisabled(t *testing.T) {
+	clients, transport := testReadOnlyClients(t, false)
+	_, err := clients.RoleAssignmentsClient.Delete(context.Background(), "/providers/Microsoft.Management/managementGroups/alz", "00000000-0000-0000-0000-000000000000", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{http.MethodDelete}, transport.methods)
+}
+
+func TestPolicyRoleAssignmentsResourceModifyPlanAssertNoAzureWrites(t *testing.T) {
+	typ := tftypes.Object{AttributeTypes: map[string]tftypes.Type{"id": tftypes.String}}
+	alz := tftypes.NewValue(typ, map[string]tftypes.Value{"id": tftypes.NewValue(tftypes.String, "alz")})
+	other := tftypes.NewValue(typ, map[string]tftypes.Value{"id": tftypes.NewValue(tftypes.String, "other")})
+	null := tftypes.NewValue(typ, nil)
+
+	cases := []struct {
+		name   string
+		state  tftypes.Value
+		plan   tftypes.Value
+		writes bool
+	}{
+		{"create", null, alz, true},
+		{"update", alz, other, true},
+		{"delete", alz, null, true},
+		{"no change", alz, alz, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, assertNoAzureWrites := range []bool{true, false} {
+				r := &PolicyRoleAssignmentsResource{alz: &alzProviderData{assertNoAzureWrites: assertNoAzureWrites}}
+				resp := new(resource.ModifyPlanResponse)
+				r.ModifyPlan(context.Background(), resource.ModifyPlanRequest{
+					State: tfsdk.State{Raw: tc.state},
+					Plan:  tfsdk.Plan{Raw: tc.plan},
+				}, resp)
+				assert.Equal(t, tc.writes && assertNoAzureWrites, resp.Diagnostics.HasError())
+			}
+		})
+	}
+}
+
+// TestAzureClientOptionsAuxiliaryTenants tests that a token for each auxiliary tenant is sent with the requests of the clients.
+func TestAzureClientOptionsAuxiliaryTenants(t *testing.T) {
+	var aux string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		aux = r.Header.Get("x-ms-authorization-auxiliary")
+		_, _ = w.Write([]byte(`{"name": "alz"}`))
+	}))
+	defer srv.Close()
+
+	cred, err := azidentity.NewChainedTokenCredential([]azcore.TokenCredential{staticTokenCredential{}}, nil)
+	require.NoError(t, err)
+	clientOptions := azcore.ClientOptions{
+		Cloud: cloud.Configuration{
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {Audience: "https://management.core.windows.net/", Endpoint: srv.URL},
+			},
+		},
+		Transport: srv.Client(),
+	}
+	tenants, diags := types.ListValueFrom(context.Background(), types.StringType, []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"})
+	require.False(t, diags.HasError())
+	data := AlzProviderModel{AssertNoAzureWrites: types.BoolValue(false), AuxiliaryTenantIds: tenants, SkipProviderRegistration: types.BoolValue(true)}
+	clients, diags := getClients(cred, data, clientOptions, "test")
+	require.False(t, diags.HasError())
+
+	_, err = clients.ManagementGroupsClient.Get(context.Background(), "alz")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token, Bearer token", aux)
+}
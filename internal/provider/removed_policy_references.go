@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// removedPolicyReference is a reference in the configuration to a policy assignment that is not in the archetype,
+// e.g. because an upgrade of the library removed it.
+type removedPolicyReference struct {
+	assignment string // the name of the policy assignment that is not in the archetype
+	attribute  string // the attribute that references it, e.g. `policy_assignments_to_modify`
+	key        string // the map key of the attribute that contains the reference, empty if the assignment is the key
+}
+
+// String is the configuration edit that removes the reference.
+func (r removedPolicyReference) String() string {
+	if r.key == "" {
+		return fmt.Sprintf("remove %q from %s", r.assignment, r.attribute)
+	}
+	return fmt.Sprintf("remove %q from %s[%q].depends_on_assignments", r.assignment, r.attribute, r.key)
+}
+
+// withoutRemovedPolicyReferences returns copies of `policy_assignments_to_modify` and `policy_assignments_to_fan_out`
+// without the references to policy assignments that are not in pas, and the references that were removed, in the order of the keys.
+// The values of the returned maps are not modified, except `depends_on_assignments`.
+func withoutRemovedPolicyReferences(
+	ctx context.Context,
+	mods map[string]PolicyAssignmentType,
+	fanOuts map[string]PolicyAssignmentFanOutType,
+	pas map[string]armpolicy.Assignment,
+) (map[string]PolicyAssignmentType, map[string]PolicyAssignmentFanOutType, []removedPolicyReference, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	refs := make([]removedPolicyReference, 0)
+	resMods := make(map[string]PolicyAssignmentType, len(mods))
+	for _, k := range sortedKeys(mods) {
+		v := mods[k]
+		if _, ok := pas[k]; !ok {
+			refs = append(refs, removedPolicyReference{assignment: k, attribute: "policy_assignments_to_modify"})
+			continue
+		}
+		if isKnown(v.DependsOnAssignments) {
+			var dependsOn []string
+			diags.Append(v.DependsOnAssignments.ElementsAs(ctx, &dependsOn, false)...)
+			kept := make([]string, 0, len(dependsOn))
+			for _, dep := range dependsOn {
+				if _, ok := pas[dep]; !ok {
+					refs = append(refs, removedPolicyReference{assignment: dep, attribute: "policy_assignments_to_modify", key: k})
+					continue
+				}
+				kept = append(kept, dep)
+			}
+			if len(kept) != len(dependsOn) {
+				var d diag.Diagnostics
+				v.DependsOnAssignments, d = types.SetValueFrom(ctx, types.StringType, kept)
+				diags.Append(d...)
+			}
+		}
+		resMods[k] = v
+	}
+	resFanOuts := make(map[string]PolicyAssignmentFanOutType, len(fanOuts))
+	for _, k := range sortedKeys(fanOuts) {
+		if _, ok := pas[k]; !ok {
+			refs = append(refs, removedPolicyReference{assignment: k, attribute: "policy_assignments_to_fan_out"})
+			continue
+		}
+		resFanOuts[k] = fanOuts[k]
+	}
+	return resMods, resFanOuts, refs, diags
+}
+
+// removedPolicyReferencesSummary lists the configuration edits that remove the references.
+func removedPolicyReferencesSummary(refs []removedPolicyReference) string {
+	var sb strings.Builder
+	for _, r := range refs {
+		sb.WriteString(fmt.Sprintf("  - %s\n", r))
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithoutRemovedPolicyReferences tests that references to policy assignments that are not in the archetype are removed
+// and listed as configuration edits, and that the other references are kept.
+func TestWithoutRemovedPolicyReferences(t *testing.T) {
+	ctx := context.Background()
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-MDFC-Config":   {},
+		"Deploy-Log-Analytics": {},
+	}
+	mods := map[string]PolicyAssignmentType{
+		"Deploy-MDFC-Config": {
+			DependsOnAssignments: types.SetValueMust(types.StringType, []attr.Value{
+				types.StringValue("Deploy-Log-Analytics"),
+				types.StringValue("Deploy-ASC-Monitoring"),
+			}),
+		},
+		"Deny-Classic-Resources": {},
+		"Deploy-Log-Analytics":   {DependsOnAssignments: types.SetNull(types.StringType)},
+	}
+	fanOuts := map[string]PolicyAssignmentFanOutType{
+		"Deploy-Log-Analytics": {},
+		"Enforce-ACSB":         {},
+	}
+
+	gotMods, gotFanOuts, refs, diags := withoutRemovedPolicyReferences(ctx, mods, fanOuts, pas)
+	require.False(t, diags.HasError())
+	assert.Equal(t, []removedPolicyReference{
+		{assignment: "Deny-Classic-Resources", attribute: "policy_assignments_to_modify"},
+		{assignment: "Deploy-ASC-Monitoring", attribute: "policy_assignments_to_modify", key: "Deploy-MDFC-Config"},
+		{assignment: "Enforce-ACSB", attribute: "policy_assignments_to_fan_out"},
+	}, refs)
+	assert.Equal(t, []string{"Deploy-Log-Analytics", "Deploy-MDFC-Config"}, sortedKeys(gotMods))
+	assert.Equal(t, types.SetValueMust(types.StringType, []attr.Value{types.StringValue("Deploy-Log-Analytics")}), gotMods["Deploy-MDFC-Config"].DependsOnAssignments)
+	assert.True(t, gotMods["Deploy-Log-Analytics"].DependsOnAssignments.IsNull())
+	assert.Equal(t, []string{"Deploy-Log-Analytics"}, sortedKeys(gotFanOuts))
+	assert.Len(t, mods, 3, "the configuration is not modified")
+	assert.Len(t, mods["Deploy-MDFC-Config"].DependsOnAssignments.Elements(), 2, "the configuration is not modified")
+
+	assert.Equal(t,
+		"  - remove \"Deny-Classic-Resources\" from policy_assignments_to_modify\n"+
+			"  - remove \"Deploy-ASC-Monitoring\" from policy_assignments_to_modify[\"Deploy-MDFC-Config\"].depends_on_assignments\n"+
+			"  - remove \"Enforce-ACSB\" from policy_assignments_to_fan_out\n",
+		removedPolicyReferencesSummary(refs))
+}
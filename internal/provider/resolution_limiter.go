@@ -0,0 +1,39 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+)
+
+// resolutionLimiter limits the number of archetype data sources that are resolved concurrently.
+// Terraform reads data sources in parallel, and each resolution holds copies of the archetype policy objects,
+// so the limit caps the memory used by large hierarchies.
+type resolutionLimiter struct {
+	slots chan struct{}
+}
+
+// newResolutionLimiter returns a limiter that allows n concurrent resolutions.
+// Values less than one are treated as one.
+func newResolutionLimiter(n int) *resolutionLimiter {
+	if n < 1 {
+		n = 1
+	}
+	return &resolutionLimiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire waits for a free slot and returns the function that releases it.
+// An error is returned if the context is done before a slot is free.
+// A nil limiter does not limit resolutions.
+func (l *resolutionLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolutionLimiter tests that no more than the limit of resolutions run at once.
+func TestResolutionLimiter(t *testing.T) {
+	l := newResolutionLimiter(2)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background())
+			require.NoError(t, err)
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+// TestResolutionLimiterContext tests that waiting for a slot stops when the context is done.
+func TestResolutionLimiterContext(t *testing.T) {
+	l := newResolutionLimiter(0)
+	release, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// A nil limiter does not limit resolutions.
+	var nilLimiter *resolutionLimiter
+	release, err = nilLimiter.Acquire(ctx)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+const resourceProvidersApiVersion = "2021-04-01"
+
+// ResourceProvidersClient reads the resource provider alias catalog.
+// The Azure SDK resources module is not a dependency of the provider,
+// so the client uses the ARM pipeline directly.
+type ResourceProvidersClient struct {
+	endpoint string
+	pl       runtime.Pipeline
+}
+
+// resourceProvidersResponse is a page of the subset of the tenant resource providers response used by the client.
+type resourceProvidersResponse struct {
+	Value []struct {
+		ResourceTypes []struct {
+			Aliases []struct {
+				Name string `json:"name"`
+			} `json:"aliases"`
+		} `json:"resourceTypes"`
+	} `json:"value"`
+	NextLink string `json:"nextLink"`
+}
+
+func newResourceProvidersClient(cred azcore.TokenCredential, options *arm.ClientOptions) (*ResourceProvidersClient, error) {
+	cl, err := arm.NewClient("provider-alz.ResourceProvidersClient", "v0.0.0", cred, options)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceProvidersClient{
+		endpoint: cl.Endpoint(),
+		pl:       cl.Pipeline(),
+	}, nil
+}
+
+// ListAliases returns the names of the policy aliases of all resource providers, in lower case.
+func (c *ResourceProvidersClient) ListAliases(ctx context.Context) (mapset.Set[string], error) {
+	res := mapset.NewThreadUnsafeSet[string]()
+	u := runtime.JoinPaths(c.endpoint, "/providers") + "?api-version=" + resourceProvidersApiVersion + "&$expand=" + url.QueryEscape("resourceTypes/aliases")
+	for u != "" {
+		page := new(resourceProvidersResponse)
+		if err := armGet(ctx, c.pl, u, resourceProvidersApiVersion, page); err != nil {
+			return nil, err
+		}
+		for _, rp := range page.Value {
+			for _, rt := range rp.ResourceTypes {
+				for _, a := range rt.Aliases {
+					res.Add(strings.ToLower(a.Name))
+				}
+			}
+		}
+		u = page.NextLink
+	}
+	return res, nil
+}
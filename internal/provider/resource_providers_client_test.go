@@ -0,0 +1,47 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceProvidersClientListAliases(t *testing.T) {
+	var c *ResourceProvidersClient
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/providers", r.URL.Path)
+		assert.Equal(t, resourceProvidersApiVersion, r.URL.Query().Get("api-version"))
+		assert.Equal(t, "resourceTypes/aliases", r.URL.Query().Get("$expand"))
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"value":[{"namespace":"Microsoft.Network","resourceTypes":[{"resourceType":"networkSecurityGroups","aliases":[{"name":"Microsoft.Network/networkSecurityGroups/securityRules[*]"}]}]}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":[` +
+			`{"namespace":"Microsoft.Storage","resourceTypes":[{"resourceType":"storageAccounts","aliases":[{"name":"Microsoft.Storage/storageAccounts/supportsHttpsTrafficOnly"}]},{"resourceType":"operations"}]}` +
+			`],"nextLink":"` + c.endpoint + `/providers?api-version=` + resourceProvidersApiVersion + `&%24expand=resourceTypes%2Faliases&page=2"}`))
+	}))
+	t.Cleanup(srv.Close)
+	c = &ResourceProvidersClient{
+		endpoint: srv.URL,
+		pl: runtime.NewPipeline("test", "v0.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{
+			Transport: srv.Client(),
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		}),
+	}
+
+	aliases, err := c.ListAliases(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"microsoft.storage/storageaccounts/supportshttpstrafficonly",
+		"microsoft.network/networksecuritygroups/securityrules[*]",
+	}, aliases.ToSlice())
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// RetryType describes the retry provider attribute.
+type RetryType struct {
+	MaxRetries    types.Int64  `tfsdk:"max_retries"`
+	MaxRetryDelay types.String `tfsdk:"max_retry_delay"`
+	RetryDelay    types.String `tfsdk:"retry_delay"`
+}
+
+// retryOptions returns the retry options of the Azure SDK clients from the retry attribute.
+// Attributes that are not set use the Azure SDK defaults. The SDK waits for the `Retry-After` of a throttled response
+// instead of the backoff, and does not retry if it is longer than the maximum delay.
+func retryOptions(src *RetryType) (policy.RetryOptions, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	var res policy.RetryOptions
+	if src == nil {
+		return res, diags
+	}
+	if !src.MaxRetries.IsNull() {
+		res.MaxRetries = int32(src.MaxRetries.ValueInt64())
+		// The SDK uses its default for zero, and no retries for a negative value.
+		if res.MaxRetries == 0 {
+			res.MaxRetries = -1
+		}
+	}
+	duration := func(name string, v types.String) time.Duration {
+		if v.IsNull() {
+			return 0
+		}
+		d, err := time.ParseDuration(v.ValueString())
+		if err != nil || d <= 0 {
+			diags.AddAttributeError(path.Root("retry").AtName(name), "Invalid duration", fmt.Sprintf("The value %s must be a positive duration, e.g. `2s`.", v.ValueString()))
+			return 0
+		}
+		return d
+	}
+	res.RetryDelay = duration("retry_delay", src.RetryDelay)
+	res.MaxRetryDelay = duration("max_retry_delay", src.MaxRetryDelay)
+	if res.RetryDelay != 0 && res.MaxRetryDelay != 0 && res.RetryDelay > res.MaxRetryDelay {
+		diags.AddAttributeError(path.Root("retry").AtName("retry_delay"), "Invalid retry delay", "The retry delay must not be longer than the maximum retry delay.")
+	}
+	return res, diags
+}
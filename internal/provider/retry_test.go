@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetryOptions tests the conversion of the retry attribute to the Azure SDK retry options.
+func TestRetryOptions(t *testing.T) {
+	res, diags := retryOptions(nil)
+	require.False(t, diags.HasError())
+	assert.Equal(t, policy.RetryOptions{}, res, "the SDK defaults are used")
+
+	res, diags = retryOptions(&RetryType{
+		MaxRetries:    types.Int64Value(8),
+		MaxRetryDelay: types.StringValue("2m"),
+		RetryDelay:    types.StringValue("2s"),
+	})
+	require.False(t, diags.HasError())
+	assert.Equal(t, policy.RetryOptions{MaxRetries: 8, MaxRetryDelay: 2 * time.Minute, RetryDelay: 2 * time.Second}, res)
+
+	res, diags = retryOptions(&RetryType{MaxRetries: types.Int64Value(0), MaxRetryDelay: types.StringNull(), RetryDelay: types.StringNull()})
+	require.False(t, diags.HasError())
+	assert.Equal(t, int32(-1), res.MaxRetries, "zero disables retries")
+
+	_, diags = retryOptions(&RetryType{MaxRetries: types.Int64Null(), MaxRetryDelay: types.StringValue("1s"), RetryDelay: types.StringValue("soon")})
+	assert.Equal(t, 1, diags.ErrorsCount())
+	_, diags = retryOptions(&RetryType{MaxRetries: types.Int64Null(), MaxRetryDelay: types.StringValue("1s"), RetryDelay: types.StringValue("2s")})
+	assert.Equal(t, 1, diags.ErrorsCount())
+}
+
+// TestRetryThrottled tests that the clients retry a throttled request after its Retry-After.
+func TestRetryThrottled(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name": "alz", "properties": {"displayName": "ALZ"}}`))
+	}))
+	defer srv.Close()
+
+	cred, err := azidentity.NewChainedTokenCredential([]azcore.TokenCredential{staticTokenCredential{}}, nil)
+	require.NoError(t, err)
+	retry, diags := retryOptions(&RetryType{MaxRetries: types.Int64Value(1), MaxRetryDelay: types.StringValue("5s"), RetryDelay: types.StringNull()})
+	require.False(t, diags.HasError())
+	clientOptions := azcore.ClientOptions{
+		Cloud: cloud.Configuration{
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {Audience: "https://management.core.windows.net/", Endpoint: srv.URL},
+			},
+		},
+		Retry:     retry,
+		Transport: srv.Client(),
+	}
+	data := AlzProviderModel{AssertNoAzureWrites: types.BoolValue(false), SkipProviderRegistration: types.BoolValue(true)}
+	clients, diags := getClients(cred, data, clientOptions, "test")
+	require.False(t, diags.HasError())
+
+	start := time.Now()
+	mg, err := clients.ManagementGroupsClient.Get(context.Background(), "alz")
+	require.NoError(t, err)
+	assert.Equal(t, "ALZ", mg.DisplayName)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the Retry-After is honoured")
+}
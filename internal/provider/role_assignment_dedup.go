@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// roleAssignmentKey returns the identity of a role assignment in Azure, which cannot be assigned twice.
+// The role definition is compared by its name, a GUID, as the same role may be referenced at different scopes,
+// and the key is empty if any value is not known.
+func roleAssignmentKey(principalId, roleDefinitionId, scope string) string {
+	if principalId == "" || roleDefinitionId == "" || scope == "" {
+		return ""
+	}
+	return strings.ToLower(strings.Join([]string{principalId, resourceIdName(roleDefinitionId), strings.TrimSuffix(scope, "/")}, "|"))
+}
+
+// dedupPolicyRoleAssignments removes the policy role assignments that have the same principal, role and scope as a role assignment
+// in `alz_role_assignments`, which would fail in Azure with `RoleAssignmentExists`. The declared role assignment is kept.
+// Only the policy role assignments with a known principal, i.e. of policy assignments with a user-assigned identity, can be removed,
+// as the principal of a system-assigned identity is not known until the policy assignment is created.
+// It returns a description of each merged role assignment, sorted.
+func dedupPolicyRoleAssignments(pras map[string]AlzPolicyRoleAssignmentType, ras map[string]AlzRoleAssignmentType) []string {
+	declared := make(map[string]string, len(ras))
+	for k, ra := range ras {
+		if key := roleAssignmentKey(ra.PrincipalId.ValueString(), ra.RoleDefinitionId.ValueString(), ra.Scope.ValueString()); key != "" {
+			declared[key] = k
+		}
+	}
+	res := make([]string, 0)
+	if len(declared) == 0 {
+		return res
+	}
+	for k, pra := range pras {
+		key := roleAssignmentKey(pra.PrincipalId.ValueString(), pra.RoleDefinitionId.ValueString(), pra.Scope.ValueString())
+		name, ok := declared[key]
+		if key == "" || !ok {
+			continue
+		}
+		delete(pras, k)
+		res = append(res, fmt.Sprintf("%s of policy assignment %s is merged into %s", k, pra.AssignmentName.ValueString(), name))
+	}
+	sort.Strings(res)
+	return res
+}
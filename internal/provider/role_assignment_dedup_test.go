@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupPolicyRoleAssignments(t *testing.T) {
+	const (
+		scope       = "/providers/Microsoft.Management/managementGroups/alz"
+		contributor = "/providers/Microsoft.Authorization/roleDefinitions/b24988ac-6180-42a0-ab88-20f7382dd24c"
+		principal   = "00000000-0000-0000-0000-000000000001"
+	)
+	pra := func(assignment, principalId, role string) AlzPolicyRoleAssignmentType {
+		pid := types.StringNull()
+		if principalId != "" {
+			pid = types.StringValue(principalId)
+		}
+		return AlzPolicyRoleAssignmentType{
+			RoleDefinitionId: types.StringValue(role),
+			Scope:            types.StringValue(scope),
+			AssignmentName:   types.StringValue(assignment),
+			PrincipalId:      pid,
+		}
+	}
+	pras := map[string]AlzPolicyRoleAssignmentType{
+		"dup":       pra("Deploy-MDFC-Config", principal, contributor),
+		"other":     pra("Deploy-MDFC-Config", principal, "/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7"),
+		"no-pid":    pra("Deploy-AzActivity-Log", "", contributor),
+		"other-pid": pra("Deploy-AzActivity-Log", "00000000-0000-0000-0000-000000000002", contributor),
+	}
+	ras := map[string]AlzRoleAssignmentType{
+		"mdfc": {
+			PrincipalId:      types.StringValue(principal),
+			RoleDefinitionId: types.StringValue("/providers/Microsoft.Management/managementGroups/alz/providers/Microsoft.Authorization/roleDefinitions/B24988AC-6180-42A0-AB88-20F7382DD24C"),
+			Scope:            types.StringValue(scope + "/"),
+		},
+		"unknown": {
+			PrincipalId:      types.StringUnknown(),
+			RoleDefinitionId: types.StringValue(contributor),
+			Scope:            types.StringValue(scope),
+		},
+	}
+
+	assert.Equal(t, []string{"dup of policy assignment Deploy-MDFC-Config is merged into mdfc"}, dedupPolicyRoleAssignments(pras, ras))
+	assert.NotContains(t, pras, "dup")
+	assert.Len(t, pras, 3)
+	assert.Len(t, ras, 2)
+
+	assert.Empty(t, dedupPolicyRoleAssignments(pras, nil))
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// roleActionRegex matches a role definition action in the format `{Company}.{ProviderName}/{resourceType}/{action}`,
+// e.g. `Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read`. Segments can be wildcards.
+var roleActionRegex = regexp.MustCompile(`^(\*|[A-Za-z][A-Za-z0-9]*(\.[A-Za-z][A-Za-z0-9]*)+(/[A-Za-z0-9_.*-]+)+)$`)
+
+// lintRoleDefinitions runs static checks on the role definitions in the libraries from index from onwards,
+// and returns the findings sorted by file and line. The names are used in the findings, with the file path appended.
+// The checks catch data actions that Azure rejects when the role definition is created or assigned:
+//   - a data action or not data action that is not in the format `{Company}.{ProviderName}/{resourceType}/{action}`
+//   - data actions in a role definition, as the role definitions are assignable at the management group,
+//     and custom roles with data actions cannot be assigned at management group scope
+func lintRoleDefinitions(libs []fs.FS, names []string, from int) ([]policyLintFinding, error) {
+	res := make([]policyLintFinding, 0)
+	for i := from; i < len(libs); i++ {
+		lib := libs[i]
+		if err := fs.WalkDir(lib, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("error walking directory %s: %w", path, err)
+			}
+			if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".json" || !strings.HasPrefix(strings.ToLower(d.Name()), roleDefinitionFilePrefix) {
+				return nil
+			}
+			b, err := fs.ReadFile(lib, path)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %w", path, err)
+			}
+			f := policyLintFile{name: names[i] + "/" + path}
+			if err := json.Unmarshal(b, &f.obj); err != nil {
+				return fmt.Errorf("error unmarshalling %s: %w", path, err)
+			}
+			if f.lines, err = jsonLineIndex(b); err != nil {
+				return fmt.Errorf("error indexing %s: %w", path, err)
+			}
+			res = append(res, f.lintRoleDefinition()...)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].file != res[j].file {
+			return res[i].file < res[j].file
+		}
+		return res[i].line < res[j].line
+	})
+	return res, nil
+}
+
+// lintRoleDefinition checks the data actions and not data actions of each permission block of a role definition.
+func (f policyLintFile) lintRoleDefinition() []policyLintFinding {
+	res := make([]policyLintFinding, 0)
+	permissions, _ := jsonPointerValue(f.obj, "/properties/permissions").([]any)
+	for i := range permissions {
+		for _, key := range []string{"dataActions", "notDataActions"} {
+			ptr := "/properties/permissions/" + strconv.Itoa(i) + "/" + key
+			actions, _ := jsonPointerValue(f.obj, ptr).([]any)
+			for j, a := range actions {
+				if s, ok := a.(string); !ok || !roleActionRegex.MatchString(s) {
+					res = append(res, f.finding(ptr+"/"+strconv.Itoa(j), "the %s entry %v is not in the format {Company}.{ProviderName}/{resourceType}/{action}", key, a))
+				}
+			}
+			if key == "dataActions" && len(actions) != 0 {
+				res = append(res, f.finding(ptr, "the role definition has data actions, so it cannot be assigned at the management group, as custom roles with data actions cannot be assigned at management group scope"))
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintRoleDefinitions(t *testing.T) {
+	alz := fstest.MapFS{
+		"role_definition_alz.json": &fstest.MapFile{Data: []byte(`{
+  "name": "alz",
+  "properties": { "permissions": [ { "dataActions": [ "invalid" ] } ] }
+}`)},
+	}
+	custom := fstest.MapFS{
+		"roles/role_definition_test.json": &fstest.MapFile{Data: []byte(`{
+  "name": "test",
+  "properties": {
+    "permissions": [
+      {
+        "actions": [ "*/read" ],
+        "dataActions": [
+          "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+          "Microsoft.KeyVault/vaults/secrets/*"
+        ],
+        "notDataActions": [
+          "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/delete",
+          "blobs/delete",
+          "Microsoft.Storage/storage Accounts/read"
+        ]
+      },
+      { "actions": [ "Microsoft.Resources/*" ], "notDataActions": [] }
+    ],
+    "assignableScopes": [ "${current_scope_resource_id}" ]
+  }
+}`)},
+	}
+
+	findings, err := lintRoleDefinitions([]fs.FS{alz, custom}, []string{"alz", "custom"}, 1)
+	require.NoError(t, err)
+	got := make([]string, len(findings))
+	for i, f := range findings {
+		got[i] = f.String()
+	}
+	assert.Equal(t, []string{
+		"custom/roles/role_definition_test.json:7: the role definition has data actions, so it cannot be assigned at the management group, as custom roles with data actions cannot be assigned at management group scope",
+		"custom/roles/role_definition_test.json:13: the notDataActions entry blobs/delete is not in the format {Company}.{ProviderName}/{resourceType}/{action}",
+		"custom/roles/role_definition_test.json:14: the notDataActions entry Microsoft.Storage/storage Accounts/read is not in the format {Company}.{ProviderName}/{resourceType}/{action}",
+	}, got)
+
+	findings, err = lintRoleDefinitions([]fs.FS{alz}, []string{"alz"}, 0)
+	require.NoError(t, err)
+	assert.Len(t, findings, 2)
+
+	_, err = lintRoleDefinitions([]fs.FS{fstest.MapFS{"role_definition_bad.json": &fstest.MapFile{Data: []byte(`{`)}}}, []string{"bad"}, 0)
+	assert.ErrorContains(t, err, "error unmarshalling role_definition_bad.json")
+}
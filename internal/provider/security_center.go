@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+)
+
+const (
+	securityContactEmailParameter           = "emailSecurityContact"
+	securityContactMinimalSeverityParameter = "minimalSeverity"
+	securityContactDefaultMinimalSeverity   = "High"
+	securityCenterEnabledEffect             = "DeployIfNotExists"
+)
+
+// securityCenterPricingParameters maps the Defender for Cloud policy assignment parameters to the pricing (plan) names.
+// The parameters are used by the ALZ Deploy-MDFC-Config policy assignment.
+var securityCenterPricingParameters = map[string]string{
+	"enableAscForApis":        "Api",
+	"enableAscForAppServices": "AppServices",
+	"enableAscForArm":         "Arm",
+	"enableAscForContainers":  "Containers",
+	"enableAscForCosmosDbs":   "CosmosDbs",
+	"enableAscForCspm":        "CloudPosture",
+	"enableAscForDns":         "Dns",
+	"enableAscForKeyVault":    "KeyVaults",
+	"enableAscForOssDb":       "OpenSourceRelationalDatabases",
+	"enableAscForServers":     "VirtualMachines",
+	"enableAscForSql":         "SqlServers",
+	"enableAscForSqlOnVm":     "SqlServerVirtualMachines",
+	"enableAscForStorage":     "StorageAccounts",
+}
+
+// securityCenterResources returns the ARM JSON bodies of the Defender for Cloud pricings and security contacts
+// that are configured by the parameters of the policy assignments.
+// Pricings are keyed by plan name and security contacts by resource name.
+// The policy assignments are read in name order, and the security contact is that of the first policy assignment that sets an email.
+func securityCenterResources(pas map[string]armpolicy.Assignment) (pricings map[string]string, contacts map[string]string, err error) {
+	pricings = make(map[string]string)
+	contacts = make(map[string]string)
+	for _, name := range sortedKeys(pas) {
+		pa := pas[name]
+		if pa.Properties == nil {
+			continue
+		}
+		for param, v := range pa.Properties.Parameters {
+			if v == nil {
+				continue
+			}
+			if plan, ok := securityCenterPricingParameters[param]; ok {
+				if s, ok := v.Value.(string); !ok || !strings.EqualFold(s, securityCenterEnabledEffect) {
+					continue
+				}
+				b, err := json.Marshal(map[string]any{
+					"name": plan,
+					"type": "Microsoft.Security/pricings",
+					"properties": map[string]any{
+						"pricingTier": "Standard",
+					},
+				})
+				if err != nil {
+					return nil, nil, fmt.Errorf("policy assignment %s: unable to marshal pricing %s: %w", name, plan, err)
+				}
+				pricings[plan] = string(b)
+			}
+		}
+		if _, ok := contacts["default"]; ok {
+			continue
+		}
+		email, ok := policyAssignmentStringParameter(pa, securityContactEmailParameter)
+		if !ok || email == "" {
+			continue
+		}
+		severity, ok := policyAssignmentStringParameter(pa, securityContactMinimalSeverityParameter)
+		if !ok || severity == "" {
+			severity = securityContactDefaultMinimalSeverity
+		}
+		b, err := json.Marshal(map[string]any{
+			"name": "default",
+			"type": "Microsoft.Security/securityContacts",
+			"properties": map[string]any{
+				"emails": email,
+				"alertNotifications": map[string]any{
+					"state":           "On",
+					"minimalSeverity": severity,
+				},
+				"notificationsByRole": map[string]any{
+					"state": "On",
+					"roles": []string{"Owner"},
+				},
+			},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("policy assignment %s: unable to marshal security contact: %w", name, err)
+		}
+		contacts["default"] = string(b)
+	}
+	return pricings, contacts, nil
+}
+
+// policyAssignmentStringParameter returns the value of the parameter if it is a string.
+func policyAssignmentStringParameter(pa armpolicy.Assignment, param string) (string, bool) {
+	if pa.Properties == nil {
+		return "", false
+	}
+	v, ok := pa.Properties.Parameters[param]
+	if !ok || v == nil {
+		return "", false
+	}
+	s, ok := v.Value.(string)
+	return s, ok
+}
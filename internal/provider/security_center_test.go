@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityCenterResources(t *testing.T) {
+	pas := map[string]armpolicy.Assignment{
+		"Deploy-MDFC-Config": {Properties: &armpolicy.AssignmentProperties{Parameters: map[string]*armpolicy.ParameterValuesValue{
+			"emailSecurityContact": {Value: "security@example.com"},
+			"enableAscForServers":  {Value: "DeployIfNotExists"},
+			"enableAscForSql":      {Value: "Disabled"},
+			"enableAscForStorage":  {Value: "deployifnotexists"},
+			"logAnalytics":         {Value: "DeployIfNotExists"},
+		}}},
+		"Other": {Properties: &armpolicy.AssignmentProperties{}},
+		"Nil":   {},
+	}
+
+	pricings, contacts, err := securityCenterResources(pas)
+	require.NoError(t, err)
+	assert.Len(t, pricings, 2)
+	assert.JSONEq(t, `{"name":"VirtualMachines","type":"Microsoft.Security/pricings","properties":{"pricingTier":"Standard"}}`, pricings["VirtualMachines"])
+	assert.Contains(t, pricings, "StorageAccounts")
+	require.Contains(t, contacts, "default")
+	assert.JSONEq(t, `{
+  "name": "default",
+  "type": "Microsoft.Security/securityContacts",
+  "properties": {
+    "emails": "security@example.com",
+    "alertNotifications": {"state": "On", "minimalSeverity": "High"},
+    "notificationsByRole": {"state": "On", "roles": ["Owner"]}
+  }
+}`, contacts["default"])
+
+	pas["Deploy-MDFC-Config"].Properties.Parameters["minimalSeverity"] = &armpolicy.ParameterValuesValue{Value: "Low"}
+	_, contacts, err = securityCenterResources(pas)
+	require.NoError(t, err)
+	assert.Contains(t, contacts["default"], `"minimalSeverity":"Low"`)
+
+	pas["Deploy-MDFC-Config-Copy"] = armpolicy.Assignment{Properties: &armpolicy.AssignmentProperties{Parameters: map[string]*armpolicy.ParameterValuesValue{
+		"emailSecurityContact": {Value: "other@example.com"},
+	}}}
+	for i := 0; i < 10; i++ {
+		_, contacts, err = securityCenterResources(pas)
+		require.NoError(t, err)
+		assert.Contains(t, contacts["default"], `"emails":"security@example.com"`, "the first policy assignment by name is used")
+	}
+
+	pricings, contacts, err = securityCenterResources(map[string]armpolicy.Assignment{
+		"pa": {Properties: &armpolicy.AssignmentProperties{Parameters: map[string]*armpolicy.ParameterValuesValue{
+			"emailSecurityContact": {Value: ""},
+		}}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, pricings)
+	assert.Empty(t, contacts)
+}
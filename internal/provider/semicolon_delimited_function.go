@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &SemicolonDelimitedFunction{}
+
+func NewSemicolonDelimitedFunction() function.Function {
+	return &SemicolonDelimitedFunction{}
+}
+
+// SemicolonDelimitedFunction joins a list of strings into a semicolon-delimited string.
+type SemicolonDelimitedFunction struct{}
+
+func (f *SemicolonDelimitedFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "semicolon_delimited"
+}
+
+func (f *SemicolonDelimitedFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Join a list of strings into a semicolon-delimited string",
+		MarkdownDescription: "Joins a list of strings into a semicolon-delimited string, e.g. `[\"a\", \"b\"]` to `a;b`. " +
+			"Use this to set the parameters of the built-in policy definitions that expect a semicolon-delimited string, rather than an array. " +
+			"The values are trimmed of surrounding white space, and empty values are omitted. " +
+			"The function returns an error if a value contains a semicolon, as it could not be told apart from the delimiter.",
+		Parameters: []function.Parameter{
+			function.ListParameter{
+				Name:                "values",
+				MarkdownDescription: "The values to join.",
+				ElementType:         types.StringType,
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *SemicolonDelimitedFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var values []string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &values))
+	if resp.Error != nil {
+		return
+	}
+
+	res, err := semicolonDelimited(values)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, res))
+}
+
+// semicolonDelimited joins the trimmed, non-empty values with semicolons.
+func semicolonDelimited(values []string) (string, error) {
+	res := make([]string, 0, len(values))
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, ";") {
+			return "", fmt.Errorf("value %d (%s) contains a semicolon", i, v)
+		}
+		res = append(res, v)
+	}
+	return strings.Join(res, ";"), nil
+}
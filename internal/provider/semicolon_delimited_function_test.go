@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runSemicolonDelimitedFunction(values ...string) *function.RunResponse {
+	elems := make([]attr.Value, len(values))
+	for i, v := range values {
+		elems[i] = types.StringValue(v)
+	}
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.ListValueMust(types.StringType, elems)}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	NewSemicolonDelimitedFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestSemicolonDelimitedFunction(t *testing.T) {
+	resp := runSemicolonDelimitedFunction("uksouth", " ukwest ", "", "westeurope")
+	require.Nil(t, resp.Error)
+	assert.Equal(t, types.StringValue("uksouth;ukwest;westeurope"), resp.Result.Value())
+
+	resp = runSemicolonDelimitedFunction()
+	require.Nil(t, resp.Error)
+	assert.Equal(t, types.StringValue(""), resp.Result.Value())
+
+	resp = runSemicolonDelimitedFunction("a", "b;c")
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "value 1 (b;c) contains a semicolon")
+}
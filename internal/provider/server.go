@@ -0,0 +1,33 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+)
+
+// ProviderServers returns the protocol version 6 servers that make up the provider.
+// Resource types implemented outside of this framework provider, e.g. using SDKv2 or
+// directly with terraform-plugin-go, are added here as additional servers.
+func ProviderServers(version string) []func() tfprotov6.ProviderServer {
+	return []func() tfprotov6.ProviderServer{
+		providerserver.NewProtocol6(New(version)()),
+	}
+}
+
+// NewProviderServer returns a factory for the mux server that combines the provider servers, which is served to Terraform.
+// Terraform negotiates the protocol version with this server, so all servers must use protocol version 6,
+// and they must have the same provider schema.
+func NewProviderServer(ctx context.Context, version string) (func() tfprotov6.ProviderServer, error) {
+	muxServer, err := tf6muxserver.NewMuxServer(ctx, ProviderServers(version)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create mux server: %w", err)
+	}
+	return muxServer.ProviderServer, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewProviderServer tests that the served provider exposes the data sources and resources of the framework provider.
+func TestNewProviderServer(t *testing.T) {
+	factory, err := NewProviderServer(context.Background(), "test")
+	require.NoError(t, err)
+
+	resp, err := factory().GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Diagnostics)
+	assert.Contains(t, resp.DataSourceSchemas, "alz_archetype")
+	assert.Contains(t, resp.ResourceSchemas, "alz_policy_role_assignments")
+}
+
+// TestProviderServersMux tests that a second server with the same provider schema is combined with the provider servers.
+func TestProviderServersMux(t *testing.T) {
+	ctx := context.Background()
+	secondary := providerserver.NewProtocol6(&secondaryTestProvider{AlzProvider: &AlzProvider{version: "test"}})
+	muxServer, err := tf6muxserver.NewMuxServer(ctx, append(ProviderServers("test"), secondary)...)
+	require.NoError(t, err)
+
+	resp, err := muxServer.ProviderServer().GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Diagnostics)
+	assert.Contains(t, resp.DataSourceSchemas, "alz_archetype")
+	assert.Contains(t, resp.DataSourceSchemas, "alz_secondary")
+}
+
+// secondaryTestProvider is a provider with the provider schema of the framework provider and one data source of its own.
+type secondaryTestProvider struct {
+	*AlzProvider
+}
+
+func (p *secondaryTestProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		func() datasource.DataSource { return &secondaryTestDataSource{} },
+	}
+}
+
+func (p *secondaryTestProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return nil
+}
+
+func (p *secondaryTestProvider) Functions(ctx context.Context) []func() function.Function {
+	return nil
+}
+
+var _ provider.ProviderWithFunctions = &secondaryTestProvider{}
+
+type secondaryTestDataSource struct{}
+
+func (d *secondaryTestDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secondary"
+}
+
+func (d *secondaryTestDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{}
+}
+
+func (d *secondaryTestDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+}
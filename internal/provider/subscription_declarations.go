@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// subscriptionDeclarations records the `subscription_ids` of each `alz_archetype` data source,
+// so that a subscription declared in more than one management group is reported.
+// A subscription can only be in one management group, so the management group applied last would silently move it.
+// A nil registry records nothing.
+type subscriptionDeclarations struct {
+	mu   sync.Mutex
+	byMg map[string]subscriptionDeclaration // keyed by management group name
+}
+
+// subscriptionDeclaration is the subscriptions declared by a management group.
+type subscriptionDeclaration struct {
+	ancestry      []string // the names of the management group and its ancestors, root first
+	subscriptions []string // lower case subscription ids, without the `/subscriptions/` prefix
+}
+
+// subscriptionConflict is a subscription declared by another management group.
+type subscriptionConflict struct {
+	subscription string
+	other        string // the other management group
+	relation     string // the relation of the other management group, `ancestor`, `descendant` or empty
+}
+
+// String describes the conflict from the point of view of the management group that found it.
+func (c subscriptionConflict) String() string {
+	if c.relation == "" {
+		return fmt.Sprintf("subscription %s is also in subscription_ids of management group %s", c.subscription, c.other)
+	}
+	article := "a"
+	if c.relation == "ancestor" {
+		article = "an"
+	}
+	return fmt.Sprintf("subscription %s is also in subscription_ids of management group %s, which is %s %s of this management group", c.subscription, c.other, article, c.relation)
+}
+
+// newSubscriptionDeclarations returns an empty registry.
+func newSubscriptionDeclarations() *subscriptionDeclarations {
+	return &subscriptionDeclarations{byMg: make(map[string]subscriptionDeclaration)}
+}
+
+// Declare records the subscriptions of the management group, replacing its previous declaration,
+// and returns the subscriptions that other management groups have declared, sorted by subscription and management group.
+// The ancestry is the names of the management group and its ancestors, root first.
+func (s *subscriptionDeclarations) Declare(ancestry, subscriptionIds []string) []subscriptionConflict {
+	if s == nil || len(ancestry) == 0 {
+		return nil
+	}
+	mg := ancestry[len(ancestry)-1]
+	subs := make([]string, len(subscriptionIds))
+	for i, id := range subscriptionIds {
+		subs[i] = strings.ToLower(trimSubscriptionsPrefix(id))
+	}
+	sort.Strings(subs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byMg[mg] = subscriptionDeclaration{ancestry: ancestry, subscriptions: subs}
+	others := sortedKeys(s.byMg)
+	res := make([]subscriptionConflict, 0)
+	for _, sub := range subs {
+		for _, other := range others {
+			decl := s.byMg[other]
+			if other == mg || !slices.Contains(decl.subscriptions, sub) {
+				continue
+			}
+			c := subscriptionConflict{subscription: sub, other: other}
+			switch {
+			case slices.Contains(ancestry, other):
+				c.relation = "ancestor"
+			case slices.Contains(decl.ancestry, mg):
+				c.relation = "descendant"
+			}
+			res = append(res, c)
+		}
+	}
+	return res
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSubscriptionDeclarations tests that a subscription declared by another management group is reported
+// with the relation of the management groups, and that a management group can declare its subscriptions again.
+func TestSubscriptionDeclarations(t *testing.T) {
+	const sub1 = "00000000-0000-0000-0000-000000000001"
+	const sub2 = "00000000-0000-0000-0000-000000000002"
+	s := newSubscriptionDeclarations()
+
+	assert.Empty(t, s.Declare([]string{"root", "landingzones"}, []string{sub1}))
+	assert.Empty(t, s.Declare([]string{"root", "landingzones"}, []string{sub1, sub2}))
+	assert.Equal(t, []subscriptionConflict{
+		{subscription: sub1, other: "landingzones", relation: "descendant"},
+	}, s.Declare([]string{"root"}, []string{"/subscriptions/" + sub1}))
+	assert.Equal(t, []subscriptionConflict{
+		{subscription: sub1, other: "landingzones", relation: "ancestor"},
+		{subscription: sub1, other: "root", relation: "ancestor"},
+		{subscription: sub2, other: "landingzones", relation: "ancestor"},
+	}, s.Declare([]string{"root", "landingzones", "corp"}, []string{sub2, sub1}))
+	assert.Equal(t, []subscriptionConflict{
+		{subscription: sub2, other: "corp"},
+		{subscription: sub2, other: "landingzones"},
+	}, s.Declare([]string{"root", "platform"}, []string{sub2}))
+
+	assert.Equal(t,
+		"subscription "+sub1+" is also in subscription_ids of management group root, which is an ancestor of this management group",
+		subscriptionConflict{subscription: sub1, other: "root", relation: "ancestor"}.String())
+
+	var nilDeclarations *subscriptionDeclarations
+	assert.Nil(t, nilDeclarations.Declare([]string{"root"}, []string{sub1}))
+}
@@ -0,0 +1,297 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &SubscriptionPlacementResource{}
+var _ resource.ResourceWithImportState = &SubscriptionPlacementResource{}
+var _ resource.ResourceWithModifyPlan = &SubscriptionPlacementResource{}
+
+// subscriptionMoveAttrTypes are the attribute types of a subscription move.
+var subscriptionMoveAttrTypes = map[string]attr.Type{
+	"from": types.StringType,
+	"to":   types.StringType,
+}
+
+func NewSubscriptionPlacementResource() resource.Resource {
+	return &SubscriptionPlacementResource{}
+}
+
+// SubscriptionPlacementResource defines the resource implementation.
+// The resource only records the placement in state, it does not move the subscriptions in Azure.
+type SubscriptionPlacementResource struct{}
+
+// SubscriptionPlacementResourceModel describes the resource data model.
+type SubscriptionPlacementResourceModel struct {
+	Id               types.String `tfsdk:"id"`
+	ManagementGroups types.Map    `tfsdk:"management_groups"` // map of set of string
+	Moves            types.Map    `tfsdk:"moves"`             // map of subscriptionMove
+}
+
+// subscriptionMove is a subscription that moves between management groups.
+type subscriptionMove struct {
+	From types.String `tfsdk:"from"`
+	To   types.String `tfsdk:"to"`
+}
+
+func (r SubscriptionPlacementResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_subscription_placement"
+}
+
+func (r *SubscriptionPlacementResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Subscription placement resource. Records the subscriptions of each management group in state, " +
+			"so that the subscriptions that move between management groups are known at plan time. " +
+			"Use `moves` to sequence the moves and to send change management notifications. " +
+			"The resource does not move the subscriptions in Azure.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The id of the placement, e.g. the name of the root management group of the hierarchy.",
+			},
+			"management_groups": schema.MapAttribute{
+				Required:            true,
+				MarkdownDescription: "A map of management group names to the ids of the subscriptions in the management group. A subscription can only be in one management group.",
+				ElementType:         types.SetType{ElemType: types.StringType},
+			},
+			"moves": schema.MapNestedAttribute{
+				Computed: true,
+				MarkdownDescription: "The subscriptions that moved between management groups in the last change to `management_groups`, keyed by the lower case subscription id without the `/subscriptions/` prefix. " +
+					"A subscription that is added has a null `from`, and a subscription that is removed has a null `to`. " +
+					"The moves are empty when the resource is created, and are kept until `management_groups` changes again.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"from": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The management group the subscription was in.",
+						},
+						"to": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The management group the subscription is in.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ModifyPlan computes the moves from the prior state, so that they are shown in the plan.
+func (r *SubscriptionPlacementResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var planned SubscriptionPlacementResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &planned)...)
+	var prior *SubscriptionPlacementResourceModel
+	if !req.State.Raw.IsNull() {
+		prior = new(SubscriptionPlacementResourceModel)
+		resp.Diagnostics.Append(req.State.Get(ctx, prior)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(planned.setMoves(ctx, prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("moves"), planned.Moves)...)
+}
+
+func (r *SubscriptionPlacementResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data SubscriptionPlacementResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(data.setMoves(ctx, nil)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SubscriptionPlacementResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data SubscriptionPlacementResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.ManagementGroups.IsNull() {
+		// Imported resources have only an id.
+		data.ManagementGroups = types.MapValueMust(types.SetType{ElemType: types.StringType}, map[string]attr.Value{})
+	}
+	if data.Moves.IsNull() {
+		data.Moves = types.MapValueMust(types.ObjectType{AttrTypes: subscriptionMoveAttrTypes}, map[string]attr.Value{})
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SubscriptionPlacementResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var planned, current SubscriptionPlacementResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &planned)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &current)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(planned.setMoves(ctx, &current)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &planned)...)
+}
+
+func (r *SubscriptionPlacementResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// There is nothing to delete in Azure, the state is removed by the framework.
+}
+
+func (r *SubscriptionPlacementResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// setMoves sets the moves of the planned placement from the prior placement, which is nil if the resource is being created.
+// The moves are unknown if the planned management groups are not yet known,
+// and the prior moves are kept if the management groups have not changed.
+func (m *SubscriptionPlacementResourceModel) setMoves(ctx context.Context, prior *SubscriptionPlacementResourceModel) diag.Diagnostics {
+	moveType := types.ObjectType{AttrTypes: subscriptionMoveAttrTypes}
+	planned, known, diags := stringSetMap(ctx, m.ManagementGroups)
+	if diags.HasError() {
+		return diags
+	}
+	if !known {
+		m.Moves = types.MapUnknown(moveType)
+		return diags
+	}
+	if dupes := duplicateSubscriptions(planned); len(dupes) > 0 {
+		diags.AddAttributeError(
+			path.Root("management_groups"),
+			"Subscription in more than one management group",
+			fmt.Sprintf("A subscription can only be in one management group, these subscriptions are in more than one: %s.", strings.Join(dupes, ", ")),
+		)
+		return diags
+	}
+	var moves map[string]subscriptionMove
+	switch {
+	case prior == nil:
+		moves = make(map[string]subscriptionMove)
+	case prior.ManagementGroups.Equal(m.ManagementGroups) && !prior.Moves.IsNull() && !prior.Moves.IsUnknown():
+		m.Moves = prior.Moves
+		return diags
+	default:
+		previous, _, d := stringSetMap(ctx, prior.ManagementGroups)
+		diags.Append(d...)
+		if diags.HasError() {
+			return diags
+		}
+		moves = subscriptionMoves(previous, planned)
+	}
+	var d diag.Diagnostics
+	m.Moves, d = types.MapValueFrom(ctx, moveType, moves)
+	diags.Append(d...)
+	return diags
+}
+
+// stringSetMap returns the strings of each key of a map of set of string, e.g. the subscription ids of each management group,
+// and false if the map or any of its values are not yet known.
+func stringSetMap(ctx context.Context, m types.Map) (map[string][]string, bool, diag.Diagnostics) {
+	res := make(map[string][]string)
+	if m.IsNull() {
+		return res, true, nil
+	}
+	if m.IsUnknown() {
+		return nil, false, nil
+	}
+	var sets map[string]types.Set
+	diags := m.ElementsAs(ctx, &sets, false)
+	if diags.HasError() {
+		return nil, false, diags
+	}
+	for mg, set := range sets {
+		if set.IsUnknown() {
+			return nil, false, diags
+		}
+		var ids []types.String
+		diags.Append(set.ElementsAs(ctx, &ids, false)...)
+		if diags.HasError() {
+			return nil, false, diags
+		}
+		res[mg] = make([]string, 0, len(ids))
+		for _, id := range ids {
+			if id.IsUnknown() {
+				return nil, false, diags
+			}
+			res[mg] = append(res[mg], id.ValueString())
+		}
+	}
+	return res, true, diags
+}
+
+// subscriptionMoves returns the subscriptions whose management group differs between the prior and planned placements, keyed by subscription id.
+// Subscription ids are compared case-insensitively, with or without the `/subscriptions/` prefix, and the keys are lower case ids without the prefix.
+func subscriptionMoves(prior, planned map[string][]string) map[string]subscriptionMove {
+	from := subscriptionManagementGroups(prior)
+	to := subscriptionManagementGroups(planned)
+	res := make(map[string]subscriptionMove)
+	for sub, mg := range to {
+		if f, ok := from[sub]; !ok {
+			res[sub] = subscriptionMove{From: types.StringNull(), To: types.StringValue(mg)}
+		} else if f != mg {
+			res[sub] = subscriptionMove{From: types.StringValue(f), To: types.StringValue(mg)}
+		}
+	}
+	for sub, mg := range from {
+		if _, ok := to[sub]; !ok {
+			res[sub] = subscriptionMove{From: types.StringValue(mg), To: types.StringNull()}
+		}
+	}
+	return res
+}
+
+// subscriptionManagementGroups returns the management group of each subscription, keyed by lower case subscription id without the `/subscriptions/` prefix.
+func subscriptionManagementGroups(placement map[string][]string) map[string]string {
+	res := make(map[string]string)
+	for mg, subs := range placement {
+		for _, sub := range subs {
+			res[strings.ToLower(trimSubscriptionsPrefix(sub))] = mg
+		}
+	}
+	return res
+}
+
+// duplicateSubscriptions returns the sorted, lower case ids of the subscriptions that are in more than one management group,
+// without the `/subscriptions/` prefix.
+func duplicateSubscriptions(placement map[string][]string) []string {
+	seen := make(map[string]string)
+	dupes := make(map[string]bool)
+	for mg, subs := range placement {
+		for _, sub := range subs {
+			sub = strings.ToLower(trimSubscriptionsPrefix(sub))
+			if other, ok := seen[sub]; ok && other != mg {
+				dupes[sub] = true
+			}
+			seen[sub] = mg
+		}
+	}
+	res := make([]string, 0, len(dupes))
+	for sub := range dupes {
+		res = append(res, sub)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionMoves(t *testing.T) {
+	prior := map[string][]string{
+		"corp":   {"AAAA", "bbbb"},
+		"online": {"cccc"},
+	}
+	planned := map[string][]string{
+		"corp":    {"aaaa"},
+		"online":  {"bbbb"},
+		"sandbox": {"dddd"},
+	}
+	assert.Equal(t, map[string]subscriptionMove{
+		"bbbb": {From: types.StringValue("corp"), To: types.StringValue("online")},
+		"cccc": {From: types.StringValue("online"), To: types.StringNull()},
+		"dddd": {From: types.StringNull(), To: types.StringValue("sandbox")},
+	}, subscriptionMoves(prior, planned))
+	assert.Empty(t, subscriptionMoves(prior, prior))
+
+	// Subscription ids with and without the prefix are the same subscription.
+	assert.Empty(t, subscriptionMoves(map[string][]string{"corp": {"/subscriptions/AAAA"}}, map[string][]string{"corp": {"aaaa"}}))
+	assert.Equal(t, map[string]subscriptionMove{
+		"aaaa": {From: types.StringValue("corp"), To: types.StringValue("online")},
+	}, subscriptionMoves(map[string][]string{"corp": {"aaaa"}}, map[string][]string{"online": {"/Subscriptions/AAAA"}}))
+}
+
+func TestDuplicateSubscriptions(t *testing.T) {
+	assert.Empty(t, duplicateSubscriptions(map[string][]string{"corp": {"aaaa"}, "online": {"bbbb"}}))
+	assert.Equal(t, []string{"aaaa"}, duplicateSubscriptions(map[string][]string{"corp": {"AAAA", "bbbb"}, "online": {"aaaa"}}))
+	assert.Equal(t, []string{"aaaa"}, duplicateSubscriptions(map[string][]string{"corp": {"/subscriptions/AAAA"}, "online": {"aaaa"}}))
+}
+
+func TestSubscriptionPlacementSetMoves(t *testing.T) {
+	ctx := context.Background()
+	placement := func(mgs map[string][]string) SubscriptionPlacementResourceModel {
+		m, diags := types.MapValueFrom(ctx, types.SetType{ElemType: types.StringType}, mgs)
+		require.False(t, diags.HasError())
+		return SubscriptionPlacementResourceModel{Id: types.StringValue("alz"), ManagementGroups: m}
+	}
+	moves := func(m SubscriptionPlacementResourceModel) map[string]subscriptionMove {
+		var res map[string]subscriptionMove
+		require.False(t, m.Moves.ElementsAs(ctx, &res, false).HasError())
+		return res
+	}
+
+	// Create
+	created := placement(map[string][]string{"corp": {"aaaa"}})
+	require.False(t, created.setMoves(ctx, nil).HasError())
+	assert.Empty(t, moves(created))
+
+	// Move
+	moved := placement(map[string][]string{"corp": {}, "online": {"aaaa"}})
+	require.False(t, moved.setMoves(ctx, &created).HasError())
+	assert.Equal(t, map[string]subscriptionMove{
+		"aaaa": {From: types.StringValue("corp"), To: types.StringValue("online")},
+	}, moves(moved))
+
+	// No change keeps the moves of the last change
+	unchanged := placement(map[string][]string{"corp": {}, "online": {"aaaa"}})
+	require.False(t, unchanged.setMoves(ctx, &moved).HasError())
+	assert.True(t, unchanged.Moves.Equal(moved.Moves))
+
+	// Unknown
+	unknown := placement(nil)
+	unknown.ManagementGroups = types.MapValueMust(types.SetType{ElemType: types.StringType}, map[string]attr.Value{
+		"corp": types.SetUnknown(types.StringType),
+	})
+	require.False(t, unknown.setMoves(ctx, &moved).HasError())
+	assert.True(t, unknown.Moves.IsUnknown())
+
+	// Duplicate
+	dupe := placement(map[string][]string{"corp": {"aaaa"}, "online": {"aaaa"}})
+	assert.True(t, dupe.setMoves(ctx, &moved).HasError())
+}
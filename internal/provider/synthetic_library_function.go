@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/Azure/terraform-provider-alz/internal/synthlib"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &SyntheticLibraryFunction{}
+
+func NewSyntheticLibraryFunction() function.Function {
+	return &SyntheticLibraryFunction{}
+}
+
+// SyntheticLibraryFunction generates a synthetic library for performance testing.
+type SyntheticLibraryFunction struct{}
+
+func (f *SyntheticLibraryFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "synthetic_library"
+}
+
+func (f *SyntheticLibraryFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Generate a synthetic library for performance testing",
+		MarkdownDescription: "Generates a synthetic library with the requested number of archetypes, policy definitions and policy set definitions. " +
+			"Each policy definition and policy set definition is assigned, and the assignments are divided between the archetypes. " +
+			"The result is a map of file names to file content. Write the files to a directory, e.g. with the `local_file` resource, " +
+			"and use the directory in `lib_urls` to measure the plan time of a hierarchy design before committing to it.",
+		Parameters: []function.Parameter{
+			function.Int64Parameter{
+				Name:                "archetypes",
+				MarkdownDescription: "The number of archetypes.",
+			},
+			function.Int64Parameter{
+				Name:                "policy_definitions",
+				MarkdownDescription: "The number of policy definitions.",
+			},
+			function.Int64Parameter{
+				Name:                "policy_set_definitions",
+				MarkdownDescription: "The number of policy set definitions. Each references up to 10 of the policy definitions in the same archetype.",
+			},
+		},
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *SyntheticLibraryFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var archetypes, pds, psds int64
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &archetypes, &pds, &psds))
+	if resp.Error != nil {
+		return
+	}
+
+	files, err := synthlib.Generate(synthlib.Options{
+		Archetypes:           int(archetypes),
+		PolicyDefinitions:    int(pds),
+		PolicySetDefinitions: int(psds),
+	})
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+	res := make(map[string]string, len(files))
+	for k, v := range files {
+		res[k] = string(v)
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, res))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runSyntheticLibraryFunction(archetypes, pds, psds int64) *function.RunResponse {
+	req := function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{
+			types.Int64Value(archetypes),
+			types.Int64Value(pds),
+			types.Int64Value(psds),
+		}),
+	}
+	resp := &function.RunResponse{
+		Result: function.NewResultData(types.MapUnknown(types.StringType)),
+	}
+	NewSyntheticLibraryFunction().Run(context.Background(), req, resp)
+	return resp
+}
+
+func TestSyntheticLibraryFunction(t *testing.T) {
+	resp := runSyntheticLibraryFunction(2, 4, 1)
+	require.Nil(t, resp.Error)
+	m, ok := resp.Result.Value().(types.Map)
+	require.True(t, ok)
+	// 4 definitions and assignments, 1 set definition and assignment, 2 archetypes
+	assert.Len(t, m.Elements(), 12)
+
+	resp = runSyntheticLibraryFunction(0, 1, 0)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Error(), "archetypes must be greater than 0")
+}
@@ -0,0 +1,211 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/alzlib"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &UnusedLibraryContentDataSource{}
+
+func NewUnusedLibraryContentDataSource() datasource.DataSource {
+	return &UnusedLibraryContentDataSource{}
+}
+
+// UnusedLibraryContentDataSource defines the data source implementation.
+type UnusedLibraryContentDataSource struct {
+	alz *alzProviderData
+}
+
+// UnusedLibraryContentDataSourceModel describes the data source data model.
+type UnusedLibraryContentDataSourceModel struct {
+	Id                   types.String `tfsdk:"id"`
+	PolicyAssignments    types.Set    `tfsdk:"policy_assignments"`     // set of string
+	PolicyDefinitions    types.Set    `tfsdk:"policy_definitions"`     // set of string
+	PolicySetDefinitions types.Set    `tfsdk:"policy_set_definitions"` // set of string
+	RoleDefinitions      types.Set    `tfsdk:"role_definitions"`       // set of string
+	WarningEnabled       types.Bool   `tfsdk:"warning_enabled"`
+}
+
+func (d *UnusedLibraryContentDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_unused_library_content"
+}
+
+func (d *UnusedLibraryContentDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Unused library content data source. Lists the library objects that are not used by any management group added by the `alz_archetype` data sources, to help prune custom libraries. " +
+			"Only the libraries from `lib_urls` are checked, not the ALZ library. " +
+			"Use `depends_on` to ensure that this data source is read after all of the `alz_archetype` data sources.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "An id used for acceptance testing.",
+				Computed:            true,
+			},
+
+			"warning_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Emit a warning listing the unused library content. Default is `true`.",
+				Optional:            true,
+			},
+
+			"policy_assignments": schema.SetAttribute{
+				MarkdownDescription: "The names of the library policy assignments that are not used.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+
+			"policy_definitions": schema.SetAttribute{
+				MarkdownDescription: "The names of the library policy definitions that are not used.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+
+			"policy_set_definitions": schema.SetAttribute{
+				MarkdownDescription: "The names of the library policy set definitions that are not used.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+
+			"role_definitions": schema.SetAttribute{
+				MarkdownDescription: "The role names of the library role definitions that are not used.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *UnusedLibraryContentDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*alzProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *alzlibWithMutex, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.alz = data
+}
+
+func (d *UnusedLibraryContentDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data UnusedLibraryContentDataSourceModel
+
+	if d.alz == nil {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider has not been configured. Please see the provider documentation for configuration instructions.",
+		)
+		return
+	}
+
+	// Read Terraform configuration data into the model.
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.alz.mu.Lock()
+	defer d.alz.mu.Unlock()
+
+	if d.alz.library == nil {
+		resp.Diagnostics.AddError("Library index not found", "The library index has not been created. Please report this issue to the provider developers.")
+		return
+	}
+
+	mgs := make([]*alzlib.AlzManagementGroup, 0)
+	for _, name := range d.alz.Deployment.ListManagementGroups() {
+		mgs = append(mgs, d.alz.Deployment.GetManagementGroup(name))
+	}
+	// Only the content of the libraries from `lib_urls` is reported, as the ALZ library is not authored by the user.
+	unused := d.alz.library.ContentFrom(d.alz.libUrlsFrom).Difference(usedLibraryContent(mgs))
+
+	data.Id = types.StringValue("unused_library_content")
+	var diags diag.Diagnostics
+	data.PolicyAssignments, diags = types.SetValueFrom(ctx, types.StringType, unused.policyAssignments.ToSlice())
+	resp.Diagnostics.Append(diags...)
+	data.PolicyDefinitions, diags = types.SetValueFrom(ctx, types.StringType, unused.policyDefinitions.ToSlice())
+	resp.Diagnostics.Append(diags...)
+	data.PolicySetDefinitions, diags = types.SetValueFrom(ctx, types.StringType, unused.policySetDefinitions.ToSlice())
+	resp.Diagnostics.Append(diags...)
+	data.RoleDefinitions, diags = types.SetValueFrom(ctx, types.StringType, unused.roleDefinitions.ToSlice())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if (data.WarningEnabled.IsNull() || data.WarningEnabled.ValueBool()) && unused.Cardinality() != 0 {
+		resp.Diagnostics.AddWarning("Unused library content", unusedLibraryContentSummary(unused))
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// usedLibraryContent returns the names of the objects used by the management groups.
+func usedLibraryContent(mgs []*alzlib.AlzManagementGroup) libraryContent {
+	res := newLibraryContent()
+	for _, mg := range mgs {
+		if mg == nil {
+			continue
+		}
+		addMapKeysToSet(res.policyAssignments, mg.GetPolicyAssignmentMap())
+		addMapKeysToSet(res.policyDefinitions, mg.GetPolicyDefinitionsMap())
+		addMapKeysToSet(res.policySetDefinitions, mg.GetPolicySetDefinitionsMap())
+		addMapKeysToSet(res.roleDefinitions, mg.GetRoleDefinitionsMap())
+	}
+	return res
+}
+
+// addMapKeysToSet adds the keys of the map to the set.
+func addMapKeysToSet[T any](set mapset.Set[string], m map[string]T) {
+	for k := range m {
+		set.Add(k)
+	}
+}
+
+// unusedLibraryContentSummary returns a sorted, human readable list of the unused content.
+func unusedLibraryContentSummary(c libraryContent) string {
+	var sb strings.Builder
+	sb.WriteString("The following library content is not used by any management group:\n")
+	sections := []struct {
+		name string
+		set  mapset.Set[string]
+	}{
+		{"Policy assignments", c.policyAssignments},
+		{"Policy definitions", c.policyDefinitions},
+		{"Policy set definitions", c.policySetDefinitions},
+		{"Role definitions", c.roleDefinitions},
+	}
+	for _, s := range sections {
+		if s.set.Cardinality() == 0 {
+			continue
+		}
+		names := s.set.ToSlice()
+		sort.Strings(names)
+		sb.WriteString(fmt.Sprintf("\n%s:\n", s.name))
+		for _, n := range names {
+			sb.WriteString(fmt.Sprintf("  - %s\n", n))
+		}
+	}
+	return sb.String()
+}
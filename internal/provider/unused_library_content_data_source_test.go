@@ -0,0 +1,33 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUnusedLibraryContent tests that content used by the deployment management groups is not reported as unused.
+func TestUnusedLibraryContent(t *testing.T) {
+	data := newTestAlzProviderData(t)
+
+	// No management groups, so everything is unused.
+	unused := data.library.Difference(usedLibraryContent(nil))
+	assert.Equal(t, data.library.Cardinality(), unused.Cardinality())
+	assert.True(t, unused.policyAssignments.Contains("BlobServicesDiagnosticsLogsToWorkspace"))
+	assert.True(t, unused.policySetDefinitions.Contains("test"))
+
+	mg := data.Deployment.GetManagementGroup("test")
+	unused = data.library.Difference(usedLibraryContent([]*alzlib.AlzManagementGroup{mg, nil}))
+	assert.False(t, unused.policyAssignments.Contains("BlobServicesDiagnosticsLogsToWorkspace"))
+	assert.False(t, unused.policyDefinitions.Contains("BlobServicesDiagnosticsLogsToWorkspace"))
+	assert.True(t, unused.policySetDefinitions.Contains("test"))
+	assert.Equal(t, 1, unused.Cardinality())
+
+	summary := unusedLibraryContentSummary(unused)
+	assert.Contains(t, summary, "Policy set definitions:\n  - test\n")
+	assert.NotContains(t, summary, "Policy assignments")
+}
@@ -4,15 +4,23 @@
 package provider
 
 import (
+	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 )
 
 const (
 	HeaderUserAgent = "User-Agent"
+
+	partnerIdPrefix = "pid-"
 )
 
+// partnerIdRegex matches a partner id, which is a UUID with an optional `pid-` prefix.
+var partnerIdRegex = regexp.MustCompile(`^(?i)(pid-)?[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}$`)
+
 type UserAgentPolicy struct {
 	UserAgent string
 }
@@ -29,3 +37,19 @@ var _ policy.Policy = UserAgentPolicy{}
 func withUserAgent(userAgent string) policy.Policy {
 	return UserAgentPolicy{UserAgent: userAgent}
 }
+
+// providerUserAgent returns the user agent of the Azure API calls.
+// The partner id is added with the `pid-` prefix, as the azurerm provider does, followed by the suffix.
+func providerUserAgent(version, partnerId, suffix string) string {
+	res := fmt.Sprintf("%s/%s", userAgentBase, version)
+	if partnerId != "" {
+		if !strings.HasPrefix(strings.ToLower(partnerId), partnerIdPrefix) {
+			partnerId = partnerIdPrefix + partnerId
+		}
+		res += " " + partnerId
+	}
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		res += " " + suffix
+	}
+	return res
+}
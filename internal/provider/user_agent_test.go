@@ -0,0 +1,35 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderUserAgent(t *testing.T) {
+	const pid = "00000000-0000-0000-0000-000000000001"
+	assert.Equal(t, "AzureTerraformAlzProvider/1.0.0", providerUserAgent("1.0.0", "", ""))
+	assert.Equal(t, "AzureTerraformAlzProvider/1.0.0 pid-"+pid, providerUserAgent("1.0.0", pid, ""))
+	assert.Equal(t, "AzureTerraformAlzProvider/1.0.0 pid-"+pid, providerUserAgent("1.0.0", "pid-"+pid, ""))
+	assert.Equal(t, "AzureTerraformAlzProvider/1.0.0 pid-"+pid+" platform-team/ci", providerUserAgent("1.0.0", pid, " platform-team/ci "))
+	assert.Equal(t, "AzureTerraformAlzProvider/1.0.0 platform-team/ci", providerUserAgent("1.0.0", "", "platform-team/ci"))
+}
+
+func TestPartnerIdRegex(t *testing.T) {
+	assert.True(t, partnerIdRegex.MatchString("00000000-0000-0000-0000-000000000001"))
+	assert.True(t, partnerIdRegex.MatchString("pid-0000000A-0000-0000-0000-000000000001"))
+	assert.False(t, partnerIdRegex.MatchString("pid-"))
+	assert.False(t, partnerIdRegex.MatchString("partner"))
+}
+
+func TestConfigureFromEnvironmentUserAgent(t *testing.T) {
+	t.Setenv("ARM_PARTNER_ID", "00000000-0000-0000-0000-000000000001")
+	t.Setenv("TF_APPEND_USER_AGENT", "platform-team")
+	data := &AlzProviderModel{}
+	configureFromEnvironment(data)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", data.PartnerId.ValueString())
+	assert.Equal(t, "platform-team", data.UserAgentSuffix.ValueString())
+}
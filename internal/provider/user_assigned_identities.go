@@ -0,0 +1,95 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// AlzUserAssignedIdentityType describes a user assigned managed identity to create for the policy assignments that name it in `identity_name`.
+type AlzUserAssignedIdentityType struct {
+	Id                types.String `tfsdk:"id"`
+	Location          types.String `tfsdk:"location"`
+	Name              types.String `tfsdk:"name"`
+	PolicyAssignments types.Set    `tfsdk:"policy_assignments"` // set of string
+	ResourceGroupId   types.String `tfsdk:"resource_group_id"`
+}
+
+// userAssignedIdentity is a user assigned managed identity named in `identity_name` of `policy_assignments_to_modify`.
+type userAssignedIdentity struct {
+	assignments     []string
+	location        string
+	name            string
+	resourceGroupId string
+}
+
+// id returns the resource id of the identity.
+func (u userAssignedIdentity) id() string {
+	return u.resourceGroupId + "/providers/Microsoft.ManagedIdentity/userAssignedIdentities/" + u.name
+}
+
+// userAssignedIdentitiesToCreate returns the identities named in `identity_name` of the policy assignments, keyed by identity name.
+// An identity is created in the location of the policy assignments that use it, which is their `location` or the default location.
+// Policy assignments that share an identity must be in the same location.
+func userAssignedIdentitiesToCreate(pas map[string]PolicyAssignmentType, resourceGroupId, defaultLocation string) (map[string]userAssignedIdentity, error) {
+	keys := make([]string, 0, len(pas))
+	for k := range pas {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make(map[string]userAssignedIdentity)
+	for _, k := range keys {
+		v := pas[k]
+		if !isKnown(v.IdentityName) {
+			continue
+		}
+		if resourceGroupId == "" {
+			return nil, fmt.Errorf("policy assignment %s has an identity_name, but identity_resource_group_id is not set", k)
+		}
+		location := defaultLocation
+		if isKnown(v.Location) {
+			location = v.Location.ValueString()
+		}
+		name := v.IdentityName.ValueString()
+		u, ok := res[name]
+		if !ok {
+			u = userAssignedIdentity{location: location, name: name, resourceGroupId: resourceGroupId}
+		}
+		if !strings.EqualFold(u.location, location) {
+			return nil, fmt.Errorf("identity %s is used by policy assignments in different locations: %s is in %s, %s is in %s", name, u.assignments[0], u.location, k, location)
+		}
+		u.assignments = append(u.assignments, k)
+		res[name] = u
+	}
+	return res, nil
+}
+
+// convertUserAssignedIdentities converts the identities to the framework type, or nil if there are none.
+func convertUserAssignedIdentities(ctx context.Context, src map[string]userAssignedIdentity) (map[string]AlzUserAssignedIdentityType, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if len(src) == 0 {
+		return nil, diags
+	}
+	res := make(map[string]AlzUserAssignedIdentityType, len(src))
+	for name, u := range src {
+		assignments := append([]string(nil), u.assignments...)
+		sort.Strings(assignments)
+		set, d := types.SetValueFrom(ctx, types.StringType, assignments)
+		diags.Append(d...)
+		res[name] = AlzUserAssignedIdentityType{
+			Id:                types.StringValue(u.id()),
+			Location:          types.StringValue(u.location),
+			Name:              types.StringValue(u.name),
+			PolicyAssignments: set,
+			ResourceGroupId:   types.StringValue(u.resourceGroupId),
+		}
+	}
+	return res, diags
+}
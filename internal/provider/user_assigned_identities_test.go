@@ -0,0 +1,51 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserAssignedIdentitiesToCreate tests that the identities named by the policy assignments are returned once, in the assignment location.
+func TestUserAssignedIdentitiesToCreate(t *testing.T) {
+	rg := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/identities"
+	pas := map[string]PolicyAssignmentType{
+		"Deploy-VM-Monitoring":   {IdentityName: types.StringValue("uami-ama"), Location: types.StringNull()},
+		"Deploy-VMSS-Monitoring": {IdentityName: types.StringValue("uami-ama"), Location: types.StringValue("WestEurope")},
+		"Deploy-MDFC-Config":     {IdentityName: types.StringNull(), Location: types.StringNull()},
+	}
+
+	res, err := userAssignedIdentitiesToCreate(pas, rg, "westeurope")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	u := res["uami-ama"]
+	assert.Equal(t, rg+"/providers/Microsoft.ManagedIdentity/userAssignedIdentities/uami-ama", u.id())
+	assert.Equal(t, "westeurope", u.location)
+	assert.Equal(t, []string{"Deploy-VM-Monitoring", "Deploy-VMSS-Monitoring"}, u.assignments)
+
+	converted, diags := convertUserAssignedIdentities(context.Background(), res)
+	require.False(t, diags.HasError())
+	assert.Equal(t, types.StringValue(rg), converted["uami-ama"].ResourceGroupId)
+	assert.Equal(t, types.SetValueMust(types.StringType, []attr.Value{
+		types.StringValue("Deploy-VM-Monitoring"),
+		types.StringValue("Deploy-VMSS-Monitoring"),
+	}), converted["uami-ama"].PolicyAssignments)
+
+	pas["Deploy-VMSS-Monitoring"] = PolicyAssignmentType{IdentityName: types.StringValue("uami-ama"), Location: types.StringValue("northeurope")}
+	_, err = userAssignedIdentitiesToCreate(pas, rg, "westeurope")
+	assert.ErrorContains(t, err, "identity uami-ama is used by policy assignments in different locations")
+
+	_, err = userAssignedIdentitiesToCreate(pas, "", "westeurope")
+	assert.ErrorContains(t, err, "identity_resource_group_id is not set")
+
+	converted, diags = convertUserAssignedIdentities(context.Background(), nil)
+	assert.False(t, diags.HasError())
+	assert.Nil(t, converted)
+}
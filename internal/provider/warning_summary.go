@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// The values of `warning_verbosity`.
+const (
+	warningVerbositySummary = "summary"
+	warningVerbosityAll     = "all"
+)
+
+// warningVerbosities are the values of `warning_verbosity`.
+var warningVerbosities = []string{warningVerbositySummary, warningVerbosityAll}
+
+// summarizeWarnings replaces the warnings of the diagnostics with a single warning that counts them by summary,
+// if the verbosity is `summary` and there is more than one warning.
+// Each replaced warning is logged, so that the details are not lost. Errors are kept in their original order.
+func summarizeWarnings(ctx context.Context, diags diag.Diagnostics, verbosity string) diag.Diagnostics {
+	if verbosity != warningVerbositySummary || diags.WarningsCount() < 2 {
+		return diags
+	}
+	res := make(diag.Diagnostics, 0, diags.ErrorsCount()+1)
+	counts := make(map[string]int)
+	summaries := make([]string, 0)
+	for _, d := range diags {
+		if d.Severity() != diag.SeverityWarning {
+			res = append(res, d)
+			continue
+		}
+		fields := map[string]interface{}{
+			"summary": d.Summary(),
+			"detail":  d.Detail(),
+		}
+		if dp, ok := d.(diag.DiagnosticWithPath); ok {
+			fields["attribute"] = dp.Path().String()
+		}
+		tflog.Warn(ctx, "Provider warning", fields)
+		if counts[d.Summary()] == 0 {
+			summaries = append(summaries, d.Summary())
+		}
+		counts[d.Summary()]++
+	}
+	lines := make([]string, len(summaries))
+	for i, s := range summaries {
+		lines[i] = fmt.Sprintf("- %s (%d)", s, counts[s])
+	}
+	res.AddWarning(
+		fmt.Sprintf("%d provider warnings", diags.WarningsCount()),
+		fmt.Sprintf("%s\n\nThe details of each warning are in the provider logs, e.g. with `TF_LOG_PROVIDER=WARN`. "+
+			"Set `warning_verbosity` to `all` in the provider block to show each warning.", strings.Join(lines, "\n")),
+	)
+	return res
+}
@@ -0,0 +1,41 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeWarnings(t *testing.T) {
+	ctx := context.Background()
+	var diags diag.Diagnostics
+	diags.AddWarning("Library policy definition issue", "a")
+	diags.AddError("Failed", "b")
+	diags.AddAttributeWarning(path.Root("lib_urls"), "Deprecated policy definition", "c")
+	diags.AddWarning("Library policy definition issue", "d")
+
+	res := summarizeWarnings(ctx, diags, warningVerbositySummary)
+	require.Len(t, res, 2)
+	assert.Equal(t, diag.NewErrorDiagnostic("Failed", "b"), res[0])
+	assert.Equal(t, "3 provider warnings", res[1].Summary())
+	assert.Contains(t, res[1].Detail(), "- Library policy definition issue (2)\n- Deprecated policy definition (1)\n\n")
+
+	assert.Equal(t, diags, summarizeWarnings(ctx, diags, warningVerbosityAll))
+
+	// The warnings are shown by default, including before the default verbosity is set.
+	assert.Equal(t, diags, summarizeWarnings(ctx, diags, ""))
+	data := AlzProviderModel{}
+	configureDefaults(&data)
+	assert.Equal(t, warningVerbosityAll, data.WarningVerbosity.ValueString())
+
+	// A single warning is kept.
+	single := diag.Diagnostics{diag.NewWarningDiagnostic("Deprecated policy definition", "c")}
+	assert.Equal(t, single, summarizeWarnings(ctx, single, warningVerbositySummary))
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package recorder records and replays HTTP interactions, so that tests of code that calls
+// Azure Resource Manager can run without a live tenant.
+// Interactions are stored as JSON fixture files. Requests are matched on method and URL,
+// in the order they were recorded, and tokens in response bodies are redacted.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Mode is the mode of a Transport.
+type Mode int
+
+const (
+	// ModeReplay returns the recorded responses and never sends requests.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests and records the responses to the fixture file.
+	ModeRecord
+)
+
+// redacted is the value used in place of tokens in recorded response bodies.
+const redacted = "REDACTED"
+
+// ErrNoInteraction is returned in replay mode when there is no unused recorded interaction for a request.
+var ErrNoInteraction = errors.New("recorder: no recorded interaction")
+
+// tokenRegexp matches the OAuth2 token fields in a JSON response body.
+var tokenRegexp = regexp.MustCompile(`"(access_token|refresh_token|id_token)"\s*:\s*"[^"]*"`)
+
+// Interaction is a recorded request and response.
+type Interaction struct {
+	Method     string      `json:"method"`
+	Url        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// recordedHeaders are the response headers that are stored in the fixture.
+var recordedHeaders = []string{"Content-Type", "Location", "Retry-After", "Azure-AsyncOperation"}
+
+// Transport is an http.RoundTripper that records or replays interactions.
+type Transport struct {
+	mode         Mode
+	path         string
+	next         http.RoundTripper
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a Transport using the fixture file at path.
+// In replay mode the fixture file is read, in record mode it is replaced and next is used to send requests.
+// If next is nil, http.DefaultTransport is used.
+func New(mode Mode, path string, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{
+		mode: mode,
+		path: path,
+		next: next,
+	}
+	if mode == ModeRecord {
+		return t, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("recorder: unable to read fixture %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &t.interactions); err != nil {
+		return nil, fmt.Errorf("recorder: unable to unmarshal fixture %s: %w", path, err)
+	}
+	t.used = make([]bool, len(t.interactions))
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == ModeRecord {
+		return t.record(req)
+	}
+	return t.replay(req)
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recorder: unable to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	i := Interaction{
+		Method:     req.Method,
+		Url:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     make(http.Header),
+		Body:       tokenRegexp.ReplaceAllString(string(body), `"$1":"`+redacted+`"`),
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) != 0 {
+			i.Header[h] = v
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, i)
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save writes the interactions to the fixture file, it must be called with the lock held.
+func (t *Transport) save() error {
+	b, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("recorder: unable to marshal fixture: %w", err)
+	}
+	if err := os.WriteFile(t.path, b, 0o600); err != nil {
+		return fmt.Errorf("recorder: unable to write fixture %s: %w", t.path, err)
+	}
+	return nil
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	url := req.URL.String()
+	for idx, i := range t.interactions {
+		if t.used[idx] || i.Method != req.Method || i.Url != url {
+			continue
+		}
+		t.used[idx] = true
+		header := i.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.StatusCode, http.StatusText(i.StatusCode)),
+			StatusCode:    i.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewBufferString(i.Body)),
+			ContentLength: int64(len(i.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, url)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecordReplay tests that recorded interactions are replayed in order, with tokens redacted.
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ignored", "ignored")
+		switch r.URL.Path {
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token": "secret", "expires_in": 3600}`))
+		default:
+			_, _ = w.Write([]byte(`{"call":` + string(rune('0'+calls)) + `}`))
+		}
+	}))
+	defer srv.Close()
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	rec, err := New(ModeRecord, fixture, nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: rec}
+	for _, p := range []string{"/token", "/policy", "/policy"} {
+		resp, err := client.Get(srv.URL + p)
+		require.NoError(t, err)
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	require.Equal(t, 3, calls)
+
+	b, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "secret")
+	assert.NotContains(t, string(b), "X-Ignored")
+
+	rep, err := New(ModeReplay, fixture, nil)
+	require.NoError(t, err)
+	client = &http.Client{Transport: rep}
+	get := func(p string) (string, error) {
+		resp, err := client.Get(srv.URL + p)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		return string(b), err
+	}
+	body, err := get("/token")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"access_token": "REDACTED", "expires_in": 3600}`, body)
+	body, err = get("/policy")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"call": 2}`, body)
+	body, err = get("/policy")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"call": 3}`, body)
+	_, err = get("/policy")
+	assert.ErrorIs(t, err, ErrNoInteraction)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewReplayMissingFixture(t *testing.T) {
+	_, err := New(ModeReplay, filepath.Join(t.TempDir(), "missing.json"), nil)
+	assert.ErrorContains(t, err, "unable to read fixture")
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package synthlib
+
+import "fmt"
+
+const (
+	policyDefinitionsIdPrefix    = "/providers/Microsoft.Management/managementGroups/synth/providers/Microsoft.Authorization/policyDefinitions/"
+	policySetDefinitionsIdPrefix = "/providers/Microsoft.Management/managementGroups/synth/providers/Microsoft.Authorization/policySetDefinitions/"
+)
+
+// archetypeDefinition is a library archetype definition file.
+type archetypeDefinition struct {
+	Name                 string   `json:"name"`
+	PolicyAssignments    []string `json:"policy_assignments"`
+	PolicyDefinitions    []string `json:"policy_definitions"`
+	PolicySetDefinitions []string `json:"policy_set_definitions"`
+	RoleDefinitions      []string `json:"role_definitions"`
+}
+
+// assignmentName returns a policy assignment name, which is limited to 24 characters by ARM.
+func assignmentName(kind string, i int) string {
+	return fmt.Sprintf("Synth-%s-%05d", kind, i)
+}
+
+// effectParameter is the effect parameter used by the synthetic policy definitions and policy set definitions.
+func effectParameter() map[string]any {
+	return map[string]any{
+		"type":          "String",
+		"allowedValues": []string{"Audit", "Disabled"},
+		"defaultValue":  "Audit",
+		"metadata": map[string]any{
+			"displayName": "Effect",
+		},
+	}
+}
+
+// policyDefinition returns a policy definition that audits a synthetic resource type.
+func policyDefinition(i int) map[string]any {
+	return map[string]any{
+		"name": PolicyDefinitionName(i),
+		"type": "Microsoft.Authorization/policyDefinitions",
+		"properties": map[string]any{
+			"policyType":  "Custom",
+			"mode":        "All",
+			"displayName": fmt.Sprintf("Synthetic policy %d", i),
+			"description": "Synthetic policy definition for performance testing.",
+			"metadata": map[string]any{
+				"version":  "1.0.0",
+				"category": "Synthetic",
+			},
+			"parameters": map[string]any{
+				"effect": effectParameter(),
+			},
+			"policyRule": map[string]any{
+				"if": map[string]any{
+					"field":  "type",
+					"equals": fmt.Sprintf("Microsoft.Synthetic/resource%d", i),
+				},
+				"then": map[string]any{
+					"effect": "[parameters('effect')]",
+				},
+			},
+		},
+	}
+}
+
+// policySetDefinition returns a policy set definition that references the policy definitions.
+func policySetDefinition(name string, refs []string) map[string]any {
+	pds := make([]map[string]any, len(refs))
+	for i, ref := range refs {
+		pds[i] = map[string]any{
+			"policyDefinitionReferenceId": ref,
+			"policyDefinitionId":          policyDefinitionsIdPrefix + ref,
+			"parameters": map[string]any{
+				"effect": map[string]any{
+					"value": "[parameters('effect')]",
+				},
+			},
+			"groupNames": []string{},
+		}
+	}
+	return map[string]any{
+		"name": name,
+		"type": "Microsoft.Authorization/policySetDefinitions",
+		"properties": map[string]any{
+			"policyType":  "Custom",
+			"displayName": "Synthetic initiative " + name,
+			"description": "Synthetic policy set definition for performance testing.",
+			"metadata": map[string]any{
+				"version":  "1.0.0",
+				"category": "Synthetic",
+			},
+			"parameters": map[string]any{
+				"effect": effectParameter(),
+			},
+			"policyDefinitions": pds,
+		},
+	}
+}
+
+// policyAssignment returns a policy assignment of the definition, without a managed identity.
+func policyAssignment(name, definitionId string) map[string]any {
+	return map[string]any{
+		"type":     "Microsoft.Authorization/policyAssignments",
+		"name":     name,
+		"location": "${default_location}",
+		"properties": map[string]any{
+			"displayName":        "Synthetic assignment " + name,
+			"description":        "Synthetic policy assignment for performance testing.",
+			"policyDefinitionId": definitionId,
+			"enforcementMode":    "Default",
+			"parameters":         map[string]any{},
+			"scope":              "${current_scope_resource_id}",
+			"notScopes":          []string{},
+		},
+	}
+}
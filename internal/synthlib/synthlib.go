@@ -0,0 +1,163 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package synthlib generates synthetic ALZ libraries of a given size.
+// The libraries are valid input for alzlib and are used to measure the performance of the provider
+// with hierarchies that are larger than the ALZ library.
+package synthlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+)
+
+const (
+	// MaxObjects is the maximum number of objects of each kind.
+	MaxObjects = 100000
+
+	// policySetDefinitionSize is the maximum number of policy definitions in each policy set definition.
+	policySetDefinitionSize = 10
+)
+
+// Options are the sizes of the generated library.
+type Options struct {
+	Archetypes           int // the policy assignments are divided between the archetypes
+	PolicyDefinitions    int // each policy definition is assigned
+	PolicySetDefinitions int // each policy set definition is assigned, and references up to 10 policy definitions
+}
+
+// Validate returns an error if the options are out of range.
+func (o Options) Validate() error {
+	for name, v := range map[string]int{
+		"archetypes":             o.Archetypes,
+		"policy_definitions":     o.PolicyDefinitions,
+		"policy_set_definitions": o.PolicySetDefinitions,
+	} {
+		if v < 0 || v > MaxObjects {
+			return fmt.Errorf("%s must be between 0 and %d, got %d", name, MaxObjects, v)
+		}
+	}
+	if o.PolicySetDefinitions > 0 && o.PolicyDefinitions == 0 {
+		return fmt.Errorf("policy_definitions must be greater than 0 if policy_set_definitions is greater than 0")
+	}
+	if o.Archetypes == 0 && o.PolicyDefinitions+o.PolicySetDefinitions > 0 {
+		return fmt.Errorf("archetypes must be greater than 0 if the library has policy definitions")
+	}
+	return nil
+}
+
+// PolicyDefinitionName returns the name of the synthetic policy definition with index i.
+func PolicyDefinitionName(i int) string {
+	return fmt.Sprintf("Synth-Policy-%05d", i)
+}
+
+// PolicySetDefinitionName returns the name of the synthetic policy set definition with index i.
+func PolicySetDefinitionName(i int) string {
+	return fmt.Sprintf("Synth-Initiative-%05d", i)
+}
+
+// ArchetypeName returns the name of the synthetic archetype with index i.
+func ArchetypeName(i int) string {
+	return fmt.Sprintf("synth_%05d", i)
+}
+
+// Generate returns the library files, keyed by file name.
+func Generate(o Options) (map[string][]byte, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, 2*(o.PolicyDefinitions+o.PolicySetDefinitions)+o.Archetypes)
+	archetypes := make([]archetypeDefinition, o.Archetypes)
+	for i := range archetypes {
+		archetypes[i] = archetypeDefinition{
+			Name:                 ArchetypeName(i),
+			PolicyAssignments:    []string{},
+			PolicyDefinitions:    []string{},
+			PolicySetDefinitions: []string{},
+			RoleDefinitions:      []string{},
+		}
+	}
+	add := func(file string, v any) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling %s: %w", file, err)
+		}
+		res[file] = b
+		return nil
+	}
+
+	for i := 0; i < o.PolicyDefinitions; i++ {
+		name := PolicyDefinitionName(i)
+		arch := &archetypes[i%o.Archetypes]
+		arch.PolicyDefinitions = append(arch.PolicyDefinitions, name)
+		arch.PolicyAssignments = append(arch.PolicyAssignments, assignmentName("P", i))
+		if err := add("policy_definition_"+name+".json", policyDefinition(i)); err != nil {
+			return nil, err
+		}
+		if err := add("policy_assignment_"+assignmentName("P", i)+".json", policyAssignment(assignmentName("P", i), policyDefinitionsIdPrefix+name)); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < o.PolicySetDefinitions; i++ {
+		name := PolicySetDefinitionName(i)
+		arch := &archetypes[i%o.Archetypes]
+		arch.PolicySetDefinitions = append(arch.PolicySetDefinitions, name)
+		arch.PolicyAssignments = append(arch.PolicyAssignments, assignmentName("S", i))
+		// The referenced policy definitions must be in the same archetype, so that they are deployed at the same scope.
+		refs := make([]string, 0, policySetDefinitionSize)
+		for j := i % o.Archetypes; j < o.PolicyDefinitions && len(refs) < policySetDefinitionSize; j += o.Archetypes {
+			refs = append(refs, PolicyDefinitionName(j))
+		}
+		if len(refs) == 0 {
+			return nil, fmt.Errorf("archetype %s has no policy definitions for policy set definition %s, increase policy_definitions", arch.Name, name)
+		}
+		if err := add("policy_set_definition_"+name+".json", policySetDefinition(name, refs)); err != nil {
+			return nil, err
+		}
+		if err := add("policy_assignment_"+assignmentName("S", i)+".json", policyAssignment(assignmentName("S", i), policySetDefinitionsIdPrefix+name)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arch := range archetypes {
+		if err := add("archetype_definition_"+arch.Name+".json", arch); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// FS returns the generated library as a file system, for use in tests and benchmarks.
+func FS(o Options) (fs.FS, error) {
+	files, err := Generate(o)
+	if err != nil {
+		return nil, err
+	}
+	res := make(fstest.MapFS, len(files))
+	for k, v := range files {
+		res[k] = &fstest.MapFile{Data: v}
+	}
+	return res, nil
+}
+
+// Write writes the generated library to the directory, which is created if it does not exist.
+func Write(dir string, o Options) error {
+	files, err := Generate(o)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+	for k, v := range files {
+		if err := os.WriteFile(filepath.Join(dir, k), v, 0o644); err != nil {
+			return fmt.Errorf("error writing %s: %w", k, err)
+		}
+	}
+	return nil
+}
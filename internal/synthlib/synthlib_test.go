@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package synthlib_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/alzlib"
+	"github.com/Azure/alzlib/to"
+	"github.com/Azure/terraform-provider-alz/internal/synthlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	files, err := synthlib.Generate(synthlib.Options{Archetypes: 3, PolicyDefinitions: 20, PolicySetDefinitions: 4})
+	require.NoError(t, err)
+	// 20 definitions and assignments, 4 set definitions and assignments, 3 archetypes
+	assert.Len(t, files, 51)
+	assert.Contains(t, files, "policy_definition_Synth-Policy-00019.json")
+	assert.Contains(t, files, "policy_set_definition_Synth-Initiative-00003.json")
+	assert.Contains(t, files, "archetype_definition_synth_00002.json")
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	_, err := synthlib.Generate(synthlib.Options{Archetypes: 1, PolicyDefinitions: -1})
+	assert.ErrorContains(t, err, "policy_definitions must be between")
+
+	_, err = synthlib.Generate(synthlib.Options{Archetypes: 1, PolicySetDefinitions: 1})
+	assert.ErrorContains(t, err, "policy_definitions must be greater than 0")
+
+	_, err = synthlib.Generate(synthlib.Options{PolicyDefinitions: 1})
+	assert.ErrorContains(t, err, "archetypes must be greater than 0")
+
+	_, err = synthlib.Generate(synthlib.Options{Archetypes: 3, PolicyDefinitions: 1, PolicySetDefinitions: 2})
+	assert.ErrorContains(t, err, "has no policy definitions")
+}
+
+// TestFSAlzLib tests that the generated library is processed by alzlib and that the archetypes can be deployed.
+func TestFSAlzLib(t *testing.T) {
+	ctx := context.Background()
+	opts := synthlib.Options{Archetypes: 2, PolicyDefinitions: 10, PolicySetDefinitions: 2}
+	lib, err := synthlib.FS(opts)
+	require.NoError(t, err)
+
+	alz := alzlib.NewAlzLib()
+	require.NoError(t, alz.Init(ctx, lib))
+	wkpv := &alzlib.WellKnownPolicyValues{DefaultLocation: to.Ptr("westeurope")}
+	for i := 0; i < opts.Archetypes; i++ {
+		arch, err := alz.CopyArchetype(synthlib.ArchetypeName(i), wkpv)
+		require.NoError(t, err)
+		req := alzlib.AlzManagementGroupAddRequest{
+			Id:        fmt.Sprintf("mg%d", i),
+			ParentId:  "mg0",
+			Archetype: arch,
+		}
+		if i == 0 {
+			req.ParentId = "00000000-0000-0000-0000-000000000000"
+			req.ParentIsExternal = true
+		}
+		require.NoError(t, alz.AddManagementGroupToDeployment(ctx, req))
+	}
+	mg := alz.Deployment.GetManagementGroup("mg1")
+	require.NotNil(t, mg)
+	assert.Len(t, mg.GetPolicyAssignmentMap(), 6)
+	assert.Len(t, mg.GetPolicySetDefinitionsMap(), 1)
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "lib")
+	require.NoError(t, synthlib.Write(dir, synthlib.Options{Archetypes: 1, PolicyDefinitions: 1}))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}